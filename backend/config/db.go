@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"final-project/cems/entity"
+	"final-project/cems/utils"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -37,6 +38,28 @@ func SetupDatabase() {
 		&entity.ClubStatus{},
 		&entity.ClubCategory{},
 		&entity.ClubMember{},
+		&entity.ClubRole{},
+		&entity.OfficerTerm{},
+		&entity.ClubHandover{},
+		&entity.ClubHandoverOfficer{},
+		&entity.Election{},
+		&entity.ElectionPosition{},
+		&entity.ElectionCandidate{},
+		&entity.ElectionEndorsement{},
+		&entity.ElectionVoter{},
+		&entity.ClubBudget{},
+		&entity.ClubExpense{},
+		&entity.ClubExpenseApproval{},
+		&entity.ClubApplicationQuestion{},
+		&entity.ClubApplication{},
+		&entity.ClubApplicationAnswer{},
+		&entity.ClubApplicationNote{},
+		&entity.ClubInviteLink{},
+		&entity.ClubInvitation{},
+		&entity.ClubStatusChange{},
+		&entity.ClubRenewalCycle{},
+		&entity.ClubRenewal{},
+		&entity.ClubRenewalOfficer{},
 		&entity.Activity{},
 		&entity.ActivityStatus{},
 		&entity.EventCategory{},
@@ -45,18 +68,55 @@ func SetupDatabase() {
 		&entity.AttendanceLog{},
 		&entity.ActivityHour{},
 		&entity.ActivityReview{},
+		&entity.ReviewReport{},
+		&entity.Survey{},
+		&entity.SurveyQuestion{},
+		&entity.SurveyInvitation{},
+		&entity.SurveyResponse{},
+		&entity.SurveyAnswer{},
+		&entity.Revision{},
 		&entity.ActivityReport{},
 		&entity.MediaUpload{},
+		&entity.StoredImage{},
 		&entity.ClubAnnouncement{},
 		&entity.Faculty{},
 		&entity.Program{},
 		&entity.ActivityPhoto{},
 		&entity.University{},
 		&entity.PasswordReset{},
+		&entity.RegistrationQuestion{},
+		&entity.RegistrationAnswer{},
+		&entity.ActivityTeamSetting{},
+		&entity.Team{},
+		&entity.TeamMember{},
+		&entity.ActivityCheckinSetting{},
+		&entity.CheckinAttempt{},
+		&entity.ApprovalStep{},
+		&entity.ActivityApproval{},
+		&entity.ScheduledJob{},
+		&entity.JobRun{},
+		&entity.ReminderRule{},
+		&entity.ActivityReminderLog{},
+		&entity.ActivitySeries{},
+		&entity.ActivityScheduleChange{},
+		&entity.CalendarFeedToken{},
+		&entity.ActivityTemplate{},
+		&entity.ActivitySession{},
+		&entity.SessionAttendance{},
+		&entity.ActivityHost{},
+		&entity.Venue{},
+		&entity.VenueBooking{},
+		&entity.Resource{},
+		&entity.ResourceReservation{},
 	)
 	if err != nil {
 		log.Fatal("AutoMigrate error:", err)
 	}
+	// บัตรลงคะแนนเก็บในตาราง WITHOUT ROWID ที่เรียงตาม ReceiptHash (สุ่ม) เท่านั้น
+	// ตารางปกติของ SQLite มี rowid ตามลำดับการ insert ซึ่งจับคู่กับลำดับของ ElectionVoter ได้ ทำให้บัตรไม่เป็นความลับ
+	if err := db.Set("gorm:table_options", " WITHOUT ROWID").AutoMigrate(&entity.ElectionBallot{}); err != nil {
+		log.Fatal("AutoMigrate error:", err)
+	}
 
 	// Initial roles
 	roles := []entity.Role{
@@ -73,6 +133,7 @@ func SetupDatabase() {
 		{Name: "pending", Description: "รอการอนุมัติ", IsActive: true},
 		{Name: "approved", Description: "อนุมัติแล้ว", IsActive: true},
 		{Name: "suspended", Description: "ถูกระงับ", IsActive: false},
+		{Name: "archived", Description: "ยุบแล้ว (เก็บถาวร)", IsActive: false},
 	}
 	for _, s := range statuses {
 		db.FirstOrCreate(&s, entity.ClubStatus{Name: s.Name})
@@ -90,6 +151,60 @@ func SetupDatabase() {
 		db.FirstOrCreate(&s, entity.ActivityStatus{Name: s.Name})
 	}
 
+	// Initial approval chain (ตั้งค่าเริ่มต้นเมื่อยังไม่มีการกำหนด)
+	var stepCount int64
+	db.Model(&entity.ApprovalStep{}).Count(&stepCount)
+	if stepCount == 0 {
+		steps := []entity.ApprovalStep{
+			{StepOrder: 1, Name: "หัวหน้าชมรม", ApproverRole: "club_president"},
+			{StepOrder: 2, Name: "อาจารย์ที่ปรึกษาชมรม", ApproverRole: "club_advisor"},
+			{StepOrder: 3, Name: "ฝ่ายกิจการนักศึกษา", ApproverRole: "admin"},
+		}
+		for _, s := range steps {
+			db.Create(&s)
+		}
+	}
+
+	// Initial reminder rules (24 ชั่วโมง และ 1 ชั่วโมงก่อนเริ่มกิจกรรม)
+	var ruleCount int64
+	db.Model(&entity.ReminderRule{}).Count(&ruleCount)
+	if ruleCount == 0 {
+		for _, minutes := range []int{1440, 60} {
+			db.Create(&entity.ReminderRule{MinutesBefore: minutes, Enabled: true})
+		}
+	}
+
+	// Initial venues
+	var venueCount int64
+	db.Model(&entity.Venue{}).Count(&venueCount)
+	if venueCount == 0 {
+		venues := []entity.Venue{
+			{Name: "หอประชุมใหญ่", Building: "อาคารหอประชุม", Capacity: 1500, Facilities: `["projector","sound_system","stage","air_conditioner"]`, Latitude: 14.8818, Longitude: 102.0207, IsActive: true},
+			{Name: "ห้องคอมพิวเตอร์ 101", Building: "อาคารเรียนรวม 1", Room: "101", Capacity: 40, Facilities: `["computer","projector","air_conditioner"]`, Latitude: 14.8796, Longitude: 102.0168, IsActive: true},
+			{Name: "ห้องเอนกประสงค์ใหญ่", Building: "อาคารกิจการนักศึกษา", Capacity: 200, Facilities: `["projector","sound_system","air_conditioner"]`, Latitude: 14.8832, Longitude: 102.0185, IsActive: true},
+			{Name: "ลานกิจกรรมนักศึกษา", Building: "อาคารกิจการนักศึกษา", Capacity: 300, Facilities: `["sound_system","outdoor"]`, Latitude: 14.8835, Longitude: 102.0190, IsActive: true},
+			{Name: "ยิมเนเซียม", Building: "ศูนย์กีฬา", Capacity: 800, Facilities: `["sound_system","scoreboard"]`, Latitude: 14.8760, Longitude: 102.0230, IsActive: true},
+		}
+		for _, v := range venues {
+			db.Create(&v)
+		}
+	}
+
+	// Initial reservable resources (ของกองกิจการนักศึกษา)
+	var resourceCount int64
+	db.Model(&entity.Resource{}).Count(&resourceCount)
+	if resourceCount == 0 {
+		resources := []entity.Resource{
+			{Name: "โปรเจกเตอร์", Category: "projector", Quantity: 5, IsActive: true},
+			{Name: "ชุดเครื่องเสียงเคลื่อนที่", Category: "sound_system", Quantity: 3, IsActive: true},
+			{Name: "เต็นท์ 3x3 เมตร", Category: "tent", Quantity: 20, IsActive: true},
+			{Name: "ชุดอุปกรณ์กีฬา", Category: "sports", Quantity: 10, IsActive: true},
+		}
+		for _, r := range resources {
+			db.Create(&r)
+		}
+	}
+
 	// Initial activity registration statuses
 	regStatuses := []entity.ActivityRegistrationStatus{
 		{Name: "registered", Description: "ลงทะเบียนแล้ว", IsActive: true},
@@ -245,6 +360,13 @@ func SetupDatabase() {
 
 	setupClubAnnouncements()
 
+	setupClubRoles()
+
+	setupOfficerTerms()
+
+	setupClubApplications()
+	backfillActivityApprovedAt()
+
 	fmt.Println("Database setup completed successfully")
 }
 
@@ -1990,3 +2112,77 @@ func setupClubAnnouncements() {
 
 	fmt.Println("Club announcements setup completed")
 }
+
+// DefaultClubRoles บทบาทเริ่มต้นของชมรม ประธานแก้ไขสิทธิ์หรือลบได้ภายหลัง
+func DefaultClubRoles(clubID uint) []entity.ClubRole {
+	return []entity.ClubRole{
+		{ClubID: clubID, Name: "vice_president", DisplayName: "รองประธานชมรม", Permissions: `["manage_members","post_announcements","create_activities","view_member_pii"]`},
+		{ClubID: clubID, Name: "treasurer", DisplayName: "เหรัญญิก", Permissions: `["manage_budget","view_member_pii"]`},
+		{ClubID: clubID, Name: "secretary", DisplayName: "เลขานุการ", Permissions: `["manage_members","post_announcements","view_member_pii"]`},
+	}
+}
+
+// Setup บทบาทเริ่มต้นให้ทุกชมรม
+func setupClubRoles() {
+	var count int64
+	db.Model(&entity.ClubRole{}).Count(&count)
+	if count > 0 {
+		return
+	}
+
+	var clubs []entity.Club
+	db.Find(&clubs)
+	for _, club := range clubs {
+		for _, r := range DefaultClubRoles(club.ID) {
+			db.Create(&r)
+		}
+	}
+}
+
+// Setup วาระของกรรมการชุดปัจจุบัน โดยถือวันที่เข้าชมรมเป็นวันเริ่มวาระ
+func setupOfficerTerms() {
+	var count int64
+	db.Model(&entity.OfficerTerm{}).Count(&count)
+	if count > 0 {
+		return
+	}
+
+	var officers []entity.ClubMember
+	db.Where("role NOT IN ?", []string{"member", "pending"}).Find(&officers)
+	for _, m := range officers {
+		db.Create(&entity.OfficerTerm{
+			ClubID:       m.ClubID,
+			UserID:       m.UserID,
+			Role:         m.Role,
+			AcademicYear: utils.AcademicYearOf(time.Now()),
+			StartDate:    m.JoinedAt,
+		})
+	}
+}
+
+// ใบสมัครของสมาชิกที่รออนุมัติอยู่ก่อนมีระบบใบสมัคร เพื่อให้อยู่ในคิวพิจารณา
+func setupClubApplications() {
+	var count int64
+	db.Model(&entity.ClubApplication{}).Count(&count)
+	if count > 0 {
+		return
+	}
+
+	var pending []entity.ClubMember
+	db.Where("role = ?", "pending").Find(&pending)
+	for _, m := range pending {
+		db.Create(&entity.ClubApplication{
+			ClubID:      m.ClubID,
+			UserID:      m.UserID,
+			Status:      "pending",
+			SubmittedAt: m.JoinedAt,
+		})
+	}
+}
+
+// กิจกรรมที่อนุมัติก่อนมี ApprovedAt ใช้เวลาแก้ไขล่าสุดแทน เพื่อให้การพิจารณารอบใหม่รู้ว่ากิจกรรมเผยแพร่แล้ว
+func backfillActivityApprovedAt() {
+	db.Model(&entity.Activity{}).
+		Where("approved_at IS NULL AND status_id IN (?)", db.Model(&entity.ActivityStatus{}).Select("id").Where("name IN ?", []string{"approved", "finished"})).
+		Update("approved_at", gorm.Expr("updated_at"))
+}
@@ -0,0 +1,503 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var approverRoles = []string{"club_president", "club_advisor", "admin"}
+
+var (
+	errNotFound  = errors.New("not found")
+	errForbidden = errors.New("forbidden")
+)
+
+// สถานะกิจกรรมที่เปลี่ยนไปได้จากแต่ละสถานะ
+var activityStatusTransitions = map[string][]string{
+	"draft":     {"pending", "cancelled"},
+	"pending":   {"approved", "draft", "cancelled"},
+	"approved":  {"cancelled", "finished"},
+	"cancelled": {},
+	"finished":  {},
+}
+
+func canTransitionActivity(from, to string) bool {
+	return from == to || slices.Contains(activityStatusTransitions[from], to)
+}
+
+func getActivityStatusByName(db *gorm.DB, name string) (*entity.ActivityStatus, error) {
+	var status entity.ActivityStatus
+	if err := db.Where("name = ?", name).First(&status).Error; err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func loadApprovalChain(db *gorm.DB) ([]entity.ApprovalStep, error) {
+	var steps []entity.ApprovalStep
+	err := db.Order("step_order ASC").Find(&steps).Error
+	return steps, err
+}
+
+// เริ่มรอบการพิจารณาใหม่ให้กิจกรรม ถ้าสายอนุมัติว่างจะอนุมัติทันที
+func startActivityApproval(db *gorm.DB, activity *entity.Activity, submitterID uint) error {
+	steps, err := loadApprovalChain(db)
+	if err != nil {
+		return err
+	}
+
+	statusName := "pending"
+	activity.ApprovalStep = nextApprovalStep(db, steps, 0, activity)
+	if activity.ApprovalStep == 0 {
+		statusName = "approved"
+	}
+	status, err := getActivityStatusByName(db, statusName)
+	if err != nil {
+		return err
+	}
+
+	activity.StatusID = status.ID
+	if statusName == "approved" && activity.ApprovedAt == nil {
+		now := time.Now()
+		activity.ApprovedAt = &now
+	}
+	activity.ApprovalRound++
+	if submitterID != 0 {
+		activity.SubmittedBy = submitterID
+	}
+	return nil
+}
+
+// ฟิลด์ที่ผู้อนุมัติพิจารณา (ชื่อ วันเวลา เนื้อหา สถานที่) ถ้าเปลี่ยนหลังอนุมัติแล้วต้องเริ่มรอบการพิจารณาใหม่
+func approvalFieldsChanged(before, after activityRevisionData) bool {
+	sameVenue := (before.VenueID == nil && after.VenueID == nil) ||
+		(before.VenueID != nil && after.VenueID != nil && *before.VenueID == *after.VenueID)
+	return before.Title != after.Title ||
+		before.Description != after.Description ||
+		before.Location != after.Location ||
+		!before.DateStart.Equal(after.DateStart) ||
+		!before.DateEnd.Equal(after.DateEnd) ||
+		!sameVenue
+}
+
+// กิจกรรมที่อนุมัติแล้วและถูกแก้ไขฟิลด์ที่ผ่านการพิจารณา จะกลับเป็น pending ในรอบการพิจารณาใหม่
+// คืน true ถ้าเริ่มรอบใหม่ (activity ถูกปรับสถานะแล้ว ผู้เรียกต้องบันทึกเอง)
+func restartApprovalIfChanged(db *gorm.DB, activity *entity.Activity, before activityRevisionData, submitterID uint) (bool, error) {
+	approved, err := getActivityStatusByName(db, "approved")
+	if err != nil {
+		return false, err
+	}
+	if activity.StatusID != approved.ID || !approvalFieldsChanged(before, activityRevisionOf(activity)) {
+		return false, nil
+	}
+	if err := checkClubActive(db, activity.ClubID); err != nil {
+		return false, err
+	}
+	return true, startActivityApproval(db, activity, submitterID)
+}
+
+// หาขั้นถัดไปตั้งแต่ steps[from] ที่มีผู้อนุมัติได้จริง ชมรมที่ไม่มีอาจารย์ที่ปรึกษาจะข้ามขั้นนั้นไป
+// คืน 0 ถ้าไม่เหลือขั้นให้พิจารณาแล้ว
+func nextApprovalStep(db *gorm.DB, steps []entity.ApprovalStep, from int, activity *entity.Activity) int {
+	for _, step := range steps[min(from, len(steps)):] {
+		if step.ApproverRole == "club_advisor" {
+			var count int64
+			db.Model(&entity.ClubMember{}).
+				Where("club_id = ? AND role = ?", activity.ClubID, "advisor").
+				Count(&count)
+			if count == 0 {
+				continue
+			}
+		}
+		return step.StepOrder
+	}
+	return 0
+}
+
+// คืน true ถ้า user เป็นผู้อนุมัติของขั้นนี้สำหรับกิจกรรมนี้
+func canApproveStep(db *gorm.DB, user *entity.User, step entity.ApprovalStep, activity *entity.Activity) (bool, error) {
+	switch step.ApproverRole {
+	case "club_president":
+		return hasClubRole(db, user.ID, activity.ClubID, "president")
+	case "club_advisor":
+		return hasClubRole(db, user.ID, activity.ClubID, "advisor")
+	case "admin":
+		return isAdmin(db, user), nil
+	}
+	return false, nil
+}
+
+// ผู้ส่งขออนุมัติ ถ้าไม่ทราบให้แจ้งหัวหน้าชมรมแทน
+func activitySubmitterID(db *gorm.DB, activity *entity.Activity) uint {
+	if activity.SubmittedBy != 0 {
+		return activity.SubmittedBy
+	}
+	var club entity.Club
+	if err := db.First(&club, activity.ClubID).Error; err == nil {
+		return club.CreatedBy
+	}
+	return 0
+}
+
+// GET /approval-chain - ดูสายการอนุมัติกิจกรรม
+func GetApprovalChain(c *gin.Context) {
+	steps, err := loadApprovalChain(config.DB())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงสายการอนุมัติได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": steps})
+}
+
+// PUT /approval-chain - กำหนดสายการอนุมัติใหม่ (admin เท่านั้น)
+func UpdateApprovalChain(c *gin.Context) {
+	db := config.DB()
+	if _, err := requireAdmin(c); err != nil {
+		return
+	}
+
+	var input struct {
+		Steps []struct {
+			Name         string `json:"name"`
+			ApproverRole string `json:"approver_role"`
+		} `json:"steps"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	// สายว่างจะทำให้กิจกรรมที่ส่งขออนุมัติผ่านทันทีโดยไม่มีใครพิจารณา
+	if len(input.Steps) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "สายการอนุมัติต้องมีอย่างน้อย 1 ขั้น"})
+		return
+	}
+	for i, s := range input.Steps {
+		if strings.TrimSpace(s.Name) == "" || !slices.Contains(approverRoles, s.ApproverRole) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ขั้นที่ %d ไม่ถูกต้อง", i+1)})
+			return
+		}
+	}
+
+	// กิจกรรมที่ค้างอยู่ระหว่างพิจารณาจะอ้างอิงลำดับขั้นเดิม จึงไม่ให้แก้ระหว่างนั้น
+	var pendingCount int64
+	db.Model(&entity.Activity{}).
+		Joins("JOIN activity_statuses ON activity_statuses.id = activities.status_id").
+		Where("activity_statuses.name = ?", "pending").
+		Count(&pendingCount)
+	if pendingCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "มีกิจกรรมรอการอนุมัติอยู่ ไม่สามารถแก้ไขสายการอนุมัติได้"})
+		return
+	}
+
+	var steps []entity.ApprovalStep
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&entity.ApprovalStep{}).Error; err != nil {
+			return err
+		}
+		for i, s := range input.Steps {
+			step := entity.ApprovalStep{
+				StepOrder:    i + 1,
+				Name:         strings.TrimSpace(s.Name),
+				ApproverRole: s.ApproverRole,
+			}
+			if err := tx.Create(&step).Error; err != nil {
+				return err
+			}
+			steps = append(steps, step)
+		}
+		return nil
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกสายการอนุมัติไม่สำเร็จ"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "บันทึกสายการอนุมัติสำเร็จ", "data": steps})
+}
+
+// POST /activities/:id/submit - ส่งกิจกรรมเข้าสู่การพิจารณา (officer เท่านั้น)
+func SubmitActivityForApproval(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.Preload("Status").First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	user, err := requireActivityOfficer(c, &activity)
+	if err != nil {
+		return
+	}
+	if activity.Status.Name != "draft" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ส่งขออนุมัติได้เฉพาะกิจกรรมที่เป็นแบบร่าง"})
+		return
+	}
+	if !requireClubActive(c, activity.ClubID) {
+		return
+	}
+
+	if err := startActivityApproval(db, &activity, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถเริ่มการพิจารณาได้"})
+		return
+	}
+	if err := db.Model(&entity.Activity{}).Where("id = ?", activity.ID).Updates(map[string]interface{}{
+		"status_id":      activity.StatusID,
+		"approval_round": activity.ApprovalRound,
+		"approval_step":  activity.ApprovalStep,
+		"submitted_by":   activity.SubmittedBy,
+		"approved_at":    activity.ApprovedAt,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถส่งขออนุมัติได้"})
+		return
+	}
+	db.Preload("Status").First(&activity, activity.ID)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ส่งขออนุมัติกิจกรรมเรียบร้อยแล้ว", "data": activity})
+}
+
+// POST /activities/:id/approval - ผู้อนุมัติขั้นปัจจุบันตัดสิน approve / reject / request_changes
+func DecideActivityApproval(c *gin.Context) {
+	db := config.DB()
+
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input struct {
+		Action  string `json:"action" binding:"required"`
+		Comment string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	decisions := map[string]string{
+		"approve":         "approved",
+		"reject":          "rejected",
+		"request_changes": "changes_requested",
+	}
+	decision, ok := decisions[input.Action]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action ต้องเป็น approve, reject หรือ request_changes"})
+		return
+	}
+	if decision != "approved" && strings.TrimSpace(input.Comment) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุเหตุผลประกอบการพิจารณา"})
+		return
+	}
+
+	var activity entity.Activity
+	var stepName string
+	var finalStatus string
+	var registrants []entity.User
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("Status").First(&activity, c.Param("id")).Error; err != nil {
+			return errNotFound
+		}
+		if activity.Status.Name != "pending" || activity.ApprovalStep == 0 {
+			return errors.New("กิจกรรมนี้ไม่ได้อยู่ระหว่างการพิจารณา")
+		}
+
+		steps, err := loadApprovalChain(tx)
+		if err != nil {
+			return err
+		}
+		idx := slices.IndexFunc(steps, func(s entity.ApprovalStep) bool { return s.StepOrder == activity.ApprovalStep })
+		if idx < 0 {
+			return errors.New("ไม่พบขั้นการอนุมัติปัจจุบัน")
+		}
+		step := steps[idx]
+		stepName = step.Name
+
+		// ชมรมที่ถูกระงับหรือยุบแล้วจะไม่ได้รับอนุมัติกิจกรรม (ยังปฏิเสธหรือขอให้แก้ไขได้)
+		if decision == "approved" {
+			if err := checkClubActive(tx, activity.ClubID); err != nil {
+				return err
+			}
+		}
+
+		allowed, err := canApproveStep(tx, user, step, &activity)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return errForbidden
+		}
+
+		if err := tx.Create(&entity.ActivityApproval{
+			ActivityID: activity.ID,
+			Round:      activity.ApprovalRound,
+			StepOrder:  step.StepOrder,
+			StepName:   step.Name,
+			ApproverID: user.ID,
+			Decision:   decision,
+			Comment:    strings.TrimSpace(input.Comment),
+			DecidedAt:  time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		nextStep := 0
+		switch decision {
+		case "approved":
+			finalStatus = "pending"
+			nextStep = nextApprovalStep(tx, steps, idx+1, &activity)
+			if nextStep == 0 {
+				finalStatus = "approved"
+			}
+		case "rejected":
+			finalStatus = "cancelled"
+		case "changes_requested":
+			finalStatus = "draft"
+		}
+
+		if !canTransitionActivity(activity.Status.Name, finalStatus) {
+			return fmt.Errorf("ไม่สามารถเปลี่ยนสถานะจาก %s เป็น %s ได้", activity.Status.Name, finalStatus)
+		}
+		// ผู้ลงทะเบียนต้องได้รับแจ้งเมื่อกิจกรรมถูกยกเลิก หรือเมื่อกิจกรรมที่เผยแพร่แล้วถูกส่งกลับไปแก้ไข
+		if finalStatus == "cancelled" || (finalStatus == "draft" && activity.ApprovedAt != nil) {
+			if registrants, err = registeredUsersOf(tx, activity.ID); err != nil {
+				return err
+			}
+		}
+		// กิจกรรมที่ไม่ได้รับอนุมัติถูกยกเลิกแบบเดียวกับการยกเลิกปกติ (การลงทะเบียน สถานที่ อุปกรณ์ ประวัติ และปฏิทิน)
+		if finalStatus == "cancelled" {
+			return markActivityCancelled(tx, &activity, rejectionReason(stepName, input.Comment), len(registrants), user.ID)
+		}
+
+		status, err := getActivityStatusByName(tx, finalStatus)
+		if err != nil {
+			return err
+		}
+		updates := map[string]interface{}{
+			"status_id":     status.ID,
+			"approval_step": nextStep,
+		}
+		if finalStatus == "approved" && activity.ApprovedAt == nil {
+			updates["approved_at"] = time.Now()
+		}
+		// ขอให้แก้ไขกิจกรรมที่เผยแพร่แล้ว: การลงทะเบียนยังคงอยู่ระหว่างที่ officer แก้ไขและส่งใหม่
+		// เพิ่ม sequence เพื่อให้ปฏิทินของผู้ติดตามแสดงกิจกรรมเป็นรอยืนยัน
+		if finalStatus == "draft" && activity.ApprovedAt != nil {
+			updates["calendar_sequence"] = gorm.Expr("calendar_sequence + 1")
+		}
+		return tx.Model(&entity.Activity{}).Where("id = ?", activity.ID).Updates(updates).Error
+	})
+	switch {
+	case errors.Is(err, errNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	case errors.Is(err, errForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden: not the approver of this step"})
+		return
+	case errors.Is(err, errClubSuspended), errors.Is(err, errClubArchived):
+		respondClubStateError(c, err)
+		return
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var message string
+	switch decision {
+	case "approved":
+		if finalStatus == "approved" {
+			message = fmt.Sprintf("กิจกรรม \"%s\" ได้รับการอนุมัติครบทุกขั้นแล้ว", activity.Title)
+		} else {
+			message = fmt.Sprintf("กิจกรรม \"%s\" ผ่านการพิจารณาขั้น %s แล้ว", activity.Title, stepName)
+		}
+	case "rejected":
+		message = fmt.Sprintf("กิจกรรม \"%s\" ไม่ได้รับการอนุมัติจาก%s: %s", activity.Title, stepName, input.Comment)
+	case "changes_requested":
+		message = fmt.Sprintf("%sขอให้แก้ไขกิจกรรม \"%s\": %s", stepName, activity.Title, input.Comment)
+	}
+	notificationType := "success"
+	if decision != "approved" {
+		notificationType = "warning"
+	}
+	if submitterID := activitySubmitterID(db, &activity); submitterID != 0 {
+		if err := getNotificationService().CreateNotification(submitterID, message, notificationType); err != nil {
+			fmt.Println("❌ Error creating approval notification:", err)
+		}
+	}
+	switch finalStatus {
+	case "cancelled":
+		notifyActivityCancelled(&activity, registrants, rejectionReason(stepName, input.Comment))
+	case "draft":
+		notifyActivityChange(&activity, registrants, "✏️ กิจกรรมอยู่ระหว่างแก้ไข",
+			fmt.Sprintf("กิจกรรม '%s' อยู่ระหว่างแก้ไขรายละเอียดตามที่ผู้พิจารณาขอ การลงทะเบียนของคุณยังคงอยู่ รายละเอียดอาจเปลี่ยนแปลง", activity.Title),
+			strings.TrimSpace(input.Comment), nil)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "บันทึกผลการพิจารณาแล้ว", "status": finalStatus})
+}
+
+// เหตุผลการยกเลิกที่แจ้งผู้ลงทะเบียนเมื่อกิจกรรมไม่ได้รับอนุมัติ
+func rejectionReason(stepName, comment string) string {
+	return fmt.Sprintf("ไม่ได้รับการอนุมัติจาก%s: %s", stepName, strings.TrimSpace(comment))
+}
+
+// GET /activities/:id/approvals - ประวัติการพิจารณาของกิจกรรม
+func GetActivityApprovals(c *gin.Context) {
+	var approvals []entity.ActivityApproval
+	if err := config.DB().
+		Preload("Approver").
+		Where("activity_id = ?", c.Param("id")).
+		Order("round ASC, step_order ASC, decided_at ASC").
+		Find(&approvals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงประวัติการพิจารณาได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": approvals})
+}
+
+// GET /approvals/pending - กิจกรรมที่รอให้ผู้ใช้ปัจจุบันพิจารณา
+func GetPendingApprovals(c *gin.Context) {
+	db := config.DB()
+
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	steps, err := loadApprovalChain(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงสายการอนุมัติได้"})
+		return
+	}
+
+	var activities []entity.Activity
+	if err := db.Preload("Club").Preload("Status").
+		Joins("JOIN activity_statuses ON activity_statuses.id = activities.status_id").
+		Where("activity_statuses.name = ? AND activities.approval_step > 0", "pending").
+		Order("activities.updated_at ASC").
+		Find(&activities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงกิจกรรมได้"})
+		return
+	}
+
+	result := make([]entity.Activity, 0)
+	for _, a := range activities {
+		idx := slices.IndexFunc(steps, func(s entity.ApprovalStep) bool { return s.StepOrder == a.ApprovalStep })
+		if idx < 0 {
+			continue
+		}
+		if ok, _ := canApproveStep(db, user, steps[idx], &a); ok {
+			result = append(result, a)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": result})
+}
@@ -0,0 +1,428 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+	"final-project/cems/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ระยะเวลายืนยันเริ่มต้นหลังเลื่อนกิจกรรม (ถ้ากิจกรรมเริ่มก่อนจะใช้เวลาเริ่มกิจกรรมแทน)
+const defaultReconfirmWindow = 72 * time.Hour
+
+// แจ้งผู้ลงทะเบียนทั้งในระบบและทางอีเมลเมื่อกิจกรรมถูกยกเลิกหรือเลื่อน
+func notifyActivityChange(activity *entity.Activity, users []entity.User, heading, message, reason string, deadline *time.Time) {
+	deadlineText := ""
+	if deadline != nil {
+		deadlineText = formatThaiDateTime(*deadline)
+	}
+	htmlBody, err := services.RenderTemplate("activity_changed.html", map[string]string{
+		"Heading":       heading,
+		"Message":       message,
+		"ActivityTitle": activity.Title,
+		"StartTime":     formatThaiDateTime(activity.DateStart),
+		"Location":      activity.Location,
+		"Reason":        reason,
+		"Deadline":      deadlineText,
+	})
+	if err != nil {
+		fmt.Println("❌ Error rendering activity change email:", err)
+	}
+
+	for _, u := range users {
+		if err := getNotificationService().CreateNotification(u.ID, message, "warning"); err != nil {
+			fmt.Println("❌ Error creating activity change notification:", err)
+		}
+		if htmlBody != "" && u.Email != "" {
+			go services.SendEmailHTML(u.Email, heading+" "+activity.Title, htmlBody)
+		}
+	}
+}
+
+// cancelActivity ยกเลิกกิจกรรม ยกเลิกการลงทะเบียนที่ยังค้างทั้งหมด บันทึกประวัติ และแจ้งผู้ลงทะเบียน
+func cancelActivity(db *gorm.DB, activity *entity.Activity, reason string, changedBy uint) (int, error) {
+	users, err := registeredUsersOf(db, activity.ID)
+	if err != nil {
+		return 0, err
+	}
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return markActivityCancelled(tx, activity, reason, len(users), changedBy)
+	}); err != nil {
+		return 0, err
+	}
+	notifyActivityCancelled(activity, users, reason)
+	return len(users), nil
+}
+
+// markActivityCancelled ยกเลิกกิจกรรมภายใน transaction ยกเลิกการลงทะเบียน คืนสถานที่และอุปกรณ์ และบันทึกประวัติ
+// ผู้เรียกต้องแจ้งผู้ลงทะเบียนด้วย notifyActivityCancelled หลัง commit
+func markActivityCancelled(tx *gorm.DB, activity *entity.Activity, reason string, affected int, changedBy uint) error {
+	cancelled, err := getActivityStatusByName(tx, "cancelled")
+	if err != nil {
+		return err
+	}
+	registeredID, err := getRegistrationStatusID(tx, "registered")
+	if err != nil {
+		return err
+	}
+	regCancelledID, err := getRegistrationStatusID(tx, "cancelled")
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Model(&entity.Activity{}).Where("id = ?", activity.ID).
+		Updates(map[string]interface{}{
+			"status_id":         cancelled.ID,
+			"approval_step":     0,
+			"calendar_sequence": gorm.Expr("calendar_sequence + 1"),
+		}).Error; err != nil {
+		return err
+	}
+	activity.StatusID = cancelled.ID
+	activity.ApprovalStep = 0
+	if err := tx.Model(&entity.ActivityRegistration{}).
+		Where("activity_id = ? AND status_id = ?", activity.ID, registeredID).
+		Updates(map[string]interface{}{"status_id": regCancelledID, "reconfirm_by": nil}).Error; err != nil {
+		return err
+	}
+	if err := releaseActivityVenue(tx, activity.ID); err != nil {
+		return err
+	}
+	if err := cancelActivityResourceReservations(tx, activity.ID); err != nil {
+		return err
+	}
+	return tx.Create(&entity.ActivityScheduleChange{
+		ActivityID:    activity.ID,
+		Kind:          "cancel",
+		Reason:        reason,
+		OldDateStart:  activity.DateStart,
+		OldDateEnd:    activity.DateEnd,
+		AffectedCount: affected,
+		ChangedBy:     changedBy,
+	}).Error
+}
+
+// แจ้งผู้ลงทะเบียนว่ากิจกรรมถูกยกเลิก
+func notifyActivityCancelled(activity *entity.Activity, users []entity.User, reason string) {
+	message := fmt.Sprintf("กิจกรรม '%s' วันที่ %s ถูกยกเลิก เนื่องจาก %s", activity.Title, formatThaiDateTime(activity.DateStart), reason)
+	notifyActivityChange(activity, users, "❌ ยกเลิกกิจกรรม", message, reason, nil)
+}
+
+// POST /activities/:id/cancel - ยกเลิกกิจกรรมพร้อมเหตุผล (officer เท่านั้น)
+func CancelActivity(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.Preload("Status").First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	user, err := requireActivityOfficer(c, &activity)
+	if err != nil {
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุเหตุผลในการยกเลิก"})
+		return
+	}
+	if activity.Status.Name == "cancelled" || !canTransitionActivity(activity.Status.Name, "cancelled") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่สามารถยกเลิกกิจกรรมในสถานะ " + activity.Status.Name + " ได้"})
+		return
+	}
+
+	affected, err := cancelActivity(db, &activity, strings.TrimSpace(input.Reason), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ยกเลิกกิจกรรมไม่สำเร็จ"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ยกเลิกกิจกรรมเรียบร้อยแล้ว", "affected": affected})
+}
+
+// POST /activities/:id/reschedule - เลื่อนกิจกรรม ผู้ลงทะเบียนต้องยืนยันใหม่ภายใน reconfirm_deadline
+func RescheduleActivity(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.Preload("Status").First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	user, err := requireActivityOfficer(c, &activity)
+	if err != nil {
+		return
+	}
+	if !requireClubActive(c, activity.ClubID) {
+		return
+	}
+
+	var input struct {
+		Reason            string     `json:"reason"`
+		DateStart         time.Time  `json:"date_start" binding:"required"`
+		DateEnd           time.Time  `json:"date_end" binding:"required"`
+		ReconfirmDeadline *time.Time `json:"reconfirm_deadline"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	reason := strings.TrimSpace(input.Reason)
+	if reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุเหตุผลในการเลื่อนกิจกรรม"})
+		return
+	}
+	if activity.Status.Name == "cancelled" || activity.Status.Name == "finished" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่สามารถเลื่อนกิจกรรมในสถานะ " + activity.Status.Name + " ได้"})
+		return
+	}
+
+	now := time.Now()
+	if !input.DateStart.After(now) || !input.DateEnd.After(input.DateStart) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "วันเวลาใหม่ต้องอยู่ในอนาคต และเวลาสิ้นสุดต้องอยู่หลังเวลาเริ่ม"})
+		return
+	}
+	deadline := defaultReconfirmDeadline(now, input.DateStart)
+	if input.ReconfirmDeadline != nil {
+		deadline = *input.ReconfirmDeadline
+	}
+	if !deadline.After(now) || deadline.After(input.DateStart) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กำหนดยืนยันต้องอยู่ระหว่างตอนนี้ถึงเวลาเริ่มกิจกรรมใหม่"})
+		return
+	}
+
+	users, err := registeredUsersOf(db, activity.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงรายชื่อผู้ลงทะเบียนได้"})
+		return
+	}
+
+	oldStart := activity.DateStart
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if activity.SeriesID != nil {
+			if err := tx.Model(&entity.Activity{}).Where("id = ?", activity.ID).Update("series_exception", true).Error; err != nil {
+				return err
+			}
+		}
+		return rescheduleActivity(tx, &activity, input.DateStart, input.DateEnd, deadline, reason, len(users), user.ID)
+	})
+	if err != nil {
+		if respondVenueError(c, err) {
+			return
+		}
+		if errors.Is(err, errSessionSpanChanged) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "เลื่อนกิจกรรมไม่สำเร็จ"})
+		return
+	}
+
+	notifyActivityRescheduled(&activity, users, oldStart, reason, deadline)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":            true,
+		"message":            "เลื่อนกิจกรรมเรียบร้อยแล้ว",
+		"affected":           len(users),
+		"reconfirm_deadline": deadline,
+	})
+}
+
+// กำหนดยืนยันเริ่มต้นหลังเลื่อนกิจกรรม ไม่เกินเวลาเริ่มกิจกรรมใหม่
+func defaultReconfirmDeadline(now, newStart time.Time) time.Time {
+	deadline := now.Add(defaultReconfirmWindow)
+	if deadline.After(newStart) {
+		deadline = newStart
+	}
+	return deadline
+}
+
+// กิจกรรมที่แบ่งรอบมีช่วงเวลาตามรอบ การเลื่อนทั้งกิจกรรมจึงต้องคงระยะเวลาเดิม
+var errSessionSpanChanged = errors.New("กิจกรรมที่แบ่งเป็นหลายรอบเลื่อนได้โดยคงระยะเวลาเดิมเท่านั้น หากต้องการเปลี่ยนช่วงเวลาให้แก้ไขที่รอบกิจกรรม")
+
+// rescheduleActivity ย้ายวันเวลากิจกรรมและรอบย่อยทั้งหมดภายใน transaction ย้ายการจองสถานที่ อุปกรณ์ และช่วงเช็คชื่อ
+// ล้างประวัติการเตือนตามรอบ ให้ผู้ลงทะเบียนยืนยันใหม่ภายใน deadline และบันทึกประวัติการเลื่อน
+// activity จะถูกปรับเป็นวันเวลาใหม่ ผู้เรียกต้องแจ้งผู้ลงทะเบียนด้วย notifyActivityRescheduled หลัง commit
+func rescheduleActivity(tx *gorm.DB, activity *entity.Activity, newStart, newEnd, deadline time.Time, reason string, affected int, changedBy uint) error {
+	shifted, err := shiftActivitySessions(tx, activity.ID, newStart.Sub(activity.DateStart))
+	if err != nil {
+		return err
+	}
+	if shifted > 0 && newEnd.Sub(newStart) != activity.DateEnd.Sub(activity.DateStart) {
+		return errSessionSpanChanged
+	}
+	return applyActivityReschedule(tx, activity, newStart, newEnd, deadline, reason, affected, changedBy)
+}
+
+// เลื่อนทุกรอบของกิจกรรมไปเท่ากับ offset คืนจำนวนรอบที่เลื่อน
+func shiftActivitySessions(tx *gorm.DB, activityID uint, offset time.Duration) (int, error) {
+	var sessions []entity.ActivitySession
+	if err := tx.Where("activity_id = ?", activityID).Find(&sessions).Error; err != nil {
+		return 0, err
+	}
+	if offset == 0 {
+		return len(sessions), nil
+	}
+	for i := range sessions {
+		if err := tx.Model(&sessions[i]).Updates(map[string]interface{}{
+			"date_start": sessions[i].DateStart.Add(offset),
+			"date_end":   sessions[i].DateEnd.Add(offset),
+		}).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(sessions), nil
+}
+
+// applyActivityReschedule ทำส่วนที่เหลือของการเลื่อนหลังรอบย่อยอยู่ในวันเวลาใหม่แล้ว
+// (การแก้รอบเรียกตรงนี้เพราะรอบถูกบันทึกไปก่อนแล้ว)
+func applyActivityReschedule(tx *gorm.DB, activity *entity.Activity, newStart, newEnd, deadline time.Time, reason string, affected int, changedBy uint) error {
+	oldStart, oldEnd := activity.DateStart, activity.DateEnd
+	if err := tx.Model(&entity.Activity{}).Where("id = ?", activity.ID).Updates(map[string]interface{}{
+		"date_start":        newStart,
+		"date_end":          newEnd,
+		"calendar_sequence": gorm.Expr("calendar_sequence + 1"),
+	}).Error; err != nil {
+		return err
+	}
+	activity.DateStart, activity.DateEnd = newStart, newEnd
+	if err := bookActivityVenue(tx, activity, changedBy); err != nil {
+		return err
+	}
+	if err := shiftActivityResourceReservations(tx, activity.ID, newStart.Sub(oldStart), newEnd.Sub(oldEnd)); err != nil {
+		return err
+	}
+	if err := shiftCheckinWindow(tx, activity.ID, newStart.Sub(oldStart), newEnd.Sub(oldEnd)); err != nil {
+		return err
+	}
+	// การเตือนตามรอบกันส่งซ้ำด้วย activity/user/kind/minutes_before ต้องล้างเพื่อให้เตือนตามวันเวลาใหม่
+	if err := tx.Where("activity_id = ? AND kind = ?", activity.ID, "scheduled").Delete(&entity.ActivityReminderLog{}).Error; err != nil {
+		return err
+	}
+
+	registeredID, err := getRegistrationStatusID(tx, "registered")
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(&entity.ActivityRegistration{}).
+		Where("activity_id = ? AND status_id = ?", activity.ID, registeredID).
+		Updates(map[string]interface{}{"reconfirm_by": deadline, "reconfirmed_at": nil}).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(&entity.ActivityScheduleChange{
+		ActivityID:        activity.ID,
+		Kind:              "reschedule",
+		Reason:            reason,
+		OldDateStart:      oldStart,
+		OldDateEnd:        oldEnd,
+		NewDateStart:      &newStart,
+		NewDateEnd:        &newEnd,
+		ReconfirmDeadline: &deadline,
+		AffectedCount:     affected,
+		ChangedBy:         changedBy,
+	}).Error
+}
+
+// แจ้งผู้ลงทะเบียนว่ากิจกรรมถูกเลื่อน (activity ต้องเป็นวันเวลาใหม่แล้ว)
+func notifyActivityRescheduled(activity *entity.Activity, users []entity.User, oldStart time.Time, reason string, deadline time.Time) {
+	message := fmt.Sprintf("กิจกรรม '%s' ถูกเลื่อนจาก %s เป็น %s กรุณายืนยันการเข้าร่วมภายใน %s",
+		activity.Title, formatThaiDateTime(oldStart), formatThaiDateTime(activity.DateStart), formatThaiDateTime(deadline))
+	notifyActivityChange(activity, users, "📅 เลื่อนกิจกรรม", message, reason, &deadline)
+}
+
+// POST /activities/:id/reconfirm - ผู้ลงทะเบียนยืนยันว่าจะเข้าร่วมในวันเวลาใหม่
+func ReconfirmRegistration(c *gin.Context) {
+	db := config.DB()
+
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	registeredID, err := getRegistrationStatusID(db, "registered")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	var reg entity.ActivityRegistration
+	if err := db.Where("activity_id = ? AND user_id = ? AND status_id = ?", c.Param("id"), user.ID, registeredID).
+		First(&reg).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบการลงทะเบียนที่ยังใช้งานอยู่"})
+		return
+	}
+	if reg.ReconfirmBy == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "การลงทะเบียนนี้ไม่ต้องยืนยันใหม่"})
+		return
+	}
+	if time.Now().After(*reg.ReconfirmBy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "เลยกำหนดยืนยันแล้ว"})
+		return
+	}
+
+	now := time.Now()
+	if err := db.Model(&reg).Updates(map[string]interface{}{"reconfirm_by": nil, "reconfirmed_at": now}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ยืนยันไม่สำเร็จ"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ยืนยันการเข้าร่วมเรียบร้อยแล้ว"})
+}
+
+// GET /activities/:id/changes - ประวัติการยกเลิก/เลื่อนกิจกรรม
+func GetActivityScheduleChanges(c *gin.Context) {
+	var changes []entity.ActivityScheduleChange
+	if err := config.DB().
+		Where("activity_id = ?", c.Param("id")).
+		Order("created_at DESC").
+		Find(&changes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงประวัติได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": changes})
+}
+
+// งานตามตาราง: ยกเลิกการลงทะเบียนของผู้ที่ไม่ยืนยันภายในกำหนดหลังกิจกรรมถูกเลื่อน
+func releaseUnconfirmedRegistrations(db *gorm.DB) error {
+	registeredID, err := getRegistrationStatusID(db, "registered")
+	if err != nil {
+		return err
+	}
+	cancelledID, err := getRegistrationStatusID(db, "cancelled")
+	if err != nil {
+		return err
+	}
+
+	var regs []entity.ActivityRegistration
+	if err := db.Preload("Activity").
+		Where("status_id = ? AND reconfirm_by IS NOT NULL AND reconfirm_by < ?", registeredID, time.Now()).
+		Find(&regs).Error; err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, reg := range regs {
+		if err := db.Model(&entity.ActivityRegistration{}).Where("id = ?", reg.ID).
+			Updates(map[string]interface{}{"status_id": cancelledID, "reconfirm_by": nil}).Error; err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		message := fmt.Sprintf("การลงทะเบียนกิจกรรม '%s' ของคุณถูกยกเลิก เนื่องจากไม่ได้ยืนยันการเข้าร่วมภายในกำหนด", reg.Activity.Title)
+		if err := getNotificationService().CreateNotification(reg.UserID, message, "warning"); err != nil {
+			fmt.Println("❌ Error creating release notification:", err)
+		}
+	}
+	return errors.Join(errs...)
+}
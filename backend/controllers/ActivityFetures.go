@@ -32,6 +32,12 @@ type ActivityResponse struct {
 	Club                  entity.Club                   `json:"Club"`
 	Category              entity.EventCategory          `json:"Category"`
 	ActivityRegistrations []entity.ActivityRegistration `json:"ActivityRegistrations"`
+	Sessions              []entity.ActivitySession      `json:"Sessions,omitempty"`
+	MinAttendancePercent  int                           `json:"MinAttendancePercent,omitempty"`
+	Hosts                 []entity.ActivityHost         `json:"Hosts,omitempty"`
+	Venue                 *entity.Venue                 `json:"Venue,omitempty"`
+	AverageRating         float64                       `json:"AverageRating"`
+	ReviewCount           int64                         `json:"ReviewCount"`
 }
 
 type FeaturedActivitiesResponse struct {
@@ -87,6 +93,7 @@ func (h *ActivityHandler) GetFeaturedActivities(c *gin.Context) {
 	for _, activity := range activities {
 		var registeredCount int64
 		h.DB.Model(&entity.ActivityRegistration{}).Where("activity_id = ?", activity.ID).Count(&registeredCount)
+		rating := activityRatingSummary(h.DB, activity.ID)
 
 		responseActivity := ActivityResponse{
 			ID:                    activity.ID,
@@ -101,6 +108,8 @@ func (h *ActivityHandler) GetFeaturedActivities(c *gin.Context) {
 			ClubID:                activity.ClubID,
 			CategoryID:            activity.CategoryID,
 			RegisteredCount:       int(registeredCount),
+			AverageRating:         rating.AverageRating,
+			ReviewCount:           rating.ReviewCount,
 			Status:                activity.Status,
 			Club:                  activity.Club,
 			Category:              activity.Category,
@@ -211,6 +220,7 @@ func (h *ActivityHandler) GetActivities(c *gin.Context) {
 	for _, activity := range activities {
 		var registeredCount int64
 		h.DB.Model(&entity.ActivityRegistration{}).Where("activity_id = ?", activity.ID).Count(&registeredCount)
+		rating := activityRatingSummary(h.DB, activity.ID)
 
 		responseActivity := ActivityResponse{
 			ID:                    activity.ID,
@@ -225,6 +235,8 @@ func (h *ActivityHandler) GetActivities(c *gin.Context) {
 			ClubID:                activity.ClubID,
 			CategoryID:            activity.CategoryID,
 			RegisteredCount:       int(registeredCount),
+			AverageRating:         rating.AverageRating,
+			ReviewCount:           rating.ReviewCount,
 			Status:                activity.Status,
 			Club:                  activity.Club,
 			Category:              activity.Category,
@@ -259,6 +271,9 @@ func (h *ActivityHandler) GetActivityByID(c *gin.Context) {
 	var activity entity.Activity
 	if err := h.DB.Preload("ActivityRegistrations").Preload("Status").Preload("Club").Preload("Club.Status").
 		Preload("Club.Category").Preload("Category").
+		Preload("Sessions", func(db *gorm.DB) *gorm.DB { return db.Order("date_start ASC") }).
+		Preload("Hosts", "status = ?", activityHostAccepted).Preload("Hosts.Club").
+		Preload("Venue").
 		First(&activity, uint(activityID)).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
@@ -277,6 +292,7 @@ func (h *ActivityHandler) GetActivityByID(c *gin.Context) {
 	// นับจำนวนผู้สมัคร
 	var registeredCount int64
 	h.DB.Model(&entity.ActivityRegistration{}).Where("activity_id = ?", activity.ID).Count(&registeredCount)
+	rating := activityRatingSummary(h.DB, activity.ID)
 
 	responseActivity := ActivityResponse{
 		ID:                    activity.ID,
@@ -291,10 +307,18 @@ func (h *ActivityHandler) GetActivityByID(c *gin.Context) {
 		ClubID:                activity.ClubID,
 		CategoryID:            activity.CategoryID,
 		RegisteredCount:       int(registeredCount),
+		AverageRating:         rating.AverageRating,
+		ReviewCount:           rating.ReviewCount,
 		Status:                activity.Status,
 		Club:                  activity.Club,
 		Category:              activity.Category,
 		ActivityRegistrations: activity.ActivityRegistrations,
+		Sessions:              activity.Sessions,
+		Hosts:                 activity.Hosts,
+		Venue:                 activity.Venue,
+	}
+	if len(activity.Sessions) > 0 {
+		responseActivity.MinAttendancePercent = requiredAttendancePercent(&activity)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -351,6 +375,7 @@ func (h *ActivityHandler) GetActivityStatistics(c *gin.Context) {
 
 	h.DB.
 		Model(&entity.ActivityReview{}).
+		Where("is_hidden = ?", false).
 		Select("AVG(rating)").
 		Scan(&stats.AverageRating)
 
@@ -379,7 +404,8 @@ func (h *ActivityHandler) GetActivityByClubID(c *gin.Context) {
 		Preload("Club.Status").
 		Preload("Club.Category").
 		Preload("Category").
-		Where("club_id = ?", uint(clubID)).
+		Preload("Hosts", "status = ?", activityHostAccepted).Preload("Hosts.Club").
+		Scopes(hostedByClub(uint(clubID))).
 		Find(&activities).Error; err != nil {
 
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -393,6 +419,7 @@ func (h *ActivityHandler) GetActivityByClubID(c *gin.Context) {
 	for _, activity := range activities {
 		var registeredCount int64
 		h.DB.Model(&entity.ActivityRegistration{}).Where("activity_id = ?", activity.ID).Count(&registeredCount)
+		rating := activityRatingSummary(h.DB, activity.ID)
 
 		responseActivities = append(responseActivities, ActivityResponse{
 			ID:                    activity.ID,
@@ -407,10 +434,13 @@ func (h *ActivityHandler) GetActivityByClubID(c *gin.Context) {
 			ClubID:                activity.ClubID,
 			CategoryID:            activity.CategoryID,
 			RegisteredCount:       int(registeredCount),
+			AverageRating:         rating.AverageRating,
+			ReviewCount:           rating.ReviewCount,
 			Status:                activity.Status,
 			Club:                  activity.Club,
 			Category:              activity.Category,
 			ActivityRegistrations: activity.ActivityRegistrations,
+			Hosts:                 activity.Hosts,
 		})
 	}
 
@@ -437,6 +467,7 @@ func (h *ActivityHandler) GetAllActivities(c *gin.Context) {
 	for _, activity := range activities {
 		var registeredCount int64
 		h.DB.Model(&entity.ActivityRegistration{}).Where("activity_id = ?", activity.ID).Count(&registeredCount)
+		rating := activityRatingSummary(h.DB, activity.ID)
 
 		responseActivities = append(responseActivities, ActivityResponse{
 			ID:                    activity.ID,
@@ -451,6 +482,8 @@ func (h *ActivityHandler) GetAllActivities(c *gin.Context) {
 			ClubID:                activity.ClubID,
 			CategoryID:            activity.CategoryID,
 			RegisteredCount:       int(registeredCount),
+			AverageRating:         rating.AverageRating,
+			ReviewCount:           rating.ReviewCount,
 			Status:                activity.Status,
 			Club:                  activity.Club,
 			Category:              activity.Category,
@@ -0,0 +1,370 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ตาราง (activity_id, club_id, credit_share) ของทุกกิจกรรม รวมกิจกรรมที่จัดชมรมเดียวซึ่งได้เครดิต 100%
+// เครดิตของผู้ร่วมจัดที่ยังไม่ตอบรับนับให้ชมรมหลักไปก่อน
+const activityHostCreditsSQL = `(
+	SELECT activity_id, club_id, SUM(credit_share) AS credit_share FROM (
+		SELECT h.activity_id, CASE WHEN h.status = 'accepted' THEN h.club_id ELSE a.club_id END AS club_id, h.credit_share
+		FROM activity_hosts h JOIN activities a ON a.id = h.activity_id
+		WHERE h.deleted_at IS NULL
+		UNION ALL
+		SELECT id, club_id, 100 FROM activities
+		WHERE deleted_at IS NULL AND id NOT IN (SELECT activity_id FROM activity_hosts WHERE deleted_at IS NULL)
+	) AS host_credits
+	GROUP BY activity_id, club_id
+)`
+
+const (
+	activityHostPending  = "pending"
+	activityHostAccepted = "accepted"
+)
+
+type ActivityHostInput struct {
+	ClubID      uint    `json:"club_id" binding:"required"`
+	IsLead      bool    `json:"is_lead"`
+	CreditShare float64 `json:"credit_share"`
+}
+
+// scope กรองกิจกรรมที่ชมรมเป็นผู้จัดหลักหรือผู้ร่วมจัด (ที่ตอบรับแล้ว)
+func hostedByClub(clubID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		coHosted := db.Session(&gorm.Session{NewDB: true}).Model(&entity.ActivityHost{}).
+			Select("activity_id").Where("club_id = ? AND status = ?", clubID, activityHostAccepted)
+		return db.Where("activities.club_id = ? OR activities.id IN (?)", clubID, coHosted)
+	}
+}
+
+// id ของทุกชมรมที่จัดกิจกรรม (ไม่รวมผู้ร่วมจัดที่ยังไม่ตอบรับ) โดยชมรมหลักอยู่ลำดับแรก
+func activityHostClubIDs(db *gorm.DB, activity *entity.Activity) ([]uint, error) {
+	var hostIDs []uint
+	if err := db.Model(&entity.ActivityHost{}).
+		Where("activity_id = ? AND club_id <> ? AND status = ?", activity.ID, activity.ClubID, activityHostAccepted).
+		Order("id ASC").Pluck("club_id", &hostIDs).Error; err != nil {
+		return nil, err
+	}
+	return append([]uint{activity.ClubID}, hostIDs...), nil
+}
+
+// สัดส่วนเครดิต (0-1) ที่ชมรมได้รับจากกิจกรรม ตามหลักเดียวกับ activityHostCreditsSQL
+func activityCreditShare(db *gorm.DB, activity *entity.Activity, clubID uint) float64 {
+	var hosts []entity.ActivityHost
+	if err := db.Where("activity_id = ?", activity.ID).Find(&hosts).Error; err != nil || len(hosts) == 0 {
+		if activity.ClubID == clubID {
+			return 1
+		}
+		return 0
+	}
+	share := 0.0
+	for _, h := range hosts {
+		owner := h.ClubID
+		if h.Status != activityHostAccepted {
+			owner = activity.ClubID
+		}
+		if owner == clubID {
+			share += h.CreditShare
+		}
+	}
+	return share / 100
+}
+
+// ผู้จัดทั้งหมดของกิจกรรม ถ้ายังไม่เคยตั้งผู้ร่วมจัดจะคืนชมรมหลักที่ได้ 100%
+func loadActivityHosts(db *gorm.DB, activity *entity.Activity) ([]entity.ActivityHost, error) {
+	var hosts []entity.ActivityHost
+	if err := db.Preload("Club").Where("activity_id = ?", activity.ID).
+		Order("is_lead DESC, id ASC").Find(&hosts).Error; err != nil {
+		return nil, err
+	}
+	if len(hosts) > 0 {
+		return hosts, nil
+	}
+	var club entity.Club
+	if err := db.First(&club, activity.ClubID).Error; err != nil {
+		return nil, err
+	}
+	return []entity.ActivityHost{{ActivityID: activity.ID, ClubID: club.ID, IsLead: true, CreditShare: 100, Status: activityHostAccepted, Club: club}}, nil
+}
+
+// แจ้งหัวหน้าชมรมที่ถูกเชิญเป็นผู้ร่วมจัดให้ตอบรับหรือปฏิเสธ
+func notifyActivityHostInvites(db *gorm.DB, activity *entity.Activity, clubIDs []uint) {
+	if len(clubIDs) == 0 {
+		return
+	}
+	var presidents []entity.ClubMember
+	db.Where("club_id IN ? AND role = ?", clubIDs, "president").Find(&presidents)
+	for _, p := range presidents {
+		message := fmt.Sprintf("ชมรมของคุณได้รับเชิญเป็นผู้ร่วมจัดกิจกรรม '%s' กรุณาตอบรับหรือปฏิเสธ", activity.Title)
+		if err := getNotificationService().CreateNotification(p.UserID, message, "info"); err != nil {
+			fmt.Println("❌ Error creating co-host notification:", err)
+		}
+	}
+}
+
+// ตรวจสอบรายชื่อผู้จัด และแบ่งเครดิตเท่ากันถ้าไม่ได้ระบุ (เศษให้ชมรมหลัก)
+func normalizeActivityHosts(hosts []ActivityHostInput) ([]ActivityHostInput, error) {
+	if len(hosts) == 0 {
+		return nil, errors.New("ต้องมีชมรมผู้จัดอย่างน้อย 1 ชมรม")
+	}
+	seen := map[uint]bool{}
+	leads, specified := 0, 0
+	total := 0.0
+	for _, h := range hosts {
+		if seen[h.ClubID] {
+			return nil, fmt.Errorf("ชมรม %d ถูกระบุซ้ำ", h.ClubID)
+		}
+		seen[h.ClubID] = true
+		if h.IsLead {
+			leads++
+		}
+		if h.CreditShare < 0 {
+			return nil, errors.New("credit_share ต้องไม่ติดลบ")
+		}
+		if h.CreditShare > 0 {
+			specified++
+		}
+		total += h.CreditShare
+	}
+	if leads != 1 {
+		return nil, errors.New("ต้องมีชมรมหลัก (is_lead) 1 ชมรม")
+	}
+
+	switch specified {
+	case 0:
+		share := math.Floor(10000/float64(len(hosts))) / 100
+		remainder := math.Round((100-share*float64(len(hosts)))*100) / 100
+		for i := range hosts {
+			hosts[i].CreditShare = share
+			if hosts[i].IsLead {
+				hosts[i].CreditShare = math.Round((share+remainder)*100) / 100
+			}
+		}
+	case len(hosts):
+		if math.Abs(total-100) > 0.01 {
+			return nil, errors.New("credit_share ของทุกชมรมรวมกันต้องเท่ากับ 100")
+		}
+	default:
+		return nil, errors.New("ต้องระบุ credit_share ให้ครบทุกชมรม หรือไม่ระบุเลยเพื่อแบ่งเท่ากัน")
+	}
+	return hosts, nil
+}
+
+// GET /activities/:id/hosts - ชมรมผู้จัดและสัดส่วนเครดิต
+func GetActivityHosts(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	hosts, err := loadActivityHosts(db, &activity)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงข้อมูลผู้จัดได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": hosts})
+}
+
+// PUT /activities/:id/hosts - กำหนดชมรมผู้ร่วมจัดทั้งชุด (officer ของชมรมหลัก หรือผู้ดูแลระบบ)
+// การเปลี่ยนชมรมหลักทำได้โดยผู้ดูแลระบบเท่านั้น ชมรมที่เพิ่มใหม่ต้องตอบรับก่อนจึงจะเป็นผู้ร่วมจัด
+func UpdateActivityHosts(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	admin := isAdmin(db, user)
+	// ผู้ร่วมจัดจัดการกิจกรรมได้ แต่การเปลี่ยนรายชื่อผู้จัดสงวนไว้ให้ชมรมหลัก
+	if admin {
+		if err := rejectArchivedClubWrite(c, activity.ClubID); err != nil {
+			return
+		}
+	} else if _, err := requireClubPermission(c, activity.ClubID, permCreateActivities); err != nil {
+		return
+	}
+
+	var input struct {
+		Hosts []ActivityHostInput `json:"hosts" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	hosts, err := normalizeActivityHosts(input.Hosts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// ชมรมที่ตอบรับแล้วคงสถานะเดิม ชมรมหลักเดิมถือว่าตอบรับแล้วเสมอ
+	var existing []entity.ActivityHost
+	if err := db.Where("activity_id = ?", activity.ID).Find(&existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	previous := map[uint]string{activity.ClubID: activityHostAccepted}
+	for _, h := range existing {
+		if h.ClubID != activity.ClubID {
+			previous[h.ClubID] = h.Status
+		}
+	}
+	var leadID uint
+	for _, h := range hosts {
+		var club entity.Club
+		if err := db.Preload("Status").First(&club, h.ClubID).Error; err != nil || !club.Status.IsActive {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ไม่พบชมรม %d หรือชมรมไม่ได้เปิดดำเนินการ", h.ClubID)})
+			return
+		}
+		if h.IsLead {
+			leadID = h.ClubID
+		}
+	}
+	// ชมรมหลักเป็นเจ้าของกิจกรรม (สิทธิ์จัดการ การอนุมัติ และผลงาน) จะโอนให้ชมรมอื่นเองไม่ได้
+	if leadID != activity.ClubID && !admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "การเปลี่ยนชมรมหลักต้องดำเนินการโดยผู้ดูแลระบบ"})
+		return
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		// ลบถาวรเพราะ (activity_id, club_id) เป็น unique index
+		if err := tx.Unscoped().Where("activity_id = ?", activity.ID).Delete(&entity.ActivityHost{}).Error; err != nil {
+			return err
+		}
+		if len(hosts) > 1 {
+			for _, h := range hosts {
+				status := activityHostPending
+				if h.IsLead || previous[h.ClubID] == activityHostAccepted {
+					status = activityHostAccepted
+				}
+				if err := tx.Create(&entity.ActivityHost{
+					ActivityID:  activity.ID,
+					ClubID:      h.ClubID,
+					IsLead:      h.IsLead,
+					CreditShare: h.CreditShare,
+					Status:      status,
+				}).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Model(&entity.Activity{}).Where("id = ?", activity.ID).Update("club_id", leadID).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกผู้จัดไม่สำเร็จ"})
+		return
+	}
+	activity.ClubID = leadID
+
+	// แจ้งหัวหน้าชมรมที่ถูกเชิญเป็นผู้ร่วมจัดใหม่ (ชมรมที่ยังรอตอบรับจากครั้งก่อนได้รับแจ้งไปแล้ว)
+	var invited []uint
+	for _, h := range hosts {
+		if _, ok := previous[h.ClubID]; !ok && !h.IsLead && len(hosts) > 1 {
+			invited = append(invited, h.ClubID)
+		}
+	}
+	notifyActivityHostInvites(db, &activity, invited)
+
+	result, err := loadActivityHosts(db, &activity)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงข้อมูลผู้จัดได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "บันทึกผู้จัดเรียบร้อยแล้ว", "data": result})
+}
+
+// POST /activities/:id/hosts/:clubId/accept - officer ของชมรมที่ได้รับเชิญตอบรับการร่วมจัด
+func AcceptActivityHost(c *gin.Context) {
+	respondActivityHost(c, true)
+}
+
+// POST /activities/:id/hosts/:clubId/decline - officer ของชมรมที่ได้รับเชิญปฏิเสธการร่วมจัด
+// เครดิตของชมรมที่ปฏิเสธคืนให้ชมรมหลัก
+func DeclineActivityHost(c *gin.Context) {
+	respondActivityHost(c, false)
+}
+
+func respondActivityHost(c *gin.Context, accept bool) {
+	db := config.DB()
+
+	clubID, err := strconv.ParseUint(c.Param("clubId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid club ID"})
+		return
+	}
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	if _, err := requireClubPermission(c, uint(clubID), permCreateActivities); err != nil {
+		return
+	}
+	if accept && !requireClubActive(c, uint(clubID)) {
+		return
+	}
+
+	var host entity.ActivityHost
+	if err := db.Where("activity_id = ? AND club_id = ? AND status = ?", activity.ID, clubID, activityHostPending).
+		First(&host).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบคำเชิญร่วมจัด"})
+		return
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if accept {
+			return tx.Model(&host).Update("status", activityHostAccepted).Error
+		}
+		if err := tx.Unscoped().Delete(&host).Error; err != nil {
+			return err
+		}
+		var remaining int64
+		if err := tx.Model(&entity.ActivityHost{}).Where("activity_id = ?", activity.ID).Count(&remaining).Error; err != nil {
+			return err
+		}
+		// เหลือชมรมหลักชมรมเดียว กลับไปเป็นกิจกรรมที่ไม่มีผู้ร่วมจัด
+		if remaining <= 1 {
+			return tx.Unscoped().Where("activity_id = ?", activity.ID).Delete(&entity.ActivityHost{}).Error
+		}
+		return tx.Model(&entity.ActivityHost{}).Where("activity_id = ? AND is_lead = ?", activity.ID, true).
+			Update("credit_share", gorm.Expr("credit_share + ?", host.CreditShare)).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถบันทึกคำตอบได้"})
+		return
+	}
+
+	var club entity.Club
+	db.First(&club, clubID)
+	action := "ปฏิเสธ"
+	if accept {
+		action = "ตอบรับ"
+	}
+	var presidents []entity.ClubMember
+	db.Where("club_id = ? AND role = ?", activity.ClubID, "president").Find(&presidents)
+	for _, p := range presidents {
+		message := fmt.Sprintf("ชมรม%s %sการร่วมจัดกิจกรรม '%s'", club.Name, action, activity.Title)
+		if err := getNotificationService().CreateNotification(p.UserID, message, "info"); err != nil {
+			fmt.Println("❌ Error creating co-host notification:", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": action + "การร่วมจัดกิจกรรมเรียบร้อยแล้ว"})
+}
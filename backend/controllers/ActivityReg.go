@@ -1,14 +1,16 @@
 package controllers
 
 import (
+	"encoding/json"
 	"final-project/cems/config"
 	"final-project/cems/entity"
 	"net/http"
+	"strings"
 	"time"
 	
 
 	"github.com/gin-gonic/gin"
-
+	"gorm.io/gorm"
 )
 
 func GetActivityRegister(c *gin.Context) {
@@ -108,10 +110,16 @@ func CreateRegister(c *gin.Context) {
         UserID     uint `json:"user_id" binding:"required"`
         ActivityID uint `json:"activity_id" binding:"required"`
         StatusID   uint `json:"status_id"` // อาจจะกำหนด default ได้ เช่น ลงทะเบียนแล้ว = 1
+        Answers    []RegistrationAnswerInput `json:"answers"`
     }
 
-    // รับข้อมูล JSON จาก client
-    if err := c.ShouldBindJSON(&input); err != nil {
+    // รับข้อมูล JSON จาก client (ถ้ามีไฟล์แนบจะส่งมาเป็น multipart พร้อม json_data)
+    if strings.HasPrefix(c.ContentType(), "multipart/") {
+        if err := json.Unmarshal([]byte(c.PostForm("json_data")), &input); err != nil || input.UserID == 0 || input.ActivityID == 0 {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูล json_data ไม่ถูกต้อง"})
+            return
+        }
+    } else if err := c.ShouldBindJSON(&input); err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
@@ -121,6 +129,38 @@ func CreateRegister(c *gin.Context) {
         input.StatusID = 1
     }
 
+    // กิจกรรมแบบทีมต้องสมัครผ่านการยืนยันทีมเท่านั้น
+    var teamSetting entity.ActivityTeamSetting
+    if err := db.Where("activity_id = ?", input.ActivityID).First(&teamSetting).Error; err == nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "กิจกรรมนี้ต้องสมัครเป็นทีม"})
+        return
+    }
+
+    // ตรวจคำตอบแบบฟอร์มลงทะเบียนของกิจกรรม (ถ้ามี)
+    questions, err := loadRegistrationQuestions(db, input.ActivityID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงแบบฟอร์มได้"})
+        return
+    }
+    files, err := readRegistrationFiles(c)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    answers := map[uint]string{}
+    for _, a := range input.Answers {
+        answers[a.QuestionID] = a.Value
+    }
+    if err := validateRegistrationAnswers(questions, answers, files); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+    attachments, err := readFormAttachments(questions, files)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
     // สร้าง record ใหม่
     reg := entity.ActivityRegistration{
         UserID:     input.UserID,
@@ -129,10 +169,31 @@ func CreateRegister(c *gin.Context) {
         RegisteredAt: time.Now(), // ถ้ามีฟิลด์เวลาสมัคร
     }
 
-    if err := db.Create(&reg).Error; err != nil {
+    var fullErr error
+    if err := db.Transaction(func(tx *gorm.DB) error {
+        // ความจุของรอบกิจกรรม (ถ้ามี) จำกัดจำนวนผู้ลงทะเบียน
+        if fullErr = checkSessionCapacity(tx, input.ActivityID, 1); fullErr != nil {
+            return fullErr
+        }
+        if err := tx.Create(&reg).Error; err != nil {
+            return err
+        }
+        return saveRegistrationAnswers(tx, reg, questions, answers, attachments)
+    }); err != nil {
+        if fullErr != nil {
+            c.JSON(http.StatusConflict, gin.H{"error": fullErr.Error()})
+            return
+        }
         c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถสร้างการสมัครได้"})
         return
     }
+    // เขียนไฟล์แนบหลัง commit ถ้าเขียนไม่สำเร็จให้ยกเลิกการลงทะเบียนนี้ทั้งหมด
+    if err := writeFormAttachments(reg, attachments); err != nil {
+        db.Unscoped().Where("registration_id = ?", reg.ID).Delete(&entity.RegistrationAnswer{})
+        db.Unscoped().Delete(&reg)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกไฟล์แนบไม่สำเร็จ กรุณาสมัครใหม่อีกครั้ง"})
+        return
+    }
 
     // โหลดข้อมูลสถานะที่สัมพันธ์กัน (ถ้ามี)
     if err := db.Preload("Status").First(&reg, reg.ID).Error; err != nil {
@@ -167,3 +228,12 @@ func DeleteActivityRegister(c *gin.Context) {
 }
 
 
+
+// หา id ของสถานะการลงทะเบียนจากชื่อ เช่น registered, cancelled, attended
+func getRegistrationStatusID(db *gorm.DB, name string) (uint, error) {
+	var status entity.ActivityRegistrationStatus
+	if err := db.Where("name = ?", name).First(&status).Error; err != nil {
+		return 0, err
+	}
+	return status.ID, nil
+}
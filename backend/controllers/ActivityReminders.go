@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+	"final-project/cems/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ผู้ใช้ที่ยังมีสถานะ registered ในกิจกรรม (คนที่ยกเลิกแล้วจะไม่ถูกนับ)
+func registeredUsersOf(db *gorm.DB, activityID uint) ([]entity.User, error) {
+	var users []entity.User
+	err := db.Joins("JOIN activity_registrations ON activity_registrations.user_id = users.id AND activity_registrations.deleted_at IS NULL").
+		Joins("JOIN activity_registration_statuses ON activity_registration_statuses.id = activity_registrations.status_id").
+		Where("activity_registrations.activity_id = ? AND activity_registration_statuses.name = ?", activityID, "registered").
+		Find(&users).Error
+	return users, err
+}
+
+func formatThaiDateTime(t time.Time) string {
+	t = t.Local()
+	return fmt.Sprintf("%s เวลา %s น.", FormatThaiDate(t), t.Format("15:04"))
+}
+
+// แสดงระยะเวลาที่เหลือแบบประมาณ เช่น "2 ชั่วโมง" หรือ "45 นาที"
+func formatTimeUntil(d time.Duration) string {
+	minutes := int(d.Round(time.Minute).Minutes())
+	switch {
+	case minutes >= 1440:
+		return fmt.Sprintf("%d วัน", (minutes+720)/1440)
+	case minutes >= 60:
+		return fmt.Sprintf("%d ชั่วโมง", (minutes+30)/60)
+	default:
+		return fmt.Sprintf("%d นาที", minutes)
+	}
+}
+
+// ส่งแจ้งเตือนในระบบและอีเมลให้ผู้ใช้ แล้วบันทึก log ไว้
+func sendActivityReminder(db *gorm.DB, activity *entity.Activity, user entity.User, message string, entry entity.ActivityReminderLog) error {
+	entry.ActivityID = activity.ID
+	entry.UserID = user.ID
+	entry.Message = message
+	entry.SentAt = time.Now()
+	if err := db.Create(&entry).Error; err != nil {
+		return err
+	}
+
+	if err := getNotificationService().CreateNotification(user.ID, message, "activity"); err != nil {
+		fmt.Println("❌ Error creating reminder notification:", err)
+	}
+	if user.Email != "" {
+		htmlBody, err := services.RenderTemplate("activity_reminder.html", map[string]string{
+			"Message":       message,
+			"ActivityTitle": activity.Title,
+			"StartTime":     formatThaiDateTime(activity.DateStart),
+			"Location":      activity.Location,
+		})
+		if err == nil {
+			go services.SendEmailHTML(user.Email, "⏰ แจ้งเตือนกิจกรรม "+activity.Title, htmlBody)
+		}
+	}
+	return nil
+}
+
+// งานตามตาราง: ส่งการเตือนตาม ReminderRule ให้ผู้ลงทะเบียนกิจกรรมที่ใกล้เริ่ม
+// แต่ละคนได้รับเฉพาะ rule ที่ใกล้เวลาที่สุดที่ยังครอบคลุมอยู่ เช่น กิจกรรมที่สร้าง 2 ชั่วโมงก่อนเริ่ม
+// จะได้การเตือน 24 ชั่วโมงทันทีหนึ่งครั้ง แล้วได้การเตือน 1 ชั่วโมงตามปกติ
+func sendScheduledReminders(db *gorm.DB) error {
+	var rules []entity.ReminderRule
+	if err := db.Where("enabled = ?", true).Order("minutes_before ASC").Find(&rules).Error; err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	approved, err := getActivityStatusByName(db, "approved")
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	maxOffset := time.Duration(rules[len(rules)-1].MinutesBefore) * time.Minute
+	var activities []entity.Activity
+	if err := db.Where("status_id = ? AND date_start > ? AND date_start <= ?", approved.ID, now, now.Add(maxOffset)).
+		Find(&activities).Error; err != nil {
+		return err
+	}
+
+	for i := range activities {
+		activity := &activities[i]
+		remaining := activity.DateStart.Sub(now)
+
+		var rule entity.ReminderRule
+		for _, r := range rules {
+			if time.Duration(r.MinutesBefore)*time.Minute >= remaining {
+				rule = r
+				break
+			}
+		}
+
+		users, err := registeredUsersOf(db, activity.ID)
+		if err != nil {
+			return err
+		}
+		message := fmt.Sprintf("อย่าลืมเข้าร่วมกิจกรรม '%s' ในอีก %s (%s)",
+			activity.Title, formatTimeUntil(remaining), formatThaiDateTime(activity.DateStart))
+		for _, user := range users {
+			var sent int64
+			db.Model(&entity.ActivityReminderLog{}).
+				Where("activity_id = ? AND user_id = ? AND kind = ? AND minutes_before = ?", activity.ID, user.ID, "scheduled", rule.MinutesBefore).
+				Count(&sent)
+			if sent > 0 {
+				continue
+			}
+			if err := sendActivityReminder(db, activity, user, message, entity.ActivityReminderLog{
+				Kind:          "scheduled",
+				MinutesBefore: rule.MinutesBefore,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GET /reminder-rules - ดูการตั้งค่าเวลาแจ้งเตือนก่อนกิจกรรม
+func GetReminderRules(c *gin.Context) {
+	var rules []entity.ReminderRule
+	if err := config.DB().Order("minutes_before DESC").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงการตั้งค่าการแจ้งเตือนได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": rules})
+}
+
+// PUT /reminder-rules - แทนที่การตั้งค่าเวลาแจ้งเตือนทั้งหมด (admin เท่านั้น)
+func UpdateReminderRules(c *gin.Context) {
+	db := config.DB()
+	if _, err := requireAdmin(c); err != nil {
+		return
+	}
+
+	var input struct {
+		Rules []struct {
+			MinutesBefore int  `json:"minutes_before"`
+			Enabled       bool `json:"enabled"`
+		} `json:"rules"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	seen := map[int]bool{}
+	for _, r := range input.Rules {
+		if r.MinutesBefore <= 0 || r.MinutesBefore > 30*1440 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "minutes_before ต้องอยู่ระหว่าง 1 ถึง 43200"})
+			return
+		}
+		if seen[r.MinutesBefore] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "minutes_before ซ้ำกัน"})
+			return
+		}
+		seen[r.MinutesBefore] = true
+	}
+
+	var rules []entity.ReminderRule
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("1 = 1").Delete(&entity.ReminderRule{}).Error; err != nil {
+			return err
+		}
+		for _, r := range input.Rules {
+			rule := entity.ReminderRule{MinutesBefore: r.MinutesBefore, Enabled: r.Enabled}
+			if err := tx.Create(&rule).Error; err != nil {
+				return err
+			}
+			rules = append(rules, rule)
+		}
+		return nil
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกการตั้งค่าการแจ้งเตือนไม่สำเร็จ"})
+		return
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].MinutesBefore > rules[j].MinutesBefore })
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "บันทึกการตั้งค่าการแจ้งเตือนสำเร็จ", "data": rules})
+}
+
+// POST /activities/:id/reminders - officer ส่งข้อความเตือนผู้ลงทะเบียนเอง
+func SendAdhocReminder(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.Preload("Status").First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	user, err := requireActivityOfficer(c, &activity)
+	if err != nil {
+		return
+	}
+
+	var input struct {
+		Message string `json:"message" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Message) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุข้อความแจ้งเตือน"})
+		return
+	}
+	if activity.Status.Name == "cancelled" || activity.Status.Name == "finished" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่สามารถส่งการแจ้งเตือนสำหรับกิจกรรมที่ยกเลิกหรือจบแล้ว"})
+		return
+	}
+
+	users, err := registeredUsersOf(db, activity.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงรายชื่อผู้ลงทะเบียนได้"})
+		return
+	}
+	message := fmt.Sprintf("[%s] %s", activity.Title, strings.TrimSpace(input.Message))
+	for _, u := range users {
+		if err := sendActivityReminder(db, &activity, u, message, entity.ActivityReminderLog{
+			Kind:   "adhoc",
+			SentBy: user.ID,
+		}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ส่งการแจ้งเตือนไม่สำเร็จ"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ส่งการแจ้งเตือนเรียบร้อยแล้ว", "recipients": len(users)})
+}
+
+// GET /activities/:id/reminders - ประวัติการเตือนของกิจกรรม (officer เท่านั้น)
+func GetActivityReminderLogs(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	if _, err := requireActivityOfficer(c, &activity); err != nil {
+		return
+	}
+
+	var logs []entity.ActivityReminderLog
+	if err := db.Preload("User").
+		Where("activity_id = ?", activity.ID).
+		Order("sent_at DESC").
+		Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงประวัติการแจ้งเตือนได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": logs})
+}
@@ -3,7 +3,6 @@ package controllers
 import (
 	"errors"
 	"net/http"
-	"path/filepath"
 	"strconv"
 	"time"
 
@@ -28,6 +27,13 @@ func UpdateActivityByID(c *gin.Context) {
 		}
 		return
 	}
+	// แก้ไขได้เฉพาะ officer ของชมรมผู้จัด (รวมชมรมผู้ร่วมจัด)
+	editor, err := requireActivityOfficer(c, &activity)
+	if err != nil {
+		return
+	}
+	editorID := editor.ID
+	before := activityRevisionOf(&activity)
 
 	// รับข้อมูลฟิลด์จาก Form-data
 	title := c.PostForm("title")
@@ -65,37 +71,108 @@ func UpdateActivityByID(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status_id"})
 		return
 	}
+	venueID, venueSent, err := parseVenueIDForm(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// รับไฟล์โปสเตอร์ถ้ามี
-	file, err := c.FormFile("poster_image")
-	if err == nil {
-		// บันทึกไฟล์ไปที่เซิร์ฟเวอร์ (เช่น โฟลเดอร์ uploads/)
-		filename := filepath.Base(file.Filename)
-		dst := "./images/activities/posterImages/" + filename
-		if err := c.SaveUploadedFile(file, dst); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
-			return
-		}
-		// เก็บเป็น URL หรือ path ที่ใช้เรียกดูได้
-		activity.PosterImage = "/images/activities/posterImages/" + filename
+	poster, err := saveImageUpload(c, "poster_image", purposeActivityPoster)
+	if respondImageUploadError(c, err) {
+		return
+	}
+	if poster != nil {
+		activity.PosterImage = storedImageURL(poster)
+	}
+
+	// วันเวลาต้องเปลี่ยนผ่านการเลื่อนกิจกรรม เพื่อแจ้งผู้ลงทะเบียนและให้ยืนยันใหม่
+	if !sameActivityTime(dateStart, activity.DateStart) || !sameActivityTime(dateEnd, activity.DateEnd) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "การเปลี่ยนวันเวลากิจกรรมต้องทำผ่าน /activities/:id/reschedule"})
+		return
 	}
 
 	// อัปเดตฟิลด์อื่น ๆ
 	activity.Title = title
 	activity.Description = description
 	activity.Location = location
-	activity.DateStart = dateStart
-	activity.DateEnd = dateEnd
 	activity.Capacity = capacity
 	activity.CategoryID = uint(categoryID)
-	activity.StatusID = uint(statusID)
+	if venueSent {
+		activity.VenueID = venueID
+	}
+
+	// การเปลี่ยนสถานะต้องเป็นไปตามลำดับ และอนุมัติได้ผ่านสายการอนุมัติเท่านั้น
+	reapproval := false
+	if uint(statusID) == activity.StatusID {
+		// กิจกรรมที่อนุมัติแล้วถ้าแก้ชื่อ เนื้อหา หรือสถานที่ ต้องกลับไปขออนุมัติใหม่
+		if reapproval, err = restartApprovalIfChanged(db, &activity, before, editorID); err != nil {
+			respondClubStateError(c, err)
+			return
+		}
+	} else {
+		var current, next entity.ActivityStatus
+		if err := db.First(&current, activity.StatusID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		if err := db.First(&next, statusID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status_id"})
+			return
+		}
+		if next.Name == "approved" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "กิจกรรมต้องได้รับการอนุมัติผ่านสายการอนุมัติ"})
+			return
+		}
+		if next.Name == "cancelled" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "การยกเลิกกิจกรรมต้องระบุเหตุผลผ่าน /activities/:id/cancel"})
+			return
+		}
+		if !canTransitionActivity(current.Name, next.Name) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่สามารถเปลี่ยนสถานะจาก " + current.Name + " เป็น " + next.Name + " ได้"})
+			return
+		}
 
-	if err := db.Save(&activity).Error; err != nil {
+		switch next.Name {
+		case "pending":
+			if !requireClubActive(c, activity.ClubID) {
+				return
+			}
+			if err := startActivityApproval(db, &activity, editorID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start approval"})
+				return
+			}
+		default:
+			activity.StatusID = next.ID
+			activity.ApprovalStep = 0
+		}
+	}
+
+	activity.CalendarSequence++
+
+	// ย้ายการจองสถานที่ตามวันเวลาใหม่ ถ้าชนกับกิจกรรมอื่นจะไม่บันทึกการแก้ไขทั้งหมด
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&activity).Error; err != nil {
+			return err
+		}
+		if err := bookActivityVenue(tx, &activity, editorID); err != nil {
+			return err
+		}
+		return recordRevision(tx, "activity", activity.ID, before, activityRevisionOf(&activity), editorID, "update", nil)
+	}); err != nil {
+		if respondVenueError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update activity"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Activity updated successfully", "data": activity})
+	c.JSON(http.StatusOK, gin.H{"message": "Activity updated successfully", "data": activity, "reapproval": reapproval, "warnings": venueCapacityWarnings(db, &activity)})
+}
+
+// เทียบวันเวลาที่ฟอร์มส่งมากับที่บันทึกไว้ระดับวินาที (ฟอร์มไม่ได้ส่งเศษของวินาทีมาด้วยเสมอ)
+func sameActivityTime(a, b time.Time) bool {
+	return a.Truncate(time.Second).Equal(b.Truncate(time.Second))
 }
 
 func CreateActivity(c *gin.Context) {
@@ -143,19 +220,39 @@ func CreateActivity(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category_id"})
 		return
 	}
+	venueID, _, err := parseVenueIDForm(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// รับรูปภาพ
 	var posterImagePath string
-	file, err := c.FormFile("poster_image")
-	if err == nil {
-		filename := filepath.Base(file.Filename)
-		dst := "./images/activities/posterImages/" + filename
+	poster, err := saveImageUpload(c, "poster_image", purposeActivityPoster)
+	if respondImageUploadError(c, err) {
+		return
+	}
+	if poster != nil {
+		posterImagePath = storedImageURL(poster)
+	}
 
-		if err := c.SaveUploadedFile(file, dst); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
-			return
-		}
-		posterImagePath = "/images/activities/posterImages/" + filename
+	// กิจกรรมใหม่เริ่มได้แค่แบบร่างหรือส่งขออนุมัติทันที
+	var status entity.ActivityStatus
+	if err := db.First(&status, statusID).Error; err != nil || (status.Name != "draft" && status.Name != "pending") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status_id ต้องเป็น draft หรือ pending"})
+		return
+	}
+	var club entity.Club
+	if err := db.First(&club, clubID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid club_id"})
+		return
+	}
+	if !requireClubActive(c, club.ID) {
+		return
+	}
+	submitterID := club.CreatedBy
+	if user, err := getUserFromJWT(c); err == nil {
+		submitterID = user.ID
 	}
 
 	// สร้าง Activity ใหม่
@@ -170,13 +267,29 @@ func CreateActivity(c *gin.Context) {
 		StatusID:    uint(statusID),
 		ClubID:      uint(clubID),
 		CategoryID:  uint(categoryID),
+		VenueID:     venueID,
+		SubmittedBy: submitterID,
+	}
+	if status.Name == "pending" {
+		if err := startActivityApproval(db, &activity, submitterID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start approval"})
+			return
+		}
 	}
 
-	// บันทึกลงฐานข้อมูล
-	if err := db.Create(&activity).Error; err != nil {
+	// บันทึกลงฐานข้อมูลพร้อมจองสถานที่ (ถ้าเลือกสถานที่ในระบบ)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&activity).Error; err != nil {
+			return err
+		}
+		return bookActivityVenue(tx, &activity, submitterID)
+	}); err != nil {
+		if respondVenueError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create activity"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Activity created successfully", "data": activity})
+	c.JSON(http.StatusOK, gin.H{"message": "Activity created successfully", "data": activity, "warnings": venueCapacityWarnings(db, &activity)})
 }
@@ -0,0 +1,636 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+	"final-project/cems/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// จำนวนครั้งสูงสุดที่สร้างได้ใน series เดียว
+const maxSeriesOccurrences = 100
+
+func loadSeriesExDates(series *entity.ActivitySeries) []time.Time {
+	var dates []time.Time
+	if series.ExDates != "" {
+		json.Unmarshal([]byte(series.ExDates), &dates)
+	}
+	return dates
+}
+
+func addSeriesExDate(tx *gorm.DB, series *entity.ActivitySeries, date time.Time) error {
+	dates := append(loadSeriesExDates(series), date)
+	b, _ := json.Marshal(dates)
+	series.ExDates = string(b)
+	return tx.Model(series).Update("ex_dates", series.ExDates).Error
+}
+
+// วันที่ตามปฏิทินของเขตเวลา loc (เที่ยงคืน) ใช้นับจำนวนวันที่เลื่อน
+func localDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// โหลด series และ occurrence ที่อยู่ใน series นั้น ตรวจว่า occurrence เป็นของ series จริง
+func loadSeriesOccurrence(db *gorm.DB, seriesID, activityID string) (*entity.ActivitySeries, *entity.Activity, error) {
+	var series entity.ActivitySeries
+	if err := db.First(&series, seriesID).Error; err != nil {
+		return nil, nil, err
+	}
+	var activity entity.Activity
+	if err := db.Preload("Status").
+		Where("id = ? AND series_id = ?", activityID, series.ID).
+		First(&activity).Error; err != nil {
+		return nil, nil, err
+	}
+	return &series, &activity, nil
+}
+
+// POST /activity-series - สร้างกิจกรรมที่เกิดซ้ำ รับ form-data เหมือน CreateActivity และเพิ่ม rrule
+func CreateActivitySeries(c *gin.Context) {
+	db := config.DB()
+
+	rule, err := utils.ParseRRule(c.PostForm("rrule"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if rule.Count == 0 && rule.Until.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "RRULE ต้องระบุ COUNT หรือ UNTIL"})
+		return
+	}
+
+	dateStart, err := time.Parse(time.RFC3339, c.PostForm("date_start"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date_start"})
+		return
+	}
+	dateEnd, err := time.Parse(time.RFC3339, c.PostForm("date_end"))
+	if err != nil || !dateEnd.After(dateStart) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date_end"})
+		return
+	}
+	capacity, err := strconv.Atoi(c.PostForm("capacity"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid capacity"})
+		return
+	}
+	statusID, err := strconv.ParseUint(c.PostForm("status_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status_id"})
+		return
+	}
+	clubID, err := strconv.ParseUint(c.PostForm("club_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid club_id"})
+		return
+	}
+	categoryID, err := strconv.ParseUint(c.PostForm("category_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category_id"})
+		return
+	}
+
+	venueID, _, err := parseVenueIDForm(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := requireClubPermission(c, uint(clubID), permCreateActivities)
+	if err != nil {
+		return
+	}
+	if !requireClubActive(c, uint(clubID)) {
+		return
+	}
+
+	var status entity.ActivityStatus
+	if err := db.First(&status, statusID).Error; err != nil || (status.Name != "draft" && status.Name != "pending") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status_id ต้องเป็น draft หรือ pending"})
+		return
+	}
+
+	occurrences := rule.Occurrences(dateStart, maxSeriesOccurrences+1)
+	if len(occurrences) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "RRULE ไม่ได้สร้างวันกิจกรรมเลย"})
+		return
+	}
+	if len(occurrences) > maxSeriesOccurrences {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("series หนึ่งสร้างได้ไม่เกิน %d ครั้ง", maxSeriesOccurrences)})
+		return
+	}
+
+	var posterImagePath string
+	poster, err := saveImageUpload(c, "poster_image", purposeActivityPoster)
+	if respondImageUploadError(c, err) {
+		return
+	}
+	if poster != nil {
+		posterImagePath = storedImageURL(poster)
+	}
+
+	duration := dateEnd.Sub(dateStart)
+	series := entity.ActivitySeries{
+		ClubID:          uint(clubID),
+		RRule:           rule.String(),
+		DTStart:         dateStart,
+		DurationMinutes: int(duration.Minutes()),
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&series).Error; err != nil {
+			return err
+		}
+		for _, start := range occurrences {
+			recurrenceID := start
+			activity := entity.Activity{
+				Title:        c.PostForm("title"),
+				Description:  c.PostForm("description"),
+				Location:     c.PostForm("location"),
+				DateStart:    start,
+				DateEnd:      start.Add(duration),
+				Capacity:     capacity,
+				PosterImage:  posterImagePath,
+				StatusID:     status.ID,
+				ClubID:       uint(clubID),
+				CategoryID:   uint(categoryID),
+				VenueID:      venueID,
+				SubmittedBy:  user.ID,
+				SeriesID:     &series.ID,
+				RecurrenceID: &recurrenceID,
+			}
+			if status.Name == "pending" {
+				if err := startActivityApproval(tx, &activity, user.ID); err != nil {
+					return err
+				}
+			}
+			if err := tx.Create(&activity).Error; err != nil {
+				return err
+			}
+			if err := bookActivityVenue(tx, &activity, user.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		if respondVenueError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create activity series"})
+		return
+	}
+
+	db.Preload("Occurrences", func(db *gorm.DB) *gorm.DB { return db.Order("date_start ASC") }).First(&series, series.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Activity series created successfully", "data": series})
+}
+
+// GET /activity-series/:id - ดู series พร้อมทุกครั้งของกิจกรรม
+func GetActivitySeries(c *gin.Context) {
+	var series entity.ActivitySeries
+	if err := config.DB().
+		Preload("Occurrences", func(db *gorm.DB) *gorm.DB { return db.Order("date_start ASC") }).
+		Preload("Occurrences.Status").
+		First(&series, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบ series ของกิจกรรม"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": series, "ex_dates": loadSeriesExDates(&series)})
+}
+
+// PATCH /activity-series/:id/occurrences/:activityId - แก้ไขครั้งนี้ (scope=this) หรือครั้งนี้และครั้งต่อไป (scope=following)
+func UpdateSeriesOccurrence(c *gin.Context) {
+	db := config.DB()
+
+	series, activity, err := loadSeriesOccurrence(db, c.Param("id"), c.Param("activityId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบกิจกรรมใน series นี้"})
+		return
+	}
+	user, err := requireActivityOfficer(c, activity)
+	if err != nil {
+		return
+	}
+
+	var input struct {
+		Scope       string     `json:"scope"`
+		Reason      string     `json:"reason"`
+		Title       *string    `json:"title"`
+		Description *string    `json:"description"`
+		Location    *string    `json:"location"`
+		Capacity    *int       `json:"capacity"`
+		CategoryID  *uint      `json:"category_id"`
+		DateStart   *time.Time `json:"date_start"`
+		DateEnd     *time.Time `json:"date_end"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	if input.Scope != "this" && input.Scope != "following" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope ต้องเป็น this หรือ following"})
+		return
+	}
+
+	// เวลาใหม่ของครั้งนี้ ครั้งต่อไปจะเลื่อนตามเท่ากัน
+	newStart, newEnd := activity.DateStart, activity.DateEnd
+	if input.DateStart != nil {
+		newStart = *input.DateStart
+		if input.DateEnd == nil {
+			newEnd = newStart.Add(activity.DateEnd.Sub(activity.DateStart))
+		}
+	}
+	if input.DateEnd != nil {
+		newEnd = *input.DateEnd
+	}
+	if !newEnd.After(newStart) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date_end ต้องอยู่หลัง date_start"})
+		return
+	}
+	shift := newStart.Sub(activity.DateStart)
+	duration := newEnd.Sub(newStart)
+
+	// การเปลี่ยนวันเวลาเป็นการเลื่อนกิจกรรม ผู้ลงทะเบียนต้องได้รับแจ้งและยืนยันใหม่เหมือน /activities/:id/reschedule
+	reason := strings.TrimSpace(input.Reason)
+	moved := shift != 0 || duration != activity.DateEnd.Sub(activity.DateStart)
+	if moved {
+		if reason == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุเหตุผลในการเลื่อนกิจกรรม"})
+			return
+		}
+		if !newStart.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "วันเวลาใหม่ต้องอยู่ในอนาคต"})
+			return
+		}
+	}
+
+	apply := func(a *entity.Activity) {
+		if input.Title != nil {
+			a.Title = *input.Title
+		}
+		if input.Description != nil {
+			a.Description = *input.Description
+		}
+		if input.Location != nil {
+			a.Location = *input.Location
+		}
+		if input.Capacity != nil {
+			a.Capacity = *input.Capacity
+		}
+		if input.CategoryID != nil {
+			a.CategoryID = *input.CategoryID
+		}
+		a.CalendarSequence++
+	}
+
+	// ครั้งที่ยังจัดต่อจะถูกเลื่อนผ่าน rescheduleActivity หลัง commit ต้องแจ้งผู้ลงทะเบียนของแต่ละครั้ง
+	type rescheduled struct {
+		activity entity.Activity
+		users    []entity.User
+		oldStart time.Time
+		deadline time.Time
+	}
+	var notices []rescheduled
+	reschedule := func(tx *gorm.DB, a *entity.Activity) error {
+		if a.Status.Name == "cancelled" || a.Status.Name == "finished" {
+			oldEnd := a.DateEnd
+			a.DateStart = a.DateStart.Add(shift)
+			a.DateEnd = a.DateStart.Add(duration)
+			if err := tx.Model(&entity.Activity{}).Where("id = ?", a.ID).
+				Updates(map[string]interface{}{"date_start": a.DateStart, "date_end": a.DateEnd}).Error; err != nil {
+				return err
+			}
+			if _, err := shiftActivitySessions(tx, a.ID, shift); err != nil {
+				return err
+			}
+			if err := shiftActivityResourceReservations(tx, a.ID, shift, a.DateEnd.Sub(oldEnd)); err != nil {
+				return err
+			}
+			return shiftCheckinWindow(tx, a.ID, shift, a.DateEnd.Sub(oldEnd))
+		}
+		users, err := registeredUsersOf(tx, a.ID)
+		if err != nil {
+			return err
+		}
+		oldStart := a.DateStart
+		start := oldStart.Add(shift)
+		deadline := defaultReconfirmDeadline(time.Now(), start)
+		if err := rescheduleActivity(tx, a, start, start.Add(duration), deadline, reason, len(users), user.ID); err != nil {
+			return err
+		}
+		notices = append(notices, rescheduled{activity: *a, users: users, oldStart: oldStart, deadline: deadline})
+		return nil
+	}
+	notify := func() {
+		for i := range notices {
+			n := &notices[i]
+			notifyActivityRescheduled(&n.activity, n.users, n.oldStart, reason, n.deadline)
+		}
+	}
+
+	if input.Scope == "this" {
+		before := activityRevisionOf(activity)
+		apply(activity)
+		activity.SeriesException = true
+		reapproval := false
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Omit("Status").Save(activity).Error; err != nil {
+				return err
+			}
+			if moved {
+				if err := reschedule(tx, activity); err != nil {
+					return err
+				}
+			} else if err := bookActivityVenue(tx, activity, user.ID); err != nil {
+				return err
+			}
+
+			// ครั้งที่อนุมัติแล้วถ้าแก้ชื่อ เนื้อหา สถานที่ หรือวันเวลา ต้องกลับไปขออนุมัติใหม่เหมือนการแก้ไขกิจกรรมปกติ
+			var err error
+			if reapproval, err = restartApprovalIfChanged(tx, activity, before, user.ID); err != nil || !reapproval {
+				return err
+			}
+			return tx.Model(&entity.Activity{}).Where("id = ?", activity.ID).Updates(map[string]interface{}{
+				"status_id":      activity.StatusID,
+				"approval_step":  activity.ApprovalStep,
+				"approval_round": activity.ApprovalRound,
+				"submitted_by":   activity.SubmittedBy,
+				"approved_at":    activity.ApprovedAt,
+			}).Error
+		}); err != nil {
+			if respondVenueError(c, err) {
+				return
+			}
+			if errors.Is(err, errSessionSpanChanged) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, errClubSuspended) || errors.Is(err, errClubArchived) {
+				respondClubStateError(c, err)
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update activity"})
+			return
+		}
+		notify()
+		c.JSON(http.StatusOK, gin.H{"message": "Activity updated successfully", "data": activity, "reapproval": reapproval})
+		return
+	}
+
+	targetSeries := series
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var following []entity.Activity
+		if err := tx.Preload("Status").
+			Where("series_id = ? AND recurrence_id >= ?", series.ID, activity.RecurrenceID).
+			Order("recurrence_id ASC").
+			Find(&following).Error; err != nil {
+			return err
+		}
+
+		rule, err := utils.ParseRRule(series.RRule)
+		if err != nil {
+			return err
+		}
+		// เลื่อนข้ามวันต้องเลื่อนวันในกฎตามด้วย เช่น จาก จันทร์/พฤหัส เป็น อังคาร/ศุกร์
+		// นับวันตามเขตเวลาของ series (Truncate จะปัดตาม UTC ทำให้กิจกรรมก่อน 07:00 ของไทยนับเป็นวันก่อนหน้า)
+		loc := series.DTStart.Location()
+		oldDay := localDay(activity.DateStart, loc)
+		dayShift := int(localDay(newStart, loc).Sub(oldDay).Hours() / 24)
+
+		// ครั้งแรกของ series แก้ที่ series เดิมได้เลย ไม่ต้องแยก
+		if !activity.RecurrenceID.After(series.DTStart) {
+			rule.ShiftDays(dayShift)
+			series.RRule = rule.String()
+			series.DTStart = series.DTStart.Add(shift)
+			series.DurationMinutes = int(duration.Minutes())
+			if err := tx.Save(series).Error; err != nil {
+				return err
+			}
+		} else {
+			newRule := *rule
+			newRule.ByDay = slices.Clone(rule.ByDay)
+			newRule.ByMonthDay = slices.Clone(rule.ByMonthDay)
+			newRule.ShiftDays(dayShift)
+			if rule.Count > 0 {
+				newRule.Count = len(following)
+			}
+			rule.Count = 0
+			rule.Until = activity.RecurrenceID.Add(-time.Second)
+			series.RRule = rule.String()
+			if err := tx.Save(series).Error; err != nil {
+				return err
+			}
+
+			parentID := series.ID
+			targetSeries = &entity.ActivitySeries{
+				ClubID:          series.ClubID,
+				RRule:           newRule.String(),
+				DTStart:         activity.RecurrenceID.Add(shift),
+				DurationMinutes: int(duration.Minutes()),
+				ParentSeriesID:  &parentID,
+			}
+			if err := tx.Create(targetSeries).Error; err != nil {
+				return err
+			}
+		}
+
+		for i := range following {
+			a := &following[i]
+			recurrenceID := a.RecurrenceID.Add(shift)
+			a.RecurrenceID = &recurrenceID
+			a.SeriesID = &targetSeries.ID
+
+			// ครั้งที่เคยแก้ไขเป็นรายครั้ง (exception) ย้ายตาม series แต่คงข้อมูลและวันเวลาที่แก้ไว้
+			if a.SeriesException && a.ID != activity.ID {
+				if err := tx.Model(&entity.Activity{}).Where("id = ?", a.ID).
+					Updates(map[string]interface{}{"series_id": a.SeriesID, "recurrence_id": a.RecurrenceID}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+
+			apply(a)
+			if err := tx.Omit("Status").Save(a).Error; err != nil {
+				return err
+			}
+			if moved {
+				if err := reschedule(tx, a); err != nil {
+					return err
+				}
+			} else if err := bookActivityVenue(tx, a, user.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if respondVenueError(c, err) {
+			return
+		}
+		if errors.Is(err, errSessionSpanChanged) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update activity series"})
+		return
+	}
+	notify()
+
+	db.Preload("Occurrences", func(db *gorm.DB) *gorm.DB { return db.Order("date_start ASC") }).First(targetSeries, targetSeries.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Activity series updated successfully", "data": targetSeries})
+}
+
+// POST /activity-series/:id/occurrences/:activityId/cancel - ยกเลิกเฉพาะครั้งนี้ (ต้องระบุเหตุผล)
+func CancelSeriesOccurrence(c *gin.Context) {
+	db := config.DB()
+
+	series, activity, err := loadSeriesOccurrence(db, c.Param("id"), c.Param("activityId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบกิจกรรมใน series นี้"})
+		return
+	}
+	user, err := requireActivityOfficer(c, activity)
+	if err != nil {
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุเหตุผลในการยกเลิก"})
+		return
+	}
+	if activity.Status.Name == "cancelled" || !canTransitionActivity(activity.Status.Name, "cancelled") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่สามารถยกเลิกกิจกรรมในสถานะ " + activity.Status.Name + " ได้"})
+		return
+	}
+
+	users, err := registeredUsersOf(db, activity.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงรายชื่อผู้ลงทะเบียนได้"})
+		return
+	}
+	reason := strings.TrimSpace(input.Reason)
+	// ยกเลิกครั้งนี้และเพิ่ม EXDATE ใน transaction เดียว กันปฏิทินสร้างครั้งที่ยกเลิกแล้วกลับมา
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := markActivityCancelled(tx, activity, reason, len(users), user.ID); err != nil {
+			return err
+		}
+		return addSeriesExDate(tx, series, *activity.RecurrenceID)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ยกเลิกกิจกรรมไม่สำเร็จ"})
+		return
+	}
+	notifyActivityCancelled(activity, users, reason)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ยกเลิกกิจกรรมครั้งนี้เรียบร้อยแล้ว", "affected": len(users)})
+}
+
+// POST /activity-series/:id/register - ลงทะเบียนทุกครั้งที่ยังไม่เริ่มของ series ในครั้งเดียว
+// ครั้งที่เต็ม ต้องสมัครเป็นทีม หรือมีแบบฟอร์มบังคับตอบจะถูกข้ามและแจ้งเหตุผลกลับไป
+func RegisterActivitySeries(c *gin.Context) {
+	db := config.DB()
+
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var series entity.ActivitySeries
+	if err := db.First(&series, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบ series ของกิจกรรม"})
+		return
+	}
+	approved, err := getActivityStatusByName(db, "approved")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	registeredID, err := getRegistrationStatusID(db, "registered")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	cancelledID, err := getRegistrationStatusID(db, "cancelled")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	var occurrences []entity.Activity
+	db.Where("series_id = ? AND status_id = ? AND date_start > ?", series.ID, approved.ID, time.Now()).
+		Order("date_start ASC").
+		Find(&occurrences)
+
+	type skipped struct {
+		ActivityID uint   `json:"activity_id"`
+		DateStart  string `json:"date_start"`
+		Reason     string `json:"reason"`
+	}
+	var registered []entity.ActivityRegistration
+	var skips []skipped
+
+	for _, a := range occurrences {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var count int64
+			tx.Model(&entity.ActivityRegistration{}).
+				Where("activity_id = ? AND user_id = ? AND status_id <> ?", a.ID, user.ID, cancelledID).
+				Count(&count)
+			if count > 0 {
+				return errors.New("ลงทะเบียนไว้แล้ว")
+			}
+			tx.Model(&entity.ActivityTeamSetting{}).Where("activity_id = ?", a.ID).Count(&count)
+			if count > 0 {
+				return errors.New("ต้องสมัครเป็นทีม")
+			}
+			tx.Model(&entity.RegistrationQuestion{}).Where("activity_id = ? AND required = ?", a.ID, true).Count(&count)
+			if count > 0 {
+				return errors.New("ต้องกรอกแบบฟอร์มลงทะเบียน")
+			}
+			if a.Capacity > 0 {
+				tx.Model(&entity.ActivityRegistration{}).
+					Where("activity_id = ? AND status_id <> ?", a.ID, cancelledID).
+					Count(&count)
+				if int(count) >= a.Capacity {
+					return errors.New("ที่นั่งเต็มแล้ว")
+				}
+			}
+			if err := checkSessionCapacity(tx, a.ID, 1); err != nil {
+				return err
+			}
+			reg := entity.ActivityRegistration{
+				ActivityID:   a.ID,
+				UserID:       user.ID,
+				StatusID:     registeredID,
+				RegisteredAt: time.Now(),
+			}
+			if err := tx.Create(&reg).Error; err != nil {
+				return err
+			}
+			registered = append(registered, reg)
+			return nil
+		})
+		if err != nil {
+			skips = append(skips, skipped{ActivityID: a.ID, DateStart: a.DateStart.Format(time.RFC3339), Reason: err.Error()})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    fmt.Sprintf("ลงทะเบียนสำเร็จ %d ครั้ง", len(registered)),
+		"registered": registered,
+		"skipped":    skips,
+	})
+}
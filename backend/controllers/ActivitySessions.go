@@ -0,0 +1,747 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// เปิดให้เช็คชื่อรอบก่อนเวลาเริ่มได้เท่านี้
+const sessionCheckinLead = 30 * time.Minute
+
+type ActivitySessionInput struct {
+	Title     string    `json:"title"`
+	Location  string    `json:"location"`
+	DateStart time.Time `json:"date_start" binding:"required"`
+	DateEnd   time.Time `json:"date_end" binding:"required"`
+	Capacity  int       `json:"capacity"`
+	Reason    string    `json:"reason"` // เหตุผลที่แจ้งผู้ลงทะเบียนเมื่อการแก้รอบทำให้กิจกรรมเลื่อน
+}
+
+// สรุปการเข้าร่วมรายคนของกิจกรรมที่มีหลายรอบ
+type SessionAttendanceSummary struct {
+	RegistrationID   uint    `json:"registration_id"`
+	UserID           uint    `json:"user_id"`
+	StudentID        string  `json:"student_id"`
+	FullName         string  `json:"full_name"`
+	AttendedSessions int     `json:"attended_sessions"`
+	TotalSessions    int     `json:"total_sessions"`
+	Percent          float64 `json:"percent"`
+	Hours            float64 `json:"hours"`
+	Completed        bool    `json:"completed"`
+}
+
+func loadActivitySessions(db *gorm.DB, activityID uint) ([]entity.ActivitySession, error) {
+	var sessions []entity.ActivitySession
+	err := db.Where("activity_id = ?", activityID).Order("date_start ASC").Find(&sessions).Error
+	return sessions, err
+}
+
+var errSessionStartInPast = errors.New("การแก้รอบทำให้กิจกรรมเริ่มในอดีต กรุณาเลือกเวลาเริ่มในอนาคต")
+
+// การเลื่อนกิจกรรมที่เกิดจากการแก้รอบ ต้องแจ้งผู้ลงทะเบียนหลัง commit
+type sessionReschedule struct {
+	activity entity.Activity
+	users    []entity.User
+	oldStart time.Time
+	reason   string
+	deadline time.Time
+}
+
+func (r *sessionReschedule) notify() {
+	if r != nil {
+		notifyActivityRescheduled(&r.activity, r.users, r.oldStart, r.reason, r.deadline)
+	}
+}
+
+// ให้วันเริ่ม/สิ้นสุดของกิจกรรมครอบคลุมทุกรอบ เพื่อให้การค้นหา ปฏิทิน และการแจ้งเตือนใช้ช่วงเวลาที่ถูกต้อง
+// ถ้าเวลาเริ่มเปลี่ยน จะเลื่อนกิจกรรมผ่าน rescheduleActivity และคืนข้อมูลสำหรับแจ้งผู้ลงทะเบียน
+func syncActivityDatesWithSessions(tx *gorm.DB, activityID uint, reason string, changedBy uint) (*sessionReschedule, error) {
+	sessions, err := loadActivitySessions(tx, activityID)
+	if err != nil {
+		return nil, err
+	}
+	var activity entity.Activity
+	if err := tx.Preload("Status").First(&activity, activityID).Error; err != nil {
+		return nil, err
+	}
+	// ลบรอบสุดท้ายแล้ว กลับไปจองสถานที่ตามช่วงเวลาของกิจกรรม
+	if len(sessions) == 0 {
+		return nil, bookActivityVenue(tx, &activity, changedBy)
+	}
+	start, end := sessions[0].DateStart, sessions[0].DateEnd
+	for _, s := range sessions[1:] {
+		if s.DateEnd.After(end) {
+			end = s.DateEnd
+		}
+	}
+
+	// ช่วงกิจกรรมไม่เปลี่ยน แต่รอบกลางอาจย้ายหรือเปลี่ยนสถานที่ จึงต้องจองสถานที่ตามรอบใหม่
+	if start.Equal(activity.DateStart) && end.Equal(activity.DateEnd) {
+		if err := tx.Model(&entity.Activity{}).Where("id = ?", activityID).
+			Update("calendar_sequence", gorm.Expr("calendar_sequence + 1")).Error; err != nil {
+			return nil, err
+		}
+		return nil, bookActivityVenue(tx, &activity, changedBy)
+	}
+
+	// กิจกรรมที่ยกเลิกหรือจบแล้วไม่มีผู้ต้องยืนยันใหม่ ย้ายวันเวลาและการจองอุปกรณ์ตามอย่างเดียว
+	shift := start.Sub(activity.DateStart)
+	if activity.Status.Name == "cancelled" || activity.Status.Name == "finished" {
+		if err := tx.Model(&entity.Activity{}).Where("id = ?", activityID).Updates(map[string]interface{}{
+			"date_start":        start,
+			"date_end":          end,
+			"calendar_sequence": gorm.Expr("calendar_sequence + 1"),
+		}).Error; err != nil {
+			return nil, err
+		}
+		if err := shiftActivityResourceReservations(tx, activityID, shift, end.Sub(activity.DateEnd)); err != nil {
+			return nil, err
+		}
+		return nil, shiftCheckinWindow(tx, activityID, shift, end.Sub(activity.DateEnd))
+	}
+
+	// กิจกรรมที่ยังไม่เปิดรับสมัคร หรือเลื่อนแค่เวลาสิ้นสุด (เช่นเพิ่มรอบท้าย) ไม่มีผู้ต้องยืนยันใหม่
+	// แต่การจองสถานที่และอุปกรณ์ต้องตามช่วงเวลาใหม่
+	if activity.Status.Name == "draft" || activity.Status.Name == "pending" || shift == 0 {
+		if err := tx.Model(&entity.Activity{}).Where("id = ?", activityID).Updates(map[string]interface{}{
+			"date_start":        start,
+			"date_end":          end,
+			"calendar_sequence": gorm.Expr("calendar_sequence + 1"),
+		}).Error; err != nil {
+			return nil, err
+		}
+		endShift := end.Sub(activity.DateEnd)
+		activity.DateStart, activity.DateEnd = start, end
+		if err := bookActivityVenue(tx, &activity, changedBy); err != nil {
+			return nil, err
+		}
+		if err := shiftActivityResourceReservations(tx, activityID, shift, endShift); err != nil {
+			return nil, err
+		}
+		return nil, shiftCheckinWindow(tx, activityID, shift, endShift)
+	}
+
+	if !start.After(time.Now()) {
+		return nil, errSessionStartInPast
+	}
+	users, err := registeredUsersOf(tx, activityID)
+	if err != nil {
+		return nil, err
+	}
+	oldStart := activity.DateStart
+	deadline := defaultReconfirmDeadline(time.Now(), start)
+	if err := applyActivityReschedule(tx, &activity, start, end, deadline, reason, len(users), changedBy); err != nil {
+		return nil, err
+	}
+	return &sessionReschedule{activity: activity, users: users, oldStart: oldStart, reason: reason, deadline: deadline}, nil
+}
+
+// เหตุผลการเลื่อนที่ officer ระบุ หรือใช้รายการที่แก้เป็นเหตุผลถ้าไม่ได้ระบุ
+func sessionChangeReason(reason, fallback string) string {
+	if r := strings.TrimSpace(reason); r != "" {
+		return r
+	}
+	return fallback
+}
+
+// ตอบกลับข้อผิดพลาดที่รู้จักจากการปรับวันเวลากิจกรรมตามรอบ คืน true ถ้าตอบไปแล้ว
+func respondSessionSyncError(c *gin.Context, err error) bool {
+	if errors.Is(err, errSessionStartInPast) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return true
+	}
+	return respondVenueError(c, err)
+}
+
+// รอบที่กำลังเปิดเช็คชื่ออยู่ ณ เวลาที่ระบุ
+func currentActivitySession(sessions []entity.ActivitySession, now time.Time) *entity.ActivitySession {
+	for i := range sessions {
+		if !now.Before(sessions[i].DateStart.Add(-sessionCheckinLead)) && !now.After(sessions[i].DateEnd) {
+			return &sessions[i]
+		}
+	}
+	return nil
+}
+
+func requiredAttendancePercent(activity *entity.Activity) int {
+	if activity.MinAttendancePercent <= 0 {
+		return 100
+	}
+	return activity.MinAttendancePercent
+}
+
+func validateSessionInput(input ActivitySessionInput) error {
+	if !input.DateEnd.After(input.DateStart) {
+		return errors.New("เวลาสิ้นสุดของรอบต้องอยู่หลังเวลาเริ่ม")
+	}
+	if input.Capacity < 0 {
+		return errors.New("capacity ต้องไม่ติดลบ")
+	}
+	return nil
+}
+
+// จำนวนผู้ที่เช็คชื่อแล้วในรอบ
+func countPresentInSession(db *gorm.DB, sessionID uint) int64 {
+	var count int64
+	db.Model(&entity.SessionAttendance{}).Where("session_id = ? AND present = ?", sessionID, true).Count(&count)
+	return count
+}
+
+// ผู้ลงทะเบียนเข้าร่วมทุกรอบ ความจุของรอบจึงจำกัดจำนวนผู้ลงทะเบียนกิจกรรม
+// ตรวจตอนลงทะเบียนว่ารับเพิ่มอีก adding คนแล้วไม่เกินความจุของรอบใด
+func checkSessionCapacity(tx *gorm.DB, activityID uint, adding int) error {
+	var sessions []entity.ActivitySession
+	if err := tx.Where("activity_id = ? AND capacity > 0", activityID).Order("capacity ASC").Find(&sessions).Error; err != nil || len(sessions) == 0 {
+		return err
+	}
+	if int(countActiveRegistrations(tx, activityID))+adding > sessions[0].Capacity {
+		return fmt.Errorf("รอบ %s เต็มแล้ว", sessions[0].Title)
+	}
+	return nil
+}
+
+// จำนวนผู้ลงทะเบียนที่ไม่ได้ยกเลิก
+func countActiveRegistrations(db *gorm.DB, activityID uint) int64 {
+	var count int64
+	cancelled := db.Model(&entity.ActivityRegistrationStatus{}).Select("id").Where("name = ?", "cancelled")
+	db.Model(&entity.ActivityRegistration{}).
+		Where("activity_id = ? AND status_id NOT IN (?)", activityID, cancelled).
+		Count(&count)
+	return count
+}
+
+// บันทึกการเข้าร่วมของผู้ลงทะเบียนในรอบ (สร้างใหม่หรือแก้ไขรายการเดิม)
+func recordSessionAttendance(tx *gorm.DB, session *entity.ActivitySession, registrationID uint, present bool, method string, recordedBy uint, at time.Time) error {
+	var record entity.SessionAttendance
+	tx.Where("session_id = ? AND registration_id = ?", session.ID, registrationID).FirstOrInit(&record)
+	record.SessionID = session.ID
+	record.RegistrationID = registrationID
+	record.Present = present
+	record.Method = method
+	record.RecordedBy = recordedBy
+	if present {
+		record.CheckinTime = &at
+	} else {
+		record.CheckinTime = nil
+	}
+	return tx.Save(&record).Error
+}
+
+// คำนวณจำนวนรอบที่เข้าร่วม ชั่วโมงสะสม และผลผ่าน/ไม่ผ่านของผู้ลงทะเบียนทุกคน
+func computeSessionAttendance(db *gorm.DB, activity *entity.Activity) ([]SessionAttendanceSummary, error) {
+	sessions, err := loadActivitySessions(db, activity.ID)
+	if err != nil {
+		return nil, err
+	}
+	hoursBySession := map[uint]float64{}
+	for _, s := range sessions {
+		hoursBySession[s.ID] = s.DateEnd.Sub(s.DateStart).Hours()
+	}
+
+	cancelledID, err := getRegistrationStatusID(db, "cancelled")
+	if err != nil {
+		return nil, err
+	}
+	var regs []entity.ActivityRegistration
+	if err := db.Preload("User").Where("activity_id = ? AND status_id <> ?", activity.ID, cancelledID).
+		Order("id ASC").Find(&regs).Error; err != nil {
+		return nil, err
+	}
+
+	var records []entity.SessionAttendance
+	if err := db.Joins("JOIN activity_sessions ON activity_sessions.id = session_attendances.session_id AND activity_sessions.deleted_at IS NULL").
+		Where("activity_sessions.activity_id = ? AND session_attendances.present = ?", activity.ID, true).
+		Find(&records).Error; err != nil {
+		return nil, err
+	}
+	attended := map[uint][]uint{}
+	for _, r := range records {
+		attended[r.RegistrationID] = append(attended[r.RegistrationID], r.SessionID)
+	}
+
+	required := float64(requiredAttendancePercent(activity))
+	summaries := make([]SessionAttendanceSummary, 0, len(regs))
+	for _, reg := range regs {
+		summary := SessionAttendanceSummary{
+			RegistrationID: reg.ID,
+			UserID:         reg.UserID,
+			StudentID:      reg.User.StudentID,
+			FullName:       strings.TrimSpace(reg.User.FirstName + " " + reg.User.LastName),
+			TotalSessions:  len(sessions),
+		}
+		for _, sessionID := range attended[reg.ID] {
+			summary.AttendedSessions++
+			summary.Hours += hoursBySession[sessionID]
+		}
+		if len(sessions) > 0 {
+			summary.Percent = math.Round(float64(summary.AttendedSessions)*10000/float64(len(sessions))) / 100
+		}
+		summary.Hours = math.Round(summary.Hours*100) / 100
+		summary.Completed = len(sessions) > 0 && summary.Percent >= required
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// สรุปผลการเข้าร่วมเมื่อกิจกรรมจบ: ผู้ที่ผ่านเกณฑ์เป็น attended และได้ชั่วโมงตามรอบที่เข้าร่วม ที่เหลือเป็น absent
+func finalizeSessionAttendance(db *gorm.DB, activity *entity.Activity, verifiedBy uint) (int, error) {
+	summaries, err := computeSessionAttendance(db, activity)
+	if err != nil {
+		return 0, err
+	}
+	attendedID, err := getRegistrationStatusID(db, "attended")
+	if err != nil {
+		return 0, err
+	}
+	absentID, err := getRegistrationStatusID(db, "absent")
+	if err != nil {
+		return 0, err
+	}
+
+	completed := 0
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, s := range summaries {
+			statusID := absentID
+			if s.Completed {
+				statusID = attendedID
+				completed++
+			}
+			if err := tx.Model(&entity.ActivityRegistration{}).Where("id = ?", s.RegistrationID).
+				Update("status_id", statusID).Error; err != nil {
+				return err
+			}
+
+			if !s.Completed {
+				if err := tx.Where("user_id = ? AND activity_id = ?", s.UserID, activity.ID).
+					Delete(&entity.ActivityHour{}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			var hour entity.ActivityHour
+			tx.Where("user_id = ? AND activity_id = ?", s.UserID, activity.ID).FirstOrInit(&hour)
+			hour.UserID = s.UserID
+			hour.ActivityID = activity.ID
+			hour.Hours = s.Hours
+			hour.VerifiedBy = verifiedBy
+			if err := tx.Omit("User", "Activity", "VerifiedUser").Save(&hour).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return completed, err
+}
+
+func loadSessionOfActivity(db *gorm.DB, activityID uint, sessionID string) (*entity.ActivitySession, error) {
+	var session entity.ActivitySession
+	if err := db.Where("id = ? AND activity_id = ?", sessionID, activityID).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GET /activities/:id/sessions - รายการรอบของกิจกรรม พร้อมจำนวนผู้เช็คชื่อ
+func GetActivitySessions(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	sessions, err := loadActivitySessions(db, activity.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงรอบกิจกรรมได้"})
+		return
+	}
+
+	data := make([]gin.H, 0, len(sessions))
+	for _, s := range sessions {
+		data = append(data, gin.H{
+			"session":       s,
+			"present_count": countPresentInSession(db, s.ID),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":                true,
+		"data":                   data,
+		"min_attendance_percent": requiredAttendancePercent(&activity),
+	})
+}
+
+// POST /activities/:id/sessions - เพิ่มรอบกิจกรรม (officer เท่านั้น)
+func CreateActivitySession(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	user, err := requireActivityOfficer(c, &activity)
+	if err != nil {
+		return
+	}
+
+	var input ActivitySessionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	if err := validateSessionInput(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session := entity.ActivitySession{
+		ActivityID: activity.ID,
+		Title:      strings.TrimSpace(input.Title),
+		Location:   strings.TrimSpace(input.Location),
+		DateStart:  input.DateStart,
+		DateEnd:    input.DateEnd,
+		Capacity:   input.Capacity,
+	}
+	var rescheduled *sessionReschedule
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if session.Title == "" {
+			var count int64
+			tx.Model(&entity.ActivitySession{}).Where("activity_id = ?", activity.ID).Count(&count)
+			session.Title = fmt.Sprintf("รอบที่ %d", count+1)
+		}
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		rescheduled, err = syncActivityDatesWithSessions(tx, activity.ID, sessionChangeReason(input.Reason, fmt.Sprintf("เพิ่มรอบกิจกรรม '%s'", session.Title)), user.ID)
+		return err
+	}); err != nil {
+		if respondSessionSyncError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "เพิ่มรอบกิจกรรมไม่สำเร็จ"})
+		return
+	}
+	rescheduled.notify()
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "เพิ่มรอบกิจกรรมเรียบร้อยแล้ว", "data": session})
+}
+
+// PATCH /activities/:id/sessions/:sessionId - แก้ไขเวลา สถานที่ หรือความจุของรอบ (officer เท่านั้น)
+func UpdateActivitySession(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	user, err := requireActivityOfficer(c, &activity)
+	if err != nil {
+		return
+	}
+	session, err := loadSessionOfActivity(db, activity.ID, c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบรอบกิจกรรม"})
+		return
+	}
+
+	var input ActivitySessionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	if err := validateSessionInput(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Capacity > 0 && countActiveRegistrations(db, activity.ID) > int64(input.Capacity) {
+		c.JSON(http.StatusConflict, gin.H{"error": "มีผู้ลงทะเบียนกิจกรรมมากกว่าความจุใหม่ของรอบแล้ว"})
+		return
+	}
+
+	if t := strings.TrimSpace(input.Title); t != "" {
+		session.Title = t
+	}
+	session.Location = strings.TrimSpace(input.Location)
+	session.DateStart = input.DateStart
+	session.DateEnd = input.DateEnd
+	session.Capacity = input.Capacity
+	var rescheduled *sessionReschedule
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Activity").Save(session).Error; err != nil {
+			return err
+		}
+		rescheduled, err = syncActivityDatesWithSessions(tx, activity.ID, sessionChangeReason(input.Reason, fmt.Sprintf("แก้ไขรอบกิจกรรม '%s'", session.Title)), user.ID)
+		return err
+	}); err != nil {
+		if respondSessionSyncError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "แก้ไขรอบกิจกรรมไม่สำเร็จ"})
+		return
+	}
+	rescheduled.notify()
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "แก้ไขรอบกิจกรรมเรียบร้อยแล้ว", "data": session})
+}
+
+// DELETE /activities/:id/sessions/:sessionId?reason= - ลบรอบที่ยังไม่มีการเช็คชื่อ (officer เท่านั้น)
+func DeleteActivitySession(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	user, err := requireActivityOfficer(c, &activity)
+	if err != nil {
+		return
+	}
+	session, err := loadSessionOfActivity(db, activity.ID, c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบรอบกิจกรรม"})
+		return
+	}
+
+	var recorded int64
+	db.Model(&entity.SessionAttendance{}).Where("session_id = ?", session.ID).Count(&recorded)
+	if recorded > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "รอบนี้มีการบันทึกการเข้าร่วมแล้ว ไม่สามารถลบได้"})
+		return
+	}
+
+	var rescheduled *sessionReschedule
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(session).Error; err != nil {
+			return err
+		}
+		rescheduled, err = syncActivityDatesWithSessions(tx, activity.ID, sessionChangeReason(c.Query("reason"), fmt.Sprintf("ยกเลิกรอบกิจกรรม '%s'", session.Title)), user.ID)
+		return err
+	}); err != nil {
+		if respondSessionSyncError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ลบรอบกิจกรรมไม่สำเร็จ"})
+		return
+	}
+	rescheduled.notify()
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ลบรอบกิจกรรมเรียบร้อยแล้ว"})
+}
+
+// PUT /activities/:id/attendance-rule - กำหนดเปอร์เซ็นต์การเข้าร่วมขั้นต่ำ (officer เท่านั้น)
+func UpdateAttendanceRule(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	if _, err := requireActivityOfficer(c, &activity); err != nil {
+		return
+	}
+
+	var input struct {
+		MinAttendancePercent int `json:"min_attendance_percent" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || input.MinAttendancePercent < 1 || input.MinAttendancePercent > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_attendance_percent ต้องอยู่ระหว่าง 1-100"})
+		return
+	}
+
+	if err := db.Model(&entity.Activity{}).Where("id = ?", activity.ID).
+		Update("min_attendance_percent", input.MinAttendancePercent).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกเกณฑ์ไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "บันทึกเกณฑ์การเข้าร่วมเรียบร้อยแล้ว", "min_attendance_percent": input.MinAttendancePercent})
+}
+
+// GET /activities/:id/sessions/:sessionId/attendance - รายชื่อการเข้าร่วมของรอบ (officer เท่านั้น)
+func GetSessionAttendance(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	if _, err := requireActivityOfficer(c, &activity); err != nil {
+		return
+	}
+	session, err := loadSessionOfActivity(db, activity.ID, c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบรอบกิจกรรม"})
+		return
+	}
+
+	var records []entity.SessionAttendance
+	if err := db.Preload("Registration.User").Where("session_id = ?", session.ID).Order("id ASC").Find(&records).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงข้อมูลการเข้าร่วมได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "session": session, "data": records})
+}
+
+// POST /activities/:id/sessions/:sessionId/attendance - officer บันทึกการเข้าร่วมของรอบทีละหลายคน
+func RecordSessionAttendanceBulk(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	user, err := requireActivityOfficer(c, &activity)
+	if err != nil {
+		return
+	}
+	session, err := loadSessionOfActivity(db, activity.ID, c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบรอบกิจกรรม"})
+		return
+	}
+
+	var input struct {
+		Records []struct {
+			UserID  uint `json:"user_id" binding:"required"`
+			Present bool `json:"present"`
+		} `json:"records" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+
+	cancelledID, err := getRegistrationStatusID(db, "cancelled")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่พบสถานะ cancelled"})
+		return
+	}
+
+	now := time.Now()
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		for _, r := range input.Records {
+			var reg entity.ActivityRegistration
+			if err := tx.Where("activity_id = ? AND user_id = ? AND status_id <> ?", activity.ID, r.UserID, cancelledID).
+				First(&reg).Error; err != nil {
+				return fmt.Errorf("ผู้ใช้ %d ไม่ได้ลงทะเบียนกิจกรรมนี้", r.UserID)
+			}
+			if err := recordSessionAttendance(tx, session, reg.ID, r.Present, "manual", user.ID, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "บันทึกการเข้าร่วมเรียบร้อยแล้ว", "present_count": countPresentInSession(db, session.ID)})
+}
+
+// GET /activities/:id/attendance-summary - สรุปการเข้าร่วมและผลผ่านเกณฑ์ของทุกคน (officer เท่านั้น)
+func GetAttendanceSummary(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	if _, err := requireActivityOfficer(c, &activity); err != nil {
+		return
+	}
+
+	summaries, err := computeSessionAttendance(db, &activity)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถสรุปการเข้าร่วมได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":                true,
+		"data":                   summaries,
+		"min_attendance_percent": requiredAttendancePercent(&activity),
+	})
+}
+
+// GET /activities/:id/my-attendance - การเข้าร่วมแต่ละรอบของผู้ใช้เอง
+func GetMyAttendance(c *gin.Context) {
+	db := config.DB()
+
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+
+	summaries, err := computeSessionAttendance(db, &activity)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถสรุปการเข้าร่วมได้"})
+		return
+	}
+	for _, s := range summaries {
+		if s.UserID != user.ID {
+			continue
+		}
+		var records []entity.SessionAttendance
+		db.Where("registration_id = ?", s.RegistrationID).Find(&records)
+		c.JSON(http.StatusOK, gin.H{
+			"success":                true,
+			"data":                   s,
+			"sessions":               records,
+			"min_attendance_percent": requiredAttendancePercent(&activity),
+		})
+		return
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "คุณไม่ได้ลงทะเบียนกิจกรรมนี้"})
+}
+
+// POST /activities/:id/attendance/finalize - ปิดผลการเข้าร่วมและให้ชั่วโมงกิจกรรม (officer เท่านั้น)
+// ระบบจะเรียกให้อัตโนมัติเมื่อกิจกรรมจบ แต่ officer สั่งซ้ำได้หลังแก้ไขการเข้าร่วม
+func FinalizeActivityAttendance(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	user, err := requireActivityOfficer(c, &activity)
+	if err != nil {
+		return
+	}
+	if time.Now().Before(activity.DateEnd) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กิจกรรมยังไม่จบ"})
+		return
+	}
+	var sessionCount int64
+	db.Model(&entity.ActivitySession{}).Where("activity_id = ?", activity.ID).Count(&sessionCount)
+	if sessionCount == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กิจกรรมนี้ไม่มีการแบ่งรอบ"})
+		return
+	}
+
+	completed, err := finalizeSessionAttendance(db, &activity, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "สรุปผลการเข้าร่วมไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "สรุปผลการเข้าร่วมเรียบร้อยแล้ว", "completed": completed})
+}
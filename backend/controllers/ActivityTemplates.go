@@ -0,0 +1,527 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+	"final-project/cems/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// การตั้งค่าเฉพาะของกิจกรรมที่ถูกคัดลอกไปพร้อมกับกิจกรรม
+// เวลาเปิดเช็คชื่อเก็บเป็นนาทีเทียบกับเวลาเริ่ม/สิ้นสุด เพื่อให้เลื่อนตามวันของกิจกรรมใหม่
+type activitySettings struct {
+	Questions            []templateQuestion      `json:"questions"`
+	Team                 *templateTeamSetting    `json:"team,omitempty"`
+	Checkin              *templateCheckinSetting `json:"checkin,omitempty"`
+	Sessions             []templateSession       `json:"sessions,omitempty"`
+	MinAttendancePercent int                     `json:"min_attendance_percent,omitempty"`
+	Hosts                []templateHost          `json:"hosts,omitempty"`
+}
+
+type templateQuestion struct {
+	Label     string `json:"label"`
+	Type      string `json:"type"`
+	Options   string `json:"options"`
+	Required  bool   `json:"required"`
+	SortOrder int    `json:"sort_order"`
+}
+
+type templateTeamSetting struct {
+	MinTeamSize  int    `json:"min_team_size"`
+	MaxTeamSize  int    `json:"max_team_size"`
+	CapacityUnit string `json:"capacity_unit"`
+}
+
+// รอบย่อยของกิจกรรม เก็บเวลาเป็นนาทีนับจาก DateStart ของกิจกรรม
+type templateSession struct {
+	Title           string `json:"title"`
+	Location        string `json:"location"`
+	StartMinutes    int    `json:"start_minutes"`
+	DurationMinutes int    `json:"duration_minutes"`
+	Capacity        int    `json:"capacity"`
+}
+
+type templateHost struct {
+	ClubID      uint    `json:"club_id"`
+	IsLead      bool    `json:"is_lead"`
+	CreditShare float64 `json:"credit_share"`
+}
+
+type templateCheckinSetting struct {
+	CodeInterval       int     `json:"code_interval"`
+	WindowStartMinutes int     `json:"window_start_minutes"` // เทียบกับ DateStart
+	WindowEndMinutes   int     `json:"window_end_minutes"`   // เทียบกับ DateEnd
+	Latitude           float64 `json:"latitude"`
+	Longitude          float64 `json:"longitude"`
+	RadiusMeters       float64 `json:"radius_meters"`
+}
+
+func snapshotActivitySettings(db *gorm.DB, activity *entity.Activity) (activitySettings, error) {
+	settings := activitySettings{Questions: []templateQuestion{}}
+
+	questions, err := loadRegistrationQuestions(db, activity.ID)
+	if err != nil {
+		return settings, err
+	}
+	for _, q := range questions {
+		settings.Questions = append(settings.Questions, templateQuestion{
+			Label:     q.Label,
+			Type:      q.Type,
+			Options:   q.Options,
+			Required:  q.Required,
+			SortOrder: q.SortOrder,
+		})
+	}
+
+	var team entity.ActivityTeamSetting
+	if err := db.Where("activity_id = ?", activity.ID).First(&team).Error; err == nil {
+		settings.Team = &templateTeamSetting{
+			MinTeamSize:  team.MinTeamSize,
+			MaxTeamSize:  team.MaxTeamSize,
+			CapacityUnit: team.CapacityUnit,
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return settings, err
+	}
+
+	var checkin entity.ActivityCheckinSetting
+	if err := db.Where("activity_id = ?", activity.ID).First(&checkin).Error; err == nil {
+		settings.Checkin = &templateCheckinSetting{
+			CodeInterval:       checkin.CodeInterval,
+			WindowStartMinutes: int(checkin.WindowStart.Sub(activity.DateStart).Minutes()),
+			WindowEndMinutes:   int(checkin.WindowEnd.Sub(activity.DateEnd).Minutes()),
+			Latitude:           checkin.Latitude,
+			Longitude:          checkin.Longitude,
+			RadiusMeters:       checkin.RadiusMeters,
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return settings, err
+	}
+
+	sessions, err := loadActivitySessions(db, activity.ID)
+	if err != nil {
+		return settings, err
+	}
+	for _, s := range sessions {
+		settings.Sessions = append(settings.Sessions, templateSession{
+			Title:           s.Title,
+			Location:        s.Location,
+			StartMinutes:    int(s.DateStart.Sub(activity.DateStart).Minutes()),
+			DurationMinutes: int(s.DateEnd.Sub(s.DateStart).Minutes()),
+			Capacity:        s.Capacity,
+		})
+	}
+	settings.MinAttendancePercent = activity.MinAttendancePercent
+
+	var hosts []entity.ActivityHost
+	if err := db.Where("activity_id = ?", activity.ID).Find(&hosts).Error; err != nil {
+		return settings, err
+	}
+	for _, h := range hosts {
+		settings.Hosts = append(settings.Hosts, templateHost{ClubID: h.ClubID, IsLead: h.IsLead, CreditShare: h.CreditShare})
+	}
+
+	return settings, nil
+}
+
+// สร้างการตั้งค่าให้กิจกรรมใหม่ รหัสลับเช็คชื่อสร้างใหม่เสมอ ไม่ใช้ร่วมกับกิจกรรมต้นฉบับ
+func applyActivitySettings(tx *gorm.DB, activity *entity.Activity, settings activitySettings) error {
+	for _, q := range settings.Questions {
+		if err := tx.Create(&entity.RegistrationQuestion{
+			ActivityID: activity.ID,
+			Label:      q.Label,
+			Type:       q.Type,
+			Options:    q.Options,
+			Required:   q.Required,
+			SortOrder:  q.SortOrder,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	if settings.Team != nil {
+		if err := tx.Create(&entity.ActivityTeamSetting{
+			ActivityID:   activity.ID,
+			MinTeamSize:  settings.Team.MinTeamSize,
+			MaxTeamSize:  settings.Team.MaxTeamSize,
+			CapacityUnit: settings.Team.CapacityUnit,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	if settings.Checkin != nil {
+		secret, err := utils.GenerateSecureToken(20)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(&entity.ActivityCheckinSetting{
+			ActivityID:   activity.ID,
+			Secret:       secret,
+			CodeInterval: settings.Checkin.CodeInterval,
+			WindowStart:  activity.DateStart.Add(time.Duration(settings.Checkin.WindowStartMinutes) * time.Minute),
+			WindowEnd:    activity.DateEnd.Add(time.Duration(settings.Checkin.WindowEndMinutes) * time.Minute),
+			Latitude:     settings.Checkin.Latitude,
+			Longitude:    settings.Checkin.Longitude,
+			RadiusMeters: settings.Checkin.RadiusMeters,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, s := range settings.Sessions {
+		start := activity.DateStart.Add(time.Duration(s.StartMinutes) * time.Minute)
+		if err := tx.Create(&entity.ActivitySession{
+			ActivityID: activity.ID,
+			Title:      s.Title,
+			Location:   s.Location,
+			DateStart:  start,
+			DateEnd:    start.Add(time.Duration(s.DurationMinutes) * time.Minute),
+			Capacity:   s.Capacity,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	if len(settings.Sessions) > 0 {
+		// กิจกรรมใหม่ยังเป็นแบบร่าง จึงไม่มีการเลื่อนที่ต้องแจ้งผู้ลงทะเบียน
+		if _, err := syncActivityDatesWithSessions(tx, activity.ID, "", activity.SubmittedBy); err != nil {
+			return err
+		}
+	}
+
+	// ชมรมผู้ร่วมจัดใช้ได้เมื่อชมรมหลักยังเป็นชมรมเดียวกับกิจกรรมใหม่ และผู้ร่วมจัดทุกชมรมยังดำเนินการอยู่
+	// (ชมรมที่ถูกระงับหรือยุบแล้วจะไม่ถูกดึงเข้าร่วมจัดกิจกรรมใหม่) ไม่เช่นนั้นกิจกรรมใหม่จะจัดโดยชมรมหลักชมรมเดียว
+	for _, h := range settings.Hosts {
+		if h.IsLead && h.ClubID != activity.ClubID {
+			return nil
+		}
+		if h.IsLead {
+			continue
+		}
+		if err := checkClubActive(tx, h.ClubID); errors.Is(err, errClubSuspended) || errors.Is(err, errClubArchived) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	// ผู้ร่วมจัดต้องตอบรับกิจกรรมใหม่อีกครั้ง
+	for _, h := range settings.Hosts {
+		status := activityHostPending
+		if h.IsLead {
+			status = activityHostAccepted
+		}
+		if err := tx.Create(&entity.ActivityHost{
+			ActivityID:  activity.ID,
+			ClubID:      h.ClubID,
+			IsLead:      h.IsLead,
+			CreditShare: h.CreditShare,
+			Status:      status,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// บันทึกกิจกรรมใหม่เป็นแบบร่างพร้อมการตั้งค่า ผู้สร้างต้องส่งขออนุมัติเองเหมือนกิจกรรมทั่วไป
+// ถ้าสถานที่เดิมไม่ว่างในวันใหม่ จะสร้างกิจกรรมโดยไม่จองสถานที่และคืนคำเตือนแทน
+func createDraftActivity(db *gorm.DB, activity *entity.Activity, settings activitySettings) ([]string, error) {
+	draft, err := getActivityStatusByName(db, "draft")
+	if err != nil {
+		return nil, err
+	}
+	warnings := []string{}
+	activity.StatusID = draft.ID
+	activity.MinAttendancePercent = settings.MinAttendancePercent
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Status", "Club", "Category", "Venue").Create(activity).Error; err != nil {
+			return err
+		}
+		if err := applyActivitySettings(tx, activity, settings); err != nil {
+			return err
+		}
+		if err := tx.First(activity, activity.ID).Error; err != nil {
+			return err
+		}
+		if err := bookActivityVenue(tx, activity, activity.SubmittedBy); err != nil {
+			var conflict *venueConflictError
+			if !errors.As(err, &conflict) && !errors.Is(err, errVenueUnavailable) {
+				return err
+			}
+			warnings = append(warnings, "ไม่ได้จองสถานที่เดิม: "+err.Error())
+			activity.VenueID = nil
+			if err := tx.Model(&entity.Activity{}).Where("id = ?", activity.ID).Update("venue_id", nil).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var invited []uint
+	db.Model(&entity.ActivityHost{}).Where("activity_id = ? AND status = ?", activity.ID, activityHostPending).Pluck("club_id", &invited)
+	notifyActivityHostInvites(db, activity, invited)
+	return append(warnings, venueCapacityWarnings(db, activity)...), nil
+}
+
+// POST /activities/:id/clone - คัดลอกกิจกรรมพร้อมการตั้งค่า โดยเลื่อนวันตาม offset_days หรือระบุ date_start ใหม่
+func CloneActivity(c *gin.Context) {
+	db := config.DB()
+
+	var source entity.Activity
+	if err := db.First(&source, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	// สำเนาเป็นกิจกรรมของชมรมหลัก ผู้คัดลอกจึงต้องสร้างกิจกรรมในชมรมหลักได้ (officer ของชมรมผู้ร่วมจัดไม่พอ)
+	user, err := requireClubPermission(c, source.ClubID, permCreateActivities)
+	if err != nil {
+		return
+	}
+	if !requireClubActive(c, source.ClubID) {
+		return
+	}
+
+	var input struct {
+		OffsetDays int        `json:"offset_days"`
+		DateStart  *time.Time `json:"date_start"`
+		Title      string     `json:"title"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	if input.OffsetDays == 0 && input.DateStart == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุ offset_days หรือ date_start"})
+		return
+	}
+
+	offset := time.Duration(input.OffsetDays) * 24 * time.Hour
+	if input.DateStart != nil {
+		offset = input.DateStart.Sub(source.DateStart)
+	}
+	dateStart := source.DateStart.Add(offset)
+	if !dateStart.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "วันเริ่มกิจกรรมใหม่ต้องอยู่ในอนาคต"})
+		return
+	}
+
+	settings, err := snapshotActivitySettings(db, &source)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถอ่านการตั้งค่ากิจกรรมได้"})
+		return
+	}
+
+	title := strings.TrimSpace(input.Title)
+	if title == "" {
+		title = source.Title
+	}
+	activity := entity.Activity{
+		Title:       title,
+		Description: source.Description,
+		Location:    source.Location,
+		DateStart:   dateStart,
+		DateEnd:     source.DateEnd.Add(offset),
+		Capacity:    source.Capacity,
+		PosterImage: source.PosterImage,
+		ClubID:      source.ClubID,
+		CategoryID:  source.CategoryID,
+		VenueID:     source.VenueID,
+		SubmittedBy: user.ID,
+	}
+	warnings, err := createDraftActivity(db, &activity, settings)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "คัดลอกกิจกรรมไม่สำเร็จ"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "คัดลอกกิจกรรมเรียบร้อยแล้ว", "data": activity, "warnings": warnings})
+}
+
+// โหลดชมรมจาก :id และตรวจว่าผู้เรียกเป็น officer ของชมรม (ส่ง response ให้เองถ้าไม่ผ่าน)
+func loadClubForOfficer(c *gin.Context) (*entity.Club, *entity.User, bool) {
+	var club entity.Club
+	if err := config.DB().First(&club, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูลชมรม"})
+		return nil, nil, false
+	}
+	user, err := requireClubPermission(c, club.ID, permCreateActivities)
+	if err != nil {
+		return nil, nil, false
+	}
+	return &club, user, true
+}
+
+// GET /clubs/:id/activity-templates - รายการแม่แบบกิจกรรมของชมรม (officer เท่านั้น)
+func GetActivityTemplates(c *gin.Context) {
+	club, _, ok := loadClubForOfficer(c)
+	if !ok {
+		return
+	}
+
+	var templates []entity.ActivityTemplate
+	if err := config.DB().Preload("Category").Where("club_id = ?", club.ID).Order("name ASC").Find(&templates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงแม่แบบกิจกรรมได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": templates})
+}
+
+// POST /clubs/:id/activity-templates - บันทึกกิจกรรมของชมรมเป็นแม่แบบ
+func CreateActivityTemplate(c *gin.Context) {
+	db := config.DB()
+
+	club, user, ok := loadClubForOfficer(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name       string `json:"name"`
+		ActivityID uint   `json:"activity_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุชื่อแม่แบบและกิจกรรมต้นฉบับ"})
+		return
+	}
+
+	var source entity.Activity
+	if err := db.Where("id = ? AND club_id = ?", input.ActivityID, club.ID).First(&source).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบกิจกรรมของชมรมนี้"})
+		return
+	}
+
+	name := strings.TrimSpace(input.Name)
+	var count int64
+	db.Model(&entity.ActivityTemplate{}).Where("club_id = ? AND name = ?", club.ID, name).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "มีแม่แบบชื่อนี้อยู่แล้ว"})
+		return
+	}
+
+	settings, err := snapshotActivitySettings(db, &source)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถอ่านการตั้งค่ากิจกรรมได้"})
+		return
+	}
+	raw, _ := json.Marshal(settings)
+
+	template := entity.ActivityTemplate{
+		ClubID:          club.ID,
+		Name:            name,
+		Title:           source.Title,
+		Description:     source.Description,
+		Location:        source.Location,
+		DurationMinutes: int(source.DateEnd.Sub(source.DateStart).Minutes()),
+		Capacity:        source.Capacity,
+		PosterImage:     source.PosterImage,
+		CategoryID:      source.CategoryID,
+		VenueID:         source.VenueID,
+		Settings:        string(raw),
+		CreatedBy:       user.ID,
+	}
+	if err := db.Create(&template).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกแม่แบบไม่สำเร็จ"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "บันทึกแม่แบบเรียบร้อยแล้ว", "data": template})
+}
+
+// DELETE /clubs/:id/activity-templates/:templateId - ลบแม่แบบ (กิจกรรมที่สร้างไปแล้วไม่ได้รับผลกระทบ)
+func DeleteActivityTemplate(c *gin.Context) {
+	club, _, ok := loadClubForOfficer(c)
+	if !ok {
+		return
+	}
+
+	// ลบถาวรเพื่อให้ใช้ชื่อเดิมสร้างแม่แบบใหม่ได้
+	result := config.DB().Unscoped().Where("id = ? AND club_id = ?", c.Param("templateId"), club.ID).Delete(&entity.ActivityTemplate{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ลบแม่แบบไม่สำเร็จ"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบแม่แบบ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ลบแม่แบบเรียบร้อยแล้ว"})
+}
+
+// POST /clubs/:id/activity-templates/:templateId/activities - สร้างกิจกรรมแบบร่างจากแม่แบบ
+func CreateActivityFromTemplate(c *gin.Context) {
+	db := config.DB()
+
+	club, user, ok := loadClubForOfficer(c)
+	if !ok {
+		return
+	}
+	if !requireClubActive(c, club.ID) {
+		return
+	}
+
+	var template entity.ActivityTemplate
+	if err := db.Where("id = ? AND club_id = ?", c.Param("templateId"), club.ID).First(&template).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบแม่แบบ"})
+		return
+	}
+
+	var input struct {
+		DateStart time.Time `json:"date_start" binding:"required"`
+		Title     string    `json:"title"`
+		Location  string    `json:"location"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุ date_start"})
+		return
+	}
+	if !input.DateStart.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "วันเริ่มกิจกรรมต้องอยู่ในอนาคต"})
+		return
+	}
+
+	var settings activitySettings
+	if template.Settings != "" {
+		if err := json.Unmarshal([]byte(template.Settings), &settings); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ข้อมูลแม่แบบไม่ถูกต้อง"})
+			return
+		}
+	}
+
+	activity := entity.Activity{
+		Title:       template.Title,
+		Description: template.Description,
+		Location:    template.Location,
+		DateStart:   input.DateStart,
+		DateEnd:     input.DateStart.Add(time.Duration(template.DurationMinutes) * time.Minute),
+		Capacity:    template.Capacity,
+		PosterImage: template.PosterImage,
+		ClubID:      club.ID,
+		CategoryID:  template.CategoryID,
+		VenueID:     template.VenueID,
+		SubmittedBy: user.ID,
+	}
+	if t := strings.TrimSpace(input.Title); t != "" {
+		activity.Title = t
+	}
+	if l := strings.TrimSpace(input.Location); l != "" {
+		activity.Location = l
+	}
+	warnings, err := createDraftActivity(db, &activity, settings)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "สร้างกิจกรรมจากแม่แบบไม่สำเร็จ"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "สร้างกิจกรรมจากแม่แบบเรียบร้อยแล้ว", "data": activity, "warnings": warnings})
+}
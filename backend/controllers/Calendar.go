@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+	"final-project/cems/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ฟีดปฏิทินรวมกิจกรรมที่จบไปแล้วไม่เกินช่วงนี้ด้วย เพื่อให้ปฏิทินปลายทางไม่ลบรายการเก่าทันที
+const calendarFeedHistory = 90 * 24 * time.Hour
+
+// กิจกรรมที่เคยเผยแพร่แล้วแต่ถูกแก้ไขจนต้องกลับไปรออนุมัติใหม่ยังต้องอยู่ในฟีด (เป็น STATUS:TENTATIVE)
+// ไม่เช่นนั้นปฏิทินของผู้ติดตามจะลบรายการทิ้งเงียบ ๆ ระหว่างรออนุมัติ
+func isPublishedActivity(activity entity.Activity) bool {
+	return activity.Status.Name == "approved" || activity.Status.Name == "finished" || activity.ApprovedAt != nil
+}
+
+func publishedActivities(db *gorm.DB) *gorm.DB {
+	return db.Where("activity_statuses.name IN ? OR activities.approved_at IS NOT NULL", []string{"approved", "finished"})
+}
+
+func activityToICalEvent(activity entity.Activity) utils.ICalEvent {
+	summary := activity.Title
+	if activity.Club.Name != "" {
+		summary = fmt.Sprintf("%s (ชมรม%s)", activity.Title, activity.Club.Name)
+	}
+	return utils.ICalEvent{
+		UID:          fmt.Sprintf("activity-%d@cems", activity.ID),
+		Sequence:     activity.CalendarSequence,
+		Summary:      summary,
+		Description:  activity.Description,
+		Location:     activity.Location,
+		URL:          fmt.Sprintf("http://localhost:5173/activities/%d", activity.ID),
+		Start:        activity.DateStart,
+		End:          activity.DateEnd,
+		LastModified: activity.UpdatedAt,
+		Cancelled:    activity.Status.Name == "cancelled",
+		Tentative:    activity.Status.Name == "pending" || activity.Status.Name == "draft",
+	}
+}
+
+func writeICalendar(c *gin.Context, filename, name string, activities []entity.Activity) {
+	events := make([]utils.ICalEvent, 0, len(activities))
+	for _, a := range activities {
+		events = append(events, activityToICalEvent(a))
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(utils.BuildICalendar(name, events)))
+}
+
+// GET /activities/:id/ics - ดาวน์โหลดกิจกรรมเป็นไฟล์ .ics
+func DownloadActivityICS(c *gin.Context) {
+	var activity entity.Activity
+	if err := config.DB().Preload("Status").Preload("Club").First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	if !isPublishedActivity(activity) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	writeICalendar(c, fmt.Sprintf("activity-%d.ics", activity.ID), activity.Title, []entity.Activity{activity})
+}
+
+// GET /clubs/:id/calendar - ฟีดสาธารณะของกิจกรรมที่อนุมัติแล้วและยังไม่เริ่มของชมรม
+// กิจกรรมที่ถูกยกเลิกจะยังอยู่ในฟีดพร้อม STATUS:CANCELLED เพื่อให้ปฏิทินของผู้ติดตามลบออก
+// ส่วนกิจกรรมที่กำลังรออนุมัติใหม่หลังแก้ไขจะแสดงเป็น STATUS:TENTATIVE
+func GetClubCalendarFeed(c *gin.Context) {
+	db := config.DB()
+
+	var club entity.Club
+	if err := db.First(&club, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูลชมรม"})
+		return
+	}
+
+	var activities []entity.Activity
+	if err := db.Preload("Status").Preload("Club").
+		Joins("JOIN activity_statuses ON activity_statuses.id = activities.status_id").
+		Scopes(hostedByClub(club.ID)).
+		Where("activities.date_end > ?", time.Now()).
+		Scopes(publishedActivities).
+		Order("activities.date_start ASC").
+		Find(&activities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงกิจกรรมได้"})
+		return
+	}
+
+	writeICalendar(c, fmt.Sprintf("club-%d.ics", club.ID), "ชมรม"+club.Name, activities)
+}
+
+func calendarFeedURL(token string) string {
+	return fmt.Sprintf("%s/calendar/feed/%s.ics", strings.TrimRight(os.Getenv("API_BASE_URL"), "/"), token)
+}
+
+func issueCalendarToken(db *gorm.DB, userID uint, regenerate bool) (*entity.CalendarFeedToken, error) {
+	var feed entity.CalendarFeedToken
+	err := db.Where("user_id = ?", userID).First(&feed).Error
+	if err == nil && !regenerate {
+		return &feed, nil
+	}
+
+	token, err := utils.GenerateSecureToken(24)
+	if err != nil {
+		return nil, err
+	}
+	feed.UserID = userID
+	feed.Token = token
+	if err := db.Save(&feed).Error; err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// GET /calendar/token - URL ฟีดปฏิทินส่วนตัวของผู้ใช้ (สร้างให้ถ้ายังไม่มี)
+func GetCalendarFeedToken(c *gin.Context) {
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	feed, err := issueCalendarToken(config.DB(), user.ID, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถสร้างลิงก์ปฏิทินได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "token": feed.Token, "url": calendarFeedURL(feed.Token)})
+}
+
+// POST /calendar/token/regenerate - สร้าง URL ใหม่ URL เดิมจะใช้ไม่ได้ทันที
+func RegenerateCalendarFeedToken(c *gin.Context) {
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	feed, err := issueCalendarToken(config.DB(), user.ID, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถสร้างลิงก์ปฏิทินได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "สร้างลิงก์ปฏิทินใหม่แล้ว", "token": feed.Token, "url": calendarFeedURL(feed.Token)})
+}
+
+// GET /calendar/feed/:token - ฟีดกิจกรรมที่ผู้ใช้ลงทะเบียนไว้ (ไม่ต้อง login ใช้ token ใน URL แทน)
+func GetUserCalendarFeed(c *gin.Context) {
+	db := config.DB()
+
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	var feed entity.CalendarFeedToken
+	if err := db.Preload("User").Where("token = ?", token).First(&feed).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบฟีดปฏิทิน"})
+		return
+	}
+
+	// การลงทะเบียนที่ผู้ใช้ยกเลิกเองจะไม่อยู่ในฟีด แต่ถ้ากิจกรรมถูกยกเลิกจะยังแสดงเป็น CANCELLED
+	var activities []entity.Activity
+	if err := db.Preload("Status").Preload("Club").
+		Joins("JOIN activity_registrations ON activity_registrations.activity_id = activities.id AND activity_registrations.deleted_at IS NULL").
+		Joins("JOIN activity_registration_statuses ON activity_registration_statuses.id = activity_registrations.status_id").
+		Joins("JOIN activity_statuses ON activity_statuses.id = activities.status_id").
+		Where("activity_registrations.user_id = ? AND activities.date_end > ?", feed.UserID, time.Now().Add(-calendarFeedHistory)).
+		Where("activity_registration_statuses.name <> ? OR activity_statuses.name = ?", "cancelled", "cancelled").
+		Group("activities.id").
+		Order("activities.date_start ASC").
+		Find(&activities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงกิจกรรมได้"})
+		return
+	}
+
+	name := fmt.Sprintf("CEMS - %s %s", feed.User.FirstName, feed.User.LastName)
+	writeICalendar(c, "cems.ics", name, activities)
+}
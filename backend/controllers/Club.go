@@ -2,12 +2,11 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -73,15 +72,6 @@ type AnnouncementPayload struct {
 	ExpiresAt *time.Time `json:"expires_at"`
 }
 
-func slugify(s string) string {
-	// แทนที่อักขระที่ไม่ใช่ตัวอักษรหรือตัวเลขด้วย "-"
-	re := regexp.MustCompile(`[^a-zA-Z0-9ก-๙]+`)
-	slug := re.ReplaceAllString(s, "-")
-	// ลบ - ซ้ำ ๆ
-	slug = strings.Trim(slug, "-")
-	return slug
-}
-
 func isDirEmpty(name string) (bool, error) {
 	f, err := os.Open(name)
 	if err != nil {
@@ -187,11 +177,17 @@ func RemoveClubMember(c *gin.Context) {
 	//ออกเองได้เสมอ
 	//ถ้าลบคนอื่น: officer เท่านั้น
 	if u.ID != targetUserID {
-		if _, err := requireOfficer(c, uint(clubIDInt)); err != nil { return }
+		if _, err := requireClubPermission(c, uint(clubIDInt), permManageMembers); err != nil { return }
+	} else if err := rejectArchivedClubWrite(c, uint(clubIDInt)); err != nil {
+		return
 	}
-
-	if err := db.Delete(&member).Error; err != nil {
-		c.JSON(500, gin.H{"error": "ไม่สามารถลบสมาชิกได้"})
+	// หัวหน้าชมรมต้องส่งต่อตำแหน่งก่อน ส่วนอาจารย์ที่ปรึกษาผู้ดูแลระบบเป็นผู้แต่งตั้งและถอดถอน
+	if member.Role == "president" {
+		c.JSON(400, gin.H{"error": "หัวหน้าชมรมออกหรือถูกลบไม่ได้ ต้องเปลี่ยนหัวหน้าชมรมก่อน"})
+		return
+	}
+	if member.Role == "advisor" && u.ID != targetUserID {
+		c.JSON(400, gin.H{"error": "อาจารย์ที่ปรึกษาถอดถอนได้โดยผู้ดูแลระบบเท่านั้น"})
 		return
 	}
 
@@ -204,6 +200,26 @@ func RemoveClubMember(c *gin.Context) {
 		}
 	}
 
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&member).Error; err != nil {
+			return err
+		}
+		if member.Role == "pending" {
+			status, decider := "rejected", &u.ID
+			if action == "cancel" {
+				status, decider = "cancelled", nil
+			}
+			if err := closePendingApplication(tx, member.ClubID, member.UserID, status, "", decider); err != nil {
+				return err
+			}
+		}
+		// กรรมการที่ออก/ถูกลบ ปิดวาระไว้เป็นประวัติ
+		return closeOfficerTerms(tx, member.ClubID, member.UserID, action, time.Now())
+	}); err != nil {
+		c.JSON(500, gin.H{"error": "ไม่สามารถลบสมาชิกได้"})
+		return
+	}
+
 	var club entity.Club
 	if err := db.First(&club, clubIDInt).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูลชมรม"})
@@ -211,15 +227,7 @@ func RemoveClubMember(c *gin.Context) {
 	}
 
 	if member.Role == "pending" && u.ID != targetUserID {
-		var student entity.User
-		if err := db.First(&student, member.UserID).Error; err == nil {
-			subject := "❌ คำขอเข้าร่วมชมรมของคุณถูกปฏิเสธ"
-			htmlBody, _ := services.RenderTemplate("reject_member.html", map[string]string{
-				"ClubName": club.Name,
-			})
-			go services.SendEmailHTML(student.Email, subject, htmlBody)
-
-		}
+		sendMembershipDecisionEmail(db, &club, member.UserID, "rejected", "")
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -255,6 +263,9 @@ func RequestJoinClub(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "รหัสชมรมไม่ถูกต้อง"})
 		return
 	}
+	if !requireClubActive(c, uint(clubIDInt)) {
+		return
+	}
 
 	var existing entity.ClubMember
 	if err := db.Where("club_id = ? AND user_id = ?", clubIDInt, user.ID).First(&existing).Error; err == nil {
@@ -266,13 +277,39 @@ func RequestJoinClub(c *gin.Context) {
 		return
 	}
 
+	// คำตอบใบสมัครตามคำถามของชมรม (ถ้ามี)
+	var input struct {
+		Answers []RegistrationAnswerInput `json:"answers"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+			return
+		}
+	}
+	questions, err := loadApplicationQuestions(db, uint(clubIDInt))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงคำถามใบสมัครได้"})
+		return
+	}
+	answers, err := validateApplicationAnswers(questions, input.Answers)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	newMember := entity.ClubMember{
 		UserID:   user.ID,
 		ClubID:   uint(clubIDInt),
 		Role:     "pending",
 		JoinedAt: time.Now(),
 	}
-	if err := db.Create(&newMember).Error; err != nil {
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newMember).Error; err != nil {
+			return err
+		}
+		return createClubApplication(tx, newMember.ClubID, user.ID, questions, answers)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถส่งคำขอเข้าร่วมได้"})
 		return
 	}
@@ -283,19 +320,7 @@ func RequestJoinClub(c *gin.Context) {
 		return
 	}
 
-	var president entity.User
-	if err := db.First(&president, club.CreatedBy).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบผู้สร้างชมรม"})
-		return
-	}
-
-	subject := fmt.Sprintf("📬 คำขอเข้าร่วมชมรม %s", club.Name)
-	htmlBody, _ := services.RenderTemplate("join_club.html", map[string]string{
-		"ClubName":  club.Name,
-		"FirstName": user.FirstName,
-		"LastName":  user.LastName,
-	})
-	go services.SendEmailHTML(president.Email, subject, htmlBody)
+	sendJoinRequestEmail(db, &club, &user)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "ส่งคำขอเข้าร่วมชมรมเรียบร้อยแล้ว โปรดรอการอนุมัติ",
@@ -315,11 +340,18 @@ func ApproveClubMember(c *gin.Context) {
 	}
 
 	//Officer only
-	if _, err := requireOfficer(c, uint(clubIDInt)); err != nil { return }
+	approver, err := requireClubPermission(c, uint(clubIDInt), permManageMembers)
+	if err != nil { return }
+	if !requireClubActive(c, uint(clubIDInt)) { return }
 
-	if err := db.Model(&entity.ClubMember{}).
-		Where("club_id = ? AND user_id = ?", clubIDInt, userIDInt).
-		Update("role", "member").Error; err != nil {
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.ClubMember{}).
+			Where("club_id = ? AND user_id = ? AND role = ?", clubIDInt, userIDInt, "pending").
+			Update("role", "member").Error; err != nil {
+			return err
+		}
+		return closePendingApplication(tx, uint(clubIDInt), uint(userIDInt), "approved", "", &approver.ID)
+	}); err != nil {
 		c.JSON(500, gin.H{"error": "ไม่สามารถอนุมัติสมาชิกได้"})
 		return
 	}
@@ -329,15 +361,7 @@ func ApproveClubMember(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูลชมรม"})
 		return
 	}
-	// หานักศึกษา
-	var student entity.User
-	if err := db.First(&student, userIDInt).Error; err == nil {
-		subject := "🎉 คำขอเข้าร่วมชมรมของคุณได้รับการอนุมัติแล้ว"
-		htmlBody, _ := services.RenderTemplate("approve_member.html", map[string]string{
-			"ClubName": club.Name,
-		})
-		go services.SendEmailHTML(student.Email, subject, htmlBody)
-	}
+	sendMembershipDecisionEmail(db, &club, uint(userIDInt), "approved", "")
 
 	c.JSON(http.StatusOK, gin.H{"message": "อนุมัติสมาชิกเรียบร้อยแล้ว"})
 }
@@ -347,11 +371,12 @@ func GetMembersByClubID(c *gin.Context) {
 	clubID := c.Param("id")
 	clubIDInt, _ := strconv.Atoi(clubID)
 
-	// officer only
-	if _, err := requireOfficer(c, uint(clubIDInt)); err != nil { return }
+	// ต้องจัดการสมาชิกหรือดูข้อมูลส่วนตัวสมาชิกได้
+	viewer, err := requireClubPermission(c, uint(clubIDInt), permManageMembers, permViewMemberPII)
+	if err != nil { return }
 
 	var members []ClubMemberInfo
-	err := db.Table("users").
+	err = db.Table("users").
 		Select("users.id, users.first_name, users.last_name, users.email, club_members.role as club_role, club_members.joined_at").
 		Joins("JOIN club_members ON club_members.user_id = users.id").
 		Where("club_members.club_id = ? AND club_members.deleted_at IS NULL", clubID).
@@ -360,6 +385,12 @@ func GetMembersByClubID(c *gin.Context) {
 		c.JSON(500, gin.H{"error": "ไม่สามารถดึงข้อมูลสมาชิกได้"})
 		return
 	}
+	// ซ่อนอีเมลถ้าไม่มีสิทธิ์ดูข้อมูลส่วนตัว
+	if ok, _ := hasClubPermission(db, viewer.ID, uint(clubIDInt), permViewMemberPII); !ok {
+		for i := range members {
+			members[i].Email = ""
+		}
+	}
 	c.JSON(200, gin.H{"success": true, "data": members})
 }
 
@@ -381,50 +412,11 @@ func ChangeClubPresident(c *gin.Context) {
 		return
 	}
 
-	var newMem entity.ClubMember
-	if err := db.Where("club_id = ? AND user_id = ?", clubID, req.NewPresidentID).
-		First(&newMem).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ผู้ใช้ไม่ได้เป็นสมาชิกชมรมนี้"})
-		return
-	}
-
 	var token string
 
 	if err := db.Transaction(func(tx *gorm.DB) error {
-		var oldPres entity.ClubMember
-		if err := tx.Where("club_id = ? AND role = ?", clubID, "president").
-			First(&oldPres).Error; err != nil {
-			return fmt.Errorf("ไม่พบหัวหน้าชมรมคนเดิม")
-		}
-
-		if oldPres.UserID != req.NewPresidentID {
-			if err := tx.Model(&entity.ClubMember{}).
-				Where("club_id = ? AND user_id = ?", clubID, oldPres.UserID).
-				Update("role", "member").Error; err != nil {
-				return fmt.Errorf("เปลี่ยนหัวหน้าเก่าไม่สำเร็จ")
-			}
-			if err := tx.Model(&entity.User{}).
-				Where("id = ?", oldPres.UserID).
-				Update("role_id", 1).Error; err != nil {
-				return fmt.Errorf("อัปเดตสิทธิ์หัวหน้าเดิมไม่สำเร็จ")
-			}
-		}
-
-		if err := tx.Model(&entity.ClubMember{}).
-			Where("club_id = ? AND user_id = ?", clubID, req.NewPresidentID).
-			Update("role", "president").Error; err != nil {
-			return fmt.Errorf("เปลี่ยนหัวหน้าใหม่ไม่สำเร็จ")
-		}
-		if err := tx.Model(&entity.User{}).
-			Where("id = ?", req.NewPresidentID).
-			Update("role_id", 2).Error; err != nil {
-			return fmt.Errorf("อัปเดตสิทธิ์หัวหน้าใหม่ไม่สำเร็จ")
-		}
-
-		if err := tx.Model(&entity.Club{}).
-			Where("id = ?", clubID).
-			Update("created_by", req.NewPresidentID).Error; err != nil {
-			return fmt.Errorf("อัปเดตผู้สร้างชมรมไม่สำเร็จ")
+		if err := transferClubPresidency(tx, uint(clubIDUint64), req.NewPresidentID, "reassigned", time.Now()); err != nil {
+			return err
 		}
 
 		var newPresident entity.User
@@ -439,20 +431,17 @@ func ChangeClubPresident(c *gin.Context) {
 
 		return nil
 	}); err != nil {
+		if errors.Is(err, errNotApprovedMember) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	var club entity.Club
 	if err := db.First(&club, clubID).Error; err == nil {
-		var newPresident entity.User
-		if err := db.First(&newPresident, req.NewPresidentID).Error; err == nil {
-			if htmlBody, _ := services.RenderTemplate("new_president.html", map[string]string{
-				"ClubName": club.Name,
-			}); htmlBody != "" {
-				go services.SendEmailHTML(newPresident.Email, "📢 คุณได้รับสิทธิ์เป็นหัวหน้าชมรม", htmlBody)
-			}
-		}
+		sendNewPresidentEmail(db, &club, req.NewPresidentID)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -462,6 +451,19 @@ func ChangeClubPresident(c *gin.Context) {
 	})
 }
 
+// แจ้งหัวหน้าชมรมคนใหม่ทางอีเมล
+func sendNewPresidentEmail(db *gorm.DB, club *entity.Club, userID uint) {
+	var newPresident entity.User
+	if err := db.First(&newPresident, userID).Error; err != nil {
+		return
+	}
+	if htmlBody, _ := services.RenderTemplate("new_president.html", map[string]string{
+		"ClubName": club.Name,
+	}); htmlBody != "" {
+		go services.SendEmailHTML(newPresident.Email, "📢 คุณได้รับสิทธิ์เป็นหัวหน้าชมรม", htmlBody)
+	}
+}
+
 func CreateClub(c *gin.Context) {
 	name := strings.TrimSpace(c.PostForm("Name"))
 	description := strings.TrimSpace(c.PostForm("Description"))
@@ -495,15 +497,10 @@ func CreateClub(c *gin.Context) {
 	}
 
 	//Validate ไฟล์รูปภาพ
-	file, err := c.FormFile("Image")
-	if err != nil {
+	if _, err := c.FormFile("Image"); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "ต้องแนบโลโก้ชมรม (Image) มาด้วย"})
 		return
 	}
-	if file.Size > 5*1024*1024 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ขนาดไฟล์ต้องไม่เกิน 5MB"})
-		return
-	}
 
 	//ตรวจสอบ status pending
 	var pendingStatus entity.ClubStatus
@@ -513,9 +510,8 @@ func CreateClub(c *gin.Context) {
 	}
 
 	//อัปโหลดไฟล์
-	uploadPath := fmt.Sprintf("images/clubs/%s/%s", slugify(name), file.Filename)
-	if err := c.SaveUploadedFile(file, uploadPath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "อัปโหลดโลโก้ไม่สำเร็จ"})
+	logo, err := saveImageUpload(c, "Image", purposeClubLogo)
+	if respondImageUploadError(c, err) {
 		return
 	}
 
@@ -525,7 +521,7 @@ func CreateClub(c *gin.Context) {
 		CategoryID:  uint(categoryID),
 		CreatedBy:   uint(createdBy),
 		StatusID:    pendingStatus.ID,
-		LogoImage:   "/" + uploadPath,
+		LogoImage:   storedImageURL(logo),
 	}
 	if err := config.DB().Create(&club).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถสร้างชมรมได้"})
@@ -541,6 +537,18 @@ func CreateClub(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "เพิ่มสมาชิกประธานชมรมไม่สำเร็จ"})
 		return
 	}
+	if err := startOfficerTerm(config.DB(), club.ID, member.UserID, "president", time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกวาระประธานชมรมไม่สำเร็จ"})
+		return
+	}
+
+	// บทบาทเริ่มต้นของชมรม
+	for _, role := range config.DefaultClubRoles(club.ID) {
+		if err := config.DB().Create(&role).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "สร้างบทบาทเริ่มต้นของชมรมไม่สำเร็จ"})
+			return
+		}
+	}
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "สร้างชมรมสำเร็จ",
@@ -551,20 +559,33 @@ func CreateClub(c *gin.Context) {
 func ApproveClub(c *gin.Context) {
 	id := c.Param("id")
 
-	var approvedStatus entity.ClubStatus
-	if err := config.DB().Where("name = ?", "approved").First(&approvedStatus).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่พบสถานะ approved"})
-		return
-	}
-
 	var club entity.Club
 	if err := config.DB().Where("id = ?", id).First(&club).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
 		return
 	}
 
-	if err := config.DB().Model(&club).
-		Update("status_id", approvedStatus.ID).Error; err != nil {
+	// ชมรมที่ยุบแล้วคืนสถานะไม่ได้ ชมรมที่ถูกระงับอยู่ถือเป็นการคืนสถานะ
+	current, err := clubStatusName(config.DB(), club.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถตรวจสอบสถานะชมรมได้"})
+		return
+	}
+	if current == "archived" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errClubArchived.Error()})
+		return
+	}
+	reason := "approved"
+	if current == "suspended" {
+		reason = "reinstated"
+	}
+	var changedBy *uint
+	if user, err := getUserFromJWT(c); err == nil {
+		changedBy = &user.ID
+	}
+	if err := config.DB().Transaction(func(tx *gorm.DB) error {
+		return setClubStatus(tx, &club, "approved", reason, "", changedBy)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "อนุมัติชมรมไม่สำเร็จ"})
 		return
 	}
@@ -607,13 +628,33 @@ func RejectClub(c *gin.Context) {
 		return
 	}
 
-	var suspendedStatus entity.ClubStatus
-	if err := config.DB().Where("name = ?", "suspended").First(&suspendedStatus).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่พบสถานะ suspended"})
+	// ใช้ทั้งปฏิเสธคำขอตั้งชมรมใหม่และระงับชมรมที่อนุมัติแล้ว {reason} ไม่บังคับ
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if c.Request.ContentLength > 0 {
+		_ = c.ShouldBindJSON(&input)
+	}
+	current, err := clubStatusName(config.DB(), club.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถตรวจสอบสถานะชมรมได้"})
 		return
 	}
-
-	if err := config.DB().Model(&club).Update("status_id", suspendedStatus.ID).Error; err != nil {
+	if current == "archived" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errClubArchived.Error()})
+		return
+	}
+	reason := "rejected"
+	if current == "approved" {
+		reason = "suspended"
+	}
+	var changedBy *uint
+	if user, err := getUserFromJWT(c); err == nil {
+		changedBy = &user.ID
+	}
+	if err := config.DB().Transaction(func(tx *gorm.DB) error {
+		return setClubStatus(tx, &club, "suspended", reason, strings.TrimSpace(input.Reason), changedBy)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "ปฏิเสธชมรมไม่สำเร็จ"})
 		return
 	}
@@ -638,6 +679,10 @@ func UpdateClub(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบบันทึกชมรม"})
 		return
 	}
+	if err := rejectArchivedClubWrite(c, club.ID); err != nil {
+		return
+	}
+	before := clubRevisionOf(&club)
 
 	// รับ json_data
 	jsonData := c.PostForm("json_data")
@@ -652,58 +697,20 @@ func UpdateClub(c *gin.Context) {
 		return
 	}
 
-	// หา category name (จำเป็นสำหรับ path)
+	// ตรวจว่าหมวดหมู่ที่เลือกมีอยู่จริง
 	var category entity.ClubCategory
 	if err := config.DB().Where("id = ?", input.CategoryID).First(&category).Error; err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่พบหมวดหมู่ที่เลือก"})
 		return
 	}
 
-	// อัปโหลดโลโก้ถ้ามี
-	file, err := c.FormFile("logo")
-	if err == nil {
-		dir := fmt.Sprintf("images/clubs/%s", strings.ToLower(club.Name))
-		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถสร้างโฟลเดอร์โลโก้ได้"})
-			return
-		}
-
-		filename := "logo.jpg"
-		path := filepath.Join(dir, filename)
-
-		if err := c.SaveUploadedFile(file, path); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "อัปโหลดโลโก้ล้มเหลว"})
-			return
-		}
-
-		club.LogoImage = strings.TrimPrefix(filepath.ToSlash(path), "/")
-
+	// อัปโหลดโลโก้ถ้ามี ไฟล์เก็บตาม hash จึงไม่ต้องย้ายเมื่อเปลี่ยนชื่อชมรม
+	logo, err := saveImageUpload(c, "logo", purposeClubLogo)
+	if respondImageUploadError(c, err) {
+		return
 	}
-
-	// เช็กชื่อเดิม vs ชื่อใหม่
-	oldName := club.Name
-	newName := input.Name
-
-	if oldName != newName {
-		oldDir := fmt.Sprintf("images/clubs/%s", strings.ToLower(oldName))
-		newDir := fmt.Sprintf("images/clubs/%s", strings.ToLower(newName))
-
-		// ย้ายโลโก้เก่าไปโฟลเดอร์ใหม่ (ถ้ามี)
-		oldLogoPath := filepath.Join(oldDir, "logo.jpg")
-		newLogoPath := filepath.Join(newDir, "logo.jpg")
-
-		if _, err := os.Stat(oldLogoPath); err == nil {
-			if err := os.MkdirAll(newDir, os.ModePerm); err == nil {
-				if err := os.Rename(oldLogoPath, newLogoPath); err == nil {
-					club.LogoImage = strings.TrimPrefix(filepath.ToSlash(newLogoPath), "/")
-				}
-			}
-		}
-
-		// ลบโฟลเดอร์เดิมหากไม่มีไฟล์
-		if isEmpty, _ := isDirEmpty(oldDir); isEmpty {
-			os.Remove(oldDir)
-		}
+	if logo != nil {
+		club.LogoImage = storedImageURL(logo)
 	}
 
 	/// อัปเดตฟิลด์แบบระบุคอลัมน์ (กัน association มาทับ)
@@ -719,7 +726,21 @@ func UpdateClub(c *gin.Context) {
 		updates["logo_image"] = club.LogoImage
 	}
 
-	if err := config.DB().Model(&club).Updates(updates).Error; err != nil {
+	// บันทึกพร้อมเก็บประวัติการแก้ไข
+	var editorID uint
+	if user, err := getUserFromJWT(c); err == nil {
+		editorID = user.ID
+	}
+	if err := config.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&club).Updates(updates).Error; err != nil {
+			return err
+		}
+		var after entity.Club
+		if err := tx.First(&after, club.ID).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, "club", club.ID, before, clubRevisionOf(&after), editorID, "update", nil)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกข้อมูลล้มเหลว"})
 		return
 	}
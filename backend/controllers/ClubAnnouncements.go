@@ -48,16 +48,19 @@ func CreateClubAnnouncement(c *gin.Context) {
     db := config.DB()
     clubIDParam := c.Param("id")
 
-    // ตรวจสิทธิ์ต้องเป็นหัวหน้า
+    // ตรวจสิทธิ์ต้องมีสิทธิ์ประกาศข่าวของชมรม
     clubID, err := strconv.ParseUint(clubIDParam, 10, 64)
     if err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": "club id ไม่ถูกต้อง"})
         return
     }
 
-    _, err = requirePresident(c, uint(clubID))
+    _, err = requireClubPermission(c, uint(clubID), permPostAnnouncements)
     if err != nil {
-        return // requirePresident จะส่ง error response เอง
+        return // requireClubPermission จะส่ง error response เอง
+    }
+    if !requireClubActive(c, uint(clubID)) {
+        return
     }
 
     var input struct {
@@ -94,7 +97,7 @@ func UpdateClubAnnouncement(c *gin.Context) {
 
     clubID, err := strconv.ParseUint(clubIDStr, 10, 64)
     if err != nil { c.JSON(400, gin.H{"error":"club id ไม่ถูกต้อง"}); return }
-    _, err = requirePresident(c, uint(clubID))
+    _, err = requireClubPermission(c, uint(clubID), permPostAnnouncements)
     if err != nil { return }
 
     var body struct {
@@ -133,7 +136,7 @@ func DeleteClubAnnouncement(c *gin.Context) {
     }
     clubID := uint(clubIDu64)
 
-    if _, err := requirePresident(c, clubID); err != nil { return }
+    if _, err := requireClubPermission(c, clubID, permPostAnnouncements); err != nil { return }
 
     var ann entity.ClubAnnouncement
     if err := db.Where("id = ? AND club_id = ?", annIDStr, clubID).
@@ -0,0 +1,536 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+	"final-project/cems/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var applicationQuestionTypes = []string{"text", "choice", "number"}
+
+type ClubApplicationResponse struct {
+	ID              uint              `json:"id"`
+	UserID          uint              `json:"user_id"`
+	StudentID       string            `json:"student_id"`
+	FirstName       string            `json:"first_name"`
+	LastName        string            `json:"last_name"`
+	Email           string            `json:"email,omitempty"`
+	Status          string            `json:"status"`
+	SubmittedAt     time.Time         `json:"submitted_at"`
+	RejectionReason string            `json:"rejection_reason,omitempty"`
+	DecidedAt       *time.Time        `json:"decided_at,omitempty"`
+	Answers         map[string]string `json:"answers"` // question_id → คำตอบ
+	NoteCount       int64             `json:"note_count"`
+}
+
+func loadApplicationQuestions(db *gorm.DB, clubID uint) ([]entity.ClubApplicationQuestion, error) {
+	var questions []entity.ClubApplicationQuestion
+	err := db.Where("club_id = ?", clubID).Order("sort_order ASC").Find(&questions).Error
+	return questions, err
+}
+
+// แปลงคำถามใบสมัครเป็นรูปแบบเดียวกับคำถามแบบฟอร์มลงทะเบียนกิจกรรม เพื่อใช้ตัวตรวจคำตอบร่วมกัน
+func applicationQuestionsAsForm(questions []entity.ClubApplicationQuestion) []entity.RegistrationQuestion {
+	form := make([]entity.RegistrationQuestion, 0, len(questions))
+	for _, q := range questions {
+		rq := entity.RegistrationQuestion{Label: q.Label, Type: q.Type, Options: q.Options, Required: q.Required, SortOrder: q.SortOrder}
+		rq.ID = q.ID
+		form = append(form, rq)
+	}
+	return form
+}
+
+func toApplicationQuestionResponse(q entity.ClubApplicationQuestion) RegistrationQuestionResponse {
+	return toQuestionResponse(applicationQuestionsAsForm([]entity.ClubApplicationQuestion{q})[0])
+}
+
+// GET /clubs/:id/application-questions - คำถามในใบสมัครเข้าชมรม
+func GetClubApplicationQuestions(c *gin.Context) {
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	questions, err := loadApplicationQuestions(config.DB(), clubID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงคำถามได้"})
+		return
+	}
+	response := make([]RegistrationQuestionResponse, 0, len(questions))
+	for _, q := range questions {
+		response = append(response, toApplicationQuestionResponse(q))
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": response})
+}
+
+// PUT /clubs/:id/application-questions - กำหนดคำถามใบสมัครใหม่ทั้งชุด (ผู้จัดการสมาชิก)
+// คำตอบเดิมเก็บหัวข้อคำถามไว้แล้ว จึงแก้ชุดคำถามได้แม้มีผู้สมัครไปแล้ว
+func UpdateClubApplicationQuestions(c *gin.Context) {
+	db := config.DB()
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	if _, err := requireClubPermission(c, clubID, permManageMembers); err != nil {
+		return
+	}
+
+	var input struct {
+		Questions []RegistrationQuestionInput `json:"questions"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	for i, q := range input.Questions {
+		if strings.TrimSpace(q.Label) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("คำถามข้อที่ %d ต้องมีหัวข้อ", i+1)})
+			return
+		}
+		if !slices.Contains(applicationQuestionTypes, q.Type) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("คำถามข้อที่ %d มีประเภทไม่ถูกต้อง", i+1)})
+			return
+		}
+		if q.Type == "choice" && len(q.Options) < 2 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("คำถามข้อที่ %d ต้องมีตัวเลือกอย่างน้อย 2 ตัวเลือก", i+1)})
+			return
+		}
+	}
+
+	var questions []entity.ClubApplicationQuestion
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("club_id = ?", clubID).Delete(&entity.ClubApplicationQuestion{}).Error; err != nil {
+			return err
+		}
+		for i, q := range input.Questions {
+			options := ""
+			if q.Type == "choice" {
+				raw, _ := json.Marshal(q.Options)
+				options = string(raw)
+			}
+			question := entity.ClubApplicationQuestion{
+				ClubID:    clubID,
+				Label:     strings.TrimSpace(q.Label),
+				Type:      q.Type,
+				Options:   options,
+				Required:  q.Required,
+				SortOrder: i + 1,
+			}
+			if err := tx.Create(&question).Error; err != nil {
+				return err
+			}
+			questions = append(questions, question)
+		}
+		return nil
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกคำถามไม่สำเร็จ"})
+		return
+	}
+
+	response := make([]RegistrationQuestionResponse, 0, len(questions))
+	for _, q := range questions {
+		response = append(response, toApplicationQuestionResponse(q))
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "บันทึกคำถามสำเร็จ", "data": response})
+}
+
+// ตรวจคำตอบใบสมัครตามชุดคำถามปัจจุบันของชมรม
+func validateApplicationAnswers(questions []entity.ClubApplicationQuestion, input []RegistrationAnswerInput) (map[uint]string, error) {
+	answers := map[uint]string{}
+	for _, a := range input {
+		answers[a.QuestionID] = a.Value
+	}
+	if err := validateRegistrationAnswers(applicationQuestionsAsForm(questions), answers, nil); err != nil {
+		return nil, err
+	}
+	return answers, nil
+}
+
+// สร้างใบสมัครพร้อมคำตอบ ภายใต้ transaction เดียวกับการสร้างสมาชิก pending
+func createClubApplication(tx *gorm.DB, clubID, userID uint, questions []entity.ClubApplicationQuestion, answers map[uint]string) error {
+	application := entity.ClubApplication{
+		ClubID:      clubID,
+		UserID:      userID,
+		Status:      "pending",
+		SubmittedAt: time.Now(),
+	}
+	if err := tx.Create(&application).Error; err != nil {
+		return err
+	}
+	for _, q := range questions {
+		value := strings.TrimSpace(answers[q.ID])
+		if value == "" {
+			continue
+		}
+		if err := tx.Create(&entity.ClubApplicationAnswer{
+			ApplicationID: application.ID,
+			QuestionID:    q.ID,
+			Question:      q.Label,
+			Value:         value,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ปิดใบสมัครที่ค้างอยู่ของผู้ใช้ในชมรม (เช่น ถอนคำขอหรือถูกลบจากรายชื่อรออนุมัติ)
+func closePendingApplication(tx *gorm.DB, clubID, userID uint, status, reason string, deciderID *uint) error {
+	now := time.Now()
+	return tx.Model(&entity.ClubApplication{}).
+		Where("club_id = ? AND user_id = ? AND status = ?", clubID, userID, "pending").
+		Updates(map[string]interface{}{
+			"status":           status,
+			"rejection_reason": reason,
+			"decided_by":       deciderID,
+			"decided_at":       now,
+		}).Error
+}
+
+// แจ้งหัวหน้าชมรมทางอีเมลว่ามีคำขอเข้าร่วมใหม่
+func sendJoinRequestEmail(db *gorm.DB, club *entity.Club, applicant *entity.User) {
+	var president entity.User
+	if err := db.First(&president, club.CreatedBy).Error; err != nil {
+		return
+	}
+	htmlBody, _ := services.RenderTemplate("join_club.html", map[string]string{
+		"ClubName":  club.Name,
+		"FirstName": applicant.FirstName,
+		"LastName":  applicant.LastName,
+	})
+	go services.SendEmailHTML(president.Email, fmt.Sprintf("📬 คำขอเข้าร่วมชมรม %s", club.Name), htmlBody)
+}
+
+// แจ้งผลการพิจารณาคำขอเข้าชมรมทางอีเมล
+func sendMembershipDecisionEmail(db *gorm.DB, club *entity.Club, userID uint, decision, reason string) {
+	var student entity.User
+	if err := db.First(&student, userID).Error; err != nil {
+		return
+	}
+	if decision == "approved" {
+		htmlBody, _ := services.RenderTemplate("approve_member.html", map[string]string{
+			"ClubName": club.Name,
+		})
+		go services.SendEmailHTML(student.Email, "🎉 คำขอเข้าร่วมชมรมของคุณได้รับการอนุมัติแล้ว", htmlBody)
+		return
+	}
+	htmlBody, _ := services.RenderTemplate("reject_member.html", map[string]string{
+		"ClubName": club.Name,
+		"Reason":   reason,
+	})
+	go services.SendEmailHTML(student.Email, "❌ คำขอเข้าร่วมชมรมของคุณถูกปฏิเสธ", htmlBody)
+}
+
+var errApplicationNotPending = errors.New("ใบสมัครนี้ได้รับการพิจารณาแล้ว")
+
+// พิจารณาใบสมัครที่รออยู่ อนุมัติ = เปลี่ยนสมาชิก pending เป็น member, ปฏิเสธ = ลบสมาชิก pending
+// คืนรหัสผู้ใช้ที่ได้รับการพิจารณา
+func decideClubApplications(tx *gorm.DB, clubID uint, applicationIDs []uint, decision, reason string, deciderID uint) ([]uint, error) {
+	var applications []entity.ClubApplication
+	if err := tx.Where("club_id = ? AND id IN ?", clubID, applicationIDs).Find(&applications).Error; err != nil {
+		return nil, err
+	}
+	if len(applications) != len(applicationIDs) {
+		return nil, errNotFound
+	}
+	now := time.Now()
+	userIDs := make([]uint, 0, len(applications))
+	for _, app := range applications {
+		if app.Status != "pending" {
+			return nil, errApplicationNotPending
+		}
+		pending := tx.Where("club_id = ? AND user_id = ? AND role = ?", clubID, app.UserID, "pending")
+		var err error
+		if decision == "approved" {
+			err = pending.Model(&entity.ClubMember{}).Update("role", "member").Error
+		} else {
+			err = pending.Delete(&entity.ClubMember{}).Error
+		}
+		if err != nil {
+			return nil, err
+		}
+		updates := map[string]interface{}{"status": decision, "decided_by": deciderID, "decided_at": now}
+		if decision == "rejected" {
+			updates["rejection_reason"] = reason
+		}
+		if err := tx.Model(&app).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, app.UserID)
+	}
+	return userIDs, nil
+}
+
+func loadApplicationResponses(db *gorm.DB, applications []entity.ClubApplication, includeEmail bool) []ClubApplicationResponse {
+	ids := make([]uint, 0, len(applications))
+	for _, a := range applications {
+		ids = append(ids, a.ID)
+	}
+	answers := map[uint]map[string]string{}
+	noteCounts := map[uint]int64{}
+	if len(ids) > 0 {
+		var rows []entity.ClubApplicationAnswer
+		db.Where("application_id IN ?", ids).Find(&rows)
+		for _, r := range rows {
+			if answers[r.ApplicationID] == nil {
+				answers[r.ApplicationID] = map[string]string{}
+			}
+			answers[r.ApplicationID][fmt.Sprint(r.QuestionID)] = r.Value
+		}
+		var counts []struct {
+			ApplicationID uint
+			Count         int64
+		}
+		db.Model(&entity.ClubApplicationNote{}).Select("application_id, COUNT(*) AS count").
+			Where("application_id IN ?", ids).Group("application_id").Scan(&counts)
+		for _, n := range counts {
+			noteCounts[n.ApplicationID] = n.Count
+		}
+	}
+
+	result := make([]ClubApplicationResponse, 0, len(applications))
+	for _, a := range applications {
+		row := ClubApplicationResponse{
+			ID:              a.ID,
+			UserID:          a.UserID,
+			StudentID:       a.User.StudentID,
+			FirstName:       a.User.FirstName,
+			LastName:        a.User.LastName,
+			Status:          a.Status,
+			SubmittedAt:     a.SubmittedAt,
+			RejectionReason: a.RejectionReason,
+			DecidedAt:       a.DecidedAt,
+			Answers:         answers[a.ID],
+			NoteCount:       noteCounts[a.ID],
+		}
+		if includeEmail {
+			row.Email = a.User.Email
+		}
+		if row.Answers == nil {
+			row.Answers = map[string]string{}
+		}
+		result = append(result, row)
+	}
+	return result
+}
+
+// GET /clubs/:id/applications - คิวใบสมัคร (ผู้จัดการสมาชิก)
+// กรองได้ด้วย status (ค่าเริ่มต้น pending), q (ชื่อ/รหัสนักศึกษา), from, to (YYYY-MM-DD), question_id + answer
+func GetClubApplications(c *gin.Context) {
+	db := config.DB()
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	viewer, err := requireClubPermission(c, clubID, permManageMembers)
+	if err != nil {
+		return
+	}
+
+	query := db.Model(&entity.ClubApplication{}).Preload("User").
+		Joins("JOIN users ON users.id = club_applications.user_id").
+		Where("club_applications.club_id = ?", clubID)
+	if status := c.DefaultQuery("status", "pending"); status != "all" {
+		query = query.Where("club_applications.status = ?", status)
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		like := "%" + q + "%"
+		query = query.Where("users.first_name LIKE ? OR users.last_name LIKE ? OR users.student_id LIKE ?", like, like, like)
+	}
+	if from := c.Query("from"); from != "" {
+		t, err := time.ParseInLocation("2006-01-02", from, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from ต้องอยู่ในรูปแบบ YYYY-MM-DD"})
+			return
+		}
+		query = query.Where("club_applications.submitted_at >= ?", t)
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := time.ParseInLocation("2006-01-02", to, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to ต้องอยู่ในรูปแบบ YYYY-MM-DD"})
+			return
+		}
+		query = query.Where("club_applications.submitted_at < ?", t.AddDate(0, 0, 1))
+	}
+	if questionID := c.Query("question_id"); questionID != "" {
+		answered := db.Session(&gorm.Session{NewDB: true}).Model(&entity.ClubApplicationAnswer{}).
+			Select("application_id").Where("question_id = ?", questionID)
+		if answer := c.Query("answer"); answer != "" {
+			answered = answered.Where("value = ?", answer)
+		}
+		query = query.Where("club_applications.id IN (?)", answered)
+	}
+
+	var applications []entity.ClubApplication
+	if err := query.Order("club_applications.submitted_at ASC").Find(&applications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงใบสมัครได้"})
+		return
+	}
+	canViewPII, _ := hasClubPermission(db, viewer.ID, clubID, permViewMemberPII)
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": loadApplicationResponses(db, applications, canViewPII)})
+}
+
+func loadClubApplication(c *gin.Context, clubID uint) (*entity.ClubApplication, bool) {
+	var application entity.ClubApplication
+	if err := config.DB().Preload("User").
+		Preload("Answers", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Where("club_id = ?", clubID).First(&application, c.Param("applicationId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบใบสมัคร"})
+		return nil, false
+	}
+	return &application, true
+}
+
+// GET /clubs/:id/applications/:applicationId - ใบสมัครพร้อมคำตอบและบันทึกของกรรมการ
+func GetClubApplicationByID(c *gin.Context) {
+	db := config.DB()
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	viewer, err := requireClubPermission(c, clubID, permManageMembers)
+	if err != nil {
+		return
+	}
+	application, ok := loadClubApplication(c, clubID)
+	if !ok {
+		return
+	}
+	var notes []entity.ClubApplicationNote
+	db.Preload("Author").Where("application_id = ?", application.ID).Order("created_at ASC").Find(&notes)
+
+	canViewPII, _ := hasClubPermission(db, viewer.ID, clubID, permViewMemberPII)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    loadApplicationResponses(db, []entity.ClubApplication{*application}, canViewPII)[0],
+		"answers": application.Answers,
+		"notes":   notes,
+	})
+}
+
+// POST /clubs/:id/applications/:applicationId/notes - บันทึกของกรรมการ (ผู้สมัครมองไม่เห็น)
+func AddClubApplicationNote(c *gin.Context) {
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	user, err := requireClubPermission(c, clubID, permManageMembers)
+	if err != nil {
+		return
+	}
+	application, ok := loadClubApplication(c, clubID)
+	if !ok {
+		return
+	}
+	var input struct {
+		Body string `json:"body"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Body) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุข้อความ"})
+		return
+	}
+	note := entity.ClubApplicationNote{ApplicationID: application.ID, AuthorID: user.ID, Body: strings.TrimSpace(input.Body)}
+	if err := config.DB().Create(&note).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถบันทึกข้อความได้"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": note})
+}
+
+// POST /clubs/:id/applications/decision - อนุมัติหรือปฏิเสธหลายใบพร้อมกัน
+// {application_ids: [...], action: approve|reject, reason} เหตุผลการปฏิเสธจะส่งถึงผู้สมัครทางอีเมล
+func DecideClubApplications(c *gin.Context) {
+	db := config.DB()
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	user, err := requireClubPermission(c, clubID, permManageMembers)
+	if err != nil {
+		return
+	}
+	var input struct {
+		ApplicationIDs []uint `json:"application_ids"`
+		Action         string `json:"action"`
+		Reason         string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || len(input.ApplicationIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ต้องระบุ application_ids"})
+		return
+	}
+	decisions := map[string]string{"approve": "approved", "reject": "rejected"}
+	decision, ok := decisions[input.Action]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action ต้องเป็น approve หรือ reject"})
+		return
+	}
+	if decision == "approved" && !requireClubActive(c, clubID) {
+		return
+	}
+	slices.Sort(input.ApplicationIDs)
+	input.ApplicationIDs = slices.Compact(input.ApplicationIDs)
+	reason := strings.TrimSpace(input.Reason)
+
+	var club entity.Club
+	if err := db.First(&club, clubID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบข้อมูลชมรม"})
+		return
+	}
+
+	var userIDs []uint
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		userIDs, err = decideClubApplications(tx, clubID, input.ApplicationIDs, decision, reason, user.ID)
+		return err
+	})
+	switch {
+	case errors.Is(err, errNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบใบสมัครบางใบในชมรมนี้"})
+		return
+	case errors.Is(err, errApplicationNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถบันทึกผลการพิจารณาได้"})
+		return
+	}
+
+	for _, id := range userIDs {
+		sendMembershipDecisionEmail(db, &club, id, decision, reason)
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": fmt.Sprintf("พิจารณาใบสมัครแล้ว %d ใบ", len(userIDs)), "count": len(userIDs)})
+}
+
+// GET /clubs/:id/my-application - ใบสมัครล่าสุดของผู้ใช้ (ไม่รวมบันทึกของกรรมการ)
+func GetMyClubApplication(c *gin.Context) {
+	db := config.DB()
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	var application entity.ClubApplication
+	if err := db.Preload("User").Where("club_id = ? AND user_id = ?", clubID, user.ID).
+		Order("submitted_at DESC").First(&application).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบใบสมัคร"})
+		return
+	}
+	response := loadApplicationResponses(db, []entity.ClubApplication{application}, true)[0]
+	response.NoteCount = 0
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": response})
+}
@@ -0,0 +1,722 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+	"final-project/cems/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ขั้นการอนุมัติค่าใช้จ่าย ขั้นอาจารย์ที่ปรึกษาจะถูกข้ามถ้าชมรมไม่มีอาจารย์ที่ปรึกษา (บันทึกการข้ามไว้ในประวัติการพิจารณา)
+var expenseApprovalSteps = []string{"treasurer", "advisor", "student_affairs"}
+
+var expenseStepNames = map[string]string{
+	"treasurer":       "เหรัญญิก",
+	"advisor":         "อาจารย์ที่ปรึกษา",
+	"student_affairs": "กองกิจการนักศึกษา",
+}
+
+// หาขั้นถัดไปตั้งแต่ expenseApprovalSteps[from] คืนค่าว่างถ้าไม่เหลือขั้นให้พิจารณาแล้ว
+// พร้อมรายชื่อขั้นที่ถูกข้ามระหว่างทาง ผู้เรียกต้องบันทึกการข้ามด้วย recordSkippedExpenseSteps
+func nextExpenseStep(db *gorm.DB, clubID uint, from int) (string, []string) {
+	var skipped []string
+	for _, step := range expenseApprovalSteps[min(from, len(expenseApprovalSteps)):] {
+		if step == "advisor" {
+			var count int64
+			db.Model(&entity.ClubMember{}).Where("club_id = ? AND role = ?", clubID, "advisor").Count(&count)
+			if count == 0 {
+				skipped = append(skipped, step)
+				continue
+			}
+		}
+		return step, skipped
+	}
+	return "", skipped
+}
+
+// บันทึกขั้นที่ถูกข้ามเป็นผลการพิจารณา "skipped" ให้เห็นในประวัติว่าไม่มีผู้พิจารณาขั้นนั้น
+func recordSkippedExpenseSteps(tx *gorm.DB, expenseID uint, steps []string) error {
+	for _, step := range steps {
+		if err := tx.Create(&entity.ClubExpenseApproval{
+			ExpenseID: expenseID,
+			Step:      step,
+			Decision:  "skipped",
+			Comment:   "ข้ามขั้น" + expenseStepNames[step] + " เนื่องจากชมรมยังไม่มี" + expenseStepNames[step],
+			DecidedAt: time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// คืน true ถ้า user เป็นผู้พิจารณาขั้นปัจจุบันของค่าใช้จ่ายนี้
+// ขั้นเหรัญญิกต้องไม่ใช่ผู้ส่งรายการเอง
+func canDecideExpenseStep(db *gorm.DB, user *entity.User, expense *entity.ClubExpense) (bool, error) {
+	switch expense.ApprovalStep {
+	case "treasurer":
+		if user.ID == expense.SubmittedBy {
+			return false, nil
+		}
+		return hasClubPermission(db, user.ID, expense.ClubID, permManageBudget)
+	case "advisor":
+		return hasClubRole(db, user.ID, expense.ClubID, "advisor")
+	case "student_affairs":
+		return isAdmin(db, user), nil
+	}
+	return false, nil
+}
+
+// ใบเสร็จมีข้อมูลการเงินและข้อมูลส่วนตัว จึงเก็บนอก static root (./images)
+// เปิดได้ผ่าน GetClubExpenseReceipt ที่ตรวจสิทธิ์ผู้ดูงบประมาณเท่านั้น
+const expenseReceiptDir = "uploads/receipts"
+
+func expenseReceiptPath(expenseID uint) string {
+	return filepath.Join(expenseReceiptDir, fmt.Sprintf("%d.jpg", expenseID))
+}
+
+func expenseReceiptURL(expenseID uint) string {
+	return fmt.Sprintf("/expenses/%d/receipt", expenseID)
+}
+
+// อ่านใบเสร็จจาก form field "receipt" ตรวจชนิดและลบ EXIF คืน nil ถ้าไม่ได้แนบมา
+func readExpenseReceipt(c *gin.Context) ([]byte, error) {
+	data, err := readImageUpload(c, "receipt", purposeExpenseReceipt)
+	if data == nil || err != nil {
+		return nil, err
+	}
+	return encodePrivateImage(data)
+}
+
+// เขียนใบเสร็จของค่าใช้จ่ายภายใน transaction ที่บันทึกรายการ ถ้าเขียนไม่สำเร็จรายการจะไม่ถูกบันทึก
+func writeExpenseReceipt(tx *gorm.DB, expense *entity.ClubExpense, data []byte) error {
+	if err := os.MkdirAll(expenseReceiptDir, 0o750); err != nil {
+		return err
+	}
+	if err := os.WriteFile(expenseReceiptPath(expense.ID), data, 0o640); err != nil {
+		return err
+	}
+	expense.ReceiptImage = expenseReceiptURL(expense.ID)
+	return tx.Model(&entity.ClubExpense{}).Where("id = ?", expense.ID).Update("receipt_image", expense.ReceiptImage).Error
+}
+
+// บังคับสิทธิ์ดูงบประมาณ: กรรมการชมรม อาจารย์ที่ปรึกษา หรือผู้ดูแลระบบ
+func requireBudgetViewer(c *gin.Context, clubID uint) (*entity.User, error) {
+	db := config.DB()
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(401, gin.H{"error": err.Error()})
+		return nil, err
+	}
+	if isAdmin(db, user) {
+		return user, nil
+	}
+	if ok, _ := hasClubRole(db, user.ID, clubID, "advisor"); ok {
+		return user, nil
+	}
+	if ok, _ := hasClubPermission(db, user.ID, clubID, clubPermissions...); ok {
+		return user, nil
+	}
+	c.JSON(403, gin.H{"error": "forbidden: club officers, advisor or admin only"})
+	return nil, errors.New("forbidden")
+}
+
+type ClubBudgetSummary struct {
+	AcademicYear int     `json:"academic_year"`
+	Budget       float64 `json:"budget"`
+	Allocated    bool    `json:"allocated"`
+	Spent        float64 `json:"spent"`   // อนุมัติครบทุกขั้นแล้ว
+	Pending      float64 `json:"pending"` // อยู่ระหว่างพิจารณา
+	Remaining    float64 `json:"remaining"`
+	Available    float64 `json:"available"` // คงเหลือหลังกันยอดที่รอพิจารณา
+}
+
+func clubBudgetSummary(db *gorm.DB, clubID uint, year int) ClubBudgetSummary {
+	summary := ClubBudgetSummary{AcademicYear: year}
+	var budget entity.ClubBudget
+	if err := db.Where("club_id = ? AND academic_year = ?", clubID, year).First(&budget).Error; err == nil {
+		summary.Budget = budget.Amount
+		summary.Allocated = true
+	}
+	db.Model(&entity.ClubExpense{}).
+		Where("club_id = ? AND academic_year = ? AND status = ?", clubID, year, "approved").
+		Select("COALESCE(SUM(amount),0)").Scan(&summary.Spent)
+	db.Model(&entity.ClubExpense{}).
+		Where("club_id = ? AND academic_year = ? AND status = ?", clubID, year, "pending").
+		Select("COALESCE(SUM(amount),0)").Scan(&summary.Pending)
+	summary.Remaining = summary.Budget - summary.Spent
+	summary.Available = summary.Remaining - summary.Pending
+	return summary
+}
+
+// เปรียบเทียบงบประมาณกับรายจ่ายจริงของทุกปีการศึกษาที่คาบเกี่ยวกับช่วงเวลา ใช้ในรายงานผลการดำเนินงานชมรม
+func clubBudgetVsActual(db *gorm.DB, clubID uint, start, end time.Time) []ClubBudgetSummary {
+	var rows []ClubBudgetSummary
+	for year := utils.AcademicYearOf(start); year <= utils.AcademicYearOf(end); year++ {
+		summary := clubBudgetSummary(db, clubID, year)
+		if !summary.Allocated && summary.Spent == 0 {
+			continue
+		}
+		rows = append(rows, summary)
+	}
+	return rows
+}
+
+func parseClubID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid club ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// GET /clubs/:id/budgets - งบประมาณทุกปีการศึกษาของชมรม
+func GetClubBudgets(c *gin.Context) {
+	db := config.DB()
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	if _, err := requireBudgetViewer(c, clubID); err != nil {
+		return
+	}
+	var budgets []entity.ClubBudget
+	if err := db.Where("club_id = ?", clubID).Order("academic_year DESC").Find(&budgets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงงบประมาณได้"})
+		return
+	}
+	data := make([]ClubBudgetSummary, 0, len(budgets))
+	for _, b := range budgets {
+		data = append(data, clubBudgetSummary(db, clubID, b.AcademicYear))
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": data})
+}
+
+// PUT /clubs/:id/budgets/:year - จัดสรรหรือปรับงบประมาณประจำปีการศึกษา (ผู้ดูแลระบบเท่านั้น)
+func SetClubBudget(c *gin.Context) {
+	db := config.DB()
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	admin, err := requireAdmin(c)
+	if err != nil {
+		return
+	}
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil || year < 2500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ปีการศึกษาต้องเป็น พ.ศ. เช่น 2569"})
+		return
+	}
+	var club entity.Club
+	if err := db.First(&club, clubID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	var input struct {
+		Amount float64 `json:"amount"`
+		Note   string  `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || input.Amount < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount ต้องไม่ติดลบ"})
+		return
+	}
+	// ลดงบได้ไม่ต่ำกว่ารายจ่ายที่อนุมัติแล้ว
+	if spent := clubBudgetSummary(db, clubID, year).Spent; input.Amount < spent {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("งบประมาณต้องไม่น้อยกว่ารายจ่ายที่อนุมัติแล้ว (%.2f บาท)", spent)})
+		return
+	}
+
+	var budget entity.ClubBudget
+	db.Where("club_id = ? AND academic_year = ?", clubID, year).First(&budget)
+	budget.ClubID = clubID
+	budget.AcademicYear = year
+	budget.Amount = input.Amount
+	budget.Note = strings.TrimSpace(input.Note)
+	budget.AllocatedBy = admin.ID
+	if err := db.Save(&budget).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถบันทึกงบประมาณได้"})
+		return
+	}
+
+	notifyUsers(clubMemberIDsWithPermission(db, clubID, permManageBudget),
+		fmt.Sprintf("ชมรม %s ได้รับจัดสรรงบประมาณปีการศึกษา %d จำนวน %.2f บาท", club.Name, year, budget.Amount), "info")
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": clubBudgetSummary(db, clubID, year)})
+}
+
+type LedgerEntry struct {
+	ExpenseID    uint      `json:"expense_id"`
+	Title        string    `json:"title"`
+	ActivityID   *uint     `json:"activity_id"`
+	SpentAt      time.Time `json:"spent_at"`
+	Amount       float64   `json:"amount"`
+	Balance      float64   `json:"balance"`
+	ReceiptImage string    `json:"receipt_image"`
+}
+
+// GET /clubs/:id/budget?year= - บัญชีรายจ่ายที่อนุมัติแล้วพร้อมยอดคงเหลือสะสม (ค่าเริ่มต้นคือปีการศึกษาปัจจุบัน)
+func GetClubBudgetLedger(c *gin.Context) {
+	db := config.DB()
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	if _, err := requireBudgetViewer(c, clubID); err != nil {
+		return
+	}
+	year := utils.AcademicYearOf(time.Now())
+	if y := c.Query("year"); y != "" {
+		parsed, err := strconv.Atoi(y)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "year ไม่ถูกต้อง"})
+			return
+		}
+		year = parsed
+	}
+
+	summary := clubBudgetSummary(db, clubID, year)
+	var expenses []entity.ClubExpense
+	if err := db.Where("club_id = ? AND academic_year = ? AND status = ?", clubID, year, "approved").
+		Order("spent_at ASC, id ASC").Find(&expenses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงรายจ่ายได้"})
+		return
+	}
+	balance := summary.Budget
+	ledger := make([]LedgerEntry, 0, len(expenses))
+	for _, e := range expenses {
+		balance -= e.Amount
+		ledger = append(ledger, LedgerEntry{
+			ExpenseID:    e.ID,
+			Title:        e.Title,
+			ActivityID:   e.ActivityID,
+			SpentAt:      e.SpentAt,
+			Amount:       e.Amount,
+			Balance:      math.Round(balance*100) / 100,
+			ReceiptImage: e.ReceiptImage,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"summary": summary, "ledger": ledger}})
+}
+
+// GET /clubs/:id/expenses?status=&year= - รายการค่าใช้จ่ายของชมรม
+func GetClubExpenses(c *gin.Context) {
+	db := config.DB()
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	if _, err := requireBudgetViewer(c, clubID); err != nil {
+		return
+	}
+	query := db.Preload("Activity").Preload("Submitter").Where("club_id = ?", clubID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if year := c.Query("year"); year != "" {
+		query = query.Where("academic_year = ?", year)
+	}
+	var expenses []entity.ClubExpense
+	if err := query.Order("spent_at DESC").Find(&expenses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงรายการค่าใช้จ่ายได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": expenses})
+}
+
+// อ่านข้อมูลค่าใช้จ่ายจาก multipart form ลงใน expense ฟิลด์ที่ไม่ได้ส่งมาจะคงค่าเดิม
+func applyExpenseForm(c *gin.Context, db *gorm.DB, expense *entity.ClubExpense) error {
+	if title, ok := c.GetPostForm("title"); ok {
+		expense.Title = strings.TrimSpace(title)
+	}
+	if desc, ok := c.GetPostForm("description"); ok {
+		expense.Description = strings.TrimSpace(desc)
+	}
+	if raw, ok := c.GetPostForm("amount"); ok {
+		amount, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return errors.New("amount ไม่ถูกต้อง")
+		}
+		expense.Amount = math.Round(amount*100) / 100
+	}
+	if raw, ok := c.GetPostForm("spent_at"); ok && strings.TrimSpace(raw) != "" {
+		spentAt, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			spentAt, err = time.ParseInLocation("2006-01-02", raw, time.Local)
+		}
+		if err != nil {
+			return errors.New("spent_at ต้องอยู่ในรูปแบบ YYYY-MM-DD หรือ RFC3339")
+		}
+		expense.SpentAt = spentAt
+	}
+	if raw, ok := c.GetPostForm("activity_id"); ok {
+		expense.ActivityID = nil
+		if raw = strings.TrimSpace(raw); raw != "" && raw != "0" {
+			id, err := strconv.ParseUint(raw, 10, 32)
+			if err != nil {
+				return errors.New("activity_id ไม่ถูกต้อง")
+			}
+			var count int64
+			db.Model(&entity.Activity{}).Scopes(hostedByClub(expense.ClubID)).Where("activities.id = ?", id).Count(&count)
+			if count == 0 {
+				return errors.New("กิจกรรมนี้ไม่ได้จัดโดยชมรม")
+			}
+			activityID := uint(id)
+			expense.ActivityID = &activityID
+		}
+	}
+
+	if expense.Title == "" {
+		return errors.New("กรุณาระบุรายการค่าใช้จ่าย")
+	}
+	if expense.Amount <= 0 {
+		return errors.New("amount ต้องมากกว่า 0")
+	}
+	if expense.SpentAt.IsZero() {
+		expense.SpentAt = time.Now()
+	}
+	if expense.SpentAt.After(time.Now()) {
+		return errors.New("วันที่จ่ายต้องไม่อยู่ในอนาคต")
+	}
+	expense.AcademicYear = utils.AcademicYearOf(expense.SpentAt)
+	return nil
+}
+
+// ตรวจว่ารายการนี้ไม่ทำให้ยอดใช้จ่ายรวมเกินงบของปีการศึกษา (ไม่นับยอดเดิมของรายการนี้)
+func checkExpenseWithinBudget(db *gorm.DB, expense *entity.ClubExpense) error {
+	summary := clubBudgetSummary(db, expense.ClubID, expense.AcademicYear)
+	if !summary.Allocated {
+		return fmt.Errorf("ชมรมยังไม่ได้รับจัดสรรงบประมาณปีการศึกษา %d", expense.AcademicYear)
+	}
+	available := summary.Available
+	if expense.ID != 0 {
+		var current entity.ClubExpense
+		if err := db.First(&current, expense.ID).Error; err == nil && current.Status != "rejected" &&
+			current.AcademicYear == expense.AcademicYear {
+			available += current.Amount
+		}
+	}
+	if expense.Amount > available+0.005 {
+		return fmt.Errorf("ยอดเกินงบประมาณคงเหลือ (คงเหลือ %.2f บาท)", available)
+	}
+	return nil
+}
+
+// POST /clubs/:id/expenses - บันทึกค่าใช้จ่าย (ผู้ดูแลงบหรือผู้สร้างกิจกรรม) แนบใบเสร็จใน field receipt
+func CreateClubExpense(c *gin.Context) {
+	db := config.DB()
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	user, err := requireClubPermission(c, clubID, permManageBudget, permCreateActivities)
+	if err != nil {
+		return
+	}
+
+	expense := entity.ClubExpense{ClubID: clubID, SubmittedBy: user.ID, Status: "pending"}
+	if err := applyExpenseForm(c, db, &expense); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := checkExpenseWithinBudget(db, &expense); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	receipt, err := readExpenseReceipt(c)
+	if respondImageUploadError(c, err) {
+		return
+	}
+	step, skipped := nextExpenseStep(db, clubID, 0)
+	expense.ApprovalStep = step
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&expense).Error; err != nil {
+			return err
+		}
+		if receipt != nil {
+			if err := writeExpenseReceipt(tx, &expense, receipt); err != nil {
+				return err
+			}
+		}
+		return recordSkippedExpenseSteps(tx, expense.ID, skipped)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถบันทึกค่าใช้จ่ายได้"})
+		return
+	}
+	notifyExpenseApprovers(db, &expense)
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": expense})
+}
+
+// PUT /expenses/:id - แก้ไขค่าใช้จ่ายที่ยังไม่ผ่านขั้นเหรัญญิกหรือถูกตีกลับ รายการที่ถูกตีกลับจะเริ่มพิจารณาใหม่
+func UpdateClubExpense(c *gin.Context) {
+	db := config.DB()
+	var expense entity.ClubExpense
+	if err := db.First(&expense, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบรายการค่าใช้จ่าย"})
+		return
+	}
+	user, err := requireClubPermission(c, expense.ClubID, permManageBudget, permCreateActivities)
+	if err != nil {
+		return
+	}
+	if canManage, _ := hasClubPermission(db, user.ID, expense.ClubID, permManageBudget); expense.SubmittedBy != user.ID && !canManage {
+		c.JSON(http.StatusForbidden, gin.H{"error": "แก้ไขได้เฉพาะผู้ส่งรายการหรือผู้ดูแลงบประมาณ"})
+		return
+	}
+	editable := expense.Status == "rejected" || (expense.Status == "pending" && expense.ApprovalStep == "treasurer")
+	if !editable {
+		c.JSON(http.StatusConflict, gin.H{"error": "รายการนี้ผ่านการพิจารณาขั้นเหรัญญิกแล้ว แก้ไขไม่ได้"})
+		return
+	}
+
+	if err := applyExpenseForm(c, db, &expense); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := checkExpenseWithinBudget(db, &expense); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	receipt, err := readExpenseReceipt(c)
+	if respondImageUploadError(c, err) {
+		return
+	}
+	resubmitted := expense.Status == "rejected"
+	expense.Status = "pending"
+	step, skipped := nextExpenseStep(db, expense.ClubID, 0)
+	expense.ApprovalStep = step
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Activity", "Submitter", "Approvals").Save(&expense).Error; err != nil {
+			return err
+		}
+		if receipt != nil {
+			if err := writeExpenseReceipt(tx, &expense, receipt); err != nil {
+				return err
+			}
+		}
+		return recordSkippedExpenseSteps(tx, expense.ID, skipped)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถบันทึกค่าใช้จ่ายได้"})
+		return
+	}
+	if resubmitted {
+		notifyExpenseApprovers(db, &expense)
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": expense})
+}
+
+// GET /expenses/:id - รายละเอียดค่าใช้จ่ายและประวัติการพิจารณา
+func GetClubExpenseByID(c *gin.Context) {
+	db := config.DB()
+	var expense entity.ClubExpense
+	if err := db.Preload("Activity").Preload("Submitter").
+		Preload("Approvals", func(db *gorm.DB) *gorm.DB { return db.Order("decided_at ASC") }).
+		Preload("Approvals.Approver").
+		First(&expense, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบรายการค่าใช้จ่าย"})
+		return
+	}
+	if _, err := requireBudgetViewer(c, expense.ClubID); err != nil {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": expense})
+}
+
+// GET /expenses/:id/receipt - ดูใบเสร็จของค่าใช้จ่าย (กรรมการชมรม อาจารย์ที่ปรึกษา หรือผู้ดูแลระบบ)
+func GetClubExpenseReceipt(c *gin.Context) {
+	var expense entity.ClubExpense
+	if err := config.DB().First(&expense, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบรายการค่าใช้จ่าย"})
+		return
+	}
+	if _, err := requireBudgetViewer(c, expense.ClubID); err != nil {
+		return
+	}
+	if expense.ReceiptImage == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "รายการนี้ไม่มีใบเสร็จ"})
+		return
+	}
+	data, err := os.ReadFile(expenseReceiptPath(expense.ID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบไฟล์ใบเสร็จ"})
+		return
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, "image/jpeg", data)
+}
+
+// แจ้งผู้พิจารณาขั้นปัจจุบันของค่าใช้จ่าย
+func notifyExpenseApprovers(db *gorm.DB, expense *entity.ClubExpense) {
+	var ids []uint
+	switch expense.ApprovalStep {
+	case "treasurer":
+		for _, id := range clubMemberIDsWithPermission(db, expense.ClubID, permManageBudget) {
+			if id != expense.SubmittedBy {
+				ids = append(ids, id)
+			}
+		}
+	case "advisor":
+		db.Model(&entity.ClubMember{}).Where("club_id = ? AND role = ?", expense.ClubID, "advisor").Pluck("user_id", &ids)
+	case "student_affairs":
+		db.Model(&entity.User{}).Joins("JOIN roles ON roles.id = users.role_id").
+			Where("roles.role_name = ?", "admin").Pluck("users.id", &ids)
+	}
+	notifyUsers(ids, fmt.Sprintf("มีค่าใช้จ่าย \"%s\" จำนวน %.2f บาท รอการพิจารณาขั้น%s", expense.Title, expense.Amount, expenseStepNames[expense.ApprovalStep]), "info")
+}
+
+// POST /expenses/:id/decision - พิจารณาค่าใช้จ่ายตามขั้น {action: approve|reject, comment}
+func DecideClubExpense(c *gin.Context) {
+	db := config.DB()
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	var input struct {
+		Action  string `json:"action" binding:"required"`
+		Comment string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	decisions := map[string]string{"approve": "approved", "reject": "rejected"}
+	decision, ok := decisions[input.Action]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action ต้องเป็น approve หรือ reject"})
+		return
+	}
+	if decision == "rejected" && strings.TrimSpace(input.Comment) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุเหตุผลประกอบการพิจารณา"})
+		return
+	}
+
+	var expense entity.ClubExpense
+	var stepName string
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&expense, c.Param("id")).Error; err != nil {
+			return errNotFound
+		}
+		if expense.Status != "pending" || expense.ApprovalStep == "" {
+			return errors.New("รายการนี้ไม่ได้อยู่ระหว่างการพิจารณา")
+		}
+		allowed, err := canDecideExpenseStep(tx, user, &expense)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return errForbidden
+		}
+		step := expense.ApprovalStep
+		stepName = expenseStepNames[step]
+
+		updates := map[string]interface{}{}
+		var skipped []string
+		if decision == "approved" {
+			if expense.ReceiptImage == "" {
+				return errors.New("ต้องแนบใบเสร็จก่อนอนุมัติ")
+			}
+			var next string
+			next, skipped = nextExpenseStep(tx, expense.ClubID, slices.Index(expenseApprovalSteps, step)+1)
+			updates["approval_step"] = next
+			if next == "" {
+				summary := clubBudgetSummary(tx, expense.ClubID, expense.AcademicYear)
+				if summary.Spent+expense.Amount > summary.Budget+0.005 {
+					return fmt.Errorf("ยอดเกินงบประมาณคงเหลือ (คงเหลือ %.2f บาท)", summary.Remaining)
+				}
+				updates["status"] = "approved"
+			}
+		} else {
+			updates["status"] = "rejected"
+			updates["approval_step"] = ""
+		}
+
+		if err := tx.Create(&entity.ClubExpenseApproval{
+			ExpenseID:  expense.ID,
+			Step:       step,
+			ApproverID: user.ID,
+			Decision:   decision,
+			Comment:    strings.TrimSpace(input.Comment),
+			DecidedAt:  time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		if err := recordSkippedExpenseSteps(tx, expense.ID, skipped); err != nil {
+			return err
+		}
+		if err := tx.Model(&expense).Updates(updates).Error; err != nil {
+			return err
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบรายการค่าใช้จ่าย"})
+		return
+	case errors.Is(err, errForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden: not the approver of this step"})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var message string
+	notificationType := "success"
+	switch {
+	case expense.Status == "approved":
+		message = fmt.Sprintf("ค่าใช้จ่าย \"%s\" ได้รับการอนุมัติครบทุกขั้นแล้ว", expense.Title)
+	case expense.Status == "rejected":
+		message = fmt.Sprintf("ค่าใช้จ่าย \"%s\" ไม่ได้รับการอนุมัติจาก%s: %s", expense.Title, stepName, input.Comment)
+		notificationType = "warning"
+	default:
+		message = fmt.Sprintf("ค่าใช้จ่าย \"%s\" ผ่านการพิจารณาขั้น%sแล้ว", expense.Title, stepName)
+		notifyExpenseApprovers(db, &expense)
+	}
+	if err := getNotificationService().CreateNotification(expense.SubmittedBy, message, notificationType); err != nil {
+		fmt.Println("❌ Error creating expense notification:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "บันทึกผลการพิจารณาแล้ว", "data": expense})
+}
+
+// GET /expenses/pending - ค่าใช้จ่ายที่รอให้ผู้ใช้ปัจจุบันพิจารณา
+func GetPendingClubExpenses(c *gin.Context) {
+	db := config.DB()
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	var expenses []entity.ClubExpense
+	if err := db.Preload("Activity").Preload("Submitter").
+		Where("status = ?", "pending").Order("created_at ASC").Find(&expenses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงรายการค่าใช้จ่ายได้"})
+		return
+	}
+	result := make([]entity.ClubExpense, 0)
+	for i := range expenses {
+		if ok, _ := canDecideExpenseStep(db, user, &expenses[i]); ok {
+			result = append(result, expenses[i])
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": result})
+}
@@ -0,0 +1,709 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+	"final-project/cems/services"
+	"final-project/cems/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	inviteLinkPurpose = "club-invite"
+	maxImportRows     = 2000
+)
+
+var (
+	errAlreadyMember   = errors.New("คุณเป็นสมาชิกชมรมนี้อยู่แล้ว")
+	errInviteExhausted = errors.New("ลิงก์เชิญนี้ถูกใช้ครบจำนวนแล้ว")
+)
+
+func inviteLinkToken(link *entity.ClubInviteLink) string {
+	return utils.SignToken(jwtService.SecretKey, inviteLinkPurpose, link.Code)
+}
+
+func inviteLinkURL(link *entity.ClubInviteLink) string {
+	return fmt.Sprintf("http://localhost:5173/clubs/join/%s", inviteLinkToken(link))
+}
+
+func invitationURL(invitation *entity.ClubInvitation) string {
+	return fmt.Sprintf("http://localhost:5173/clubs/invitations/%s", invitation.Token)
+}
+
+// addApprovedClubMember เพิ่มผู้ใช้เป็นสมาชิกที่อนุมัติแล้ว ถ้ามีคำขอค้างอยู่จะอนุมัติคำขอนั้นแทน
+// role อื่นนอกจาก member จะเปิดวาระกรรมการผ่าน changeMemberRole
+func addApprovedClubMember(tx *gorm.DB, clubID, userID uint, role string, deciderID *uint) error {
+	now := time.Now()
+	var member entity.ClubMember
+	err := tx.Where("club_id = ? AND user_id = ?", clubID, userID).First(&member).Error
+	switch {
+	case err == nil && member.Role != "pending":
+		return errAlreadyMember
+	case err == nil:
+		if err := tx.Model(&member).Update("role", "member").Error; err != nil {
+			return err
+		}
+		if err := closePendingApplication(tx, clubID, userID, "approved", "", deciderID); err != nil {
+			return err
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if err := tx.Create(&entity.ClubMember{ClubID: clubID, UserID: userID, Role: "member", JoinedAt: now}).Error; err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+	if role != "member" {
+		return changeMemberRole(tx, clubID, userID, role, "reassigned", now)
+	}
+	return nil
+}
+
+type InviteLinkResponse struct {
+	entity.ClubInviteLink
+	URL   string `json:"url"`
+	Token string `json:"token"`
+}
+
+// GET /clubs/:id/invite-links - ลิงก์เชิญของชมรม (ผู้จัดการสมาชิก)
+func GetClubInviteLinks(c *gin.Context) {
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	if _, err := requireClubPermission(c, clubID, permManageMembers); err != nil {
+		return
+	}
+	var links []entity.ClubInviteLink
+	if err := config.DB().Where("club_id = ?", clubID).Order("created_at DESC").Find(&links).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงลิงก์เชิญได้"})
+		return
+	}
+	data := make([]InviteLinkResponse, 0, len(links))
+	for i := range links {
+		data = append(data, InviteLinkResponse{links[i], inviteLinkURL(&links[i]), inviteLinkToken(&links[i])})
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": data})
+}
+
+// POST /clubs/:id/invite-links - สร้างลิงก์เชิญ {label, expires_at, max_uses, auto_approve}
+func CreateClubInviteLink(c *gin.Context) {
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	user, err := requireClubPermission(c, clubID, permManageMembers)
+	if err != nil {
+		return
+	}
+	if !requireClubActive(c, clubID) {
+		return
+	}
+	var input struct {
+		Label       string     `json:"label"`
+		ExpiresAt   *time.Time `json:"expires_at"`
+		MaxUses     int        `json:"max_uses"`
+		AutoApprove bool       `json:"auto_approve"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "เวลาหมดอายุต้องอยู่ในอนาคต"})
+		return
+	}
+	if input.MaxUses < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_uses ต้องไม่ติดลบ"})
+		return
+	}
+	code, err := utils.GenerateSecureToken(12)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถสร้างลิงก์เชิญได้"})
+		return
+	}
+	link := entity.ClubInviteLink{
+		ClubID:      clubID,
+		Code:        code,
+		Label:       strings.TrimSpace(input.Label),
+		ExpiresAt:   input.ExpiresAt,
+		MaxUses:     input.MaxUses,
+		AutoApprove: input.AutoApprove,
+		CreatedBy:   user.ID,
+	}
+	if err := config.DB().Create(&link).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถสร้างลิงก์เชิญได้"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": InviteLinkResponse{link, inviteLinkURL(&link), inviteLinkToken(&link)}})
+}
+
+// POST /clubs/:id/invite-links/:linkId/revoke - ยกเลิกลิงก์เชิญ
+func RevokeClubInviteLink(c *gin.Context) {
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	if _, err := requireClubPermission(c, clubID, permManageMembers); err != nil {
+		return
+	}
+	res := config.DB().Model(&entity.ClubInviteLink{}).
+		Where("id = ? AND club_id = ? AND revoked_at IS NULL", c.Param("linkId"), clubID).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถยกเลิกลิงก์ได้"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบลิงก์เชิญที่ใช้งานอยู่"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ยกเลิกลิงก์เชิญเรียบร้อยแล้ว"})
+}
+
+// ตรวจลายเซ็นและสถานะของลิงก์เชิญ ตอบ error ให้แล้วถ้าใช้ไม่ได้
+func loadInviteLink(c *gin.Context) (*entity.ClubInviteLink, bool) {
+	code, valid := utils.VerifySignedToken(jwtService.SecretKey, inviteLinkPurpose, c.Param("token"))
+	var link entity.ClubInviteLink
+	if !valid || config.DB().Where("code = ?", code).First(&link).Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ลิงก์เชิญไม่ถูกต้อง"})
+		return nil, false
+	}
+	switch {
+	case link.RevokedAt != nil:
+		c.JSON(http.StatusGone, gin.H{"error": "ลิงก์เชิญนี้ถูกยกเลิกแล้ว"})
+		return nil, false
+	case link.ExpiresAt != nil && time.Now().After(*link.ExpiresAt):
+		c.JSON(http.StatusGone, gin.H{"error": "ลิงก์เชิญนี้หมดอายุแล้ว"})
+		return nil, false
+	case link.MaxUses > 0 && link.UseCount >= link.MaxUses:
+		c.JSON(http.StatusGone, gin.H{"error": errInviteExhausted.Error()})
+		return nil, false
+	}
+	return &link, true
+}
+
+// GET /club-invites/:token - ข้อมูลลิงก์เชิญสำหรับหน้าตอบรับ (ไม่ต้องเข้าสู่ระบบ)
+func GetClubInviteLink(c *gin.Context) {
+	db := config.DB()
+	link, ok := loadInviteLink(c)
+	if !ok {
+		return
+	}
+	var club entity.Club
+	if err := db.First(&club, link.ClubID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	data := gin.H{
+		"club_id":      club.ID,
+		"club_name":    club.Name,
+		"logo_image":   club.LogoImage,
+		"auto_approve": link.AutoApprove,
+		"expires_at":   link.ExpiresAt,
+	}
+	// ลิงก์ที่ยังต้องพิจารณาจะส่งใบสมัครด้วย จึงต้องตอบคำถามของชมรม
+	if !link.AutoApprove {
+		questions, _ := loadApplicationQuestions(db, club.ID)
+		items := make([]RegistrationQuestionResponse, 0, len(questions))
+		for _, q := range questions {
+			items = append(items, toApplicationQuestionResponse(q))
+		}
+		data["questions"] = items
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": data})
+}
+
+// POST /club-invites/:token/join - เข้าชมรมผ่านลิงก์เชิญ
+// auto_approve เข้าเป็นสมาชิกทันที ไม่เช่นนั้นส่งคำขอพร้อมคำตอบใบสมัครเหมือน RequestJoinClub
+func JoinClubByInviteLink(c *gin.Context) {
+	db := config.DB()
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	link, ok := loadInviteLink(c)
+	if !ok {
+		return
+	}
+	var club entity.Club
+	if err := db.First(&club, link.ClubID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	if !requireClubActive(c, club.ID) {
+		return
+	}
+
+	var input struct {
+		Answers []RegistrationAnswerInput `json:"answers"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+			return
+		}
+	}
+	var questions []entity.ClubApplicationQuestion
+	var answers map[uint]string
+	if !link.AutoApprove {
+		if questions, err = loadApplicationQuestions(db, club.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงคำถามใบสมัครได้"})
+			return
+		}
+		if answers, err = validateApplicationAnswers(questions, input.Answers); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// นับการใช้ลิงก์แบบมีเงื่อนไข กันการใช้เกินจำนวนเมื่อมีคนกดพร้อมกัน
+		res := tx.Model(&entity.ClubInviteLink{}).
+			Where("id = ? AND (max_uses = 0 OR use_count < max_uses)", link.ID).
+			Update("use_count", gorm.Expr("use_count + 1"))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errInviteExhausted
+		}
+
+		if link.AutoApprove {
+			return addApprovedClubMember(tx, club.ID, user.ID, "member", nil)
+		}
+		var existing int64
+		tx.Model(&entity.ClubMember{}).Where("club_id = ? AND user_id = ?", club.ID, user.ID).Count(&existing)
+		if existing > 0 {
+			return errAlreadyMember
+		}
+		if err := tx.Create(&entity.ClubMember{ClubID: club.ID, UserID: user.ID, Role: "pending", JoinedAt: time.Now()}).Error; err != nil {
+			return err
+		}
+		return createClubApplication(tx, club.ID, user.ID, questions, answers)
+	})
+	switch {
+	case errors.Is(err, errAlreadyMember):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "คุณเป็นสมาชิกหรือส่งคำขอเข้าชมรมนี้แล้ว"})
+		return
+	case errors.Is(err, errInviteExhausted):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถเข้าร่วมชมรมได้"})
+		return
+	}
+
+	if link.AutoApprove {
+		sendMembershipDecisionEmail(db, &club, user.ID, "approved", "")
+		c.JSON(http.StatusOK, gin.H{"success": true, "status": "member", "message": fmt.Sprintf("เข้าร่วมชมรม %s เรียบร้อยแล้ว", club.Name)})
+		return
+	}
+	sendJoinRequestEmail(db, &club, user)
+	c.JSON(http.StatusOK, gin.H{"success": true, "status": "pending", "message": "ส่งคำขอเข้าร่วมชมรมเรียบร้อยแล้ว โปรดรอการอนุมัติ"})
+}
+
+// GET /clubs/:id/invitations - คำเชิญรายอีเมลของชมรม
+func GetClubInvitations(c *gin.Context) {
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	if _, err := requireClubPermission(c, clubID, permManageMembers); err != nil {
+		return
+	}
+	query := config.DB().Where("club_id = ?", clubID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var invitations []entity.ClubInvitation
+	if err := query.Order("created_at DESC").Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงคำเชิญได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": invitations})
+}
+
+// POST /clubs/:id/invitations - เชิญรายอีเมล {emails: [...], expires_at}
+// อีเมลที่เป็นสมาชิกอยู่แล้วหรือมีคำเชิญค้างอยู่จะถูกข้ามและรายงานกลับ
+func CreateClubInvitations(c *gin.Context) {
+	db := config.DB()
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	inviter, err := requireClubPermission(c, clubID, permManageMembers)
+	if err != nil {
+		return
+	}
+	if !requireClubActive(c, clubID) {
+		return
+	}
+	var club entity.Club
+	if err := db.First(&club, clubID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	var input struct {
+		Emails    []string   `json:"emails"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || len(input.Emails) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ต้องระบุ emails"})
+		return
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "เวลาหมดอายุต้องอยู่ในอนาคต"})
+		return
+	}
+
+	expiresText := ""
+	if input.ExpiresAt != nil {
+		expiresText = formatThaiDateTime(*input.ExpiresAt)
+	}
+	invited := []entity.ClubInvitation{}
+	skipped := []gin.H{}
+	seen := map[string]bool{}
+	for _, raw := range input.Emails {
+		email := strings.ToLower(strings.TrimSpace(raw))
+		if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+			skipped = append(skipped, gin.H{"email": raw, "reason": "อีเมลไม่ถูกต้อง"})
+			continue
+		}
+		if seen[email] {
+			continue
+		}
+		seen[email] = true
+
+		var memberCount int64
+		db.Model(&entity.ClubMember{}).Joins("JOIN users ON users.id = club_members.user_id").
+			Where("club_members.club_id = ? AND LOWER(users.email) = ? AND club_members.role <> ?", clubID, email, "pending").
+			Count(&memberCount)
+		if memberCount > 0 {
+			skipped = append(skipped, gin.H{"email": email, "reason": "เป็นสมาชิกอยู่แล้ว"})
+			continue
+		}
+		var pendingCount int64
+		db.Model(&entity.ClubInvitation{}).
+			Where("club_id = ? AND email = ? AND status = ? AND (expires_at IS NULL OR expires_at > ?)", clubID, email, "pending", time.Now()).
+			Count(&pendingCount)
+		if pendingCount > 0 {
+			skipped = append(skipped, gin.H{"email": email, "reason": "มีคำเชิญที่ยังใช้ได้อยู่แล้ว"})
+			continue
+		}
+
+		token, err := utils.GenerateSecureToken(24)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถสร้างคำเชิญได้"})
+			return
+		}
+		invitation := entity.ClubInvitation{
+			ClubID:    clubID,
+			Email:     email,
+			Token:     token,
+			Status:    "pending",
+			ExpiresAt: input.ExpiresAt,
+			InvitedBy: inviter.ID,
+		}
+		if err := db.Create(&invitation).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถสร้างคำเชิญได้"})
+			return
+		}
+		invited = append(invited, invitation)
+
+		htmlBody, _ := services.RenderTemplate("club_invitation.html", map[string]string{
+			"ClubName":    club.Name,
+			"InviterName": inviter.FirstName + " " + inviter.LastName,
+			"InviteLink":  invitationURL(&invitation),
+			"ExpiresAt":   expiresText,
+		})
+		go services.SendEmailHTML(email, fmt.Sprintf("✉️ คำเชิญเข้าร่วมชมรม %s", club.Name), htmlBody)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": gin.H{"invited": invited, "skipped": skipped}})
+}
+
+// POST /clubs/:id/invitations/:invitationId/revoke - ยกเลิกคำเชิญที่ยังไม่ถูกตอบรับ
+func RevokeClubInvitation(c *gin.Context) {
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	if _, err := requireClubPermission(c, clubID, permManageMembers); err != nil {
+		return
+	}
+	res := config.DB().Model(&entity.ClubInvitation{}).
+		Where("id = ? AND club_id = ? AND status = ?", c.Param("invitationId"), clubID, "pending").
+		Update("status", "revoked")
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถยกเลิกคำเชิญได้"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบคำเชิญที่รอตอบรับ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ยกเลิกคำเชิญเรียบร้อยแล้ว"})
+}
+
+// POST /club-invitations/:token/accept - ตอบรับคำเชิญ ต้องเข้าสู่ระบบด้วยอีเมลที่ได้รับเชิญ
+func AcceptClubInvitation(c *gin.Context) {
+	db := config.DB()
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	var invitation entity.ClubInvitation
+	if err := db.Where("token = ?", c.Param("token")).First(&invitation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "คำเชิญไม่ถูกต้อง"})
+		return
+	}
+	switch {
+	case invitation.Status != "pending":
+		c.JSON(http.StatusGone, gin.H{"error": "คำเชิญนี้ถูกใช้หรือถูกยกเลิกแล้ว"})
+		return
+	case invitation.ExpiresAt != nil && time.Now().After(*invitation.ExpiresAt):
+		c.JSON(http.StatusGone, gin.H{"error": "คำเชิญนี้หมดอายุแล้ว"})
+		return
+	case !strings.EqualFold(user.Email, invitation.Email):
+		c.JSON(http.StatusForbidden, gin.H{"error": "คำเชิญนี้ส่งถึงอีเมลอื่น"})
+		return
+	}
+	var club entity.Club
+	if err := db.First(&club, invitation.ClubID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	if !requireClubActive(c, club.ID) {
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		res := tx.Model(&entity.ClubInvitation{}).Where("id = ? AND status = ?", invitation.ID, "pending").
+			Updates(map[string]interface{}{"status": "accepted", "accepted_by": user.ID, "accepted_at": now})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errApplicationNotPending
+		}
+		return addApprovedClubMember(tx, club.ID, user.ID, "member", &invitation.InvitedBy)
+	})
+	switch {
+	case errors.Is(err, errAlreadyMember):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errApplicationNotPending):
+		c.JSON(http.StatusGone, gin.H{"error": "คำเชิญนี้ถูกใช้หรือถูกยกเลิกแล้ว"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถตอบรับคำเชิญได้"})
+		return
+	}
+	notifyUsers([]uint{invitation.InvitedBy}, fmt.Sprintf("%s %s ตอบรับคำเชิญเข้าชมรม %s แล้ว", user.FirstName, user.LastName, club.Name), "info")
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": fmt.Sprintf("เข้าร่วมชมรม %s เรียบร้อยแล้ว", club.Name)})
+}
+
+type MemberImportRow struct {
+	Row       int    `json:"row"`
+	StudentID string `json:"student_id"`
+	UserID    uint   `json:"user_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Role      string `json:"role"`
+	Action    string `json:"action"` // add, approve_pending, change_role, unchanged, error
+	Error     string `json:"error,omitempty"`
+}
+
+// POST /clubs/:id/members/import - นำเข้าสมาชิกจากไฟล์ CSV (field file) คอลัมน์ student_id และ role (ไม่บังคับ)
+// ค่าเริ่มต้น dry_run=true ตรวจและรายงานผลโดยไม่บันทึก ต้องส่ง dry_run=false จึงบันทึกจริง แถวที่มีปัญหาจะถูกข้าม แถวที่เหลือบันทึกใน transaction เดียว
+// กำหนดบทบาทกรรมการได้เฉพาะหัวหน้าชมรม เช่นเดียวกับ AssignClubMemberRole
+func ImportClubMembers(c *gin.Context) {
+	db := config.DB()
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	user, err := requireClubPermission(c, clubID, permManageMembers)
+	if err != nil {
+		return
+	}
+	if !requireClubActive(c, clubID) {
+		return
+	}
+	dryRun, err := strconv.ParseBool(c.DefaultPostForm("dry_run", c.DefaultQuery("dry_run", "true")))
+	if err != nil {
+		dryRun = true
+	}
+	isPresident, _ := hasClubRole(db, user.ID, clubID, "president")
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาแนบไฟล์ CSV ใน field file"})
+		return
+	}
+	f, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "อ่านไฟล์ไม่ได้"})
+		return
+	}
+	defer f.Close()
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "รูปแบบไฟล์ CSV ไม่ถูกต้อง: " + err.Error()})
+		return
+	}
+	if len(records) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ไฟล์ว่าง"})
+		return
+	}
+
+	// หาคอลัมน์จากหัวตาราง ถ้าไม่มีหัวตารางถือว่าคอลัมน์แรกคือ student_id และคอลัมน์ที่สองคือ role
+	studentCol, roleCol, start := 0, 1, 0
+	first := make([]string, len(records[0]))
+	for i, v := range records[0] {
+		first[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(v, "\ufeff")))
+	}
+	for i, v := range first {
+		switch v {
+		case "student_id", "studentid":
+			studentCol, start = i, 1
+		case "role":
+			roleCol = i
+		}
+	}
+	if len(records)-start > maxImportRows {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("นำเข้าได้ครั้งละไม่เกิน %d แถว", maxImportRows)})
+		return
+	}
+
+	labels := clubRoleLabels(db, clubID)
+	report := make([]MemberImportRow, 0, len(records)-start)
+	seen := map[string]int{}
+	for i, record := range records[start:] {
+		row := MemberImportRow{Row: start + i + 1, Role: "member"}
+		if studentCol < len(record) {
+			row.StudentID = strings.ToUpper(strings.TrimSpace(record[studentCol]))
+		}
+		if roleCol < len(record) && strings.TrimSpace(record[roleCol]) != "" {
+			row.Role = strings.ToLower(strings.TrimSpace(record[roleCol]))
+		}
+		fail := func(msg string) {
+			row.Action, row.Error = "error", msg
+			report = append(report, row)
+		}
+
+		if row.StudentID == "" {
+			fail("ไม่มีรหัสนักศึกษา")
+			continue
+		}
+		if prev, dup := seen[row.StudentID]; dup {
+			fail(fmt.Sprintf("รหัสนักศึกษาซ้ำกับแถวที่ %d", prev))
+			continue
+		}
+		seen[row.StudentID] = row.Row
+		if _, known := labels[row.Role]; row.Role != "member" && !known {
+			fail("ไม่พบบทบาทนี้ในชมรม")
+			continue
+		}
+		if row.Role == "president" {
+			fail("เปลี่ยนหัวหน้าชมรมได้ผ่านการเปลี่ยนหัวหน้าชมรมเท่านั้น")
+			continue
+		}
+		if row.Role == "advisor" {
+			fail("อาจารย์ที่ปรึกษาแต่งตั้งได้โดยผู้ดูแลระบบเท่านั้น")
+			continue
+		}
+		if row.Role != "member" && !isPresident {
+			fail("ต้องเป็นหัวหน้าชมรมจึงกำหนดบทบาทกรรมการได้")
+			continue
+		}
+
+		var student entity.User
+		if err := db.Where("UPPER(student_id) = ?", row.StudentID).First(&student).Error; err != nil {
+			fail("ไม่พบนักศึกษารหัสนี้")
+			continue
+		}
+		row.UserID = student.ID
+		row.Name = student.FirstName + " " + student.LastName
+
+		var member entity.ClubMember
+		switch err := db.Where("club_id = ? AND user_id = ?", clubID, student.ID).First(&member).Error; {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			row.Action = "add"
+		case err != nil:
+			fail("ตรวจสอบสมาชิกไม่สำเร็จ")
+			continue
+		case member.Role == "pending":
+			row.Action = "approve_pending"
+		case member.Role == "president":
+			fail("เปลี่ยนหัวหน้าชมรมได้ผ่านการเปลี่ยนหัวหน้าชมรมเท่านั้น")
+			continue
+		case member.Role == "advisor":
+			fail("อาจารย์ที่ปรึกษาแต่งตั้งและถอดถอนได้โดยผู้ดูแลระบบเท่านั้น")
+			continue
+		case member.Role == row.Role:
+			row.Action = "unchanged"
+		case !isPresident:
+			fail("ต้องเป็นหัวหน้าชมรมจึงเปลี่ยนบทบาทสมาชิกได้")
+			continue
+		default:
+			row.Action = "change_role"
+		}
+		report = append(report, row)
+	}
+
+	summary := map[string]int{}
+	for _, r := range report {
+		summary[r.Action]++
+	}
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{"success": true, "dry_run": true, "summary": summary, "rows": report})
+		return
+	}
+
+	var approvedIDs []uint
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for _, r := range report {
+			switch r.Action {
+			case "add", "approve_pending":
+				if err := addApprovedClubMember(tx, clubID, r.UserID, r.Role, &user.ID); err != nil {
+					return fmt.Errorf("แถวที่ %d: %w", r.Row, err)
+				}
+				approvedIDs = append(approvedIDs, r.UserID)
+			case "change_role":
+				if err := changeMemberRole(tx, clubID, r.UserID, r.Role, "reassigned", now); err != nil {
+					return fmt.Errorf("แถวที่ %d: %w", r.Row, err)
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "นำเข้าไม่สำเร็จ ไม่มีการบันทึกข้อมูล: " + err.Error()})
+		return
+	}
+
+	var club entity.Club
+	if db.First(&club, clubID).Error == nil {
+		notifyUsers(approvedIDs, fmt.Sprintf("คุณได้รับการเพิ่มเป็นสมาชิกชมรม %s", club.Name), "info")
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "dry_run": false, "summary": summary, "rows": report})
+}
@@ -0,0 +1,604 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	errClubSuspended  = errors.New("ชมรมถูกระงับ ไม่สามารถดำเนินการนี้ได้จนกว่าจะได้รับการคืนสถานะ")
+	errClubArchived   = errors.New("ชมรมถูกยุบและเก็บถาวรแล้ว ข้อมูลเป็นแบบอ่านอย่างเดียว")
+	errAlreadyRenewed = errors.New("ชมรมได้รับอนุมัติการต่ออายุของปีการศึกษานี้แล้ว")
+)
+
+func clubStatusName(db *gorm.DB, clubID uint) (string, error) {
+	var name string
+	err := db.Model(&entity.Club{}).
+		Joins("JOIN club_statuses ON club_statuses.id = clubs.status_id").
+		Where("clubs.id = ?", clubID).
+		Pluck("club_statuses.name", &name).Error
+	return name, err
+}
+
+// checkClubActive ใช้ก่อนสร้างกิจกรรม ประกาศข่าว และรับสมาชิก ชมรมที่ถูกระงับหรือยุบแล้วทำไม่ได้
+func checkClubActive(db *gorm.DB, clubID uint) error {
+	status, err := clubStatusName(db, clubID)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case "suspended":
+		return errClubSuspended
+	case "archived":
+		return errClubArchived
+	}
+	return nil
+}
+
+// checkClubWritable ชมรมที่ยุบแล้วแก้ไขข้อมูลใดๆ ไม่ได้ ส่วนชมรมที่ถูกระงับยังจัดการข้อมูลเดิมและยื่นต่ออายุได้
+func checkClubWritable(db *gorm.DB, clubID uint) error {
+	status, err := clubStatusName(db, clubID)
+	if err != nil {
+		return err
+	}
+	if status == "archived" {
+		return errClubArchived
+	}
+	return nil
+}
+
+// บังคับให้ชมรมยังดำเนินงานได้ ตอบ error ให้แล้วถ้าไม่ได้
+func requireClubActive(c *gin.Context, clubID uint) bool {
+	if err := checkClubActive(config.DB(), clubID); err != nil {
+		respondClubStateError(c, err)
+		return false
+	}
+	return true
+}
+
+func respondClubStateError(c *gin.Context, err error) {
+	if errors.Is(err, errClubSuspended) || errors.Is(err, errClubArchived) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถตรวจสอบสถานะชมรมได้"})
+}
+
+// setClubStatus เปลี่ยนสถานะชมรมพร้อมบันทึกประวัติ
+func setClubStatus(tx *gorm.DB, club *entity.Club, statusName, reason, note string, changedBy *uint) error {
+	var status entity.ClubStatus
+	if err := tx.Where("name = ?", statusName).First(&status).Error; err != nil {
+		return fmt.Errorf("ไม่พบสถานะ %s", statusName)
+	}
+	from, err := clubStatusName(tx, club.ID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(club).Update("status_id", status.ID).Error; err != nil {
+		return err
+	}
+	return tx.Create(&entity.ClubStatusChange{
+		ClubID:     club.ID,
+		FromStatus: from,
+		ToStatus:   statusName,
+		Reason:     reason,
+		Note:       note,
+		ChangedBy:  changedBy,
+	}).Error
+}
+
+// GET /clubs/:id/status-history - ประวัติสถานะของชมรม
+func GetClubStatusHistory(c *gin.Context) {
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	var changes []entity.ClubStatusChange
+	if err := config.DB().Where("club_id = ?", clubID).Order("created_at ASC").Find(&changes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงประวัติสถานะได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": changes})
+}
+
+// รอบต่ออายุที่เปิดแล้วล่าสุด (รวมรอบที่เลยกำหนดแล้ว ชมรมที่ถูกระงับยังยื่นย้อนหลังได้)
+func currentRenewalCycle(db *gorm.DB, now time.Time) (*entity.ClubRenewalCycle, error) {
+	var cycle entity.ClubRenewalCycle
+	if err := db.Where("opens_at <= ?", now).Order("academic_year DESC").First(&cycle).Error; err != nil {
+		return nil, err
+	}
+	return &cycle, nil
+}
+
+// อาจารย์ที่ปรึกษาไม่นับเป็นสมาชิกสำหรับเกณฑ์จำนวนสมาชิกขั้นต่ำ
+func approvedMemberCount(db *gorm.DB, clubID uint) int64 {
+	var count int64
+	db.Model(&entity.ClubMember{}).Where("club_id = ? AND role NOT IN ?", clubID, []string{"pending", "advisor"}).Count(&count)
+	return count
+}
+
+// GET /club-renewal-cycles - รอบการต่ออายุชมรมทั้งหมด
+func GetClubRenewalCycles(c *gin.Context) {
+	var cycles []entity.ClubRenewalCycle
+	if err := config.DB().Order("academic_year DESC").Find(&cycles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงรอบการต่ออายุได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": cycles})
+}
+
+// PUT /club-renewal-cycles/:year - กำหนดรอบการต่ออายุของปีการศึกษา (ผู้ดูแลระบบ) {opens_at, deadline, min_members}
+func UpsertClubRenewalCycle(c *gin.Context) {
+	db := config.DB()
+	if _, err := requireAdmin(c); err != nil {
+		return
+	}
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil || year < 2500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ปีการศึกษาไม่ถูกต้อง (พ.ศ.)"})
+		return
+	}
+	var input struct {
+		OpensAt    time.Time `json:"opens_at" binding:"required"`
+		Deadline   time.Time `json:"deadline" binding:"required"`
+		MinMembers int       `json:"min_members"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุ opens_at และ deadline"})
+		return
+	}
+	if !input.Deadline.After(input.OpensAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กำหนดส่งต้องอยู่หลังวันเปิดรับ"})
+		return
+	}
+	if input.MinMembers < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_members ต้องมากกว่า 0"})
+		return
+	}
+
+	var cycle entity.ClubRenewalCycle
+	db.Where("academic_year = ?", year).First(&cycle)
+	cycle.AcademicYear = year
+	cycle.OpensAt = input.OpensAt
+	cycle.Deadline = input.Deadline
+	cycle.MinMembers = input.MinMembers
+	if err := db.Save(&cycle).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถบันทึกรอบการต่ออายุได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": cycle})
+}
+
+// GET /clubs/:id/renewals - สถานะการต่ออายุรอบปัจจุบันและประวัติ (กรรมการชมรมหรือผู้ดูแลระบบ)
+func GetClubRenewals(c *gin.Context) {
+	db := config.DB()
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if officer, _ := hasClubPermission(db, user.ID, clubID, clubPermissions...); !officer && !isAdmin(db, user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden: club officers only"})
+		return
+	}
+
+	var renewals []entity.ClubRenewal
+	if err := db.Preload("Officers.User").Where("club_id = ?", clubID).Order("academic_year DESC").Find(&renewals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงข้อมูลการต่ออายุได้"})
+		return
+	}
+	data := gin.H{"renewals": renewals, "member_count": approvedMemberCount(db, clubID)}
+	if cycle, err := currentRenewalCycle(db, time.Now()); err == nil {
+		data["cycle"] = cycle
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": data})
+}
+
+// POST /clubs/:id/renewals - ยื่นต่ออายุชมรมพร้อมกรรมการชุดใหม่ (หัวหน้าชมรม) {officers: [{user_id, role}]}
+// ต้องมีหัวหน้าชมรมหนึ่งคน และจำนวนสมาชิกต้องไม่น้อยกว่าขั้นต่ำของรอบ ยื่นซ้ำได้จนกว่าจะได้รับอนุมัติ
+func SubmitClubRenewal(c *gin.Context) {
+	db := config.DB()
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	user, err := requirePresident(c, clubID)
+	if err != nil {
+		return
+	}
+	var club entity.Club
+	if err := db.First(&club, clubID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	cycle, err := currentRenewalCycle(db, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ยังไม่เปิดรับการต่ออายุชมรม"})
+		return
+	}
+
+	var input struct {
+		Officers []struct {
+			UserID uint   `json:"user_id"`
+			Role   string `json:"role"`
+		} `json:"officers" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุรายชื่อกรรมการ"})
+		return
+	}
+
+	labels := clubRoleLabels(db, clubID)
+	officers := make([]entity.ClubRenewalOfficer, 0, len(input.Officers))
+	seen := map[uint]bool{}
+	presidents := 0
+	for _, o := range input.Officers {
+		role := strings.TrimSpace(o.Role)
+		if _, known := labels[role]; !known {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ไม่พบบทบาท %s ในชมรม", role)})
+			return
+		}
+		if seen[o.UserID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "มีผู้ใช้ซ้ำในรายชื่อกรรมการ"})
+			return
+		}
+		seen[o.UserID] = true
+		if _, ok := approvedClubMember(db, clubID, o.UserID); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ผู้ใช้ %d ไม่ได้เป็นสมาชิกที่ได้รับอนุมัติของชมรม", o.UserID)})
+			return
+		}
+		if role == "president" {
+			presidents++
+		}
+		officers = append(officers, entity.ClubRenewalOfficer{UserID: o.UserID, Role: role})
+	}
+	if presidents != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ต้องระบุหัวหน้าชมรมหนึ่งคน"})
+		return
+	}
+	memberCount := approvedMemberCount(db, clubID)
+	if memberCount < int64(cycle.MinMembers) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":        fmt.Sprintf("ชมรมต้องมีสมาชิกอย่างน้อย %d คน (ปัจจุบัน %d คน)", cycle.MinMembers, memberCount),
+			"member_count": memberCount,
+			"min_members":  cycle.MinMembers,
+		})
+		return
+	}
+
+	var renewal entity.ClubRenewal
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("club_id = ? AND academic_year = ?", clubID, cycle.AcademicYear).First(&renewal).Error
+		switch {
+		case err == nil && renewal.Status == "approved":
+			return errAlreadyRenewed
+		case err == nil:
+			if err := tx.Unscoped().Where("renewal_id = ?", renewal.ID).Delete(&entity.ClubRenewalOfficer{}).Error; err != nil {
+				return err
+			}
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		renewal.ClubID = clubID
+		renewal.AcademicYear = cycle.AcademicYear
+		renewal.Status = "submitted"
+		renewal.MemberCount = memberCount
+		renewal.SubmittedBy = user.ID
+		renewal.SubmittedAt = time.Now()
+		renewal.ReviewedBy = nil
+		renewal.ReviewedAt = nil
+		renewal.ReviewNote = ""
+		renewal.Officers = nil
+		if err := tx.Save(&renewal).Error; err != nil {
+			return err
+		}
+		for i := range officers {
+			officers[i].RenewalID = renewal.ID
+		}
+		renewal.Officers = officers
+		return tx.Create(&officers).Error
+	})
+	if errors.Is(err, errAlreadyRenewed) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถยื่นต่ออายุชมรมได้"})
+		return
+	}
+
+	var adminIDs []uint
+	db.Model(&entity.User{}).Joins("JOIN roles ON roles.id = users.role_id").
+		Where("roles.role_name = ?", "admin").Pluck("users.id", &adminIDs)
+	notifyUsers(adminIDs, fmt.Sprintf("ชมรม %s ยื่นต่ออายุประจำปีการศึกษา %d รอการพิจารณา", club.Name, cycle.AcademicYear), "info")
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ยื่นต่ออายุชมรมเรียบร้อยแล้ว", "data": renewal})
+}
+
+// GET /club-renewals - คำขอต่ออายุทั้งหมด (ผู้ดูแลระบบ) ค่าเริ่มต้นเฉพาะที่รอพิจารณา
+func GetClubRenewalQueue(c *gin.Context) {
+	if _, err := requireAdmin(c); err != nil {
+		return
+	}
+	query := config.DB().Preload("Club").Preload("Officers.User")
+	if status := c.DefaultQuery("status", "submitted"); status != "all" {
+		query = query.Where("status = ?", status)
+	}
+	if year := c.Query("academic_year"); year != "" {
+		query = query.Where("academic_year = ?", year)
+	}
+	var renewals []entity.ClubRenewal
+	if err := query.Order("submitted_at ASC").Find(&renewals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงคำขอต่ออายุได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": renewals})
+}
+
+// POST /club-renewals/:id/decision - พิจารณาคำขอต่ออายุ (ผู้ดูแลระบบ) {action: approve|reject, note}
+// อนุมัติแล้วกรรมการชุดใหม่มีผลทันที และชมรมที่ถูกระงับเพราะต่ออายุไม่ทันจะได้รับคืนสถานะ
+func DecideClubRenewal(c *gin.Context) {
+	db := config.DB()
+	admin, err := requireAdmin(c)
+	if err != nil {
+		return
+	}
+	var input struct {
+		Action string `json:"action" binding:"required"`
+		Note   string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || (input.Action != "approve" && input.Action != "reject") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action ต้องเป็น approve หรือ reject"})
+		return
+	}
+	input.Note = strings.TrimSpace(input.Note)
+	if input.Action == "reject" && input.Note == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุเหตุผลที่ไม่อนุมัติ"})
+		return
+	}
+
+	var renewal entity.ClubRenewal
+	if err := db.Preload("Club").Preload("Officers").First(&renewal, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบคำขอต่ออายุ"})
+		return
+	}
+	if renewal.Status != "submitted" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "คำขอนี้ได้รับการพิจารณาแล้ว"})
+		return
+	}
+	if err := checkClubWritable(db, renewal.ClubID); err != nil {
+		respondClubStateError(c, err)
+		return
+	}
+
+	reinstated := false
+	err = db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		status := map[string]string{"approve": "approved", "reject": "rejected"}[input.Action]
+		if err := tx.Model(&renewal).Updates(map[string]interface{}{
+			"status": status, "reviewed_by": admin.ID, "reviewed_at": now, "review_note": input.Note,
+		}).Error; err != nil {
+			return err
+		}
+		if status == "rejected" {
+			return nil
+		}
+		if err := applyRenewalCommittee(tx, &renewal, now); err != nil {
+			return err
+		}
+
+		// คืนสถานะเฉพาะชมรมที่ถูกระงับเพราะไม่ต่ออายุ การระงับด้วยเหตุผลอื่นต้องให้ผู้ดูแลระบบคืนสถานะเอง
+		var last entity.ClubStatusChange
+		current, _ := clubStatusName(tx, renewal.ClubID)
+		if current == "suspended" &&
+			tx.Where("club_id = ?", renewal.ClubID).Order("created_at DESC").First(&last).Error == nil &&
+			last.Reason == "renewal_overdue" {
+			reinstated = true
+			return setClubStatus(tx, &renewal.Club, "approved", "renewal_approved", "", &admin.ID)
+		}
+		return nil
+	})
+	if errors.Is(err, errNotApprovedMember) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรรมการที่เสนอบางคนไม่ได้เป็นสมาชิกของชมรมแล้ว ต้องให้ชมรมยื่นใหม่"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถบันทึกผลการพิจารณาได้"})
+		return
+	}
+
+	message := fmt.Sprintf("การต่ออายุชมรม %s ประจำปีการศึกษา %d ได้รับการอนุมัติแล้ว", renewal.Club.Name, renewal.AcademicYear)
+	if input.Action == "reject" {
+		message = fmt.Sprintf("การต่ออายุชมรม %s ประจำปีการศึกษา %d ไม่ได้รับการอนุมัติ: %s", renewal.Club.Name, renewal.AcademicYear, input.Note)
+	}
+	notifyUsers(clubMemberIDsWithPermission(db, renewal.ClubID, permManageMembers), message, "info")
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "บันทึกผลการพิจารณาเรียบร้อยแล้ว", "reinstated": reinstated})
+}
+
+// applyRenewalCommittee ตั้งกรรมการตามคำขอต่ออายุ กรรมการเดิมที่ไม่อยู่ในชุดใหม่กลับเป็นสมาชิก (ยกเว้นอาจารย์ที่ปรึกษา)
+func applyRenewalCommittee(tx *gorm.DB, renewal *entity.ClubRenewal, at time.Time) error {
+	proposed := map[uint]string{}
+	var presidentID uint
+	for _, o := range renewal.Officers {
+		proposed[o.UserID] = o.Role
+		if o.Role == "president" {
+			presidentID = o.UserID
+		}
+	}
+	if err := transferClubPresidency(tx, renewal.ClubID, presidentID, "renewal", at); err != nil {
+		if errors.Is(err, errNotApprovedMember) {
+			return err
+		}
+		return fmt.Errorf("เปลี่ยนหัวหน้าชมรมไม่สำเร็จ: %w", err)
+	}
+
+	var members []entity.ClubMember
+	if err := tx.Where("club_id = ? AND role NOT IN ?", renewal.ClubID, []string{"member", "pending", "president", "advisor"}).
+		Find(&members).Error; err != nil {
+		return err
+	}
+	for _, m := range members {
+		if proposed[m.UserID] != m.Role {
+			if err := changeMemberRole(tx, renewal.ClubID, m.UserID, "member", "renewal", at); err != nil {
+				return err
+			}
+		}
+	}
+	for userID, role := range proposed {
+		if role == "president" {
+			continue
+		}
+		if _, ok := approvedClubMember(tx, renewal.ClubID, userID); !ok {
+			return errNotApprovedMember
+		}
+		if err := changeMemberRole(tx, renewal.ClubID, userID, role, "renewal", at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ระงับชมรมที่ไม่ได้ยื่นต่ออายุภายในกำหนดของรอบล่าสุด ชมรมที่ยื่นแล้วรอพิจารณาไม่ถูกระงับ
+// ชมรมที่เคยถูกระงับในรอบนี้แล้วและผู้ดูแลระบบคืนสถานะให้เอง จะไม่ถูกระงับซ้ำ
+func suspendOverdueClubs(db *gorm.DB) error {
+	now := time.Now()
+	var cycle entity.ClubRenewalCycle
+	if err := db.Where("deadline < ?", now).Order("deadline DESC").First(&cycle).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	var clubs []entity.Club
+	if err := db.Where("status_id IN (SELECT id FROM club_statuses WHERE name = ?)", "approved").
+		Where("created_at < ?", cycle.OpensAt).
+		Where("id NOT IN (SELECT club_id FROM club_renewals WHERE deleted_at IS NULL AND academic_year = ? AND status IN ?)",
+			cycle.AcademicYear, []string{"submitted", "approved"}).
+		Where("id NOT IN (SELECT club_id FROM club_status_changes WHERE deleted_at IS NULL AND reason = ? AND created_at >= ?)",
+			"renewal_overdue", cycle.Deadline).
+		Find(&clubs).Error; err != nil {
+		return err
+	}
+
+	note := fmt.Sprintf("ไม่ได้ต่ออายุชมรมประจำปีการศึกษา %d ภายใน %s", cycle.AcademicYear, formatThaiDateTime(cycle.Deadline))
+	for i := range clubs {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return setClubStatus(tx, &clubs[i], "suspended", "renewal_overdue", note, nil)
+		}); err != nil {
+			return err
+		}
+		notifyUsers(clubMemberIDsWithPermission(db, clubs[i].ID, permManageMembers),
+			fmt.Sprintf("ชมรม %s ถูกระงับเนื่องจาก%s ยื่นต่ออายุเพื่อขอคืนสถานะได้", clubs[i].Name, note), "warning")
+	}
+	return nil
+}
+
+// POST /clubs/:id/archive - ยุบชมรมและเก็บถาวร (ผู้ดูแลระบบ) {reason}
+// สมาชิก กิจกรรม และวาระกรรมการยังอยู่เป็นประวัติ แต่ชมรมจะแก้ไขอะไรไม่ได้อีก
+func ArchiveClub(c *gin.Context) {
+	db := config.DB()
+	admin, err := requireAdmin(c)
+	if err != nil {
+		return
+	}
+	clubID, ok := parseClubID(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุเหตุผลในการยุบชมรม"})
+		return
+	}
+	var club entity.Club
+	if err := db.First(&club, clubID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	if err := checkClubWritable(db, clubID); err != nil {
+		respondClubStateError(c, err)
+		return
+	}
+
+	// กิจกรรมที่ยังไม่จบต้องยกเลิกก่อน เพื่อให้ผู้ลงทะเบียนได้รับแจ้งตามขั้นตอนปกติ
+	var upcoming []entity.Activity
+	db.Joins("JOIN activity_statuses ON activity_statuses.id = activities.status_id").
+		Where("activities.club_id = ? AND activities.date_end > ? AND activity_statuses.name IN ?",
+			clubID, time.Now(), []string{"draft", "pending", "approved"}).
+		Find(&upcoming)
+	if len(upcoming) > 0 {
+		titles := make([]string, 0, len(upcoming))
+		for _, a := range upcoming {
+			titles = append(titles, a.Title)
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "ต้องยกเลิกกิจกรรมที่ยังไม่จบก่อนยุบชมรม", "activities": titles})
+		return
+	}
+
+	var memberIDs []uint
+	err = db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := setClubStatus(tx, &club, "archived", "dissolved", strings.TrimSpace(input.Reason), &admin.ID); err != nil {
+			return err
+		}
+
+		var members []entity.ClubMember
+		if err := tx.Where("club_id = ?", clubID).Find(&members).Error; err != nil {
+			return err
+		}
+		for i := range members {
+			m := members[i]
+			if m.Role == "pending" {
+				if err := tx.Delete(&m).Error; err != nil {
+					return err
+				}
+				if err := closePendingApplication(tx, clubID, m.UserID, "cancelled", "", &admin.ID); err != nil {
+					return err
+				}
+				continue
+			}
+			memberIDs = append(memberIDs, m.UserID)
+			// บทบาทสุดท้ายยังเก็บไว้ใน ClubMember ส่วนวาระปิดลงพร้อมการยุบชมรม
+			if isOfficerRole(m.Role) {
+				if err := closeOfficerTerms(tx, clubID, m.UserID, "dissolved", now); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := tx.Model(&entity.ClubInviteLink{}).Where("club_id = ? AND revoked_at IS NULL", clubID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&entity.ClubInvitation{}).Where("club_id = ? AND status = ?", clubID, "pending").
+			Update("status", "revoked").Error; err != nil {
+			return err
+		}
+		return tx.Model(&entity.Election{}).Where("club_id = ? AND status = ?", clubID, "scheduled").
+			Update("status", "cancelled").Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถยุบชมรมได้"})
+		return
+	}
+
+	notifyUsers(memberIDs, fmt.Sprintf("ชมรม %s ถูกยุบแล้ว ข้อมูลของชมรมยังเปิดดูได้ย้อนหลัง", club.Name), "info")
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ยุบชมรมและเก็บถาวรเรียบร้อยแล้ว"})
+}
@@ -0,0 +1,432 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ชื่อบทบาทใช้เป็นค่าใน ClubMember.Role จึงจำกัดเป็นตัวพิมพ์เล็ก ตัวเลข และ _
+var clubRoleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,29}$`)
+
+type ClubRolePayload struct {
+	Name        string   `json:"name"`
+	DisplayName string   `json:"display_name"`
+	Permissions []string `json:"permissions"`
+}
+
+type ClubRoleResponse struct {
+	ID          uint     `json:"id"`
+	Name        string   `json:"name"`
+	DisplayName string   `json:"display_name"`
+	Permissions []string `json:"permissions"`
+	MemberCount int64    `json:"member_count"`
+}
+
+func clubRoleResponse(db *gorm.DB, role *entity.ClubRole) ClubRoleResponse {
+	var count int64
+	db.Model(&entity.ClubMember{}).Where("club_id = ? AND role = ?", role.ClubID, role.Name).Count(&count)
+	return ClubRoleResponse{
+		ID:          role.ID,
+		Name:        role.Name,
+		DisplayName: role.DisplayName,
+		Permissions: decodeClubPermissions(role.Permissions),
+		MemberCount: count,
+	}
+}
+
+// ตรวจและตัดสิทธิ์ซ้ำ คืน JSON ที่พร้อมบันทึก
+func encodeClubPermissions(perms []string) (string, error) {
+	cleaned := []string{}
+	for _, p := range perms {
+		if !slices.Contains(clubPermissions, p) {
+			return "", fmt.Errorf("ไม่รู้จักสิทธิ์ %s", p)
+		}
+		if !slices.Contains(cleaned, p) {
+			cleaned = append(cleaned, p)
+		}
+	}
+	raw, _ := json.Marshal(cleaned)
+	return string(raw), nil
+}
+
+func loadClubRole(c *gin.Context, clubID uint) (*entity.ClubRole, bool) {
+	var role entity.ClubRole
+	if err := config.DB().Where("id = ? AND club_id = ?", c.Param("roleId"), clubID).First(&role).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบบทบาท"})
+		return nil, false
+	}
+	return &role, true
+}
+
+// GET /clubs/:id/roles - บทบาทของชมรมและสิทธิ์ของแต่ละบทบาท
+func GetClubRoles(c *gin.Context) {
+	db := config.DB()
+	var club entity.Club
+	if err := db.First(&club, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	if _, err := requireClubPermission(c, club.ID, permManageMembers); err != nil {
+		return
+	}
+
+	var roles []entity.ClubRole
+	if err := db.Where("club_id = ?", club.ID).Order("id").Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงบทบาทได้"})
+		return
+	}
+	data := make([]ClubRoleResponse, 0, len(roles))
+	for i := range roles {
+		data = append(data, clubRoleResponse(db, &roles[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": data, "available_permissions": clubPermissions})
+}
+
+// GET /clubs/:id/permissions/me - บทบาทและสิทธิ์ของผู้ใช้ปัจจุบันในชมรม
+func GetMyClubPermissions(c *gin.Context) {
+	db := config.DB()
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	var member entity.ClubMember
+	if err := db.Where("club_id = ? AND user_id = ?", c.Param("id"), user.ID).First(&member).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"role": "", "permissions": []string{}}})
+		return
+	}
+	perms, err := clubRolePermissions(db, member.ClubID, member.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "permission check failed"})
+		return
+	}
+	if perms == nil {
+		perms = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"role": member.Role, "permissions": perms}})
+}
+
+// POST /clubs/:id/roles - สร้างบทบาทใหม่ (หัวหน้าชมรมเท่านั้น)
+func CreateClubRole(c *gin.Context) {
+	db := config.DB()
+	var club entity.Club
+	if err := db.First(&club, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	if _, err := requirePresident(c, club.ID); err != nil {
+		return
+	}
+
+	var payload ClubRolePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	payload.Name = strings.TrimSpace(payload.Name)
+	if !clubRoleNamePattern.MatchString(payload.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ชื่อบทบาทต้องเป็นตัวพิมพ์เล็ก ตัวเลข หรือ _ ยาว 2-30 ตัวอักษร"})
+		return
+	}
+	if slices.Contains(reservedClubRoles, payload.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ชื่อบทบาทนี้ถูกสงวนไว้สำหรับระบบ"})
+		return
+	}
+	perms, err := encodeClubPermissions(payload.Permissions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing int64
+	db.Model(&entity.ClubRole{}).Where("club_id = ? AND name = ?", club.ID, payload.Name).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "มีบทบาทชื่อนี้ในชมรมแล้ว"})
+		return
+	}
+
+	role := entity.ClubRole{
+		ClubID:      club.ID,
+		Name:        payload.Name,
+		DisplayName: strings.TrimSpace(payload.DisplayName),
+		Permissions: perms,
+	}
+	if role.DisplayName == "" {
+		role.DisplayName = role.Name
+	}
+	if err := db.Create(&role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถสร้างบทบาทได้"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": clubRoleResponse(db, &role)})
+}
+
+// PUT /clubs/:id/roles/:roleId - แก้ชื่อที่แสดงและสิทธิ์ของบทบาท (หัวหน้าชมรมเท่านั้น)
+func UpdateClubRole(c *gin.Context) {
+	db := config.DB()
+	var club entity.Club
+	if err := db.First(&club, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	if _, err := requirePresident(c, club.ID); err != nil {
+		return
+	}
+	role, ok := loadClubRole(c, club.ID)
+	if !ok {
+		return
+	}
+
+	var payload ClubRolePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	updates := map[string]interface{}{}
+	if name := strings.TrimSpace(payload.DisplayName); name != "" {
+		updates["display_name"] = name
+	}
+	if payload.Permissions != nil {
+		perms, err := encodeClubPermissions(payload.Permissions)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates["permissions"] = perms
+	}
+	if len(updates) > 0 {
+		if err := db.Model(role).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถแก้ไขบทบาทได้"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": clubRoleResponse(db, role)})
+}
+
+// DELETE /clubs/:id/roles/:roleId - ลบบทบาท สมาชิกที่ถือบทบาทนี้จะกลับเป็น member (หัวหน้าชมรมเท่านั้น)
+func DeleteClubRole(c *gin.Context) {
+	db := config.DB()
+	var club entity.Club
+	if err := db.First(&club, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	if _, err := requirePresident(c, club.ID); err != nil {
+		return
+	}
+	role, ok := loadClubRole(c, club.ID)
+	if !ok {
+		return
+	}
+
+	var demoted int64
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		var holders []entity.ClubMember
+		if err := tx.Where("club_id = ? AND role = ?", club.ID, role.Name).Find(&holders).Error; err != nil {
+			return err
+		}
+		for _, m := range holders {
+			if err := changeMemberRole(tx, club.ID, m.UserID, "member", "role_deleted", time.Now()); err != nil {
+				return err
+			}
+		}
+		demoted = int64(len(holders))
+		// ลบถาวรเพื่อให้สร้างบทบาทชื่อเดิมใหม่ได้
+		return tx.Unscoped().Delete(role).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถลบบทบาทได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ลบบทบาทเรียบร้อยแล้ว", "demoted_members": demoted})
+}
+
+// PUT /clubs/:id/members/:userId/role - กำหนดบทบาทให้สมาชิก (หัวหน้าชมรมเท่านั้น)
+// การเปลี่ยนหัวหน้าชมรมต้องใช้ /clubs/:id/change-president
+func AssignClubMemberRole(c *gin.Context) {
+	db := config.DB()
+	var club entity.Club
+	if err := db.First(&club, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	president, err := requirePresident(c, club.ID)
+	if err != nil {
+		return
+	}
+
+	var body struct {
+		Role string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.Role == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ต้องระบุ role"})
+		return
+	}
+	roleLabel := "สมาชิก"
+	if body.Role != "member" {
+		var role entity.ClubRole
+		if err := db.Where("club_id = ? AND name = ?", club.ID, body.Role).First(&role).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่พบบทบาทนี้ในชมรม"})
+			return
+		}
+		roleLabel = role.DisplayName
+	}
+
+	var member entity.ClubMember
+	if err := db.Where("club_id = ? AND user_id = ?", club.ID, c.Param("userId")).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบสมาชิกในชมรม"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		}
+		return
+	}
+	switch {
+	case member.UserID == president.ID || member.Role == "president":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "เปลี่ยนบทบาทหัวหน้าชมรมได้ผ่านการเปลี่ยนหัวหน้าชมรมเท่านั้น"})
+		return
+	case member.Role == "advisor":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "อาจารย์ที่ปรึกษาแต่งตั้งและถอดถอนได้โดยผู้ดูแลระบบเท่านั้น"})
+		return
+	case member.Role == "pending":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ต้องอนุมัติสมาชิกก่อนกำหนดบทบาท"})
+		return
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return changeMemberRole(tx, club.ID, member.UserID, body.Role, "reassigned", time.Now())
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถกำหนดบทบาทได้"})
+		return
+	}
+	member.Role = body.Role
+	if err := getNotificationService().CreateNotification(member.UserID,
+		fmt.Sprintf("บทบาทของคุณในชมรม %s เปลี่ยนเป็น %s", club.Name, roleLabel), "info"); err != nil {
+		fmt.Println("❌ Error creating role notification:", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "กำหนดบทบาทเรียบร้อยแล้ว", "data": member})
+}
+
+// PUT /clubs/:id/advisor - ผู้ดูแลระบบแต่งตั้งอาจารย์ที่ปรึกษาชมรม (แทนคนเดิมถ้ามี)
+// อาจารย์ที่ปรึกษาเป็นผู้อนุมัติขั้นที่ปรึกษาของกิจกรรมและค่าใช้จ่ายชมรม
+func AssignClubAdvisor(c *gin.Context) {
+	db := config.DB()
+	if _, err := requireAdmin(c); err != nil {
+		return
+	}
+	var club entity.Club
+	if err := db.First(&club, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	if err := rejectArchivedClubWrite(c, club.ID); err != nil {
+		return
+	}
+
+	var body struct {
+		UserID uint `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ต้องระบุ user_id"})
+		return
+	}
+	var advisor entity.User
+	if err := db.First(&advisor, body.UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบผู้ใช้"})
+		return
+	}
+
+	var member entity.ClubMember
+	err := db.Where("club_id = ? AND user_id = ?", club.ID, advisor.ID).First(&member).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	switch member.Role {
+	case "president":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "หัวหน้าชมรมเป็นอาจารย์ที่ปรึกษาของชมรมเดียวกันไม่ได้"})
+		return
+	case "pending":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ผู้ใช้นี้มีคำขอเข้าชมรมค้างอยู่ ต้องพิจารณาคำขอก่อน"})
+		return
+	case "advisor":
+		c.JSON(http.StatusOK, gin.H{"success": true, "message": "ผู้ใช้นี้เป็นอาจารย์ที่ปรึกษาของชมรมอยู่แล้ว", "data": member})
+		return
+	}
+
+	now := time.Now()
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := removeClubAdvisors(tx, club.ID, "reassigned", now); err != nil {
+			return err
+		}
+		if member.ID != 0 {
+			return changeMemberRole(tx, club.ID, advisor.ID, "advisor", "reassigned", now)
+		}
+		member = entity.ClubMember{ClubID: club.ID, UserID: advisor.ID, Role: "advisor", JoinedAt: now}
+		if err := tx.Create(&member).Error; err != nil {
+			return err
+		}
+		return startOfficerTerm(tx, club.ID, advisor.ID, "advisor", now)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถแต่งตั้งอาจารย์ที่ปรึกษาได้"})
+		return
+	}
+	member.Role = "advisor"
+
+	if err := getNotificationService().CreateNotification(advisor.ID,
+		fmt.Sprintf("คุณได้รับแต่งตั้งเป็นอาจารย์ที่ปรึกษาชมรม %s", club.Name), "info"); err != nil {
+		fmt.Println("❌ Error creating advisor notification:", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "แต่งตั้งอาจารย์ที่ปรึกษาเรียบร้อยแล้ว", "data": member})
+}
+
+// DELETE /clubs/:id/advisor - ผู้ดูแลระบบถอดถอนอาจารย์ที่ปรึกษาชมรม
+func RemoveClubAdvisor(c *gin.Context) {
+	db := config.DB()
+	if _, err := requireAdmin(c); err != nil {
+		return
+	}
+	var club entity.Club
+	if err := db.First(&club, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	if err := rejectArchivedClubWrite(c, club.ID); err != nil {
+		return
+	}
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return removeClubAdvisors(tx, club.ID, "removed", time.Now())
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถถอดถอนอาจารย์ที่ปรึกษาได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ถอดถอนอาจารย์ที่ปรึกษาเรียบร้อยแล้ว"})
+}
+
+// ลบอาจารย์ที่ปรึกษาทั้งหมดของชมรมออกจากสมาชิก และปิดวาระไว้เป็นประวัติ
+func removeClubAdvisors(tx *gorm.DB, clubID uint, reason string, at time.Time) error {
+	var advisors []entity.ClubMember
+	if err := tx.Where("club_id = ? AND role = ?", clubID, "advisor").Find(&advisors).Error; err != nil {
+		return err
+	}
+	for _, a := range advisors {
+		if err := tx.Delete(&a).Error; err != nil {
+			return err
+		}
+		if err := closeOfficerTerms(tx, clubID, a.UserID, reason, at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -160,6 +160,7 @@ func (h *DashboardHandler) GetReviewStats(c *gin.Context) {
 	// คำนวณค่าเฉลี่ย
 	h.DB.
 		Table("activity_reviews").
+		Where("is_hidden = ? AND deleted_at IS NULL", false).
 		Select("COALESCE(AVG(rating), 0)").
 		Row().
 		Scan(&stats.AverageRating)
@@ -167,6 +168,7 @@ func (h *DashboardHandler) GetReviewStats(c *gin.Context) {
 	// นับจำนวนรีวิวทั้งหมด
 	h.DB.
 		Table("activity_reviews").
+		Where("is_hidden = ? AND deleted_at IS NULL", false).
 		Count(&stats.TotalReviews)
 
 	c.JSON(http.StatusOK, stats)
@@ -0,0 +1,901 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+	"final-project/cems/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var electionTieBreaks = []string{"seniority", "lot", "runoff"}
+
+type ElectionPositionInput struct {
+	Role  string `json:"role"`
+	Title string `json:"title"`
+}
+
+type ElectionPayload struct {
+	Title                string                  `json:"title"`
+	Description          string                  `json:"description"`
+	NominationStart      time.Time               `json:"nomination_start"`
+	NominationEnd        time.Time               `json:"nomination_end"`
+	VotingStart          time.Time               `json:"voting_start"`
+	VotingEnd            time.Time               `json:"voting_end"`
+	EndorsementsRequired int                     `json:"endorsements_required"`
+	QuorumPercent        int                     `json:"quorum_percent"`
+	TieBreak             string                  `json:"tie_break"`
+	Positions            []ElectionPositionInput `json:"positions"`
+}
+
+// ช่วงของการเลือกตั้ง ณ เวลาที่กำหนด
+func electionPhase(e *entity.Election, now time.Time) string {
+	switch {
+	case e.Status == "cancelled" || e.Status == "certified":
+		return e.Status
+	case now.Before(e.NominationStart):
+		return "upcoming"
+	case now.Before(e.NominationEnd):
+		return "nomination"
+	case now.Before(e.VotingStart):
+		return "review" // ปิดรับสมัครแล้ว รอเปิดลงคะแนน
+	case now.Before(e.VotingEnd):
+		return "voting"
+	}
+	return "closed"
+}
+
+func loadElection(c *gin.Context) (*entity.Election, bool) {
+	var election entity.Election
+	if err := config.DB().Preload("Positions").First(&election, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบการเลือกตั้ง"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		}
+		return nil, false
+	}
+	return &election, true
+}
+
+// บังคับสิทธิ์: หัวหน้าชมรมหรือผู้ดูแลระบบ (ฝ่ายกิจการนักศึกษา) ชมรมที่ยุบแล้วแก้ไขการเลือกตั้งไม่ได้
+func requireElectionManager(c *gin.Context, clubID uint) (*entity.User, error) {
+	db := config.DB()
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(401, gin.H{"error": err.Error()})
+		return nil, err
+	}
+	if isAdmin(db, user) {
+		if err := rejectArchivedClubWrite(c, clubID); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	ok, err := hasClubRole(db, user.ID, clubID, "president")
+	if err != nil {
+		c.JSON(500, gin.H{"error": "permission check failed"})
+		return nil, err
+	}
+	if !ok {
+		c.JSON(403, gin.H{"error": "forbidden: president or admin only"})
+		return nil, errors.New("forbidden")
+	}
+	if err := rejectArchivedClubWrite(c, clubID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// บทบาทที่ไม่มีสิทธิ์ในการเลือกตั้ง: ผู้สมัครที่ยังไม่อนุมัติ และอาจารย์ที่ปรึกษาซึ่งไม่ใช่สมาชิกนักศึกษา
+var electionExcludedRoles = []string{"pending", "advisor"}
+
+// สมาชิกที่ได้รับอนุมัติแล้วของชมรม (ไม่รวมอาจารย์ที่ปรึกษา)
+func approvedClubMember(db *gorm.DB, clubID, userID uint) (*entity.ClubMember, bool) {
+	var member entity.ClubMember
+	if err := db.Where("club_id = ? AND user_id = ? AND role NOT IN ?", clubID, userID, electionExcludedRoles).First(&member).Error; err != nil {
+		return nil, false
+	}
+	return &member, true
+}
+
+// ผู้มีสิทธิ์ลงคะแนน: สมาชิกที่อนุมัติแล้ว (ไม่รวมอาจารย์ที่ปรึกษา) และเข้าชมรมก่อนเปิดลงคะแนน
+func electionVoterScope(db *gorm.DB, e *entity.Election) *gorm.DB {
+	return db.Model(&entity.ClubMember{}).
+		Where("club_id = ? AND role NOT IN ? AND joined_at <= ?", e.ClubID, electionExcludedRoles, e.VotingStart)
+}
+
+func candidateQualified(e *entity.Election, candidate *entity.ElectionCandidate) bool {
+	return candidate.WithdrawnAt == nil && len(candidate.Endorsements) >= e.EndorsementsRequired
+}
+
+type ElectionCandidateResponse struct {
+	ID               uint   `json:"id"`
+	PositionID       uint   `json:"position_id"`
+	UserID           uint   `json:"user_id"`
+	FirstName        string `json:"first_name"`
+	LastName         string `json:"last_name"`
+	Statement        string `json:"statement"`
+	EndorsementCount int    `json:"endorsement_count"`
+	Qualified        bool   `json:"qualified"`
+}
+
+func electionCandidates(db *gorm.DB, e *entity.Election) ([]entity.ElectionCandidate, error) {
+	var candidates []entity.ElectionCandidate
+	err := db.Preload("User").Preload("Endorsements").
+		Where("election_id = ? AND withdrawn_at IS NULL", e.ID).
+		Order("id").Find(&candidates).Error
+	return candidates, err
+}
+
+// GET /clubs/:id/elections - การเลือกตั้งของชมรม
+func GetClubElections(c *gin.Context) {
+	var elections []entity.Election
+	if err := config.DB().Preload("Positions").Where("club_id = ?", c.Param("id")).
+		Order("voting_start DESC").Find(&elections).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงการเลือกตั้งได้"})
+		return
+	}
+	now := time.Now()
+	data := make([]gin.H, 0, len(elections))
+	for i := range elections {
+		data = append(data, gin.H{"election": elections[i], "phase": electionPhase(&elections[i], now)})
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": data})
+}
+
+// POST /clubs/:id/elections - สร้างการเลือกตั้ง (หัวหน้าชมรมหรือผู้ดูแลระบบ)
+func CreateElection(c *gin.Context) {
+	db := config.DB()
+	var club entity.Club
+	if err := db.First(&club, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	user, err := requireElectionManager(c, club.ID)
+	if err != nil {
+		return
+	}
+	if !requireClubActive(c, club.ID) {
+		return
+	}
+
+	var payload ElectionPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	payload.Title = strings.TrimSpace(payload.Title)
+	if payload.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ต้องระบุชื่อการเลือกตั้ง"})
+		return
+	}
+	if !payload.NominationStart.Before(payload.NominationEnd) ||
+		payload.VotingStart.Before(payload.NominationEnd) ||
+		!payload.VotingStart.Before(payload.VotingEnd) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ช่วงเวลาต้องเรียงเป็น เปิดรับสมัคร < ปิดรับสมัคร <= เปิดลงคะแนน < ปิดลงคะแนน"})
+		return
+	}
+	if payload.QuorumPercent < 0 || payload.QuorumPercent > 100 || payload.EndorsementsRequired < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "quorum_percent ต้องอยู่ระหว่าง 0-100 และ endorsements_required ต้องไม่ติดลบ"})
+		return
+	}
+	if payload.TieBreak == "" {
+		payload.TieBreak = "runoff"
+	}
+	if !slices.Contains(electionTieBreaks, payload.TieBreak) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tie_break ต้องเป็น seniority, lot หรือ runoff"})
+		return
+	}
+	if len(payload.Positions) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ต้องมีตำแหน่งที่เลือกตั้งอย่างน้อยหนึ่งตำแหน่ง"})
+		return
+	}
+	labels := clubRoleLabels(db, club.ID)
+	election := entity.Election{
+		ClubID:               club.ID,
+		Title:                payload.Title,
+		Description:          payload.Description,
+		NominationStart:      payload.NominationStart,
+		NominationEnd:        payload.NominationEnd,
+		VotingStart:          payload.VotingStart,
+		VotingEnd:            payload.VotingEnd,
+		EndorsementsRequired: payload.EndorsementsRequired,
+		QuorumPercent:        payload.QuorumPercent,
+		TieBreak:             payload.TieBreak,
+		Status:               "scheduled",
+		CreatedBy:            user.ID,
+	}
+	seen := map[string]bool{}
+	for _, p := range payload.Positions {
+		label, ok := labels[p.Role]
+		if !ok || slices.Contains(electionExcludedRoles, p.Role) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ไม่พบบทบาท %s ในชมรม", p.Role)})
+			return
+		}
+		if seen[p.Role] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ตำแหน่ง %s ซ้ำ", p.Role)})
+			return
+		}
+		seen[p.Role] = true
+		title := strings.TrimSpace(p.Title)
+		if title == "" {
+			title = label
+		}
+		election.Positions = append(election.Positions, entity.ElectionPosition{Role: p.Role, Title: title})
+	}
+
+	if err := db.Create(&election).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถสร้างการเลือกตั้งได้"})
+		return
+	}
+
+	var memberIDs []uint
+	db.Model(&entity.ClubMember{}).Where("club_id = ? AND role NOT IN ?", club.ID, electionExcludedRoles).Pluck("user_id", &memberIDs)
+	notifyUsers(memberIDs, fmt.Sprintf("ชมรม %s เปิดการเลือกตั้ง \"%s\" รับสมัครตั้งแต่ %s", club.Name, election.Title, formatThaiDateTime(election.NominationStart)), "info")
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": election})
+}
+
+// GET /elections/:id - รายละเอียดการเลือกตั้งและผู้สมัคร
+func GetElectionByID(c *gin.Context) {
+	db := config.DB()
+	election, ok := loadElection(c)
+	if !ok {
+		return
+	}
+	candidates, err := electionCandidates(db, election)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงผู้สมัครได้"})
+		return
+	}
+	items := make([]ElectionCandidateResponse, 0, len(candidates))
+	for i := range candidates {
+		cand := &candidates[i]
+		items = append(items, ElectionCandidateResponse{
+			ID:               cand.ID,
+			PositionID:       cand.PositionID,
+			UserID:           cand.UserID,
+			FirstName:        cand.User.FirstName,
+			LastName:         cand.User.LastName,
+			Statement:        cand.Statement,
+			EndorsementCount: len(cand.Endorsements),
+			Qualified:        candidateQualified(election, cand),
+		})
+	}
+	var voterCount int64
+	db.Model(&entity.ElectionVoter{}).Where("election_id = ?", election.ID).Distinct("user_id").Count(&voterCount)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"data":        election,
+		"phase":       electionPhase(election, time.Now()),
+		"candidates":  items,
+		"voter_count": voterCount,
+	})
+}
+
+// POST /elections/:id/cancel - ยกเลิกการเลือกตั้งที่ยังไม่รับรองผล
+func CancelElection(c *gin.Context) {
+	election, ok := loadElection(c)
+	if !ok {
+		return
+	}
+	if _, err := requireElectionManager(c, election.ClubID); err != nil {
+		return
+	}
+	if election.Status != "scheduled" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "การเลือกตั้งนี้ถูกยกเลิกหรือรับรองผลไปแล้ว"})
+		return
+	}
+	if err := config.DB().Model(election).Update("status", "cancelled").Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถยกเลิกการเลือกตั้งได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ยกเลิกการเลือกตั้งเรียบร้อยแล้ว"})
+}
+
+// POST /elections/:id/candidates - สมัครรับเลือกตั้งด้วยตัวเอง (ช่วงรับสมัคร สมาชิกที่อนุมัติแล้ว)
+func NominateElectionCandidate(c *gin.Context) {
+	db := config.DB()
+	election, ok := loadElection(c)
+	if !ok {
+		return
+	}
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if electionPhase(election, time.Now()) != "nomination" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่อยู่ในช่วงรับสมัคร"})
+		return
+	}
+	member, ok := approvedClubMember(db, election.ClubID, user.ID)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "เฉพาะสมาชิกที่ได้รับอนุมัติแล้วเท่านั้น"})
+		return
+	}
+
+	var body struct {
+		PositionID uint   `json:"position_id"`
+		Statement  string `json:"statement"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	idx := slices.IndexFunc(election.Positions, func(p entity.ElectionPosition) bool { return p.ID == body.PositionID })
+	if idx < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่พบตำแหน่งในการเลือกตั้งนี้"})
+		return
+	}
+	// ประธานคนปัจจุบันสมัครตำแหน่งอื่นได้เฉพาะเมื่อมีการเลือกประธานคนใหม่ในครั้งเดียวกัน ไม่เช่นนั้นชมรมจะไม่มีประธาน
+	if member.Role == "president" && election.Positions[idx].Role != "president" && !electionHasPresident(election) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ประธานชมรมสมัครตำแหน่งอื่นได้เฉพาะการเลือกตั้งที่มีตำแหน่งประธาน"})
+		return
+	}
+
+	// สมัครได้ตำแหน่งเดียว ถ้าเคยถอนตัวให้สมัครใหม่ได้
+	var candidate entity.ElectionCandidate
+	err = db.Where("election_id = ? AND user_id = ?", election.ID, user.ID).First(&candidate).Error
+	switch {
+	case err == nil && candidate.WithdrawnAt == nil:
+		c.JSON(http.StatusConflict, gin.H{"error": "คุณสมัครรับเลือกตั้งแล้ว"})
+		return
+	case err == nil:
+		// ลบถาวรเพราะ (candidate_id, user_id) เป็น unique index ผู้รับรองเดิมต้องรับรองใหม่ได้
+		db.Unscoped().Where("candidate_id = ?", candidate.ID).Delete(&entity.ElectionEndorsement{})
+		err = db.Model(&candidate).Updates(map[string]interface{}{
+			"position_id": body.PositionID, "statement": strings.TrimSpace(body.Statement), "withdrawn_at": nil,
+		}).Error
+	default:
+		candidate = entity.ElectionCandidate{
+			ElectionID: election.ID,
+			PositionID: body.PositionID,
+			UserID:     user.ID,
+			Statement:  strings.TrimSpace(body.Statement),
+		}
+		err = db.Create(&candidate).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถสมัครรับเลือกตั้งได้"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": candidate})
+}
+
+// POST /elections/:id/candidates/:candidateId/withdraw - ผู้สมัครถอนตัวก่อนเปิดลงคะแนน
+func WithdrawElectionCandidate(c *gin.Context) {
+	db := config.DB()
+	election, ok := loadElection(c)
+	if !ok {
+		return
+	}
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if phase := electionPhase(election, time.Now()); phase != "nomination" && phase != "review" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ถอนตัวได้ก่อนเปิดลงคะแนนเท่านั้น"})
+		return
+	}
+	now := time.Now()
+	res := db.Model(&entity.ElectionCandidate{}).
+		Where("id = ? AND election_id = ? AND user_id = ? AND withdrawn_at IS NULL", c.Param("candidateId"), election.ID, user.ID).
+		Update("withdrawn_at", now)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถถอนตัวได้"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบการสมัครของคุณ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ถอนตัวเรียบร้อยแล้ว"})
+}
+
+// POST /elections/:id/candidates/:candidateId/endorse - รับรองผู้สมัคร (สมาชิกที่อนุมัติแล้ว ช่วงรับสมัคร)
+func EndorseElectionCandidate(c *gin.Context) {
+	db := config.DB()
+	election, ok := loadElection(c)
+	if !ok {
+		return
+	}
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if electionPhase(election, time.Now()) != "nomination" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่อยู่ในช่วงรับสมัคร"})
+		return
+	}
+	if _, ok := approvedClubMember(db, election.ClubID, user.ID); !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "เฉพาะสมาชิกที่ได้รับอนุมัติแล้วเท่านั้น"})
+		return
+	}
+	var candidate entity.ElectionCandidate
+	if err := db.Where("id = ? AND election_id = ? AND withdrawn_at IS NULL", c.Param("candidateId"), election.ID).
+		First(&candidate).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบผู้สมัคร"})
+		return
+	}
+	if candidate.UserID == user.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "รับรองตัวเองไม่ได้"})
+		return
+	}
+
+	var existing int64
+	db.Model(&entity.ElectionEndorsement{}).Where("candidate_id = ? AND user_id = ?", candidate.ID, user.ID).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "คุณรับรองผู้สมัครคนนี้แล้ว"})
+		return
+	}
+	if err := db.Create(&entity.ElectionEndorsement{CandidateID: candidate.ID, UserID: user.ID}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถรับรองผู้สมัครได้"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "รับรองผู้สมัครเรียบร้อยแล้ว"})
+}
+
+func hashBallotReceipt(receipt string) string {
+	sum := sha256.Sum256([]byte(receipt))
+	return hex.EncodeToString(sum[:])
+}
+
+var (
+	errAlreadyVoted    = errors.New("คุณลงคะแนนตำแหน่งนี้แล้ว")
+	errPresidentWinner = errors.New("ประธานชมรมคนปัจจุบันชนะตำแหน่งอื่นโดยไม่มีประธานคนใหม่ ไม่สามารถรับรองผลได้")
+)
+
+func electionHasPresident(e *entity.Election) bool {
+	return slices.ContainsFunc(e.Positions, func(p entity.ElectionPosition) bool { return p.Role == "president" })
+}
+
+// POST /elections/:id/vote - ลงคะแนน หนึ่งคะแนนต่อตำแหน่ง คืนใบรับสำหรับตรวจสอบภายหลัง
+func CastElectionVote(c *gin.Context) {
+	db := config.DB()
+	election, ok := loadElection(c)
+	if !ok {
+		return
+	}
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if electionPhase(election, time.Now()) != "voting" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่อยู่ในช่วงลงคะแนน"})
+		return
+	}
+	var eligible int64
+	electionVoterScope(db, election).Where("user_id = ?", user.ID).Count(&eligible)
+	if eligible == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "คุณไม่มีสิทธิ์ลงคะแนนในการเลือกตั้งนี้"})
+		return
+	}
+
+	var body struct {
+		Votes []struct {
+			PositionID  uint `json:"position_id"`
+			CandidateID uint `json:"candidate_id"`
+		} `json:"votes"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || len(body.Votes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ต้องระบุ votes อย่างน้อยหนึ่งตำแหน่ง"})
+		return
+	}
+
+	candidates, err := electionCandidates(db, election)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงผู้สมัครได้"})
+		return
+	}
+	seen := map[uint]bool{}
+	for _, v := range body.Votes {
+		if seen[v.PositionID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ลงคะแนนได้ตำแหน่งละหนึ่งคะแนน"})
+			return
+		}
+		seen[v.PositionID] = true
+		valid := slices.ContainsFunc(candidates, func(cand entity.ElectionCandidate) bool {
+			return cand.ID == v.CandidateID && cand.PositionID == v.PositionID && candidateQualified(election, &cand)
+		})
+		if !valid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ผู้สมัคร %d ไม่อยู่ในบัตรของตำแหน่ง %d", v.CandidateID, v.PositionID)})
+			return
+		}
+	}
+
+	// สร้างบัตรและใบรับไว้ก่อน แล้วบันทึกผู้ลงคะแนนทั้งหมดก่อนบัตรทั้งหมด (สลับลำดับบัตร)
+	// เพื่อไม่ให้ลำดับการ insert ผูกบัตรกับผู้ลงคะแนน ตารางบัตรเองไม่มี rowid (ดู config.migrateElectionBallots)
+	receipts := make([]gin.H, 0, len(body.Votes))
+	ballots := make([]entity.ElectionBallot, 0, len(body.Votes))
+	for _, v := range body.Votes {
+		receipt, err := utils.GenerateSecureToken(16)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถลงคะแนนได้"})
+			return
+		}
+		ballots = append(ballots, entity.ElectionBallot{
+			ReceiptHash: hashBallotReceipt(receipt),
+			ElectionID:  election.ID,
+			PositionID:  v.PositionID,
+			CandidateID: v.CandidateID,
+		})
+		receipts = append(receipts, gin.H{"position_id": v.PositionID, "receipt": receipt})
+	}
+	rand.Shuffle(len(ballots), func(i, j int) { ballots[i], ballots[j] = ballots[j], ballots[i] })
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		for _, v := range body.Votes {
+			var voted int64
+			tx.Model(&entity.ElectionVoter{}).
+				Where("election_id = ? AND position_id = ? AND user_id = ?", election.ID, v.PositionID, user.ID).
+				Count(&voted)
+			if voted > 0 {
+				return errAlreadyVoted
+			}
+			if err := tx.Create(&entity.ElectionVoter{ElectionID: election.ID, PositionID: v.PositionID, UserID: user.ID}).Error; err != nil {
+				return err
+			}
+		}
+		for i := range ballots {
+			if err := tx.Create(&ballots[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		if errors.Is(err, errAlreadyVoted) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถลงคะแนนได้"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"success":  true,
+		"message":  "ลงคะแนนเรียบร้อยแล้ว เก็บใบรับไว้เพื่อตรวจสอบว่าคะแนนของคุณถูกนับ",
+		"receipts": receipts,
+	})
+}
+
+// GET /elections/:id/my-votes - ตำแหน่งที่ผู้ใช้ลงคะแนนแล้ว (ไม่บอกว่าเลือกใคร)
+func GetMyElectionVotes(c *gin.Context) {
+	election, ok := loadElection(c)
+	if !ok {
+		return
+	}
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	var positionIDs []uint
+	config.DB().Model(&entity.ElectionVoter{}).
+		Where("election_id = ? AND user_id = ?", election.ID, user.ID).
+		Pluck("position_id", &positionIDs)
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"voted_position_ids": positionIDs}})
+}
+
+type CandidateTally struct {
+	CandidateID uint   `json:"candidate_id"`
+	UserID      uint   `json:"user_id"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	Votes       int64  `json:"votes"`
+}
+
+type ElectionPositionResult struct {
+	PositionID        uint             `json:"position_id"`
+	Role              string           `json:"role"`
+	Title             string           `json:"title"`
+	Ballots           int64            `json:"ballots"`
+	Voters            int64            `json:"voters"`
+	Tally             []CandidateTally `json:"tally"`
+	WinnerCandidateID *uint            `json:"winner_candidate_id"`
+	WinnerUserID      *uint            `json:"winner_user_id"`
+	Tied              bool             `json:"tied"`
+	TieBrokenBy       string           `json:"tie_broken_by,omitempty"`
+}
+
+type ElectionResult struct {
+	EligibleVoters int64                    `json:"eligible_voters"`
+	Turnout        int64                    `json:"turnout"`
+	QuorumPercent  int                      `json:"quorum_percent"`
+	QuorumMet      bool                     `json:"quorum_met"`
+	Positions      []ElectionPositionResult `json:"positions"`
+	Certifiable    bool                     `json:"certifiable"`
+}
+
+// ตัดสินผู้ชนะเมื่อคะแนนเท่ากัน
+// seniority: เป็นสมาชิกชมรมนานที่สุด, lot: จับฉลากที่ทำซ้ำได้จาก hash ของบัตรทั้งหมดในตำแหน่ง, runoff: ไม่ตัดสิน ต้องเลือกตั้งใหม่
+func breakElectionTie(db *gorm.DB, e *entity.Election, tied []CandidateTally, receiptHashes []string) *CandidateTally {
+	switch e.TieBreak {
+	case "seniority":
+		var best *CandidateTally
+		var bestJoined time.Time
+		for i := range tied {
+			var m entity.ClubMember
+			if err := db.Where("club_id = ? AND user_id = ?", e.ClubID, tied[i].UserID).First(&m).Error; err != nil {
+				continue
+			}
+			if best == nil || m.JoinedAt.Before(bestJoined) {
+				best, bestJoined = &tied[i], m.JoinedAt
+			}
+		}
+		return best
+	case "lot":
+		sort.Strings(receiptHashes)
+		seed := sha256.Sum256([]byte(strings.Join(receiptHashes, "")))
+		sort.Slice(tied, func(i, j int) bool { return tied[i].CandidateID < tied[j].CandidateID })
+		idx := new(big.Int).Mod(new(big.Int).SetBytes(seed[:]), big.NewInt(int64(len(tied)))).Int64()
+		return &tied[idx]
+	}
+	return nil
+}
+
+func computeElectionResults(db *gorm.DB, e *entity.Election) (*ElectionResult, error) {
+	result := &ElectionResult{QuorumPercent: e.QuorumPercent, Positions: []ElectionPositionResult{}}
+	if err := electionVoterScope(db, e).Count(&result.EligibleVoters).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&entity.ElectionVoter{}).Where("election_id = ?", e.ID).
+		Distinct("user_id").Count(&result.Turnout).Error; err != nil {
+		return nil, err
+	}
+	result.QuorumMet = result.EligibleVoters > 0 && result.Turnout*100 >= int64(e.QuorumPercent)*result.EligibleVoters
+
+	candidates, err := electionCandidates(db, e)
+	if err != nil {
+		return nil, err
+	}
+	var ballots []entity.ElectionBallot
+	if err := db.Where("election_id = ?", e.ID).Find(&ballots).Error; err != nil {
+		return nil, err
+	}
+
+	result.Certifiable = result.QuorumMet
+	for _, p := range e.Positions {
+		pr := ElectionPositionResult{PositionID: p.ID, Role: p.Role, Title: p.Title, Tally: []CandidateTally{}}
+		db.Model(&entity.ElectionVoter{}).Where("election_id = ? AND position_id = ?", e.ID, p.ID).Count(&pr.Voters)
+
+		counts := map[uint]int64{}
+		var hashes []string
+		for _, b := range ballots {
+			if b.PositionID == p.ID {
+				counts[b.CandidateID]++
+				hashes = append(hashes, b.ReceiptHash)
+				pr.Ballots++
+			}
+		}
+		for i := range candidates {
+			cand := &candidates[i]
+			if cand.PositionID != p.ID || !candidateQualified(e, cand) {
+				continue
+			}
+			pr.Tally = append(pr.Tally, CandidateTally{
+				CandidateID: cand.ID,
+				UserID:      cand.UserID,
+				FirstName:   cand.User.FirstName,
+				LastName:    cand.User.LastName,
+				Votes:       counts[cand.ID],
+			})
+		}
+		sort.SliceStable(pr.Tally, func(i, j int) bool { return pr.Tally[i].Votes > pr.Tally[j].Votes })
+
+		if len(pr.Tally) > 0 && pr.Tally[0].Votes > 0 {
+			top := pr.Tally[0].Votes
+			var tied []CandidateTally
+			for _, t := range pr.Tally {
+				if t.Votes == top {
+					tied = append(tied, t)
+				}
+			}
+			winner := &pr.Tally[0]
+			if len(tied) > 1 {
+				pr.Tied = true
+				winner = breakElectionTie(db, e, tied, hashes)
+				if winner != nil {
+					pr.TieBrokenBy = e.TieBreak
+				}
+			}
+			if winner != nil {
+				pr.WinnerCandidateID = &winner.CandidateID
+				pr.WinnerUserID = &winner.UserID
+			}
+		}
+		if pr.WinnerUserID == nil {
+			result.Certifiable = false
+		}
+		result.Positions = append(result.Positions, pr)
+	}
+	return result, nil
+}
+
+// GET /elections/:id/results - ผลการเลือกตั้ง (หลังปิดลงคะแนน)
+func GetElectionResults(c *gin.Context) {
+	election, ok := loadElection(c)
+	if !ok {
+		return
+	}
+	if phase := electionPhase(election, time.Now()); phase != "closed" && phase != "certified" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ดูผลได้หลังปิดลงคะแนน"})
+		return
+	}
+	result, err := computeElectionResults(config.DB(), election)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถนับคะแนนได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": result, "status": election.Status})
+}
+
+// GET /elections/:id/audit - บัตรทั้งหมด (ไม่มีข้อมูลผู้ลงคะแนน) ให้ทุกคนนับคะแนนซ้ำได้ และเทียบจำนวนบัตรกับผู้มาลงคะแนน
+func GetElectionAudit(c *gin.Context) {
+	db := config.DB()
+	election, ok := loadElection(c)
+	if !ok {
+		return
+	}
+	if phase := electionPhase(election, time.Now()); phase != "closed" && phase != "certified" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ตรวจสอบบัตรได้หลังปิดลงคะแนน"})
+		return
+	}
+	var ballots []entity.ElectionBallot
+	if err := db.Where("election_id = ?", election.ID).Order("receipt_hash").Find(&ballots).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงบัตรได้"})
+		return
+	}
+	positions := make([]gin.H, 0, len(election.Positions))
+	for _, p := range election.Positions {
+		var voters, ballotCount int64
+		db.Model(&entity.ElectionVoter{}).Where("election_id = ? AND position_id = ?", election.ID, p.ID).Count(&voters)
+		db.Model(&entity.ElectionBallot{}).Where("election_id = ? AND position_id = ?", election.ID, p.ID).Count(&ballotCount)
+		positions = append(positions, gin.H{
+			"position_id": p.ID,
+			"voters":      voters,
+			"ballots":     ballotCount,
+			"consistent":  voters == ballotCount,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"ballots": ballots, "positions": positions}})
+}
+
+// GET /elections/:id/verify?receipt= - ตรวจว่าบัตรของใบรับนี้อยู่ในการนับคะแนน
+func VerifyElectionBallot(c *gin.Context) {
+	election, ok := loadElection(c)
+	if !ok {
+		return
+	}
+	receipt := strings.TrimSpace(c.Query("receipt"))
+	if receipt == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ต้องระบุ receipt"})
+		return
+	}
+	var ballot entity.ElectionBallot
+	if err := config.DB().Where("receipt_hash = ? AND election_id = ?", hashBallotReceipt(receipt), election.ID).
+		First(&ballot).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"found": false}})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{
+		"found":        true,
+		"receipt_hash": ballot.ReceiptHash,
+		"position_id":  ballot.PositionID,
+		"candidate_id": ballot.CandidateID,
+	}})
+}
+
+// POST /elections/:id/certify - รับรองผลและแต่งตั้งผู้ชนะ (หัวหน้าชมรมหรือผู้ดูแลระบบ)
+// ตำแหน่งประธานเปลี่ยนผ่าน transferClubPresidency เช่นเดียวกับ ChangeClubPresident
+func CertifyElection(c *gin.Context) {
+	db := config.DB()
+	election, ok := loadElection(c)
+	if !ok {
+		return
+	}
+	user, err := requireElectionManager(c, election.ClubID)
+	if err != nil {
+		return
+	}
+	if !requireClubActive(c, election.ClubID) {
+		return
+	}
+	if electionPhase(election, time.Now()) != "closed" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "รับรองผลได้หลังปิดลงคะแนนและยังไม่เคยรับรองเท่านั้น"})
+		return
+	}
+	result, err := computeElectionResults(db, election)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถนับคะแนนได้"})
+		return
+	}
+	if !result.QuorumMet {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ผู้มาลงคะแนนไม่ถึงองค์ประชุม", "data": result})
+		return
+	}
+	if !result.Certifiable {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "บางตำแหน่งยังไม่มีผู้ชนะ (ไม่มีคะแนนหรือคะแนนเท่ากันและต้องเลือกตั้งรอบใหม่)", "data": result})
+		return
+	}
+
+	// ประธานก่อน เพื่อให้ประธานเดิมที่ชนะตำแหน่งอื่นได้บทบาทใหม่ถูกต้อง
+	positions := slices.Clone(result.Positions)
+	sort.SliceStable(positions, func(i, j int) bool { return positions[i].Role == "president" && positions[j].Role != "president" })
+
+	now := time.Now()
+	var newPresidentID uint
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		for _, p := range positions {
+			winner := *p.WinnerUserID
+			if p.Role == "president" {
+				newPresidentID = winner
+				if err := transferClubPresidency(tx, election.ClubID, winner, "election", now); err != nil {
+					return err
+				}
+				continue
+			}
+			var holders []entity.ClubMember
+			if err := tx.Where("club_id = ? AND role = ? AND user_id <> ?", election.ClubID, p.Role, winner).Find(&holders).Error; err != nil {
+				return err
+			}
+			for _, h := range holders {
+				if err := changeMemberRole(tx, election.ClubID, h.UserID, "member", "election", now); err != nil {
+					return err
+				}
+			}
+			member, ok := approvedClubMember(tx, election.ClubID, winner)
+			if !ok {
+				return errNotApprovedMember
+			}
+			// ประธานที่ยังไม่ถูกแทนที่ (ไม่มีตำแหน่งประธานหรือชนะตำแหน่งประธานด้วย) รับตำแหน่งอื่นไม่ได้
+			if member.Role == "president" {
+				return errPresidentWinner
+			}
+			if err := changeMemberRole(tx, election.ClubID, winner, p.Role, "election", now); err != nil {
+				return err
+			}
+		}
+		return tx.Model(election).Updates(map[string]interface{}{
+			"status": "certified", "certified_by": user.ID, "certified_at": now,
+		}).Error
+	}); err != nil {
+		if errors.Is(err, errNotApprovedMember) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ผู้ชนะบางคนไม่ได้เป็นสมาชิกชมรมแล้ว"})
+			return
+		}
+		if errors.Is(err, errPresidentWinner) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var club entity.Club
+	db.First(&club, election.ClubID)
+	if newPresidentID != 0 {
+		sendNewPresidentEmail(db, &club, newPresidentID)
+	}
+	for _, p := range positions {
+		notifyUsers([]uint{*p.WinnerUserID}, fmt.Sprintf("คุณได้รับเลือกเป็น %s ของชมรม %s", p.Title, club.Name), "success")
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "รับรองผลการเลือกตั้งเรียบร้อยแล้ว", "data": result})
+}
@@ -0,0 +1,367 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+	"final-project/cems/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ประเภทรูปที่อัปโหลดได้ แต่ละประเภทมีขนาดไฟล์สูงสุดของตัวเอง
+type imagePurpose struct {
+	Name     string
+	MaxBytes int64
+}
+
+var (
+	purposeClubLogo       = imagePurpose{Name: "club_logo", MaxBytes: 5 << 20}
+	purposeActivityPoster = imagePurpose{Name: "activity_poster", MaxBytes: 10 << 20}
+	purposeProfilePicture = imagePurpose{Name: "profile_picture", MaxBytes: 5 << 20}
+	purposeExpenseReceipt = imagePurpose{Name: "expense_receipt", MaxBytes: 10 << 20}
+)
+
+// ขนาดที่สร้างจากรูปต้นฉบับ (กรอบสูงสุด กว้าง x สูง) รูปที่เล็กกว่ากรอบจะไม่ถูกขยาย
+// ไฟล์อยู่ที่ images/uploads/<hash[:2]>/<hash>/<ขนาด>.<นามสกุล> client จึงแทนชื่อไฟล์ใน URL เพื่อเลือกขนาดได้
+var imageVariants = []struct {
+	Name      string
+	MaxWidth  int
+	MaxHeight int
+}{
+	{"thumbnail", 240, 240},
+	{"card", 640, 640},
+	{"full", 1600, 1600},
+}
+
+// รูปแบบไฟล์ที่สร้างในแต่ละขนาด
+// (ไม่สร้าง WebP เพราะ encoder ที่มีเป็นแบบ lossless ซึ่งได้ไฟล์ใหญ่กว่า JPEG สำหรับภาพถ่าย)
+var imageEncoders = []struct {
+	Format string
+	Ext    string
+	Encode func(io.Writer, image.Image) error
+}{
+	{"jpeg", ".jpg", func(w io.Writer, img image.Image) error { return utils.EncodeJPEG(w, img, 85) }},
+}
+
+const (
+	imageUploadDir    = "images/uploads"
+	maxImagePixels    = 40_000_000 // กันไฟล์เล็กที่ขยายแล้วใช้หน่วยความจำมหาศาล
+	orphanImageMaxAge = 24 * time.Hour
+)
+
+// ข้อผิดพลาดจากไฟล์ของผู้ใช้ ตอบกลับเป็น 4xx พร้อมข้อความ
+type imageUploadError struct {
+	Status  int
+	Message string
+}
+
+func (e *imageUploadError) Error() string { return e.Message }
+
+// saveImageUpload รับรูปจาก form field ผ่านขั้นตอน ตรวจชนิดจากเนื้อไฟล์ จำกัดขนาด ลบ EXIF
+// และสร้างไฟล์ย่อยทุกขนาด คืน nil ถ้าไม่ได้แนบไฟล์มา
+func saveImageUpload(c *gin.Context, field string, purpose imagePurpose) (*entity.StoredImage, error) {
+	data, err := readImageUpload(c, field, purpose)
+	if data == nil || err != nil {
+		return nil, err
+	}
+	var uploadedBy uint
+	if user, err := getUserFromJWT(c); err == nil {
+		uploadedBy = user.ID
+	}
+	return storeImage(config.DB(), data, purpose, uploadedBy)
+}
+
+// readImageUpload อ่านไฟล์จาก form field โดยจำกัดขนาดตาม purpose คืน nil ถ้าไม่ได้แนบไฟล์มา
+func readImageUpload(c *gin.Context, field string, purpose imagePurpose) ([]byte, error) {
+	header, err := c.FormFile(field)
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &imageUploadError{http.StatusBadRequest, "อ่านไฟล์ที่อัปโหลดไม่ได้"}
+	}
+	tooLarge := &imageUploadError{http.StatusRequestEntityTooLarge, fmt.Sprintf("ขนาดไฟล์ต้องไม่เกิน %dMB", purpose.MaxBytes>>20)}
+	if header.Size > purpose.MaxBytes {
+		return nil, tooLarge
+	}
+	f, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, purpose.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > purpose.MaxBytes {
+		return nil, tooLarge
+	}
+	return data, nil
+}
+
+// respondImageUploadError ตอบ error ของการอัปโหลดรูป คืน true ถ้าตอบไปแล้ว
+func respondImageUploadError(c *gin.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	var uploadErr *imageUploadError
+	if errors.As(err, &uploadErr) {
+		c.JSON(uploadErr.Status, gin.H{"error": uploadErr.Message})
+		return true
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกรูปภาพไม่สำเร็จ"})
+	return true
+}
+
+// decodeUploadedImage ตรวจชนิดจากเนื้อไฟล์และจำนวนพิกเซล แล้วถอดรหัสพร้อมหมุนตาม EXIF
+func decodeUploadedImage(data []byte) (image.Image, string, error) {
+	mime := utils.SniffImageType(data)
+	if mime == "" {
+		return nil, "", &imageUploadError{http.StatusUnsupportedMediaType, "รองรับเฉพาะไฟล์รูปภาพ JPEG, PNG หรือ GIF"}
+	}
+	cfg, err := utils.DecodeImageConfig(data)
+	if err != nil {
+		return nil, "", &imageUploadError{http.StatusBadRequest, "ไฟล์รูปภาพเสียหรืออ่านไม่ได้"}
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, "", &imageUploadError{http.StatusBadRequest, "ขนาดรูปภาพ (พิกเซล) ใหญ่เกินไป"}
+	}
+	img, err := utils.DecodeImage(data)
+	if err != nil {
+		return nil, "", &imageUploadError{http.StatusBadRequest, "ไฟล์รูปภาพเสียหรืออ่านไม่ได้"}
+	}
+	return img, mime, nil
+}
+
+// encodePrivateImage ลบ EXIF และย่อเป็นขนาด full แบบ JPEG สำหรับรูปที่ไม่เปิดผ่าน /images
+func encodePrivateImage(data []byte) ([]byte, error) {
+	img, _, err := decodeUploadedImage(data)
+	if err != nil {
+		return nil, err
+	}
+	full := imageVariants[len(imageVariants)-1]
+	var buf bytes.Buffer
+	if err := utils.EncodeJPEG(&buf, utils.ResizeToFit(img, full.MaxWidth, full.MaxHeight), 85); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func storeImage(db *gorm.DB, data []byte, purpose imagePurpose, uploadedBy uint) (*entity.StoredImage, error) {
+	if mime := utils.SniffImageType(data); mime == "" {
+		return nil, &imageUploadError{http.StatusUnsupportedMediaType, "รองรับเฉพาะไฟล์รูปภาพ JPEG, PNG หรือ GIF"}
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	now := time.Now()
+
+	// ไฟล์เดิมที่เคยประมวลผลแล้ว ใช้ชุดเดิมถ้าไฟล์ยังอยู่ครบ
+	var existing entity.StoredImage
+	if err := db.Where("hash = ?", hash).First(&existing).Error; err == nil {
+		if imageVariantsExist(&existing) {
+			db.Model(&existing).Update("last_used_at", now)
+			return &existing, nil
+		}
+	}
+
+	img, mime, err := decodeUploadedImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := imageHashDir(hash)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	variants := map[string]map[string]string{}
+	for _, v := range imageVariants {
+		resized := utils.ResizeToFit(img, v.MaxWidth, v.MaxHeight)
+		variants[v.Name] = map[string]string{}
+		for _, enc := range imageEncoders {
+			var buf bytes.Buffer
+			if err := enc.Encode(&buf, resized); err != nil {
+				return nil, err
+			}
+			name := v.Name + enc.Ext
+			if err := writeFileAtomic(filepath.Join(dir, name), buf.Bytes()); err != nil {
+				return nil, err
+			}
+			variants[v.Name][enc.Format] = "/" + filepath.ToSlash(filepath.Join(dir, name))
+		}
+	}
+	variantsJSON, _ := json.Marshal(variants)
+
+	bounds := img.Bounds()
+	stored := entity.StoredImage{
+		Hash:       hash,
+		Purpose:    purpose.Name,
+		MimeType:   mime,
+		Size:       int64(len(data)),
+		Width:      bounds.Dx(),
+		Height:     bounds.Dy(),
+		Variants:   string(variantsJSON),
+		UploadedBy: uploadedBy,
+		LastUsedAt: now,
+	}
+	// อัปโหลดไฟล์เดียวกันพร้อมกันได้ ให้ record เดียวชนะแล้วอ่านกลับมา
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "hash"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"variants": stored.Variants, "last_used_at": now, "deleted_at": nil}),
+	}).Create(&stored).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("hash = ?", hash).First(&stored).Error; err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// imageVariantURL คืน URL ของรูปตามขนาดและรูปแบบ
+func imageVariantURL(img *entity.StoredImage, size, format string) string {
+	var variants map[string]map[string]string
+	if err := json.Unmarshal([]byte(img.Variants), &variants); err != nil {
+		return ""
+	}
+	return variants[size][format]
+}
+
+// URL หลักที่บันทึกลงฟิลด์รูปของ entity (ขนาด full แบบ JPEG)
+func storedImageURL(img *entity.StoredImage) string {
+	return imageVariantURL(img, "full", "jpeg")
+}
+
+// รายละเอียดรูปสำหรับตอบกลับ client
+func imageUploadResponse(img *entity.StoredImage) gin.H {
+	var variants map[string]map[string]string
+	_ = json.Unmarshal([]byte(img.Variants), &variants)
+	return gin.H{
+		"hash":      img.Hash,
+		"mime_type": img.MimeType,
+		"width":     img.Width,
+		"height":    img.Height,
+		"url":       storedImageURL(img),
+		"variants":  variants,
+	}
+}
+
+func imageHashDir(hash string) string {
+	return filepath.Join(imageUploadDir, hash[:2], hash)
+}
+
+func imageVariantsExist(img *entity.StoredImage) bool {
+	var variants map[string]map[string]string
+	if err := json.Unmarshal([]byte(img.Variants), &variants); err != nil || len(variants) == 0 {
+		return false
+	}
+	for _, v := range imageVariants {
+		formats := variants[v.Name]
+		// รูปที่ประมวลผลก่อนเพิ่มรูปแบบใหม่ต้องสร้างใหม่ให้ครบ
+		for _, enc := range imageEncoders {
+			if formats[enc.Format] == "" {
+				return false
+			}
+		}
+		for _, url := range formats {
+			if _, err := os.Stat(filepath.FromSlash(url[1:])); err != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// เขียนไฟล์ชั่วคราวแล้วย้ายทับ ผู้อ่านจึงไม่เห็นไฟล์ที่เขียนไม่ครบ
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ตาราง/คอลัมน์ที่อาจอ้างถึงรูปที่อัปโหลด รวม snapshot ของประวัติการแก้ไขเพื่อให้ revert แล้วรูปยังอยู่
+var imageReferenceColumns = []struct {
+	Table  string
+	Column string
+}{
+	{"activities", "poster_image"},
+	{"activity_templates", "poster_image"},
+	{"clubs", "logo_image"},
+	{"users", "profile_image"},
+	{"media_uploads", "url"},
+	{"revisions", "snapshot"},
+}
+
+func imageIsReferenced(db *gorm.DB, hash string) (bool, error) {
+	for _, ref := range imageReferenceColumns {
+		var count int64
+		if err := db.Table(ref.Table).Where(ref.Column+" LIKE ?", "%"+hash+"%").Count(&count).Error; err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// cleanupOrphanImages ลบรูปที่ไม่มีข้อมูลใดอ้างถึงแล้ว (เช่น ถูกแทนด้วยรูปใหม่ หรืออัปโหลดแล้วบันทึกไม่สำเร็จ)
+// เว้นรูปที่เพิ่งใช้ภายใน orphanImageMaxAge
+func cleanupOrphanImages(db *gorm.DB) error {
+	var candidates []entity.StoredImage
+	if err := db.Where("last_used_at < ?", time.Now().Add(-orphanImageMaxAge)).Find(&candidates).Error; err != nil {
+		return err
+	}
+	removed := 0
+	for _, img := range candidates {
+		referenced, err := imageIsReferenced(db, img.Hash)
+		if err != nil {
+			return err
+		}
+		if referenced {
+			continue
+		}
+		if err := os.RemoveAll(imageHashDir(img.Hash)); err != nil {
+			return err
+		}
+		// ลบโฟลเดอร์ prefix ถ้าว่างแล้ว
+		if isEmpty, _ := isDirEmpty(filepath.Dir(imageHashDir(img.Hash))); isEmpty {
+			os.Remove(filepath.Dir(imageHashDir(img.Hash)))
+		}
+		if err := db.Unscoped().Delete(&img).Error; err != nil {
+			return err
+		}
+		removed++
+	}
+	if removed > 0 {
+		fmt.Printf("🧹 removed %d orphan images\n", removed)
+	}
+	return nil
+}
@@ -29,11 +29,23 @@ type NotificationResponse struct {
 	CreatedAt string    `json:"CreatedAt"` // ใช้ string แทน time.Time เพื่อให้ JSON format ถูกต้อง
 }
 
+// notificationService ใช้ร่วมกันทุก controller เพื่อให้ client ที่เปิด SSE ไว้ได้รับการแจ้งเตือนทันที
+var notificationService *services.NotificationService
+
 func NewNotificationHandler(db *gorm.DB) *NotificationHandler {
+	notificationService = services.NewNotificationService(db)
 	return &NotificationHandler{
 		DB:                db,
-		NotificationService: services.NewNotificationService(db),
+		NotificationService: notificationService,
+	}
+}
+
+// getNotificationService คืน service ตัวเดียวกับที่ NotificationHandler ใช้
+func getNotificationService() *services.NotificationService {
+	if notificationService == nil {
+		notificationService = services.NewNotificationService(config.DB())
 	}
+	return notificationService
 }
 
 // GetNotifications ดึงการแจ้งเตือนทั้งหมดของ user
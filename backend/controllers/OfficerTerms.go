@@ -0,0 +1,425 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+	"final-project/cems/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// บทบาทที่ถือเป็นกรรมการชมรม (มีวาระ)
+func isOfficerRole(role string) bool {
+	return role != "" && role != "member" && role != "pending"
+}
+
+// เริ่มวาระใหม่ของกรรมการ
+func startOfficerTerm(tx *gorm.DB, clubID, userID uint, role string, at time.Time) error {
+	return tx.Create(&entity.OfficerTerm{
+		ClubID:       clubID,
+		UserID:       userID,
+		Role:         role,
+		AcademicYear: utils.AcademicYearOf(at),
+		StartDate:    at,
+	}).Error
+}
+
+// ปิดวาระที่ยังเปิดอยู่ของผู้ใช้ในชมรม
+func closeOfficerTerms(tx *gorm.DB, clubID, userID uint, reason string, at time.Time) error {
+	return tx.Model(&entity.OfficerTerm{}).
+		Where("club_id = ? AND user_id = ? AND end_date IS NULL", clubID, userID).
+		Updates(map[string]interface{}{"end_date": at, "end_reason": reason}).Error
+}
+
+// changeMemberRole เปลี่ยนบทบาทของสมาชิกพร้อมปิด/เปิดวาระกรรมการตามบทบาทเดิมและใหม่
+func changeMemberRole(tx *gorm.DB, clubID, userID uint, newRole, reason string, at time.Time) error {
+	var member entity.ClubMember
+	if err := tx.Where("club_id = ? AND user_id = ?", clubID, userID).First(&member).Error; err != nil {
+		return err
+	}
+	if member.Role == newRole {
+		return nil
+	}
+	if isOfficerRole(member.Role) {
+		if err := closeOfficerTerms(tx, clubID, userID, reason, at); err != nil {
+			return err
+		}
+	}
+	if err := tx.Model(&member).Update("role", newRole).Error; err != nil {
+		return err
+	}
+	if isOfficerRole(newRole) {
+		return startOfficerTerm(tx, clubID, userID, newRole, at)
+	}
+	return nil
+}
+
+var errNotApprovedMember = errors.New("ผู้ใช้ไม่ได้เป็นสมาชิกที่ได้รับอนุมัติของชมรมนี้")
+
+// transferClubPresidency เปลี่ยนหัวหน้าชมรม ใช้ร่วมกันระหว่างการเปลี่ยนโดยหัวหน้า การส่งต่อกรรมการ และผลการเลือกตั้ง
+// หัวหน้าคนใหม่ต้องเป็นสมาชิกที่อนุมัติแล้วและไม่ใช่อาจารย์ที่ปรึกษา
+func transferClubPresidency(tx *gorm.DB, clubID, newPresidentID uint, reason string, at time.Time) error {
+	var newMem entity.ClubMember
+	if err := tx.Where("club_id = ? AND user_id = ?", clubID, newPresidentID).First(&newMem).Error; err != nil || newMem.Role == "pending" || newMem.Role == "advisor" {
+		return errNotApprovedMember
+	}
+
+	var oldPres entity.ClubMember
+	if err := tx.Where("club_id = ? AND role = ?", clubID, "president").
+		First(&oldPres).Error; err != nil {
+		return fmt.Errorf("ไม่พบหัวหน้าชมรมคนเดิม")
+	}
+
+	if oldPres.UserID != newPresidentID {
+		if err := changeMemberRole(tx, clubID, oldPres.UserID, "member", reason, at); err != nil {
+			return fmt.Errorf("เปลี่ยนหัวหน้าเก่าไม่สำเร็จ")
+		}
+		if err := tx.Model(&entity.User{}).
+			Where("id = ?", oldPres.UserID).
+			Update("role_id", 1).Error; err != nil {
+			return fmt.Errorf("อัปเดตสิทธิ์หัวหน้าเดิมไม่สำเร็จ")
+		}
+	}
+
+	if err := changeMemberRole(tx, clubID, newPresidentID, "president", reason, at); err != nil {
+		return fmt.Errorf("เปลี่ยนหัวหน้าใหม่ไม่สำเร็จ")
+	}
+	if err := tx.Model(&entity.User{}).
+		Where("id = ?", newPresidentID).
+		Update("role_id", 2).Error; err != nil {
+		return fmt.Errorf("อัปเดตสิทธิ์หัวหน้าใหม่ไม่สำเร็จ")
+	}
+
+	if err := tx.Model(&entity.Club{}).
+		Where("id = ?", clubID).
+		Update("created_by", newPresidentID).Error; err != nil {
+		return fmt.Errorf("อัปเดตผู้สร้างชมรมไม่สำเร็จ")
+	}
+	return nil
+}
+
+// ชื่อที่แสดงของบทบาทในชมรม
+func clubRoleLabels(db *gorm.DB, clubID uint) map[string]string {
+	labels := map[string]string{"president": "ประธานชมรม"}
+	var roles []entity.ClubRole
+	db.Where("club_id = ?", clubID).Find(&roles)
+	for _, r := range roles {
+		labels[r.Name] = r.DisplayName
+	}
+	return labels
+}
+
+type CommitteeOfficer struct {
+	UserID    uint       `json:"user_id"`
+	FirstName string     `json:"first_name"`
+	LastName  string     `json:"last_name"`
+	Role      string     `json:"role"`
+	RoleLabel string     `json:"role_label"`
+	StartDate time.Time  `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+}
+
+type Committee struct {
+	AcademicYear int                `json:"academic_year"`
+	IsCurrent    bool               `json:"is_current"`
+	Officers     []CommitteeOfficer `json:"officers"`
+}
+
+// GET /clubs/:id/committees - กรรมการชมรมแต่ละปีการศึกษา (สาธารณะ ไม่มีข้อมูลส่วนตัว)
+func GetClubCommittees(c *gin.Context) {
+	db := config.DB()
+	var club entity.Club
+	if err := db.First(&club, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+
+	var terms []entity.OfficerTerm
+	if err := db.Preload("User").Where("club_id = ?", club.ID).
+		Order("academic_year DESC, start_date ASC").Find(&terms).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงข้อมูลกรรมการได้"})
+		return
+	}
+
+	labels := clubRoleLabels(db, club.ID)
+	byYear := map[int]*Committee{}
+	var years []int
+	for _, t := range terms {
+		committee, ok := byYear[t.AcademicYear]
+		if !ok {
+			committee = &Committee{AcademicYear: t.AcademicYear, Officers: []CommitteeOfficer{}}
+			byYear[t.AcademicYear] = committee
+			years = append(years, t.AcademicYear)
+		}
+		if t.EndDate == nil {
+			committee.IsCurrent = true
+		}
+		label := labels[t.Role]
+		if label == "" {
+			label = t.Role
+		}
+		committee.Officers = append(committee.Officers, CommitteeOfficer{
+			UserID:    t.UserID,
+			FirstName: t.User.FirstName,
+			LastName:  t.User.LastName,
+			Role:      t.Role,
+			RoleLabel: label,
+			StartDate: t.StartDate,
+			EndDate:   t.EndDate,
+		})
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(years)))
+
+	data := make([]Committee, 0, len(years))
+	for _, y := range years {
+		data = append(data, *byYear[y])
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": data})
+}
+
+type HandoverOfficerInput struct {
+	UserID uint   `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// ปีการศึกษาของกรรมการชุดใหม่คำนวณจาก EffectiveAt
+type HandoverPayload struct {
+	EffectiveAt time.Time              `json:"effective_at"`
+	Officers    []HandoverOfficerInput `json:"officers"`
+}
+
+// ตรวจรายชื่อกรรมการชุดใหม่: มีหัวหน้าหนึ่งคน บทบาทมีอยู่จริง ไม่ซ้ำคน และเป็นสมาชิกที่อนุมัติแล้ว
+func validateHandoverOfficers(db *gorm.DB, clubID uint, officers []HandoverOfficerInput) error {
+	labels := clubRoleLabels(db, clubID)
+	presidents := 0
+	seen := map[uint]bool{}
+	for _, o := range officers {
+		if o.UserID == 0 || o.Role == "" {
+			return fmt.Errorf("ต้องระบุ user_id และ role ของกรรมการทุกคน")
+		}
+		if _, ok := labels[o.Role]; !ok {
+			return fmt.Errorf("ไม่พบบทบาท %s ในชมรม", o.Role)
+		}
+		if seen[o.UserID] {
+			return fmt.Errorf("ผู้ใช้ %d ถูกระบุมากกว่าหนึ่งบทบาท", o.UserID)
+		}
+		seen[o.UserID] = true
+		if o.Role == "president" {
+			presidents++
+		}
+		var member entity.ClubMember
+		if err := db.Where("club_id = ? AND user_id = ?", clubID, o.UserID).First(&member).Error; err != nil || member.Role == "pending" {
+			return fmt.Errorf("ผู้ใช้ %d ไม่ได้เป็นสมาชิกที่ได้รับอนุมัติของชมรม", o.UserID)
+		}
+		if member.Role == "advisor" {
+			return fmt.Errorf("ผู้ใช้ %d เป็นอาจารย์ที่ปรึกษา ไม่สามารถเป็นกรรมการชมรมได้", o.UserID)
+		}
+	}
+	if presidents != 1 {
+		return fmt.Errorf("กรรมการชุดใหม่ต้องมีประธานชมรมหนึ่งคน")
+	}
+	return nil
+}
+
+// GET /clubs/:id/handovers - รายการส่งต่อกรรมการของชมรม
+func GetClubHandovers(c *gin.Context) {
+	db := config.DB()
+	var club entity.Club
+	if err := db.First(&club, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	if _, err := requireClubPermission(c, club.ID, permManageMembers); err != nil {
+		return
+	}
+
+	var handovers []entity.ClubHandover
+	if err := db.Preload("Officers.User").Where("club_id = ?", club.ID).
+		Order("effective_at DESC").Find(&handovers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงรายการส่งต่อได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": handovers})
+}
+
+// POST /clubs/:id/handovers - ตั้งเวลาส่งต่อกรรมการชุดใหม่ (หัวหน้าชมรมเท่านั้น)
+func ScheduleClubHandover(c *gin.Context) {
+	db := config.DB()
+	var club entity.Club
+	if err := db.First(&club, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	user, err := requirePresident(c, club.ID)
+	if err != nil {
+		return
+	}
+
+	var payload HandoverPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	if !payload.EffectiveAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "effective_at ต้องเป็นเวลาในอนาคต"})
+		return
+	}
+	if err := validateHandoverOfficers(db, club.ID, payload.Officers); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var pending int64
+	db.Model(&entity.ClubHandover{}).Where("club_id = ? AND status = ?", club.ID, "scheduled").Count(&pending)
+	if pending > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "มีการส่งต่อที่ตั้งเวลาไว้แล้ว ยกเลิกรายการเดิมก่อน"})
+		return
+	}
+
+	handover := entity.ClubHandover{
+		ClubID:       club.ID,
+		AcademicYear: utils.AcademicYearOf(payload.EffectiveAt),
+		EffectiveAt:  payload.EffectiveAt,
+		Status:       "scheduled",
+		CreatedBy:    user.ID,
+	}
+	for _, o := range payload.Officers {
+		handover.Officers = append(handover.Officers, entity.ClubHandoverOfficer{UserID: o.UserID, Role: o.Role})
+	}
+	if err := db.Create(&handover).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถบันทึกการส่งต่อได้"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": handover})
+}
+
+// POST /clubs/:id/handovers/:handoverId/cancel - ยกเลิกการส่งต่อที่ยังไม่ถึงเวลา (หัวหน้าชมรมเท่านั้น)
+func CancelClubHandover(c *gin.Context) {
+	db := config.DB()
+	var club entity.Club
+	if err := db.First(&club, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	if _, err := requirePresident(c, club.ID); err != nil {
+		return
+	}
+
+	res := db.Model(&entity.ClubHandover{}).
+		Where("id = ? AND club_id = ? AND status = ?", c.Param("handoverId"), club.ID, "scheduled").
+		Update("status", "cancelled")
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถยกเลิกการส่งต่อได้"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบการส่งต่อที่รอดำเนินการ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ยกเลิกการส่งต่อเรียบร้อยแล้ว"})
+}
+
+// applyClubHandover เปลี่ยนกรรมการทั้งชุดในครั้งเดียว กรรมการเดิมที่ไม่อยู่ในชุดใหม่กลับเป็นสมาชิก
+// กรรมการที่อยู่ต่อจะเริ่มวาระใหม่ของปีการศึกษาใหม่ อาจารย์ที่ปรึกษาไม่ถูกเปลี่ยน (ผู้ดูแลระบบแต่งตั้งเอง)
+func applyClubHandover(tx *gorm.DB, handover *entity.ClubHandover, at time.Time) error {
+	officers := make([]HandoverOfficerInput, 0, len(handover.Officers))
+	next := map[uint]string{}
+	var newPresident uint
+	for _, o := range handover.Officers {
+		officers = append(officers, HandoverOfficerInput{UserID: o.UserID, Role: o.Role})
+		next[o.UserID] = o.Role
+		if o.Role == "president" {
+			newPresident = o.UserID
+		}
+	}
+	if err := validateHandoverOfficers(tx, handover.ClubID, officers); err != nil {
+		return err
+	}
+
+	// ปิดวาระชุดเดิมทั้งหมด
+	var current []entity.ClubMember
+	if err := tx.Where("club_id = ? AND role NOT IN ?", handover.ClubID, []string{"member", "pending", "advisor"}).Find(&current).Error; err != nil {
+		return err
+	}
+	for _, m := range current {
+		if err := closeOfficerTerms(tx, handover.ClubID, m.UserID, "handover", at); err != nil {
+			return err
+		}
+		if m.Role == "president" {
+			continue // เปลี่ยนผ่าน transferClubPresidency ด้านล่าง
+		}
+		if err := tx.Model(&m).Update("role", "member").Error; err != nil {
+			return err
+		}
+	}
+
+	if err := transferClubPresidency(tx, handover.ClubID, newPresident, "handover", at); err != nil {
+		return err
+	}
+	// หัวหน้าที่อยู่ต่อไม่ถูกเปลี่ยนบทบาท จึงต้องเปิดวาระปีใหม่เอง
+	var openTerms int64
+	tx.Model(&entity.OfficerTerm{}).Where("club_id = ? AND user_id = ? AND end_date IS NULL", handover.ClubID, newPresident).Count(&openTerms)
+	if openTerms == 0 {
+		if err := startOfficerTerm(tx, handover.ClubID, newPresident, "president", at); err != nil {
+			return err
+		}
+	}
+
+	for userID, role := range next {
+		if role == "president" {
+			continue
+		}
+		if err := tx.Model(&entity.ClubMember{}).
+			Where("club_id = ? AND user_id = ?", handover.ClubID, userID).
+			Update("role", role).Error; err != nil {
+			return err
+		}
+		if err := startOfficerTerm(tx, handover.ClubID, userID, role, at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyDueHandovers ส่งต่อกรรมการที่ถึงเวลา รายการที่ข้อมูลไม่ถูกต้องแล้ว (เช่น ผู้รับตำแหน่งออกจากชมรม) จะถูกบันทึกว่า failed
+func applyDueHandovers(db *gorm.DB) error {
+	var due []entity.ClubHandover
+	if err := db.Preload("Officers").
+		Where("status = ? AND effective_at <= ?", "scheduled", time.Now()).
+		Find(&due).Error; err != nil {
+		return err
+	}
+
+	for i := range due {
+		handover := &due[i]
+		now := time.Now()
+		// วาระเริ่มตามเวลาที่กำหนด แม้งานจะรันช้ากว่านั้นเล็กน้อย
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return applyClubHandover(tx, handover, handover.EffectiveAt)
+		})
+
+		var club entity.Club
+		db.First(&club, handover.ClubID)
+		if err != nil {
+			db.Model(handover).Updates(map[string]interface{}{"status": "failed", "note": err.Error()})
+			notifyUsers([]uint{handover.CreatedBy},
+				fmt.Sprintf("การส่งต่อกรรมการชมรม %s ไม่สำเร็จ: %s", club.Name, err.Error()), "warning")
+			continue
+		}
+		db.Model(handover).Updates(map[string]interface{}{"status": "completed", "applied_at": now})
+
+		ids := make([]uint, 0, len(handover.Officers))
+		for _, o := range handover.Officers {
+			ids = append(ids, o.UserID)
+		}
+		notifyUsers(ids, fmt.Sprintf("คุณเริ่มดำรงตำแหน่งกรรมการชมรม %s ปีการศึกษา %d แล้ว", club.Name, handover.AcademicYear), "success")
+	}
+	return nil
+}
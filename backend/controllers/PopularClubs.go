@@ -25,6 +25,7 @@ type ClubResponse struct {
 	Category     entity.ClubCategory  `json:"category"`
 	Activities   []entity.Activity    `json:"activities,omitempty"`
 	Members      []entity.ClubMember  `json:"members,omitempty"`
+	Rating       RatingSummary        `json:"rating"`
 }
 
 // GetPopularClubs - ดึงข้อมูลชมรมยอดนิยม (เรียงตามจำนวนสมาชิก)
@@ -77,6 +78,7 @@ func (h *ClubHandler) GetPopularClubs(c *gin.Context) {
 			Category:    club.Category,
 			ActivityCount: activityCount,
 			Activities:  club.Activities,
+			Rating:      clubRatingSummary(h.DB, club.ID),
 		}
 		clubResponses = append(clubResponses, clubResponse)
 	}
@@ -182,6 +184,7 @@ func (h *ClubHandler) GetAllClubs(c *gin.Context) {
 			MemberCount: int64(len(club.Members)),
 			Status:      club.Status,
 			Category:    club.Category,
+			Rating:      clubRatingSummary(h.DB, club.ID),
 		})
 	}
 
@@ -229,6 +232,7 @@ func (h *ClubHandler) GetClubByID(c *gin.Context) {
 		Category:    club.Category,
 		Activities:  club.Activities,
 		Members:     club.Members,
+		Rating:      clubRatingSummary(h.DB, club.ID),
 	}
 
 	c.JSON(http.StatusOK, gin.H{
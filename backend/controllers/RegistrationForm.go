@@ -0,0 +1,540 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var registrationQuestionTypes = []string{"text", "choice", "number", "file"}
+
+type RegistrationQuestionInput struct {
+	Label    string   `json:"label"`
+	Type     string   `json:"type"`
+	Options  []string `json:"options"`
+	Required bool     `json:"required"`
+}
+
+type RegistrationQuestionResponse struct {
+	ID        uint     `json:"id"`
+	Label     string   `json:"label"`
+	Type      string   `json:"type"`
+	Options   []string `json:"options"`
+	Required  bool     `json:"required"`
+	SortOrder int      `json:"sort_order"`
+}
+
+type RegistrationAnswerInput struct {
+	QuestionID uint   `json:"question_id"`
+	Value      string `json:"value"`
+}
+
+type RegistrantAnswers struct {
+	RegistrationID uint            `json:"registration_id"`
+	UserID         uint            `json:"user_id"`
+	StudentID      string          `json:"student_id"`
+	FirstName      string          `json:"first_name"`
+	LastName       string          `json:"last_name"`
+	Email          string          `json:"email"`
+	RegisteredAt   time.Time       `json:"registered_at"`
+	Answers        map[uint]string `json:"answers"`
+}
+
+func toQuestionResponse(q entity.RegistrationQuestion) RegistrationQuestionResponse {
+	options := []string{}
+	if q.Options != "" {
+		_ = json.Unmarshal([]byte(q.Options), &options)
+	}
+	return RegistrationQuestionResponse{
+		ID:        q.ID,
+		Label:     q.Label,
+		Type:      q.Type,
+		Options:   options,
+		Required:  q.Required,
+		SortOrder: q.SortOrder,
+	}
+}
+
+func loadRegistrationQuestions(db *gorm.DB, activityID uint) ([]entity.RegistrationQuestion, error) {
+	var questions []entity.RegistrationQuestion
+	err := db.Where("activity_id = ?", activityID).Order("sort_order ASC").Find(&questions).Error
+	return questions, err
+}
+
+// ตรวจคำตอบตาม schema ของแบบฟอร์ม คืน error ข้อแรกที่พบ
+func validateRegistrationAnswers(questions []entity.RegistrationQuestion, answers map[uint]string, files map[uint]*multipart.FileHeader) error {
+	known := map[uint]bool{}
+	for _, q := range questions {
+		known[q.ID] = true
+		value := strings.TrimSpace(answers[q.ID])
+
+		if q.Type == "file" {
+			if files[q.ID] == nil && q.Required {
+				return fmt.Errorf("กรุณาแนบไฟล์สำหรับคำถาม \"%s\"", q.Label)
+			}
+			if files[q.ID] != nil && files[q.ID].Size > maxFormAttachmentBytes {
+				return fmt.Errorf("ไฟล์ของคำถาม \"%s\" ต้องมีขนาดไม่เกิน 5MB", q.Label)
+			}
+			continue
+		}
+
+		if value == "" {
+			if q.Required {
+				return fmt.Errorf("กรุณาตอบคำถาม \"%s\"", q.Label)
+			}
+			continue
+		}
+
+		switch q.Type {
+		case "number":
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return fmt.Errorf("คำตอบของ \"%s\" ต้องเป็นตัวเลข", q.Label)
+			}
+		case "choice":
+			if !slices.Contains(toQuestionResponse(q).Options, value) {
+				return fmt.Errorf("คำตอบของ \"%s\" ไม่อยู่ในตัวเลือก", q.Label)
+			}
+		}
+	}
+
+	for id := range answers {
+		if !known[id] {
+			return fmt.Errorf("ไม่พบคำถามรหัส %d ในแบบฟอร์มนี้", id)
+		}
+	}
+	for id := range files {
+		if !known[id] {
+			return fmt.Errorf("ไม่พบคำถามรหัส %d ในแบบฟอร์มนี้", id)
+		}
+	}
+	return nil
+}
+
+// ไฟล์แนบของแบบฟอร์มเก็บนอก static root (./images) เพราะมีข้อมูลส่วนตัวของผู้สมัคร
+// เปิดได้ผ่าน GetRegistrationFormFile ที่ตรวจสิทธิ์เท่านั้น
+const (
+	formAttachmentDir      = "uploads/forms"
+	maxFormAttachmentBytes = 5 << 20
+)
+
+// ชนิดไฟล์แนบที่รับ ตรวจจากเนื้อไฟล์ ไม่เชื่อนามสกุลหรือ Content-Type ที่ client ส่งมา
+var formAttachmentTypes = []string{"application/pdf", "image/jpeg", "image/png", "image/webp"}
+
+type formAttachment struct {
+	QuestionID uint
+	Data       []byte
+}
+
+// อ่านไฟล์แนบทั้งหมดเข้าหน่วยความจำและตรวจชนิดก่อนเริ่ม transaction
+func readFormAttachments(questions []entity.RegistrationQuestion, files map[uint]*multipart.FileHeader) ([]formAttachment, error) {
+	var attachments []formAttachment
+	for _, q := range questions {
+		header := files[q.ID]
+		if q.Type != "file" || header == nil {
+			continue
+		}
+		f, err := header.Open()
+		if err != nil {
+			return nil, fmt.Errorf("อ่านไฟล์ของคำถาม \"%s\" ไม่ได้", q.Label)
+		}
+		data, err := io.ReadAll(io.LimitReader(f, maxFormAttachmentBytes+1))
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("อ่านไฟล์ของคำถาม \"%s\" ไม่ได้", q.Label)
+		}
+		if len(data) > maxFormAttachmentBytes {
+			return nil, fmt.Errorf("ไฟล์ของคำถาม \"%s\" ต้องมีขนาดไม่เกิน 5MB", q.Label)
+		}
+		if !slices.Contains(formAttachmentTypes, http.DetectContentType(data)) {
+			return nil, fmt.Errorf("ไฟล์ของคำถาม \"%s\" ต้องเป็น PDF, JPEG, PNG หรือ WebP", q.Label)
+		}
+		attachments = append(attachments, formAttachment{QuestionID: q.ID, Data: data})
+	}
+	return attachments, nil
+}
+
+func formAttachmentPath(activityID, registrationID, questionID uint) string {
+	return filepath.Join(formAttachmentDir, strconv.Itoa(int(activityID)), fmt.Sprintf("%d_%d", registrationID, questionID))
+}
+
+func formAttachmentURL(activityID, registrationID, questionID uint) string {
+	return fmt.Sprintf("/activities/%d/form/files/%d/%d", activityID, registrationID, questionID)
+}
+
+// บันทึกคำตอบของแบบฟอร์มภายใต้ transaction ของการลงทะเบียน
+// คำตอบแบบไฟล์เก็บเป็น URL สำหรับดาวน์โหลด ตัวไฟล์เขียนด้วย writeFormAttachments หลัง commit แล้วเท่านั้น
+func saveRegistrationAnswers(tx *gorm.DB, reg entity.ActivityRegistration, questions []entity.RegistrationQuestion, answers map[uint]string, attachments []formAttachment) error {
+	attached := map[uint]bool{}
+	for _, a := range attachments {
+		attached[a.QuestionID] = true
+	}
+	for _, q := range questions {
+		value := strings.TrimSpace(answers[q.ID])
+		if q.Type == "file" {
+			value = ""
+			if attached[q.ID] {
+				value = formAttachmentURL(reg.ActivityID, reg.ID, q.ID)
+			}
+		}
+
+		if value == "" {
+			continue
+		}
+		if err := tx.Create(&entity.RegistrationAnswer{
+			RegistrationID: reg.ID,
+			QuestionID:     q.ID,
+			Value:          value,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// เขียนไฟล์แนบของการลงทะเบียนที่ commit แล้วลงดิสก์
+func writeFormAttachments(reg entity.ActivityRegistration, attachments []formAttachment) error {
+	for _, a := range attachments {
+		path := formAttachmentPath(reg.ActivityID, reg.ID, a.QuestionID)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, a.Data, 0o640); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GET /activities/:id/form/files/:registrationId/:questionId - ดาวน์โหลดไฟล์แนบ (officer ของกิจกรรมหรือเจ้าของคำตอบ)
+func GetRegistrationFormFile(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	var reg entity.ActivityRegistration
+	if err := db.Where("id = ? AND activity_id = ?", c.Param("registrationId"), activity.ID).First(&reg).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบการลงทะเบียน"})
+		return
+	}
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if user.ID != reg.UserID && !isAdmin(db, user) {
+		if _, err := requireActivityOfficer(c, &activity); err != nil {
+			return
+		}
+	}
+	questionID, err := strconv.ParseUint(c.Param("questionId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "question id ไม่ถูกต้อง"})
+		return
+	}
+
+	data, err := os.ReadFile(formAttachmentPath(activity.ID, reg.ID, uint(questionID)))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบไฟล์แนบ"})
+		return
+	}
+	contentType := http.DetectContentType(data)
+	if !slices.Contains(formAttachmentTypes, contentType) {
+		contentType = "application/octet-stream"
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=registration_%d_question_%d", reg.ID, questionID))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// GET /activities/:id/form - ดึงแบบฟอร์มลงทะเบียนของกิจกรรม
+func GetRegistrationForm(c *gin.Context) {
+	activityID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid activity ID"})
+		return
+	}
+
+	questions, err := loadRegistrationQuestions(config.DB(), uint(activityID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงแบบฟอร์มได้"})
+		return
+	}
+
+	response := make([]RegistrationQuestionResponse, 0, len(questions))
+	for _, q := range questions {
+		response = append(response, toQuestionResponse(q))
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": response})
+}
+
+// PUT /activities/:id/form - กำหนดแบบฟอร์มลงทะเบียนใหม่ทั้งชุด (officer เท่านั้น)
+func UpdateRegistrationForm(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	if _, err := requireActivityOfficer(c, &activity); err != nil {
+		return
+	}
+
+	var input struct {
+		Questions []RegistrationQuestionInput `json:"questions"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+
+	for i, q := range input.Questions {
+		if strings.TrimSpace(q.Label) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("คำถามข้อที่ %d ต้องมีหัวข้อ", i+1)})
+			return
+		}
+		if !slices.Contains(registrationQuestionTypes, q.Type) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("คำถามข้อที่ %d มีประเภทไม่ถูกต้อง", i+1)})
+			return
+		}
+		if q.Type == "choice" && len(q.Options) < 2 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("คำถามข้อที่ %d ต้องมีตัวเลือกอย่างน้อย 2 ตัวเลือก", i+1)})
+			return
+		}
+	}
+
+	// ไม่อนุญาตให้เปลี่ยนแบบฟอร์มหลังมีคนตอบแล้ว เพื่อไม่ให้คำตอบเดิมหลุดจากคำถาม
+	var answerCount int64
+	db.Model(&entity.RegistrationAnswer{}).
+		Joins("JOIN registration_questions ON registration_questions.id = registration_answers.question_id").
+		Where("registration_questions.activity_id = ? AND registration_questions.deleted_at IS NULL", activity.ID).
+		Count(&answerCount)
+	if answerCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "มีผู้ตอบแบบฟอร์มแล้ว ไม่สามารถแก้ไขแบบฟอร์มได้"})
+		return
+	}
+
+	var questions []entity.RegistrationQuestion
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("activity_id = ?", activity.ID).Delete(&entity.RegistrationQuestion{}).Error; err != nil {
+			return err
+		}
+		for i, q := range input.Questions {
+			options := ""
+			if q.Type == "choice" {
+				raw, _ := json.Marshal(q.Options)
+				options = string(raw)
+			}
+			question := entity.RegistrationQuestion{
+				ActivityID: activity.ID,
+				Label:      strings.TrimSpace(q.Label),
+				Type:       q.Type,
+				Options:    options,
+				Required:   q.Required,
+				SortOrder:  i + 1,
+			}
+			if err := tx.Create(&question).Error; err != nil {
+				return err
+			}
+			questions = append(questions, question)
+		}
+		return nil
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกแบบฟอร์มไม่สำเร็จ"})
+		return
+	}
+
+	response := make([]RegistrationQuestionResponse, 0, len(questions))
+	for _, q := range questions {
+		response = append(response, toQuestionResponse(q))
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "บันทึกแบบฟอร์มสำเร็จ", "data": response})
+}
+
+func loadRegistrantAnswers(db *gorm.DB, activityID uint) ([]RegistrantAnswers, error) {
+	// ผู้ที่ยกเลิกการลงทะเบียนแล้วไม่อยู่ในรายชื่อคำตอบ
+	cancelled := db.Model(&entity.ActivityRegistrationStatus{}).Select("id").Where("name = ?", "cancelled")
+	var regs []entity.ActivityRegistration
+	if err := db.Preload("User").
+		Where("activity_id = ? AND status_id NOT IN (?)", activityID, cancelled).
+		Order("registered_at ASC").
+		Find(&regs).Error; err != nil {
+		return nil, err
+	}
+
+	regIDs := make([]uint, 0, len(regs))
+	for _, r := range regs {
+		regIDs = append(regIDs, r.ID)
+	}
+
+	var answers []entity.RegistrationAnswer
+	if len(regIDs) > 0 {
+		if err := db.Where("registration_id IN ?", regIDs).Find(&answers).Error; err != nil {
+			return nil, err
+		}
+	}
+	byReg := map[uint]map[uint]string{}
+	for _, a := range answers {
+		if byReg[a.RegistrationID] == nil {
+			byReg[a.RegistrationID] = map[uint]string{}
+		}
+		byReg[a.RegistrationID][a.QuestionID] = a.Value
+	}
+
+	result := make([]RegistrantAnswers, 0, len(regs))
+	for _, r := range regs {
+		row := RegistrantAnswers{
+			RegistrationID: r.ID,
+			UserID:         r.UserID,
+			StudentID:      r.User.StudentID,
+			FirstName:      r.User.FirstName,
+			LastName:       r.User.LastName,
+			Email:          r.User.Email,
+			RegisteredAt:   r.RegisteredAt,
+			Answers:        byReg[r.ID],
+		}
+		if row.Answers == nil {
+			row.Answers = map[uint]string{}
+		}
+		result = append(result, row)
+	}
+	return result, nil
+}
+
+// GET /activities/:id/form/answers - ดูคำตอบของผู้สมัครทุกคน (officer เท่านั้น)
+func GetRegistrationAnswers(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	if _, err := requireActivityOfficer(c, &activity); err != nil {
+		return
+	}
+
+	questions, err := loadRegistrationQuestions(db, activity.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงแบบฟอร์มได้"})
+		return
+	}
+	registrants, err := loadRegistrantAnswers(db, activity.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงคำตอบได้"})
+		return
+	}
+
+	questionResponses := make([]RegistrationQuestionResponse, 0, len(questions))
+	for _, q := range questions {
+		questionResponses = append(questionResponses, toQuestionResponse(q))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"questions":   questionResponses,
+		"registrants": registrants,
+	})
+}
+
+// GET /activities/:id/form/export - ส่งออกคำตอบเป็นไฟล์ CSV (officer เท่านั้น)
+func ExportRegistrationAnswers(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	if _, err := requireActivityOfficer(c, &activity); err != nil {
+		return
+	}
+
+	questions, err := loadRegistrationQuestions(db, activity.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงแบบฟอร์มได้"})
+		return
+	}
+	registrants, err := loadRegistrantAnswers(db, activity.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงคำตอบได้"})
+		return
+	}
+
+	filename := fmt.Sprintf("activity_%d_registrations.csv", activity.ID)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+
+	// ใส่ BOM เพื่อให้ Excel อ่านภาษาไทยได้ถูกต้อง
+	c.Writer.Write([]byte("\xEF\xBB\xBF"))
+	w := csv.NewWriter(c.Writer)
+
+	header := []string{"student_id", "first_name", "last_name", "email", "registered_at"}
+	for _, q := range questions {
+		header = append(header, csvSafeCell(q.Label))
+	}
+	w.Write(header)
+
+	for _, r := range registrants {
+		row := []string{csvSafeCell(r.StudentID), csvSafeCell(r.FirstName), csvSafeCell(r.LastName), csvSafeCell(r.Email), r.RegisteredAt.Format(time.RFC3339)}
+		for _, q := range questions {
+			row = append(row, csvSafeCell(r.Answers[q.ID]))
+		}
+		w.Write(row)
+	}
+	w.Flush()
+}
+
+// กันสูตรใน spreadsheet (CSV injection) ค่าที่ขึ้นต้นด้วย = + - @ หรือ tab/CR จะถูกนำหน้าด้วย '
+// ยกเว้นค่าที่เป็นตัวเลข (เช่น -5 หรือ +66) ซึ่งไม่ใช่สูตรและต้องคงเป็นตัวเลขใน spreadsheet
+func csvSafeCell(value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// อ่านคำตอบแบบฟอร์มจาก request ที่เป็น multipart (json_data + answer_<question_id>)
+func readRegistrationFiles(c *gin.Context) (map[uint]*multipart.FileHeader, error) {
+	files := map[uint]*multipart.FileHeader{}
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		return files, nil
+	}
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	for key, headers := range form.File {
+		if !strings.HasPrefix(key, "answer_") || len(headers) == 0 {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimPrefix(key, "answer_"), 10, 32)
+		if err != nil {
+			return nil, errors.New("ชื่อฟิลด์ไฟล์แนบไม่ถูกต้อง")
+		}
+		files[uint(id)] = headers[0]
+	}
+	return files, nil
+}
@@ -15,6 +15,7 @@ import (
     "gorm.io/gorm"
     "github.com/jung-kurt/gofpdf"
     "math"
+    "sort"
 )
 
 // 1. Dashboard Statistics 
@@ -123,12 +124,12 @@ func (h *ReportHandler) GetDashboardStats(c *gin.Context) {
     var currentRating, lastMonthRating float64
     h.DB.Table("activity_reviews ar").
         Joins("JOIN activities a ON ar.activity_id = a.id").
-        Where("a.created_at >= ? AND a.created_at <= ?", startOfMonth, endOfMonth).
+        Where("a.created_at >= ? AND a.created_at <= ? AND ar.is_hidden = ?", startOfMonth, endOfMonth, false).
         Select("COALESCE(AVG(ar.rating), 0)").Scan(&currentRating)
     
     h.DB.Table("activity_reviews ar").
         Joins("JOIN activities a ON ar.activity_id = a.id").
-        Where("a.created_at >= ? AND a.created_at <= ?", startOfLastMonth, endOfLastMonth).
+        Where("a.created_at >= ? AND a.created_at <= ? AND ar.is_hidden = ?", startOfLastMonth, endOfLastMonth, false).
         Select("COALESCE(AVG(ar.rating), 0)").Scan(&lastMonthRating)
     
     stats.AverageRating = currentRating
@@ -993,9 +994,11 @@ func generateClubPerformanceReport(db *gorm.DB, clubID uint, start, end time.Tim
 		return fmt.Errorf("club not found: %w", err)
 	}
 
+	// รวมกิจกรรมที่ชมรมร่วมจัด ชั่วโมงกิจกรรมคิดตามสัดส่วนเครดิตของชมรม
 	var activities []entity.Activity
     db.Preload("Category").
-        Where("club_id = ? AND date_start BETWEEN ? AND ?", clubID, start, end).
+        Scopes(hostedByClub(clubID)).
+        Where("date_start BETWEEN ? AND ?", start, end).
         Order("date_start").
         Find(&activities)
 
@@ -1004,6 +1007,7 @@ func generateClubPerformanceReport(db *gorm.DB, clubID uint, start, end time.Tim
 		ParticipantCount  int64
 		TotalHours        float64
 		AvgRating         float64
+		CreditShare       float64
 	}
 	rows := []ActRow{}
 
@@ -1011,8 +1015,14 @@ func generateClubPerformanceReport(db *gorm.DB, clubID uint, start, end time.Tim
 	var grandHours float64
 	var ratingSum float64
 	var ratingCount int64
+	var coHosted int
 
 	for _, act := range activities {
+		share := activityCreditShare(db, &act, clubID)
+		if share < 1 {
+			coHosted++
+		}
+
 		var pCount int64
 		db.Model(&entity.ActivityRegistration{}).
 			Where("activity_id = ? AND status_id IN (SELECT id FROM activity_registration_statuses WHERE name = 'registered')",
@@ -1025,9 +1035,10 @@ func generateClubPerformanceReport(db *gorm.DB, clubID uint, start, end time.Tim
 
 		var avgRating float64
 		db.Model(&entity.ActivityReview{}).
-			Where("activity_id = ?", act.ID).
+			Where("activity_id = ? AND is_hidden = ?", act.ID, false).
 			Select("COALESCE(AVG(rating),0)").Scan(&avgRating)
 
+		hours *= share
 		grandParticipants += pCount
 		grandHours += hours
 		if avgRating > 0 {
@@ -1040,6 +1051,7 @@ func generateClubPerformanceReport(db *gorm.DB, clubID uint, start, end time.Tim
 			ParticipantCount: pCount,
 			TotalHours:       hours,
 			AvgRating:        avgRating,
+			CreditShare:      share,
 		})
 	}
 	
@@ -1072,7 +1084,7 @@ func generateClubPerformanceReport(db *gorm.DB, clubID uint, start, end time.Tim
 	pdf.SetFont("THSarabunNew", "B", 16)
 	pdf.CellFormat(0, 8, "สรุปผลการดำเนินงาน", "0", 1, "L", false, 0, "")
 	pdf.SetFont("THSarabunNew", "", 14)
-	pdf.CellFormat(0, 8, fmt.Sprintf("• จำนวนกิจกรรมที่จัด: %d กิจกรรม", len(activities)), "0", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 8, fmt.Sprintf("• จำนวนกิจกรรมที่จัด: %d กิจกรรม (ร่วมจัดกับชมรมอื่น %d กิจกรรม)", len(activities), coHosted), "0", 1, "L", false, 0, "")
 	pdf.CellFormat(0, 8, fmt.Sprintf("• ผู้เข้าร่วมทั้งหมด: %d คน", grandParticipants), "0", 1, "L", false, 0, "")
 	pdf.CellFormat(0, 8, fmt.Sprintf("• ชั่วโมงกิจกรรมรวม: %.1f ชั่วโมง", grandHours), "0", 1, "L", false, 0, "")
 	pdf.CellFormat(0, 8, fmt.Sprintf("• คะแนนเฉลี่ยกิจกรรม: %.2f จาก 5.00", overallRating), "0", 1, "L", false, 0, "")
@@ -1087,11 +1099,12 @@ func generateClubPerformanceReport(db *gorm.DB, clubID uint, start, end time.Tim
 		A string
 	}{
 		{12, "ลำดับ", "C"},
-		{60, "ชื่อกิจกรรม", "L"},
-		{25, "วันที่จัด", "C"},
-		{25, "ผู้เข้าร่วม", "C"},
-		{25, "ชั่วโมง", "C"},
-		{25, "คะแนน", "C"},
+		{53, "ชื่อกิจกรรม", "L"},
+		{22, "วันที่จัด", "C"},
+		{22, "ผู้เข้าร่วม", "C"},
+		{22, "สัดส่วน", "C"},
+		{22, "ชั่วโมง", "C"},
+		{22, "คะแนน", "C"},
 	}
 	
 	for _, h := range header {
@@ -1114,11 +1127,85 @@ func generateClubPerformanceReport(db *gorm.DB, clubID uint, start, end time.Tim
 		}
 
 		pdf.CellFormat(12, 8, fmt.Sprintf("%d", i+1), "1", 0, "C", false, 0, "")
-		pdf.CellFormat(60, 8, r.Act.Title, "1", 0, "L", false, 0, "")
-		pdf.CellFormat(25, 8, r.Act.DateStart.Format("02/01/06"), "1", 0, "C", false, 0, "")
-		pdf.CellFormat(25, 8, fmt.Sprintf("%d", r.ParticipantCount), "1", 0, "C", false, 0, "")
-		pdf.CellFormat(25, 8, fmt.Sprintf("%.1f", r.TotalHours), "1", 0, "C", false, 0, "")
-		pdf.CellFormat(25, 8, fmt.Sprintf("%.2f", r.AvgRating), "1", 1, "C", false, 0, "")
+		pdf.CellFormat(53, 8, r.Act.Title, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(22, 8, r.Act.DateStart.Format("02/01/06"), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(22, 8, fmt.Sprintf("%d", r.ParticipantCount), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(22, 8, fmt.Sprintf("%.0f%%", r.CreditShare*100), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(22, 8, fmt.Sprintf("%.1f", r.TotalHours), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(22, 8, fmt.Sprintf("%.2f", r.AvgRating), "1", 1, "C", false, 0, "")
+	}
+
+	// งบประมาณเทียบกับรายจ่ายจริง (นับเฉพาะรายจ่ายที่อนุมัติครบทุกขั้น)
+	if budgets := clubBudgetVsActual(db, clubID, start, end); len(budgets) > 0 {
+		if pdf.GetY() > 220 {
+			pdf.AddPage()
+			pdf.SetY(20)
+		}
+		pdf.Ln(8)
+		pdf.SetFont("THSarabunNew", "B", 16)
+		pdf.CellFormat(0, 8, "งบประมาณเทียบกับรายจ่ายจริง", "0", 1, "L", false, 0, "")
+
+		pdf.SetFont("THSarabunNew", "B", 14)
+		pdf.SetFillColor(240, 240, 240)
+		budgetHeader := []struct {
+			W float64
+			T string
+		}{
+			{30, "ปีการศึกษา"},
+			{37, "งบที่ได้รับ"},
+			{37, "ใช้จริง"},
+			{37, "คงเหลือ"},
+			{34, "ร้อยละที่ใช้"},
+		}
+		for _, h := range budgetHeader {
+			pdf.CellFormat(h.W, 10, h.T, "1", 0, "C", true, 0, "")
+		}
+		pdf.Ln(-1)
+
+		pdf.SetFont("THSarabunNew", "", 12)
+		for _, b := range budgets {
+			used := "-"
+			if b.Budget > 0 {
+				used = fmt.Sprintf("%.1f%%", b.Spent/b.Budget*100)
+			}
+			pdf.CellFormat(30, 8, fmt.Sprintf("%d", b.AcademicYear), "1", 0, "C", false, 0, "")
+			pdf.CellFormat(37, 8, fmt.Sprintf("%.2f", b.Budget), "1", 0, "R", false, 0, "")
+			pdf.CellFormat(37, 8, fmt.Sprintf("%.2f", b.Spent), "1", 0, "R", false, 0, "")
+			pdf.CellFormat(37, 8, fmt.Sprintf("%.2f", b.Remaining), "1", 0, "R", false, 0, "")
+			pdf.CellFormat(34, 8, used, "1", 1, "C", false, 0, "")
+		}
+
+		// รายจ่ายของกิจกรรมในช่วงรายงาน
+		type ActivitySpend struct {
+			Title string
+			Items int64
+			Total float64
+		}
+		var spends []ActivitySpend
+		db.Table("club_expenses AS e").
+			Select("COALESCE(a.title, 'ค่าใช้จ่ายทั่วไปของชมรม') AS title, COUNT(e.id) AS items, SUM(e.amount) AS total").
+			Joins("LEFT JOIN activities a ON a.id = e.activity_id").
+			Where("e.deleted_at IS NULL AND e.club_id = ? AND e.status = ? AND e.spent_at BETWEEN ? AND ?", clubID, "approved", start, end).
+			Group("e.activity_id, a.title").
+			Order("total DESC").
+			Scan(&spends)
+		if len(spends) > 0 {
+			pdf.Ln(4)
+			pdf.SetFont("THSarabunNew", "B", 14)
+			pdf.CellFormat(115, 10, "รายจ่ายตามกิจกรรม", "1", 0, "L", true, 0, "")
+			pdf.CellFormat(25, 10, "รายการ", "1", 0, "C", true, 0, "")
+			pdf.CellFormat(35, 10, "ยอดรวม (บาท)", "1", 1, "C", true, 0, "")
+			pdf.SetFont("THSarabunNew", "", 12)
+			for _, s := range spends {
+				if pdf.GetY() > 260 {
+					pdf.AddPage()
+					pdf.SetY(20)
+				}
+				pdf.CellFormat(115, 8, s.Title, "1", 0, "L", false, 0, "")
+				pdf.CellFormat(25, 8, fmt.Sprintf("%d", s.Items), "1", 0, "C", false, 0, "")
+				pdf.CellFormat(35, 8, fmt.Sprintf("%.2f", s.Total), "1", 1, "R", false, 0, "")
+			}
+		}
 	}
 
 	// ลายเซ็นผู้รับรอง
@@ -1140,27 +1227,56 @@ func generateClubPerformanceReport(db *gorm.DB, clubID uint, start, end time.Tim
 func generateClubRankingReport(db *gorm.DB, start, end time.Time, savePath string) error {
 	type ClubStat struct {
 		Rank          int
+		ClubID        uint
 		ClubName      string
 		Activities    int
+		Credits       float64
 		Participants  int
 		AvgRating     float64
 	}
 
+	// นับทั้งกิจกรรมที่จัดเองและร่วมจัด แต่เรียงอันดับตามเครดิตที่ได้ตามสัดส่วนของแต่ละกิจกรรม
 	var stats []ClubStat
     db.Table("activities AS a").
         Select(`
+            c.id AS club_id,
             c.name AS club_name,
-            COUNT(a.id) AS activities,
+            COUNT(DISTINCT a.id) AS activities,
             COUNT(DISTINCT ar.user_id) AS participants,
             COALESCE(AVG(rv.rating),0) AS avg_rating`).
-        Joins("JOIN clubs c ON a.club_id = c.id").
+        Joins("JOIN "+activityHostCreditsSQL+" h ON h.activity_id = a.id").
+        Joins("JOIN clubs c ON h.club_id = c.id").
         Joins("LEFT JOIN activity_registrations ar ON a.id = ar.activity_id").
-        Joins("LEFT JOIN activity_reviews rv ON a.id = rv.activity_id").
-        Where("a.date_start BETWEEN ? AND ?", start, end).  
+        Joins("LEFT JOIN activity_reviews rv ON a.id = rv.activity_id AND rv.is_hidden = ? AND rv.deleted_at IS NULL", false).
+        Where("a.deleted_at IS NULL AND a.date_start BETWEEN ? AND ?", start, end).
         Group("c.id, c.name").
-        Order("activities DESC").
         Scan(&stats)
 
+	type ClubCredit struct {
+		ClubID  uint
+		Credits float64
+	}
+	var credits []ClubCredit
+	db.Table("activities AS a").
+		Select("h.club_id AS club_id, SUM(h.credit_share) / 100 AS credits").
+		Joins("JOIN "+activityHostCreditsSQL+" h ON h.activity_id = a.id").
+		Where("a.deleted_at IS NULL AND a.date_start BETWEEN ? AND ?", start, end).
+		Group("h.club_id").
+		Scan(&credits)
+	creditByClub := map[uint]float64{}
+	for _, cr := range credits {
+		creditByClub[cr.ClubID] = cr.Credits
+	}
+
+	for i := range stats {
+		stats[i].Credits = creditByClub[stats[i].ClubID]
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].Credits != stats[j].Credits {
+			return stats[i].Credits > stats[j].Credits
+		}
+		return stats[i].Activities > stats[j].Activities
+	})
 	for i := range stats {
 		stats[i].Rank = i + 1
 	}
@@ -1185,8 +1301,8 @@ func generateClubRankingReport(db *gorm.DB, start, end time.Time, savePath strin
 	// ตารางอันดับ
 	pdf.SetFont("THSarabunNew", "B", 14)
 	pdf.SetFillColor(240, 240, 240)
-	colW := []float64{15, 65, 30, 35, 30}
-	headers := []string{"อันดับ", "ชื่อชมรม", "กิจกรรม", "ผู้เข้าร่วม", "คะแนนเฉลี่ย"}
+	colW := []float64{15, 55, 25, 25, 30, 30}
+	headers := []string{"อันดับ", "ชื่อชมรม", "กิจกรรม", "เครดิต", "ผู้เข้าร่วม", "คะแนนเฉลี่ย"}
 
 	for i, h := range headers {
 		pdf.CellFormat(colW[i], 10, h, "1", 0, "C", true, 0, "")
@@ -1224,8 +1340,9 @@ func generateClubRankingReport(db *gorm.DB, start, end time.Time, savePath strin
 		pdf.CellFormat(colW[0], 8, fmt.Sprintf("%d", s.Rank), "1", 0, "C", fillColor, 0, "")
 		pdf.CellFormat(colW[1], 8, s.ClubName, "1", 0, "L", fillColor, 0, "")
 		pdf.CellFormat(colW[2], 8, fmt.Sprintf("%d", s.Activities), "1", 0, "C", fillColor, 0, "")
-		pdf.CellFormat(colW[3], 8, fmt.Sprintf("%d", s.Participants), "1", 0, "C", fillColor, 0, "")
-		pdf.CellFormat(colW[4], 8, fmt.Sprintf("%.2f", s.AvgRating), "1", 1, "C", fillColor, 0, "")
+		pdf.CellFormat(colW[3], 8, fmt.Sprintf("%.2f", s.Credits), "1", 0, "C", fillColor, 0, "")
+		pdf.CellFormat(colW[4], 8, fmt.Sprintf("%d", s.Participants), "1", 0, "C", fillColor, 0, "")
+		pdf.CellFormat(colW[5], 8, fmt.Sprintf("%.2f", s.AvgRating), "1", 1, "C", fillColor, 0, "")
 	}
 
 
@@ -1328,6 +1445,96 @@ func generateCategoryAnalyticsReport(db *gorm.DB, start, end time.Time, savePath
     return nil
 }
 
+// ----------------------
+// Generate Survey Summary Report
+// ----------------------
+// สรุปแบบสอบถามหลังกิจกรรมของกิจกรรมที่เริ่มในช่วงเวลา (ระบุ clubID เพื่อดูเฉพาะชมรม)
+func generateSurveySummaryReport(db *gorm.DB, clubID *uint, start, end time.Time, savePath string) error {
+	query := db.Joins("JOIN activities ON activities.id = surveys.activity_id AND activities.deleted_at IS NULL").
+		Where("surveys.sent_at IS NOT NULL AND activities.date_start BETWEEN ? AND ?", start, end)
+	if clubID != nil {
+		query = query.Scopes(hostedByClub(*clubID))
+	}
+	var surveys []entity.Survey
+	if err := query.Preload("Questions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order ASC")
+	}).Order("activities.date_start ASC").Find(&surveys).Error; err != nil {
+		return err
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8Font("THSarabunNew", "", "./fonts/THSarabunNew.ttf")
+	pdf.AddUTF8Font("THSarabunNew", "B", "./fonts/THSarabunNew-Bold.ttf")
+	pdf.AddUTF8Font("THSarabunNew", "I", "./fonts/THSarabunNew-Italic.ttf")
+
+	applyHeaderFooter(pdf, "สรุปผลแบบสอบถามหลังกิจกรรม (Survey Summary Report)")
+
+	pdf.AddPage()
+	pdf.SetY(28)
+	pdf.SetFont("THSarabunNew", "", 16)
+	pdf.CellFormat(0, 8, fmt.Sprintf("ช่วงเวลา: %s - %s", FormatThaiDate(start), FormatThaiDate(end)), "0", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 8, fmt.Sprintf("จำนวนแบบสอบถาม: %d ชุด", len(surveys)), "0", 1, "L", false, 0, "")
+	pdf.Ln(2)
+
+	colW := []float64{10, 95, 25, 50}
+	heads := []string{"ข้อ", "คำถาม", "ผู้ตอบ", "ผลสรุป"}
+	for i := range surveys {
+		results, err := computeSurveyResults(db, &surveys[i])
+		if err != nil {
+			return err
+		}
+		if pdf.GetY() > 240 {
+			pdf.AddPage()
+		}
+		pdf.SetFont("THSarabunNew", "B", 16)
+		pdf.CellFormat(0, 9, fmt.Sprintf("%s (%s)", results.ActivityTitle, surveys[i].Title), "0", 1, "L", false, 0, "")
+		pdf.SetFont("THSarabunNew", "", 14)
+		pdf.CellFormat(0, 7, fmt.Sprintf("ตอบกลับ %d จาก %d คน (%.2f%%)", results.Responded, results.Invited, results.ResponseRate), "0", 1, "L", false, 0, "")
+
+		pdf.SetFont("THSarabunNew", "B", 14)
+		for j, h := range heads {
+			pdf.CellFormat(colW[j], 8, h, "1", 0, "CM", false, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("THSarabunNew", "", 13)
+		for j, q := range results.Questions {
+			if pdf.GetY() > 265 {
+				pdf.AddPage()
+			}
+			pdf.CellFormat(colW[0], 8, fmt.Sprintf("%d", j+1), "1", 0, "C", false, 0, "")
+			pdf.CellFormat(colW[1], 8, q.Label, "1", 0, "L", false, 0, "")
+			pdf.CellFormat(colW[2], 8, fmt.Sprintf("%d", q.Responses), "1", 0, "C", false, 0, "")
+			pdf.CellFormat(colW[3], 8, surveyResultSummary(q), "1", 1, "L", false, 0, "")
+		}
+		pdf.Ln(5)
+	}
+
+	if err := pdf.OutputFileAndClose(savePath); err != nil {
+		return fmt.Errorf("failed to save PDF file: %w", err)
+	}
+	return nil
+}
+
+// ข้อความสรุปสั้น ๆ ของคำถามหนึ่งข้อสำหรับตารางในรายงาน
+func surveyResultSummary(q SurveyQuestionResult) string {
+	switch q.Type {
+	case "likert":
+		return fmt.Sprintf("เฉลี่ย %.2f / 5", q.Average)
+	case "choice":
+		options := make([]string, 0, len(q.Distribution))
+		for option := range q.Distribution {
+			options = append(options, option)
+		}
+		sort.Slice(options, func(a, b int) bool { return q.Distribution[options[a]] > q.Distribution[options[b]] })
+		if len(options) == 0 || q.Distribution[options[0]] == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%s (%d)", options[0], q.Distribution[options[0]])
+	default:
+		return fmt.Sprintf("ความคิดเห็น %d รายการ", len(q.TextAnswers))
+	}
+}
+
 func (h *ReportHandler) GenerateReportByType(req ReportRequest, savePath string) error {
 	start, end, err := getReportPeriod(req)
 	if err != nil {
@@ -1348,6 +1555,8 @@ func (h *ReportHandler) GenerateReportByType(req ReportRequest, savePath string)
 		return generateClubRankingReport(h.DB, start, end, savePath)
 	case "category_analytics":
 		return generateCategoryAnalyticsReport(h.DB, start, end, savePath)
+	case "survey_summary":
+		return generateSurveySummaryReport(h.DB, req.ClubID, start, end, savePath)
 	default:
 		return fmt.Errorf("invalid report type")
 	}
@@ -1433,6 +1642,8 @@ func getReportName(reportType string, req ReportRequest) string {
 		return fmt.Sprintf("Club Ranking Report - %s", time.Now().Format("January 2006"))
 	case "category_analytics":
 		return fmt.Sprintf("Category Analytics Report - %s", time.Now().Format("January 2006"))
+	case "survey_summary":
+		return fmt.Sprintf("Survey Summary Report - %s", time.Now().Format("January 2006"))
 	default:
 		return fmt.Sprintf("CEMS Report - %s", time.Now().Format("2006-01-02"))
 	}
@@ -1453,6 +1664,8 @@ func getDisplayFilename(reportType string, req ReportRequest) string {
 		return fmt.Sprintf("club_ranking_%s.pdf", timestamp)
 	case "category_analytics":
 		return fmt.Sprintf("category_analytics_%s.pdf", timestamp)
+	case "survey_summary":
+		return fmt.Sprintf("survey_summary_%s.pdf", timestamp)
 	default:
 		return fmt.Sprintf("report_%s.pdf", timestamp)
 	}
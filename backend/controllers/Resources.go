@@ -0,0 +1,655 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// แจ้งเตือนซ้ำทุกเท่านี้ถ้ายังไม่คืนอุปกรณ์
+const resourceOverdueRepeat = 24 * time.Hour
+
+type ResourceInput struct {
+	Name        string `json:"name" binding:"required"`
+	Category    string `json:"category"`
+	Description string `json:"description"`
+	Quantity    int    `json:"quantity"`
+	OwnerClubID *uint  `json:"owner_club_id"`
+	IsActive    *bool  `json:"is_active"`
+}
+
+type ResourceReservationInput struct {
+	ResourceID uint       `json:"resource_id" binding:"required"`
+	Quantity   int        `json:"quantity" binding:"required"`
+	StartTime  *time.Time `json:"start_time"`
+	EndTime    *time.Time `json:"end_time"`
+	Note       string     `json:"note"`
+}
+
+var errResourceShortage = errors.New("อุปกรณ์มีไม่พอในช่วงเวลานี้")
+
+// จำนวนที่ถูกจองไว้แล้วในช่วง [start, end) ไม่นับคำขอ excludeID
+// อุปกรณ์ที่รับไปแล้วแต่ยังไม่คืนถือว่าถูกใช้อยู่แม้จะเลยกำหนดคืนแล้ว
+func reservedResourceQuantity(db *gorm.DB, resourceID uint, start, end time.Time, excludeID uint) (int, error) {
+	var total int
+	err := db.Model(&entity.ResourceReservation{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("resource_id = ? AND id <> ? AND start_time < ?", resourceID, excludeID, end).
+		Where("(status = ? AND end_time > ?) OR status = ?", "approved", start, "picked_up").
+		Scan(&total).Error
+	return total, err
+}
+
+// จำนวนที่ยังยืมได้ในช่วงเวลา
+func availableResourceQuantity(db *gorm.DB, resource *entity.Resource, start, end time.Time, excludeID uint) (int, error) {
+	reserved, err := reservedResourceQuantity(db, resource.ID, start, end, excludeID)
+	if err != nil {
+		return 0, err
+	}
+	if available := resource.Quantity - reserved; available > 0 {
+		return available, nil
+	}
+	return 0, nil
+}
+
+// ผู้ดูแลอุปกรณ์: สมาชิกที่มีสิทธิ์ดูแลงบประมาณและทรัพย์สินของชมรมเจ้าของ หรือผู้ดูแลระบบถ้าเป็นของกองกิจการนักศึกษา
+func resourceManagerIDs(db *gorm.DB, resource *entity.Resource) []uint {
+	if resource.OwnerClubID != nil {
+		return clubMemberIDsWithPermission(db, *resource.OwnerClubID, permManageBudget)
+	}
+	var ids []uint
+	db.Model(&entity.User{}).Joins("JOIN roles ON roles.id = users.role_id").
+		Where("roles.role_name = ?", "admin").Pluck("users.id", &ids)
+	return ids
+}
+
+// บังคับสิทธิ์: ต้องเป็นผู้ดูแลอุปกรณ์ (admin จัดการได้ทุกรายการ)
+func requireResourceManager(c *gin.Context, resource *entity.Resource) (*entity.User, error) {
+	db := config.DB()
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(401, gin.H{"error": err.Error()})
+		return nil, err
+	}
+	if isAdmin(db, user) {
+		return user, nil
+	}
+	if resource.OwnerClubID != nil {
+		ok, err := hasClubPermission(db, user.ID, *resource.OwnerClubID, permManageBudget)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "permission check failed"})
+			return nil, err
+		}
+		if ok {
+			return user, nil
+		}
+	}
+	c.JSON(403, gin.H{"error": "forbidden: resource owner only"})
+	return nil, errors.New("forbidden")
+}
+
+func notifyUsers(userIDs []uint, message, notificationType string) {
+	for _, id := range userIDs {
+		if err := getNotificationService().CreateNotification(id, message, notificationType); err != nil {
+			fmt.Println("❌ Error creating resource notification:", err)
+		}
+	}
+}
+
+func applyResourceInput(db *gorm.DB, resource *entity.Resource, input ResourceInput) error {
+	if strings.TrimSpace(input.Name) == "" {
+		return errors.New("กรุณาระบุชื่ออุปกรณ์")
+	}
+	if input.Quantity < 1 {
+		return errors.New("quantity ต้องมากกว่า 0")
+	}
+	if input.OwnerClubID != nil {
+		var club entity.Club
+		if err := db.First(&club, *input.OwnerClubID).Error; err != nil {
+			return errors.New("ไม่พบชมรมเจ้าของอุปกรณ์")
+		}
+	}
+	resource.Name = strings.TrimSpace(input.Name)
+	resource.Category = strings.TrimSpace(input.Category)
+	resource.Description = input.Description
+	resource.Quantity = input.Quantity
+	resource.OwnerClubID = input.OwnerClubID
+	if input.IsActive != nil {
+		resource.IsActive = *input.IsActive
+	}
+	return nil
+}
+
+// โหลดคำขอยืมพร้อมอุปกรณ์และกิจกรรม
+func loadResourceReservation(c *gin.Context) (*entity.ResourceReservation, bool) {
+	var reservation entity.ResourceReservation
+	if err := config.DB().Preload("Resource").Preload("Activity").First(&reservation, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบคำขอยืมอุปกรณ์"})
+		return nil, false
+	}
+	return &reservation, true
+}
+
+// ยกเลิกคำขอที่ยังไม่ได้รับอุปกรณ์ทั้งหมดของกิจกรรม (ใช้ตอนยกเลิกกิจกรรม)
+func cancelActivityResourceReservations(tx *gorm.DB, activityID uint) error {
+	return tx.Model(&entity.ResourceReservation{}).
+		Where("activity_id = ? AND status IN ?", activityID, []string{"pending", "approved"}).
+		Update("status", "cancelled").Error
+}
+
+// เลื่อนช่วงเวลายืมตามช่วงกิจกรรมที่เปลี่ยน (เวลารับเลื่อนตามเวลาเริ่ม กำหนดคืนเลื่อนตามเวลาสิ้นสุด)
+// คำขอที่อนุมัติแล้วต้องให้เจ้าของอนุมัติใหม่ ถ้าช่วงกิจกรรมไม่เปลี่ยนจะไม่แตะคำขอ
+func shiftActivityResourceReservations(tx *gorm.DB, activityID uint, startOffset, endOffset time.Duration) error {
+	if startOffset == 0 && endOffset == 0 {
+		return nil
+	}
+	var reservations []entity.ResourceReservation
+	if err := tx.Where("activity_id = ? AND status IN ?", activityID, []string{"pending", "approved"}).
+		Find(&reservations).Error; err != nil {
+		return err
+	}
+	for _, r := range reservations {
+		if err := tx.Model(&entity.ResourceReservation{}).Where("id = ?", r.ID).Updates(map[string]interface{}{
+			"start_time":  r.StartTime.Add(startOffset),
+			"end_time":    r.EndTime.Add(endOffset),
+			"status":      "pending",
+			"reviewed_by": nil,
+			"reviewed_at": nil,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GET /resources - รายการอุปกรณ์ กรองด้วย category, owner_club_id และถ้าระบุ start/end จะคำนวณจำนวนที่ยืมได้
+func GetResources(c *gin.Context) {
+	db := config.DB()
+
+	query := db.Preload("OwnerClub").Where("is_active = ?", true)
+	if category := strings.TrimSpace(c.Query("category")); category != "" {
+		query = query.Where("category = ?", category)
+	}
+	if ownerID, err := strconv.ParseUint(c.Query("owner_club_id"), 10, 32); err == nil {
+		query = query.Where("owner_club_id = ?", ownerID)
+	}
+	var resources []entity.Resource
+	if err := query.Order("category ASC, name ASC").Find(&resources).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงข้อมูลอุปกรณ์ได้"})
+		return
+	}
+
+	if c.Query("start") == "" && c.Query("end") == "" {
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": resources})
+		return
+	}
+	start, end, err := parseTimeRangeQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	data := make([]gin.H, 0, len(resources))
+	for i := range resources {
+		available, err := availableResourceQuantity(db, &resources[i], start, end, 0)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถตรวจสอบจำนวนคงเหลือได้"})
+			return
+		}
+		data = append(data, gin.H{"resource": resources[i], "available": available})
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": data})
+}
+
+// GET /resources/:id - รายละเอียดอุปกรณ์
+func GetResourceByID(c *gin.Context) {
+	var resource entity.Resource
+	if err := config.DB().Preload("OwnerClub").First(&resource, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบอุปกรณ์"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": resource})
+}
+
+// POST /resources - เพิ่มอุปกรณ์ (admin หรือ officer ของชมรมเจ้าของ)
+func CreateResource(c *gin.Context) {
+	db := config.DB()
+
+	var input ResourceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	resource := entity.Resource{IsActive: true}
+	if err := applyResourceInput(db, &resource, input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := requireResourceManager(c, &resource); err != nil {
+		return
+	}
+
+	var count int64
+	db.Model(&entity.Resource{}).Where("name = ?", resource.Name).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "มีอุปกรณ์ชื่อนี้อยู่แล้ว"})
+		return
+	}
+	if err := db.Omit("OwnerClub").Create(&resource).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "เพิ่มอุปกรณ์ไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "เพิ่มอุปกรณ์เรียบร้อยแล้ว", "data": resource})
+}
+
+// PUT /resources/:id - แก้ไขจำนวนหรือรายละเอียดอุปกรณ์ (ผู้ดูแลอุปกรณ์)
+// ลดจำนวนต่ำกว่าที่ถูกจองไว้ได้ แต่คำขอใหม่จะยืมได้ตามจำนวนที่เหลือจริง
+func UpdateResource(c *gin.Context) {
+	db := config.DB()
+
+	var resource entity.Resource
+	if err := db.First(&resource, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบอุปกรณ์"})
+		return
+	}
+	if _, err := requireResourceManager(c, &resource); err != nil {
+		return
+	}
+	var input ResourceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	if err := applyResourceInput(db, &resource, input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var count int64
+	db.Model(&entity.Resource{}).Where("name = ? AND id <> ?", resource.Name, resource.ID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "มีอุปกรณ์ชื่อนี้อยู่แล้ว"})
+		return
+	}
+	if err := db.Omit("OwnerClub").Save(&resource).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "แก้ไขอุปกรณ์ไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "แก้ไขอุปกรณ์เรียบร้อยแล้ว", "data": resource})
+}
+
+// GET /resources/:id/availability?start=&end= - จำนวนที่ยืมได้และรายการจองในช่วงเวลา
+func GetResourceAvailability(c *gin.Context) {
+	db := config.DB()
+
+	var resource entity.Resource
+	if err := db.First(&resource, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบอุปกรณ์"})
+		return
+	}
+	start, end, err := parseTimeRangeQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	available, err := availableResourceQuantity(db, &resource, start, end, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถตรวจสอบจำนวนคงเหลือได้"})
+		return
+	}
+
+	var reservations []entity.ResourceReservation
+	db.Preload("Activity").
+		Where("resource_id = ? AND start_time < ?", resource.ID, end).
+		Where("(status IN ? AND end_time > ?) OR status = ?", []string{"pending", "approved"}, start, "picked_up").
+		Order("start_time ASC").Find(&reservations)
+	items := make([]gin.H, 0, len(reservations))
+	pending := 0
+	for _, r := range reservations {
+		if r.Status == "pending" {
+			pending += r.Quantity
+		}
+		items = append(items, gin.H{
+			"reservation_id": r.ID,
+			"activity_id":    r.ActivityID,
+			"title":          r.Activity.Title,
+			"quantity":       r.Quantity,
+			"status":         r.Status,
+			"start_time":     r.StartTime,
+			"end_time":       r.EndTime,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"quantity":     resource.Quantity,
+		"available":    available,
+		"pending":      pending,
+		"is_active":    resource.IsActive,
+		"reservations": items,
+	})
+}
+
+// GET /activities/:id/resources - คำขอยืมอุปกรณ์ของกิจกรรม
+func GetActivityResources(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	var reservations []entity.ResourceReservation
+	if err := db.Preload("Resource").Where("activity_id = ?", activity.ID).
+		Order("id ASC").Find(&reservations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงคำขอยืมอุปกรณ์ได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": reservations})
+}
+
+// POST /activities/:id/resources - ขอยืมอุปกรณ์ตามช่วงเวลากิจกรรม (officer ของชมรมผู้จัด)
+// ไม่ระบุ start_time/end_time จะใช้วันเวลาของกิจกรรม
+func RequestActivityResource(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.Preload("Status").First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	user, err := requireActivityOfficer(c, &activity)
+	if err != nil {
+		return
+	}
+	if activity.Status.Name == "cancelled" || activity.Status.Name == "finished" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่สามารถขอยืมอุปกรณ์สำหรับกิจกรรมในสถานะ " + activity.Status.Name + " ได้"})
+		return
+	}
+
+	var input ResourceReservationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	if input.Quantity < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "quantity ต้องมากกว่า 0"})
+		return
+	}
+	start, end := activity.DateStart, activity.DateEnd
+	if input.StartTime != nil {
+		start = *input.StartTime
+	}
+	if input.EndTime != nil {
+		end = *input.EndTime
+	}
+	if !end.After(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "เวลาคืนต้องอยู่หลังเวลารับ"})
+		return
+	}
+	if end.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ช่วงเวลายืมผ่านไปแล้ว"})
+		return
+	}
+
+	var resource entity.Resource
+	if err := db.Where("id = ? AND is_active = ?", input.ResourceID, true).First(&resource).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่พบอุปกรณ์หรืออุปกรณ์งดให้ยืม"})
+		return
+	}
+	available, err := availableResourceQuantity(db, &resource, start, end, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถตรวจสอบจำนวนคงเหลือได้"})
+		return
+	}
+	if input.Quantity > available {
+		c.JSON(http.StatusConflict, gin.H{"error": errResourceShortage.Error(), "available": available})
+		return
+	}
+
+	reservation := entity.ResourceReservation{
+		ResourceID:  resource.ID,
+		ActivityID:  activity.ID,
+		Quantity:    input.Quantity,
+		StartTime:   start,
+		EndTime:     end,
+		Status:      "pending",
+		Note:        strings.TrimSpace(input.Note),
+		RequestedBy: user.ID,
+	}
+	if err := db.Omit("Resource", "Activity").Create(&reservation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ส่งคำขอยืมอุปกรณ์ไม่สำเร็จ"})
+		return
+	}
+
+	notifyUsers(resourceManagerIDs(db, &resource),
+		fmt.Sprintf("มีคำขอยืม %s จำนวน %d สำหรับกิจกรรม '%s' รอการอนุมัติ", resource.Name, reservation.Quantity, activity.Title), "info")
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "ส่งคำขอยืมอุปกรณ์เรียบร้อยแล้ว", "data": reservation})
+}
+
+// GET /resource-reservations?status=&club_id= - คิวคำขอของผู้ดูแลอุปกรณ์
+// ระบุ club_id เพื่อดูอุปกรณ์ของชมรม ไม่ระบุคืออุปกรณ์ของกองกิจการนักศึกษา (admin)
+func GetResourceReservationQueue(c *gin.Context) {
+	db := config.DB()
+
+	owner := entity.Resource{}
+	if clubID, err := strconv.ParseUint(c.Query("club_id"), 10, 32); err == nil {
+		id := uint(clubID)
+		owner.OwnerClubID = &id
+	}
+	if _, err := requireResourceManager(c, &owner); err != nil {
+		return
+	}
+
+	query := db.Preload("Resource").Preload("Activity").
+		Joins("JOIN resources ON resources.id = resource_reservations.resource_id")
+	if owner.OwnerClubID != nil {
+		query = query.Where("resources.owner_club_id = ?", *owner.OwnerClubID)
+	} else {
+		query = query.Where("resources.owner_club_id IS NULL")
+	}
+	switch status := c.DefaultQuery("status", "pending"); status {
+	case "overdue":
+		query = query.Where("resource_reservations.status = ? AND resource_reservations.end_time < ?", "picked_up", time.Now())
+	case "all":
+	default:
+		query = query.Where("resource_reservations.status = ?", status)
+	}
+
+	var reservations []entity.ResourceReservation
+	if err := query.Order("resource_reservations.start_time ASC").Find(&reservations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงคำขอยืมอุปกรณ์ได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": reservations})
+}
+
+// POST /resource-reservations/:id/approve - อนุมัติคำขอ (ผู้ดูแลอุปกรณ์) ตรวจจำนวนคงเหลืออีกครั้งก่อนอนุมัติ
+func ApproveResourceReservation(c *gin.Context) {
+	reviewResourceReservation(c, true)
+}
+
+// POST /resource-reservations/:id/reject - ปฏิเสธคำขอพร้อมเหตุผล (ผู้ดูแลอุปกรณ์)
+func RejectResourceReservation(c *gin.Context) {
+	reviewResourceReservation(c, false)
+}
+
+func reviewResourceReservation(c *gin.Context, approve bool) {
+	db := config.DB()
+
+	reservation, ok := loadResourceReservation(c)
+	if !ok {
+		return
+	}
+	user, err := requireResourceManager(c, &reservation.Resource)
+	if err != nil {
+		return
+	}
+	if reservation.Status != "pending" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "คำขอนี้ไม่ได้อยู่ในสถานะรออนุมัติ"})
+		return
+	}
+	var input struct {
+		Note string `json:"note"`
+	}
+	c.ShouldBindJSON(&input)
+	note := strings.TrimSpace(input.Note)
+	if !approve && note == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุเหตุผลที่ปฏิเสธ"})
+		return
+	}
+
+	status := "rejected"
+	if approve {
+		status = "approved"
+	}
+	now := time.Now()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if approve {
+			available, err := availableResourceQuantity(tx, &reservation.Resource, reservation.StartTime, reservation.EndTime, reservation.ID)
+			if err != nil {
+				return err
+			}
+			if reservation.Quantity > available {
+				return errResourceShortage
+			}
+		}
+		return tx.Model(&entity.ResourceReservation{}).Where("id = ?", reservation.ID).Updates(map[string]interface{}{
+			"status":      status,
+			"reviewed_by": user.ID,
+			"reviewed_at": now,
+			"review_note": note,
+		}).Error
+	})
+	if errors.Is(err, errResourceShortage) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกผลการพิจารณาไม่สำเร็จ"})
+		return
+	}
+	reservation.Status, reservation.ReviewedBy, reservation.ReviewedAt, reservation.ReviewNote = status, &user.ID, &now, note
+
+	if approve {
+		notifyUsers([]uint{reservation.RequestedBy}, fmt.Sprintf("คำขอยืม %s จำนวน %d สำหรับกิจกรรม '%s' ได้รับการอนุมัติ กรุณารับอุปกรณ์วันที่ %s",
+			reservation.Resource.Name, reservation.Quantity, reservation.Activity.Title, formatThaiDateTime(reservation.StartTime)), "success")
+	} else {
+		notifyUsers([]uint{reservation.RequestedBy}, fmt.Sprintf("คำขอยืม %s สำหรับกิจกรรม '%s' ไม่ได้รับการอนุมัติ เนื่องจาก %s",
+			reservation.Resource.Name, reservation.Activity.Title, note), "warning")
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "บันทึกผลการพิจารณาเรียบร้อยแล้ว", "data": reservation})
+}
+
+// POST /resource-reservations/:id/cancel - ยกเลิกคำขอที่ยังไม่ได้รับอุปกรณ์ (officer ของชมรมผู้จัด)
+func CancelResourceReservation(c *gin.Context) {
+	reservation, ok := loadResourceReservation(c)
+	if !ok {
+		return
+	}
+	if _, err := requireActivityOfficer(c, &reservation.Activity); err != nil {
+		return
+	}
+	if reservation.Status != "pending" && reservation.Status != "approved" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ยกเลิกได้เฉพาะคำขอที่ยังไม่ได้รับอุปกรณ์"})
+		return
+	}
+	if err := config.DB().Model(&entity.ResourceReservation{}).Where("id = ?", reservation.ID).
+		Update("status", "cancelled").Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ยกเลิกคำขอไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ยกเลิกคำขอยืมอุปกรณ์เรียบร้อยแล้ว"})
+}
+
+// POST /resource-reservations/:id/pickup - บันทึกการส่งมอบอุปกรณ์ (ผู้ดูแลอุปกรณ์)
+func PickupResourceReservation(c *gin.Context) {
+	reservation, ok := loadResourceReservation(c)
+	if !ok {
+		return
+	}
+	user, err := requireResourceManager(c, &reservation.Resource)
+	if err != nil {
+		return
+	}
+	if reservation.Status != "approved" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "รับอุปกรณ์ได้เฉพาะคำขอที่อนุมัติแล้ว"})
+		return
+	}
+	now := time.Now()
+	if err := config.DB().Model(&entity.ResourceReservation{}).Where("id = ?", reservation.ID).Updates(map[string]interface{}{
+		"status":       "picked_up",
+		"picked_up_at": now,
+		"handed_by":    user.ID,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกการรับอุปกรณ์ไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "บันทึกการรับอุปกรณ์เรียบร้อยแล้ว", "due": reservation.EndTime})
+}
+
+// POST /resource-reservations/:id/return - บันทึกการคืนอุปกรณ์พร้อมสภาพ (ผู้ดูแลอุปกรณ์)
+func ReturnResourceReservation(c *gin.Context) {
+	reservation, ok := loadResourceReservation(c)
+	if !ok {
+		return
+	}
+	user, err := requireResourceManager(c, &reservation.Resource)
+	if err != nil {
+		return
+	}
+	if reservation.Status != "picked_up" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "คืนอุปกรณ์ได้เฉพาะรายการที่รับไปแล้ว"})
+		return
+	}
+	var input struct {
+		Note string `json:"note"`
+	}
+	c.ShouldBindJSON(&input)
+
+	now := time.Now()
+	if err := config.DB().Model(&entity.ResourceReservation{}).Where("id = ?", reservation.ID).Updates(map[string]interface{}{
+		"status":      "returned",
+		"returned_at": now,
+		"received_by": user.ID,
+		"return_note": strings.TrimSpace(input.Note),
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกการคืนอุปกรณ์ไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "บันทึกการคืนอุปกรณ์เรียบร้อยแล้ว",
+		"late":    now.After(reservation.EndTime),
+	})
+}
+
+// แจ้งผู้ยืมและผู้ดูแลเมื่อเลยกำหนดคืนอุปกรณ์ และแจ้งซ้ำทุก resourceOverdueRepeat จนกว่าจะคืน
+func notifyOverdueResources(db *gorm.DB) error {
+	now := time.Now()
+	var overdue []entity.ResourceReservation
+	if err := db.Preload("Resource").Preload("Activity").
+		Where("status = ? AND end_time < ?", "picked_up", now).
+		Where("overdue_notified_at IS NULL OR overdue_notified_at < ?", now.Add(-resourceOverdueRepeat)).
+		Find(&overdue).Error; err != nil {
+		return err
+	}
+	for _, r := range overdue {
+		message := fmt.Sprintf("⏰ %s จำนวน %d ของกิจกรรม '%s' เลยกำหนดคืนตั้งแต่ %s",
+			r.Resource.Name, r.Quantity, r.Activity.Title, formatThaiDateTime(r.EndTime))
+		notifyUsers(append([]uint{r.RequestedBy}, resourceManagerIDs(db, &r.Resource)...), message, "warning")
+		if err := db.Model(&entity.ResourceReservation{}).Where("id = ?", r.ID).
+			Update("overdue_notified_at", now).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,455 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// แก้ไขรีวิวได้ภายในระยะเวลานี้หลังจากเขียน
+const reviewEditWindow = 7 * 24 * time.Hour
+
+type ReviewInput struct {
+	Rating  int    `json:"rating" binding:"required"`
+	Comment string `json:"comment"`
+}
+
+// คะแนนเฉลี่ยและจำนวนรีวิวที่ไม่ถูกซ่อน
+type RatingSummary struct {
+	AverageRating float64 `json:"average_rating"`
+	ReviewCount   int64   `json:"review_count"`
+}
+
+func visibleReviews(db *gorm.DB) *gorm.DB {
+	return db.Where("activity_reviews.is_hidden = ?", false)
+}
+
+func activityRatingSummary(db *gorm.DB, activityID uint) RatingSummary {
+	var summary RatingSummary
+	db.Model(&entity.ActivityReview{}).Scopes(visibleReviews).
+		Where("activity_id = ?", activityID).
+		Select("COALESCE(AVG(rating), 0) AS average_rating, COUNT(*) AS review_count").
+		Scan(&summary)
+	summary.AverageRating = roundRating(summary.AverageRating)
+	return summary
+}
+
+// คะแนนรวมของทุกกิจกรรมที่ชมรมเป็นผู้จัดหลักหรือผู้ร่วมจัด
+func clubRatingSummary(db *gorm.DB, clubID uint) RatingSummary {
+	var summary RatingSummary
+	hosted := db.Session(&gorm.Session{NewDB: true}).Model(&entity.Activity{}).
+		Select("activities.id").Scopes(hostedByClub(clubID))
+	db.Model(&entity.ActivityReview{}).Scopes(visibleReviews).
+		Where("activity_id IN (?)", hosted).
+		Select("COALESCE(AVG(rating), 0) AS average_rating, COUNT(*) AS review_count").
+		Scan(&summary)
+	summary.AverageRating = roundRating(summary.AverageRating)
+	return summary
+}
+
+func roundRating(v float64) float64 {
+	return float64(int(v*100+0.5)) / 100
+}
+
+func validateReviewInput(input *ReviewInput) error {
+	if input.Rating < 1 || input.Rating > 5 {
+		return errors.New("คะแนนต้องอยู่ระหว่าง 1 ถึง 5")
+	}
+	input.Comment = strings.TrimSpace(input.Comment)
+	if len([]rune(input.Comment)) > 2000 {
+		return errors.New("ความคิดเห็นยาวเกิน 2000 ตัวอักษร")
+	}
+	return nil
+}
+
+// ตรวจว่าผู้ใช้รีวิวกิจกรรมได้หรือไม่: ต้องเข้าร่วม (attended) และกิจกรรมจบแล้ว
+func checkReviewEligibility(db *gorm.DB, activity *entity.Activity, userID uint) error {
+	if time.Now().Before(activity.DateEnd) {
+		return errors.New("รีวิวได้หลังกิจกรรมสิ้นสุดแล้วเท่านั้น")
+	}
+	attendedID, err := getRegistrationStatusID(db, "attended")
+	if err != nil {
+		return err
+	}
+	var count int64
+	db.Model(&entity.ActivityRegistration{}).
+		Where("activity_id = ? AND user_id = ? AND status_id = ?", activity.ID, userID, attendedID).
+		Count(&count)
+	if count == 0 {
+		return errors.New("เฉพาะผู้ที่เข้าร่วมกิจกรรมเท่านั้นที่รีวิวได้")
+	}
+	return nil
+}
+
+// โหลดรีวิวของตัวเองจาก :id ที่ยังไม่ถูกซ่อน
+func loadOwnReview(c *gin.Context) (*entity.ActivityReview, *entity.User, bool) {
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+	var review entity.ActivityReview
+	if err := config.DB().First(&review, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบรีวิว"})
+		return nil, nil, false
+	}
+	if review.UserID != user.ID && !isAdmin(config.DB(), user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "แก้ไขได้เฉพาะรีวิวของตัวเอง"})
+		return nil, nil, false
+	}
+	// รีวิวที่ถูกซ่อนต้องคงไว้เป็นหลักฐานการกลั่นกรอง ห้ามแก้หรือลบเพื่อหลบการซ่อน
+	if review.IsHidden {
+		c.JSON(http.StatusForbidden, gin.H{"error": "รีวิวนี้ถูกซ่อนโดยผู้ดูแลระบบ ไม่สามารถแก้ไขหรือลบได้"})
+		return nil, nil, false
+	}
+	return &review, user, true
+}
+
+// GET /activities/:id/reviews - รีวิวที่แสดงได้ของกิจกรรม พร้อมคะแนนเฉลี่ยและการกระจายคะแนน
+func GetActivityReviews(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+
+	var reviews []entity.ActivityReview
+	if err := db.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, first_name, last_name, profile_image")
+	}).Scopes(visibleReviews).Where("activity_id = ?", activity.ID).
+		Order("created_at DESC").Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงรีวิวได้"})
+		return
+	}
+
+	distribution := map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}
+	data := make([]gin.H, 0, len(reviews))
+	for _, r := range reviews {
+		distribution[r.Rating]++
+		data = append(data, gin.H{
+			"id":         r.ID,
+			"rating":     r.Rating,
+			"comment":    r.Comment,
+			"created_at": r.CreatedAt,
+			"updated_at": r.UpdatedAt,
+			"user": gin.H{
+				"id":            r.User.ID,
+				"first_name":    r.User.FirstName,
+				"last_name":     r.User.LastName,
+				"profile_image": r.User.ProfileImage,
+			},
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"data":         data,
+		"summary":      activityRatingSummary(db, activity.ID),
+		"distribution": distribution,
+	})
+}
+
+// GET /activities/:id/reviews/me - รีวิวของตัวเองและสิทธิ์ในการรีวิว/แก้ไข
+func GetMyActivityReview(c *gin.Context) {
+	db := config.DB()
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+
+	var review entity.ActivityReview
+	if err := db.Where("activity_id = ? AND user_id = ?", activity.ID, user.ID).First(&review).Error; err == nil {
+		editableUntil := review.CreatedAt.Add(reviewEditWindow)
+		c.JSON(http.StatusOK, gin.H{
+			"success":        true,
+			"data":           review,
+			"can_review":     false,
+			"can_edit":       time.Now().Before(editableUntil),
+			"editable_until": editableUntil,
+		})
+		return
+	}
+
+	response := gin.H{"success": true, "data": nil, "can_review": true, "can_edit": false}
+	if err := checkReviewEligibility(db, &activity, user.ID); err != nil {
+		response["can_review"] = false
+		response["reason"] = err.Error()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// POST /activities/:id/reviews - เขียนรีวิว (ผู้เข้าร่วมที่ attended หลังกิจกรรมจบ ครั้งเดียวต่อกิจกรรม)
+func CreateActivityReview(c *gin.Context) {
+	db := config.DB()
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+
+	var input ReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	if err := validateReviewInput(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := checkReviewEligibility(db, &activity, user.ID); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	var count int64
+	db.Model(&entity.ActivityReview{}).Where("activity_id = ? AND user_id = ?", activity.ID, user.ID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "คุณรีวิวกิจกรรมนี้แล้ว"})
+		return
+	}
+
+	review := entity.ActivityReview{
+		ActivityID: activity.ID,
+		UserID:     user.ID,
+		Rating:     input.Rating,
+		Comment:    input.Comment,
+		CreatedAt:  time.Now(),
+	}
+	if err := db.Omit("User", "Reports").Create(&review).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกรีวิวไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"success":        true,
+		"message":        "บันทึกรีวิวเรียบร้อยแล้ว",
+		"data":           review,
+		"editable_until": review.CreatedAt.Add(reviewEditWindow),
+	})
+}
+
+// PUT /reviews/:id - แก้ไขรีวิวของตัวเองภายใน reviewEditWindow
+func UpdateActivityReview(c *gin.Context) {
+	review, user, ok := loadOwnReview(c)
+	if !ok {
+		return
+	}
+	if review.UserID != user.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "แก้ไขได้เฉพาะรีวิวของตัวเอง"})
+		return
+	}
+	if time.Now().After(review.CreatedAt.Add(reviewEditWindow)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "เลยระยะเวลาที่แก้ไขรีวิวได้แล้ว"})
+		return
+	}
+
+	var input ReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	if err := validateReviewInput(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := config.DB().Model(&entity.ActivityReview{}).Where("id = ?", review.ID).Updates(map[string]interface{}{
+		"rating":  input.Rating,
+		"comment": input.Comment,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "แก้ไขรีวิวไม่สำเร็จ"})
+		return
+	}
+	review.Rating, review.Comment = input.Rating, input.Comment
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "แก้ไขรีวิวเรียบร้อยแล้ว", "data": review})
+}
+
+// DELETE /reviews/:id - ลบรีวิวของตัวเอง (หรือผู้ดูแลระบบ)
+func DeleteActivityReview(c *gin.Context) {
+	review, _, ok := loadOwnReview(c)
+	if !ok {
+		return
+	}
+	err := config.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("review_id = ?", review.ID).Delete(&entity.ReviewReport{}).Error; err != nil {
+			return err
+		}
+		// ลบถาวรเพราะ (activity_id, user_id) เป็น unique index
+		return tx.Unscoped().Delete(&entity.ActivityReview{}, review.ID).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ลบรีวิวไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ลบรีวิวเรียบร้อยแล้ว"})
+}
+
+// POST /reviews/:id/report - รายงานรีวิวที่ไม่เหมาะสม
+func ReportActivityReview(c *gin.Context) {
+	db := config.DB()
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	var review entity.ActivityReview
+	if err := db.Scopes(visibleReviews).First(&review, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบรีวิว"})
+		return
+	}
+	if review.UserID == user.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่สามารถรายงานรีวิวของตัวเองได้"})
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุเหตุผลในการรายงาน"})
+		return
+	}
+
+	var count int64
+	db.Model(&entity.ReviewReport{}).Where("review_id = ? AND reporter_id = ?", review.ID, user.ID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "คุณรายงานรีวิวนี้แล้ว"})
+		return
+	}
+	report := entity.ReviewReport{
+		ReviewID:   review.ID,
+		ReporterID: user.ID,
+		Reason:     strings.TrimSpace(input.Reason),
+		Status:     "pending",
+	}
+	if err := db.Omit("Reporter").Create(&report).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "รายงานรีวิวไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "ส่งรายงานให้ผู้ดูแลตรวจสอบแล้ว"})
+}
+
+// GET /reviews/moderation?status=pending - คิวรีวิวที่ถูกรายงาน (admin เท่านั้น)
+// status: pending (มีรายงานรอตรวจ), hidden (ถูกซ่อนแล้ว)
+func GetReviewModerationQueue(c *gin.Context) {
+	if _, err := requireAdmin(c); err != nil {
+		return
+	}
+	db := config.DB()
+
+	query := db.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, first_name, last_name, email")
+	})
+	switch c.DefaultQuery("status", "pending") {
+	case "hidden":
+		query = query.Where("is_hidden = ?", true).
+			Preload("Reports").Preload("Reports.Reporter", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, first_name, last_name")
+		})
+	default:
+		pending := db.Session(&gorm.Session{NewDB: true}).Model(&entity.ReviewReport{}).
+			Select("review_id").Where("status = ?", "pending")
+		query = query.Where("id IN (?)", pending).
+			Preload("Reports", "status = ?", "pending").Preload("Reports.Reporter", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, first_name, last_name")
+		})
+	}
+
+	var reviews []entity.ActivityReview
+	if err := query.Order("updated_at ASC").Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงคิวตรวจสอบได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": reviews, "total": len(reviews)})
+}
+
+// POST /reviews/:id/moderate - ผลการตรวจสอบ (admin เท่านั้น)
+// action: hide (ซ่อนรีวิวและรับรายงาน), dismiss (ยกรายงาน), restore (แสดงรีวิวที่ถูกซ่อนอีกครั้ง)
+func ModerateActivityReview(c *gin.Context) {
+	admin, err := requireAdmin(c)
+	if err != nil {
+		return
+	}
+	db := config.DB()
+
+	var review entity.ActivityReview
+	if err := db.Preload("User").First(&review, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบรีวิว"})
+		return
+	}
+	var input struct {
+		Action string `json:"action" binding:"required"`
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	reason := strings.TrimSpace(input.Reason)
+
+	now := time.Now()
+	reviewUpdates := map[string]interface{}{"moderated_by": admin.ID, "moderated_at": now}
+	reportStatus := ""
+	switch input.Action {
+	case "hide":
+		if reason == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุเหตุผลที่ซ่อนรีวิว"})
+			return
+		}
+		reviewUpdates["is_hidden"] = true
+		reviewUpdates["hidden_reason"] = reason
+		reportStatus = "upheld"
+	case "dismiss":
+		reportStatus = "dismissed"
+	case "restore":
+		reviewUpdates["is_hidden"] = false
+		reviewUpdates["hidden_reason"] = ""
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action ต้องเป็น hide, dismiss หรือ restore"})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.ActivityReview{}).Where("id = ?", review.ID).Updates(reviewUpdates).Error; err != nil {
+			return err
+		}
+		if reportStatus == "" {
+			return nil
+		}
+		return tx.Model(&entity.ReviewReport{}).Where("review_id = ? AND status = ?", review.ID, "pending").
+			Updates(map[string]interface{}{"status": reportStatus, "resolved_by": admin.ID, "resolved_at": now}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกผลการตรวจสอบไม่สำเร็จ"})
+		return
+	}
+
+	if input.Action == "hide" {
+		var activity entity.Activity
+		db.Select("id, title").First(&activity, review.ActivityID)
+		message := fmt.Sprintf("รีวิวของคุณในกิจกรรม '%s' ถูกซ่อนโดยผู้ดูแลระบบ เนื่องจาก %s", activity.Title, reason)
+		if err := getNotificationService().CreateNotification(review.UserID, message, "warning"); err != nil {
+			fmt.Println("❌ Error creating review moderation notification:", err)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "บันทึกผลการตรวจสอบเรียบร้อยแล้ว"})
+}
@@ -0,0 +1,478 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ฟิลด์ของกิจกรรมที่เก็บประวัติ
+type activityRevisionData struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Location    string    `json:"location"`
+	DateStart   time.Time `json:"date_start"`
+	DateEnd     time.Time `json:"date_end"`
+	Capacity    int       `json:"capacity"`
+	PosterImage string    `json:"poster_image"`
+	CategoryID  uint      `json:"category_id"`
+	StatusID    uint      `json:"status_id"`
+	VenueID     *uint     `json:"venue_id"`
+}
+
+// ฟิลด์ของชมรมที่เก็บประวัติ
+type clubRevisionData struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	LogoImage   string `json:"logo_image"`
+	CategoryID  uint   `json:"category_id"`
+	StatusID    uint   `json:"status_id"`
+}
+
+// สถานะไม่ถูกย้อนกลับ เพราะต้องเปลี่ยนผ่านสายการอนุมัติเท่านั้น
+var nonRevertibleFields = []string{"status_id"}
+
+type FieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from"`
+	To    json.RawMessage `json:"to"`
+}
+
+type RevisionResponse struct {
+	ID            uint      `json:"id"`
+	Version       int       `json:"version"`
+	Action        string    `json:"action"`
+	RevertedFrom  *int      `json:"reverted_from,omitempty"`
+	ChangedFields []string  `json:"changed_fields"`
+	EditedBy      uint      `json:"edited_by"`
+	EditorName    string    `json:"editor_name"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func activityRevisionOf(a *entity.Activity) activityRevisionData {
+	return activityRevisionData{
+		Title:       a.Title,
+		Description: a.Description,
+		Location:    a.Location,
+		DateStart:   a.DateStart.UTC(),
+		DateEnd:     a.DateEnd.UTC(),
+		Capacity:    a.Capacity,
+		PosterImage: a.PosterImage,
+		CategoryID:  a.CategoryID,
+		StatusID:    a.StatusID,
+		VenueID:     a.VenueID,
+	}
+}
+
+func clubRevisionOf(c *entity.Club) clubRevisionData {
+	return clubRevisionData{
+		Name:        c.Name,
+		Description: c.Description,
+		LogoImage:   c.LogoImage,
+		CategoryID:  c.CategoryID,
+		StatusID:    c.StatusID,
+	}
+}
+
+// เปรียบเทียบ snapshot สองชุดรายฟิลด์ เรียงตามชื่อฟิลด์
+func diffSnapshots(from, to string) ([]FieldChange, error) {
+	var a, b map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(from), &a); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(to), &b); err != nil {
+		return nil, err
+	}
+	fields := make([]string, 0, len(b))
+	for field := range b {
+		fields = append(fields, field)
+	}
+	for field := range a {
+		if _, ok := b[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	changes := []FieldChange{}
+	for _, field := range fields {
+		if !bytes.Equal(a[field], b[field]) {
+			changes = append(changes, FieldChange{Field: field, From: a[field], To: b[field]})
+		}
+	}
+	return changes, nil
+}
+
+func latestRevision(tx *gorm.DB, entityType string, entityID uint) (*entity.Revision, error) {
+	var rev entity.Revision
+	err := tx.Where("entity_type = ? AND entity_id = ?", entityType, entityID).Order("version DESC").First(&rev).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &rev, err
+}
+
+// บันทึกเวอร์ชันใหม่ ถ้ายังไม่มีประวัติจะเก็บข้อมูลก่อนแก้ไข (before) เป็นเวอร์ชันแรกด้วย
+// ถ้า before ไม่ตรงกับเวอร์ชันล่าสุด แปลว่าข้อมูลถูกเปลี่ยนจากทางอื่น (เลื่อน/ยกเลิก ผลการอนุมัติ ซีรีส์ session รูปภาพ สถานะชมรม)
+// จะบันทึก before เป็นเวอร์ชันของระบบก่อน เพื่อไม่ให้การเปลี่ยนแปลงนั้นถูกนับเป็นของผู้แก้ไขคนนี้
+// การแก้ไขที่ไม่มีฟิลด์ใดเปลี่ยนจะไม่สร้างเวอร์ชันใหม่
+func recordRevision(tx *gorm.DB, entityType string, entityID uint, before, after interface{}, editedBy uint, action string, revertedFrom *int) error {
+	latest, err := latestRevision(tx, entityType, entityID)
+	if err != nil {
+		return err
+	}
+	beforeRaw, err := json.Marshal(before)
+	if err != nil {
+		return err
+	}
+	if latest == nil {
+		latest = &entity.Revision{
+			EntityType:    entityType,
+			EntityID:      entityID,
+			Version:       1,
+			Snapshot:      string(beforeRaw),
+			ChangedFields: "[]",
+			Action:        "initial",
+		}
+		if err := tx.Create(latest).Error; err != nil {
+			return err
+		}
+	} else if latest, err = appendRevision(tx, latest, string(beforeRaw), 0, "system", nil); err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(after)
+	if err != nil {
+		return err
+	}
+	_, err = appendRevision(tx, latest, string(raw), editedBy, action, revertedFrom)
+	return err
+}
+
+// เพิ่มเวอร์ชันต่อจาก latest ถ้า snapshot ต่างจากเดิม คืนเวอร์ชันล่าสุดหลังบันทึก
+func appendRevision(tx *gorm.DB, latest *entity.Revision, snapshot string, editedBy uint, action string, revertedFrom *int) (*entity.Revision, error) {
+	changes, err := diffSnapshots(latest.Snapshot, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return latest, nil
+	}
+	fields := make([]string, 0, len(changes))
+	for _, ch := range changes {
+		fields = append(fields, ch.Field)
+	}
+	changed, _ := json.Marshal(fields)
+	rev := &entity.Revision{
+		EntityType:    latest.EntityType,
+		EntityID:      latest.EntityID,
+		Version:       latest.Version + 1,
+		Snapshot:      snapshot,
+		ChangedFields: string(changed),
+		Action:        action,
+		RevertedFrom:  revertedFrom,
+		EditedBy:      editedBy,
+	}
+	if err := tx.Create(rev).Error; err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
+
+func findRevision(db *gorm.DB, entityType string, entityID uint, version string) (*entity.Revision, error) {
+	v, err := strconv.Atoi(version)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	var rev entity.Revision
+	if err := db.Where("entity_type = ? AND entity_id = ? AND version = ?", entityType, entityID, v).First(&rev).Error; err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+func listRevisions(c *gin.Context, entityType string, entityID uint) {
+	db := config.DB()
+	var revisions []entity.Revision
+	if err := db.Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("version DESC").Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงประวัติการแก้ไขได้"})
+		return
+	}
+
+	editorIDs := []uint{}
+	for _, r := range revisions {
+		if r.EditedBy != 0 {
+			editorIDs = append(editorIDs, r.EditedBy)
+		}
+	}
+	var editors []entity.User
+	if len(editorIDs) > 0 {
+		db.Select("id, first_name, last_name").Where("id IN ?", editorIDs).Find(&editors)
+	}
+	names := map[uint]string{}
+	for _, u := range editors {
+		names[u.ID] = strings.TrimSpace(u.FirstName + " " + u.LastName)
+	}
+
+	data := make([]RevisionResponse, 0, len(revisions))
+	for _, r := range revisions {
+		fields := []string{}
+		_ = json.Unmarshal([]byte(r.ChangedFields), &fields)
+		data = append(data, RevisionResponse{
+			ID:            r.ID,
+			Version:       r.Version,
+			Action:        r.Action,
+			RevertedFrom:  r.RevertedFrom,
+			ChangedFields: fields,
+			EditedBy:      r.EditedBy,
+			EditorName:    names[r.EditedBy],
+			CreatedAt:     r.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": data})
+}
+
+// ?from=&to= ถ้าไม่ระบุ to จะใช้เวอร์ชันล่าสุด และ from จะเป็นเวอร์ชันก่อนหน้า to
+func diffRevisions(c *gin.Context, entityType string, entityID uint) {
+	db := config.DB()
+
+	var to *entity.Revision
+	var err error
+	if c.Query("to") != "" {
+		to, err = findRevision(db, entityType, entityID, c.Query("to"))
+	} else if to, err = latestRevision(db, entityType, entityID); err == nil && to == nil {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบเวอร์ชันที่ต้องการ"})
+		return
+	}
+	fromVersion := c.DefaultQuery("from", strconv.Itoa(to.Version-1))
+	from, err := findRevision(db, entityType, entityID, fromVersion)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบเวอร์ชันที่ต้องการ"})
+		return
+	}
+
+	changes, err := diffSnapshots(from.Snapshot, to.Snapshot)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถเปรียบเทียบเวอร์ชันได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "from": from.Version, "to": to.Version, "changes": changes})
+}
+
+// GET /activities/:id/revisions - ประวัติการแก้ไขกิจกรรม (officer ของชมรมผู้จัด)
+func GetActivityRevisions(c *gin.Context) {
+	var activity entity.Activity
+	if err := config.DB().First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	if _, err := requireActivityOfficer(c, &activity); err != nil {
+		return
+	}
+	listRevisions(c, "activity", activity.ID)
+}
+
+// GET /activities/:id/revisions/diff?from=&to= - เปรียบเทียบสองเวอร์ชันรายฟิลด์
+func DiffActivityRevisions(c *gin.Context) {
+	var activity entity.Activity
+	if err := config.DB().First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	if _, err := requireActivityOfficer(c, &activity); err != nil {
+		return
+	}
+	diffRevisions(c, "activity", activity.ID)
+}
+
+// POST /activities/:id/revisions/:version/revert - ย้อนข้อมูลกิจกรรมกลับไปเป็นเวอร์ชันที่เลือก
+// สถานะไม่ถูกย้อนกลับ วันเวลาต้องตรงกับปัจจุบัน (เลื่อนผ่าน reschedule ก่อน) และการย้อนกลับจะถูกบันทึกเป็นเวอร์ชันใหม่
+func RevertActivityRevision(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	user, err := requireActivityOfficer(c, &activity)
+	if err != nil {
+		return
+	}
+	rev, err := findRevision(db, "activity", activity.ID, c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบเวอร์ชันที่ต้องการ"})
+		return
+	}
+	var data activityRevisionData
+	if err := json.Unmarshal([]byte(rev.Snapshot), &data); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ข้อมูลเวอร์ชันเสียหาย"})
+		return
+	}
+
+	// วันเวลาต้องเปลี่ยนผ่านการเลื่อนกิจกรรม เพื่อแจ้งผู้ลงทะเบียนและปรับ session การจองให้ตรงกัน
+	if !data.DateStart.Equal(activity.DateStart) || !data.DateEnd.Equal(activity.DateEnd) {
+		c.JSON(http.StatusConflict, gin.H{"error": "เวอร์ชันนี้มีวันเวลาต่างจากปัจจุบัน กรุณาเลื่อนกิจกรรมผ่าน /activities/:id/reschedule ก่อนย้อนกลับ"})
+		return
+	}
+
+	before := activityRevisionOf(&activity)
+	activity.Title = data.Title
+	activity.Description = data.Description
+	activity.Location = data.Location
+	activity.Capacity = data.Capacity
+	activity.PosterImage = data.PosterImage
+	activity.CategoryID = data.CategoryID
+	activity.VenueID = data.VenueID
+
+	// ย้อนกลับเนื้อหาของกิจกรรมที่อนุมัติแล้ว ต้องกลับไปขออนุมัติใหม่เช่นเดียวกับการแก้ไขปกติ
+	reapproval, err := restartApprovalIfChanged(db, &activity, before, user.ID)
+	if err != nil {
+		respondClubStateError(c, err)
+		return
+	}
+	updates := map[string]interface{}{
+		"title":             activity.Title,
+		"description":       activity.Description,
+		"location":          activity.Location,
+		"capacity":          activity.Capacity,
+		"poster_image":      activity.PosterImage,
+		"category_id":       activity.CategoryID,
+		"venue_id":          activity.VenueID,
+		"calendar_sequence": gorm.Expr("calendar_sequence + 1"),
+	}
+	if reapproval {
+		updates["status_id"] = activity.StatusID
+		updates["approval_step"] = activity.ApprovalStep
+		updates["approval_round"] = activity.ApprovalRound
+		updates["submitted_by"] = activity.SubmittedBy
+		updates["approved_at"] = activity.ApprovedAt
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.Activity{}).Where("id = ?", activity.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := bookActivityVenue(tx, &activity, user.ID); err != nil {
+			return err
+		}
+		return recordRevision(tx, "activity", activity.ID, before, activityRevisionOf(&activity), user.ID, "revert", &rev.Version)
+	}); err != nil {
+		if respondVenueError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ย้อนกลับเวอร์ชันไม่สำเร็จ"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"message":        fmt.Sprintf("ย้อนกลับเป็นเวอร์ชัน %d เรียบร้อยแล้ว", rev.Version),
+		"data":           activity,
+		"reapproval":     reapproval,
+		"skipped_fields": nonRevertibleFields,
+	})
+}
+
+// GET /clubs/:id/revisions - ประวัติการแก้ไขข้อมูลชมรม (สมาชิกที่มีสิทธิ์จัดการอย่างใดอย่างหนึ่ง)
+func GetClubRevisions(c *gin.Context) {
+	var club entity.Club
+	if err := config.DB().First(&club, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	if _, err := requireClubPermission(c, club.ID, clubPermissions...); err != nil {
+		return
+	}
+	listRevisions(c, "club", club.ID)
+}
+
+// GET /clubs/:id/revisions/diff?from=&to= - เปรียบเทียบสองเวอร์ชันรายฟิลด์
+func DiffClubRevisions(c *gin.Context) {
+	var club entity.Club
+	if err := config.DB().First(&club, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	if _, err := requireClubPermission(c, club.ID, clubPermissions...); err != nil {
+		return
+	}
+	diffRevisions(c, "club", club.ID)
+}
+
+// POST /clubs/:id/revisions/:version/revert - ย้อนข้อมูลชมรมกลับไปเป็นเวอร์ชันที่เลือก (หัวหน้าชมรมเท่านั้น)
+// สถานะชมรมไม่ถูกย้อนกลับ
+func RevertClubRevision(c *gin.Context) {
+	db := config.DB()
+
+	var club entity.Club
+	if err := db.First(&club, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบชมรม"})
+		return
+	}
+	user, err := requirePresident(c, club.ID)
+	if err != nil {
+		return
+	}
+	rev, err := findRevision(db, "club", club.ID, c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบเวอร์ชันที่ต้องการ"})
+		return
+	}
+	var data clubRevisionData
+	if err := json.Unmarshal([]byte(rev.Snapshot), &data); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ข้อมูลเวอร์ชันเสียหาย"})
+		return
+	}
+
+	var count int64
+	db.Model(&entity.Club{}).Where("name = ? AND id <> ?", data.Name, club.ID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "มีชมรมอื่นใช้ชื่อนี้แล้ว"})
+		return
+	}
+
+	before := clubRevisionOf(&club)
+	club.Name = data.Name
+	club.Description = data.Description
+	club.LogoImage = data.LogoImage
+	club.CategoryID = data.CategoryID
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.Club{}).Where("id = ?", club.ID).Updates(map[string]interface{}{
+			"name":        club.Name,
+			"description": club.Description,
+			"logo_image":  club.LogoImage,
+			"category_id": club.CategoryID,
+		}).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, "club", club.ID, before, clubRevisionOf(&club), user.ID, "revert", &rev.Version)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ย้อนกลับเวอร์ชันไม่สำเร็จ"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"message":        fmt.Sprintf("ย้อนกลับเป็นเวอร์ชัน %d เรียบร้อยแล้ว", rev.Version),
+		"data":           club,
+		"skipped_fields": nonRevertibleFields,
+	})
+}
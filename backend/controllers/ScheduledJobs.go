@@ -0,0 +1,248 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+	"final-project/cems/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var jobScheduler *services.Scheduler
+
+// StartScheduler ลงทะเบียนงานเบื้องหลังทั้งหมดแล้วเริ่มรัน
+func StartScheduler(db *gorm.DB) *services.Scheduler {
+	jobScheduler = services.NewScheduler(db)
+
+	jobs := []struct {
+		name        string
+		description string
+		cronExpr    string
+		maxRetries  int
+		run         services.JobFunc
+	}{
+		{"finish_activities", "เปลี่ยนสถานะกิจกรรมที่เลยเวลาสิ้นสุดเป็น finished", "*/5 * * * *", 2, finishEndedActivities},
+		{"purge_password_resets", "ลบ token รีเซ็ตรหัสผ่านที่หมดอายุหรือใช้แล้ว", "0 3 * * *", 2, purgeExpiredPasswordResets},
+		{"activity_reminders", "แจ้งเตือนผู้ลงทะเบียนก่อนกิจกรรมเริ่มตาม reminder rules", "*/5 * * * *", 1, sendScheduledReminders},
+		{"release_unconfirmed_registrations", "ยกเลิกการลงทะเบียนที่ไม่ยืนยันภายในกำหนดหลังกิจกรรมถูกเลื่อน", "*/10 * * * *", 1, releaseUnconfirmedRegistrations},
+		{"survey_dispatch", "ส่งแบบสอบถามหลังกิจกรรมและเตือนผู้ที่ยังไม่ตอบ", "*/15 * * * *", 1, dispatchSurveys},
+		{"resource_overdue", "แจ้งเตือนอุปกรณ์ที่เลยกำหนดคืน", "0 * * * *", 1, notifyOverdueResources},
+		{"officer_handover", "ส่งต่อกรรมการชมรมชุดใหม่ที่ตั้งเวลาไว้", "*/15 * * * *", 1, applyDueHandovers},
+		{"suspend_unrenewed_clubs", "ระงับชมรมที่ไม่ยื่นต่ออายุภายในกำหนด", "0 1 * * *", 1, suspendOverdueClubs},
+		{"cleanup_orphan_images", "ลบรูปที่อัปโหลดแล้วไม่มีข้อมูลใดอ้างถึง", "30 3 * * *", 1, cleanupOrphanImages},
+		{"monthly_reports", "สร้างรายงานประจำเดือนของเดือนที่แล้ว", "0 2 1 * *", 3, generateMonthlyReports},
+	}
+	for _, j := range jobs {
+		if err := jobScheduler.Register(j.name, j.description, j.cronExpr, j.maxRetries, j.run); err != nil {
+			fmt.Println("❌ Error registering job", j.name+":", err)
+		}
+	}
+
+	jobScheduler.Start()
+	return jobScheduler
+}
+
+// กิจกรรมที่อนุมัติแล้วและเลย DateEnd ไปแล้วถือว่าจบ
+func finishEndedActivities(db *gorm.DB) error {
+	approved, err := getActivityStatusByName(db, "approved")
+	if err != nil {
+		return err
+	}
+	finished, err := getActivityStatusByName(db, "finished")
+	if err != nil {
+		return err
+	}
+
+	var ended []entity.Activity
+	if err := db.Where("status_id = ? AND date_end < ?", approved.ID, time.Now()).Find(&ended).Error; err != nil {
+		return err
+	}
+	for i := range ended {
+		// เปลี่ยนสถานะและสรุปผลรอบใน transaction เดียว ถ้าสรุปไม่สำเร็จกิจกรรมจะยังเป็น approved ให้รอบถัดไปทำใหม่
+		err := db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&entity.Activity{}).Where("id = ? AND status_id = ?", ended[i].ID, approved.ID).Update("status_id", finished.ID)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+
+			// กิจกรรมที่มีหลายรอบ สรุปผลผ่านเกณฑ์และชั่วโมงให้อัตโนมัติในนามผู้ส่งกิจกรรม
+			var sessionCount int64
+			tx.Model(&entity.ActivitySession{}).Where("activity_id = ?", ended[i].ID).Count(&sessionCount)
+			if sessionCount > 0 {
+				if _, err := finalizeSessionAttendance(tx, &ended[i], ended[i].SubmittedBy); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func purgeExpiredPasswordResets(db *gorm.DB) error {
+	return db.Unscoped().
+		Where("expired_at < ? OR used = ?", time.Now(), true).
+		Delete(&entity.PasswordReset{}).Error
+}
+
+// รายงานสรุปกิจกรรม อันดับชมรม และผลการดำเนินงานของทุกชมรมที่อนุมัติแล้ว สำหรับเดือนที่ผ่านมา
+func generateMonthlyReports(db *gorm.DB) error {
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, now.Location())
+	end := start.AddDate(0, 1, -1)
+
+	// รายงานเก็บในชื่อของผู้ดูแลระบบคนแรก
+	var admin entity.User
+	if err := db.Joins("JOIN roles ON roles.id = users.role_id").
+		Where("roles.role_name = ?", "admin").
+		Order("users.id ASC").
+		First(&admin).Error; err != nil {
+		return fmt.Errorf("ไม่พบผู้ดูแลระบบสำหรับบันทึกรายงาน: %w", err)
+	}
+
+	requests := []ReportRequest{
+		{Type: "activity_summary"},
+		{Type: "club_ranking"},
+	}
+	var clubs []entity.Club
+	if err := db.Where("status_id IN (SELECT id FROM club_statuses WHERE name = 'approved')").
+		Find(&clubs).Error; err != nil {
+		return err
+	}
+	for _, club := range clubs {
+		clubID := club.ID
+		requests = append(requests, ReportRequest{Type: "club_performance", ClubID: &clubID, ClubName: club.Name})
+	}
+
+	h := &ReportHandler{DB: db}
+	var errs []error
+	for _, req := range requests {
+		req.Period = "custom"
+		req.StartDate = start.Format("2006-01-02")
+		req.EndDate = end.Format("2006-01-02")
+		req.UserID = admin.ID
+
+		reportDir := getReportDirectory(req.Type)
+		if err := os.MkdirAll(reportDir, 0o755); err != nil {
+			return err
+		}
+		filename := fmt.Sprintf("%s_%d_%s_monthly.pdf", req.Type, admin.ID, start.Format("200601"))
+		if req.ClubID != nil {
+			filename = fmt.Sprintf("%s_club%d_%s_monthly.pdf", req.Type, *req.ClubID, start.Format("200601"))
+		}
+		if err := h.GenerateReportByType(req, filepath.Join(reportDir, filename)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filename, err))
+			continue
+		}
+
+		report := entity.ActivityReport{
+			Name:        fmt.Sprintf("%s (%s)", getReportName(req.Type, req), start.Format("January 2006")),
+			UserID:      admin.ID,
+			Type:        req.Type,
+			FileURL:     filename,
+			GeneratedAt: time.Now(),
+			Status:      "completed",
+		}
+		// รันซ้ำ (retry หรือสั่งรันเอง) ให้ทับรายงานเดิมของเดือนเดียวกัน
+		if err := db.Where(entity.ActivityReport{Type: req.Type, FileURL: filename}).
+			Assign(report).
+			FirstOrCreate(&entity.ActivityReport{}).Error; err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// GET /jobs - รายการงานเบื้องหลัง (admin เท่านั้น)
+func GetScheduledJobs(c *gin.Context) {
+	if _, err := requireAdmin(c); err != nil {
+		return
+	}
+	var jobs []entity.ScheduledJob
+	if err := config.DB().Order("name ASC").Find(&jobs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงรายการงานได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": jobs})
+}
+
+// GET /jobs/:name/runs - ประวัติการรันของงาน
+func GetScheduledJobRuns(c *gin.Context) {
+	if _, err := requireAdmin(c); err != nil {
+		return
+	}
+	db := config.DB()
+	var job entity.ScheduledJob
+	if err := db.Where("name = ?", c.Param("name")).First(&job).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบงาน"})
+		return
+	}
+	var runs []entity.JobRun
+	if err := db.Where("job_id = ?", job.ID).
+		Order("started_at DESC").
+		Limit(100).
+		Find(&runs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงประวัติการรันได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": runs})
+}
+
+// POST /jobs/:name/trigger - สั่งรันงานทันที
+func TriggerScheduledJob(c *gin.Context) {
+	if _, err := requireAdmin(c); err != nil {
+		return
+	}
+	if jobScheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "scheduler ยังไม่ได้เริ่มทำงาน"})
+		return
+	}
+	job, err := jobScheduler.Trigger(c.Param("name"))
+	switch {
+	case errors.Is(err, services.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบงาน"})
+		return
+	case errors.Is(err, services.ErrJobLocked):
+		c.JSON(http.StatusConflict, gin.H{"error": "งานนี้กำลังรันอยู่"})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"success": true, "message": "สั่งรันงานแล้ว", "data": job})
+}
+
+// POST /jobs/:name/pause และ /jobs/:name/resume
+func PauseScheduledJob(c *gin.Context)  { setScheduledJobPaused(c, true) }
+func ResumeScheduledJob(c *gin.Context) { setScheduledJobPaused(c, false) }
+
+func setScheduledJobPaused(c *gin.Context, paused bool) {
+	if _, err := requireAdmin(c); err != nil {
+		return
+	}
+	if jobScheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "scheduler ยังไม่ได้เริ่มทำงาน"})
+		return
+	}
+	job, err := jobScheduler.SetPaused(c.Param("name"), paused)
+	if errors.Is(err, services.ErrJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบงาน"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถอัปเดตงานได้"})
+		return
+	}
+	message := "เปิดการทำงานตามตารางแล้ว"
+	if paused {
+		message = "หยุดการทำงานตามตารางแล้ว"
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": message, "data": job})
+}
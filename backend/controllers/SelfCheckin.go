@@ -0,0 +1,345 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+	"final-project/cems/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// จำกัดการกรอกรหัสผิด รหัสมี 6 หลักจึงต้องกันการเดาทั้งรายคนและราย IP (กันการใช้หลายบัญชีจากเครื่องเดียวช่วยกันเดา)
+// นับแยกตามกิจกรรม ผู้ที่พิมพ์ผิดจะไม่ทำให้ผู้เข้าร่วมคนอื่นถูกล็อกไปด้วย
+// เกณฑ์ราย IP สูงกว่ารายคนเพราะนักศึกษาทั้งห้องมักใช้ Wi-Fi ที่ออก IP เดียวกัน
+const (
+	checkinUserMaxFailures = 5
+	checkinIPMaxFailures   = 50
+	checkinLockout         = 10 * time.Minute
+)
+
+type CheckinSettingInput struct {
+	WindowStart  *time.Time `json:"window_start"`
+	WindowEnd    *time.Time `json:"window_end"`
+	Latitude     float64    `json:"latitude"`
+	Longitude    float64    `json:"longitude"`
+	RadiusMeters float64    `json:"radius_meters"`
+	CodeInterval int        `json:"code_interval"`
+}
+
+// GET /activities/:id/checkin-settings - ดูการตั้งค่าการเช็คชื่อ (officer เท่านั้น)
+func GetCheckinSetting(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	if _, err := requireActivityOfficer(c, &activity); err != nil {
+		return
+	}
+
+	var setting entity.ActivityCheckinSetting
+	if err := db.Where("activity_id = ?", activity.ID).First(&setting).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": true, "enabled": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "enabled": true, "data": setting})
+}
+
+// PUT /activities/:id/checkin-settings - เปิดการเช็คชื่อด้วยตนเอง (officer เท่านั้น)
+func UpdateCheckinSetting(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	if _, err := requireActivityOfficer(c, &activity); err != nil {
+		return
+	}
+
+	var input CheckinSettingInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+
+	// ค่าเริ่มต้น: เปิดเช็คชื่อก่อนเริ่ม 30 นาทีจนจบกิจกรรม
+	windowStart := activity.DateStart.Add(-30 * time.Minute)
+	windowEnd := activity.DateEnd
+	if input.WindowStart != nil {
+		windowStart = *input.WindowStart
+	}
+	if input.WindowEnd != nil {
+		windowEnd = *input.WindowEnd
+	}
+	if !windowEnd.After(windowStart) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ช่วงเวลาเช็คชื่อไม่ถูกต้อง"})
+		return
+	}
+	if input.RadiusMeters < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "radius_meters ต้องไม่ติดลบ"})
+		return
+	}
+	if input.RadiusMeters > 0 && (input.Latitude < -90 || input.Latitude > 90 || input.Longitude < -180 || input.Longitude > 180) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "พิกัดสถานที่ไม่ถูกต้อง"})
+		return
+	}
+	if input.CodeInterval == 0 {
+		input.CodeInterval = 30
+	}
+	if input.CodeInterval < 10 || input.CodeInterval > 300 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code_interval ต้องอยู่ระหว่าง 10-300 วินาที"})
+		return
+	}
+
+	var setting entity.ActivityCheckinSetting
+	db.Where("activity_id = ?", activity.ID).FirstOrInit(&setting)
+	if setting.Secret == "" {
+		secret, err := utils.GenerateSecureToken(20)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถสร้างรหัสลับได้"})
+			return
+		}
+		setting.Secret = secret
+	}
+	setting.ActivityID = activity.ID
+	setting.CodeInterval = input.CodeInterval
+	setting.WindowStart = windowStart
+	setting.WindowEnd = windowEnd
+	setting.Latitude = input.Latitude
+	setting.Longitude = input.Longitude
+	setting.RadiusMeters = input.RadiusMeters
+
+	if err := db.Save(&setting).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกการตั้งค่าไม่สำเร็จ"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "บันทึกการตั้งค่าสำเร็จ", "data": setting})
+}
+
+// GET /activities/:id/checkin-code - รหัสปัจจุบันสำหรับแสดงบนจอหน้างาน (officer เท่านั้น)
+func GetCheckinCode(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	if _, err := requireActivityOfficer(c, &activity); err != nil {
+		return
+	}
+
+	var setting entity.ActivityCheckinSetting
+	if err := db.Where("activity_id = ?", activity.ID).First(&setting).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "กิจกรรมนี้ยังไม่เปิดการเช็คชื่อด้วยตนเอง"})
+		return
+	}
+
+	now := time.Now()
+	interval := int64(setting.CodeInterval)
+	expiresAt := time.Unix((now.Unix()/interval+1)*interval, 0)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"code":        utils.GenerateRotatingCode(setting.Secret, now, setting.CodeInterval),
+		"expires_at":  expiresAt.Format(time.RFC3339),
+		"interval":    setting.CodeInterval,
+		"window_open": !now.Before(setting.WindowStart) && !now.After(setting.WindowEnd),
+	})
+}
+
+// POST /activities/:id/self-checkin - นักศึกษาเช็คชื่อด้วยรหัสจากจอหน้างาน
+func SelfCheckin(c *gin.Context) {
+	db := config.DB()
+
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input struct {
+		Code      string   `json:"code" binding:"required"`
+		Latitude  *float64 `json:"latitude"`
+		Longitude *float64 `json:"longitude"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณากรอกรหัสเช็คชื่อ"})
+		return
+	}
+
+	var setting entity.ActivityCheckinSetting
+	if err := db.Where("activity_id = ?", c.Param("id")).First(&setting).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "กิจกรรมนี้ไม่ได้เปิดการเช็คชื่อด้วยตนเอง"})
+		return
+	}
+
+	now := time.Now()
+	if now.Before(setting.WindowStart) || now.After(setting.WindowEnd) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ไม่อยู่ในช่วงเวลาเช็คชื่อ"})
+		return
+	}
+
+	registeredID, err := getRegistrationStatusID(db, "registered")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่พบสถานะ registered"})
+		return
+	}
+	attendedID, err := getRegistrationStatusID(db, "attended")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่พบสถานะ attended"})
+		return
+	}
+
+	var reg entity.ActivityRegistration
+	if err := db.Where("activity_id = ? AND user_id = ? AND status_id IN ?", setting.ActivityID, user.ID, []uint{registeredID, attendedID}).
+		First(&reg).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "คุณไม่ได้ลงทะเบียนกิจกรรมนี้"})
+		return
+	}
+
+	// กิจกรรมที่มีหลายรอบเช็คชื่อแยกตามรอบที่กำลังจัดอยู่
+	sessions, err := loadActivitySessions(db, setting.ActivityID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	var session *entity.ActivitySession
+	if len(sessions) > 0 {
+		session = currentActivitySession(sessions, now)
+		if session == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ขณะนี้ไม่มีรอบกิจกรรมที่เปิดเช็คชื่อ"})
+			return
+		}
+		var existing entity.SessionAttendance
+		if err := db.Where("session_id = ? AND registration_id = ? AND present = ?", session.ID, reg.ID, true).First(&existing).Error; err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "คุณเช็คชื่อรอบนี้แล้ว"})
+			return
+		}
+	} else {
+		var existing entity.AttendanceLog
+		if err := db.Where("registration_id = ?", reg.ID).First(&existing).Error; err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "คุณเช็คชื่อกิจกรรมนี้แล้ว"})
+			return
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+	}
+
+	if retryAfter, err := checkinLockedFor(db, setting.ActivityID, user.ID, c.ClientIP(), now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	} else if retryAfter > 0 {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       "กรอกรหัสผิดหลายครั้งเกินไป กรุณารอสักครู่แล้วลองใหม่",
+			"retry_after": int(retryAfter.Seconds()) + 1,
+		})
+		return
+	}
+
+	if !utils.VerifyRotatingCode(setting.Secret, strings.TrimSpace(input.Code), now, setting.CodeInterval) {
+		db.Create(&entity.CheckinAttempt{ActivityID: setting.ActivityID, UserID: user.ID, IPAddress: c.ClientIP(), AttemptedAt: now})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "รหัสเช็คชื่อไม่ถูกต้องหรือหมดอายุ"})
+		return
+	}
+
+	// พิกัดมาจากเครื่องของผู้ใช้และปลอมได้ การตรวจระยะจึงเป็นเพียงด่านเสริม
+	// สิ่งที่ยืนยันว่าอยู่หน้างานจริงคือรหัสหมุนเวียนที่แสดงบนจอ
+	if setting.RadiusMeters > 0 {
+		if input.Latitude == nil || input.Longitude == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "กิจกรรมนี้ต้องใช้ตำแหน่ง GPS ในการเช็คชื่อ"})
+			return
+		}
+		distance := utils.DistanceMeters(setting.Latitude, setting.Longitude, *input.Latitude, *input.Longitude)
+		if distance > setting.RadiusMeters {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":           "คุณอยู่นอกพื้นที่จัดกิจกรรม",
+				"distance_meters": int(distance),
+			})
+			return
+		}
+	}
+
+	log := entity.AttendanceLog{
+		RegistrationID: reg.ID,
+		CheckinTime:    now,
+		Method:         "self_checkin",
+		Latitude:       input.Latitude,
+		Longitude:      input.Longitude,
+	}
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&log).Error; err != nil {
+			return err
+		}
+		// รอบย่อยบันทึกแยก ผลผ่าน/ไม่ผ่านของทั้งกิจกรรมจะสรุปเมื่อกิจกรรมจบ
+		if session != nil {
+			return recordSessionAttendance(tx, session, reg.ID, true, "self_checkin", user.ID, now)
+		}
+		return tx.Model(&reg).Update("status_id", attendedID).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกการเช็คชื่อไม่สำเร็จ"})
+		return
+	}
+
+	response := gin.H{
+		"success":      true,
+		"message":      "เช็คชื่อสำเร็จ",
+		"checkin_time": now.Format(time.RFC3339),
+	}
+	if session != nil {
+		response["session"] = session.Title
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// เวลาที่ต้องรอก่อนกรอกรหัสได้อีก (0 = กรอกได้)
+// ผู้ใช้หรือ IP ที่กรอกผิดครบจำนวนในกิจกรรมนี้ถูกล็อกจนครั้งที่ผิดเก่าสุดในช่วงล็อกหมดอายุ
+func checkinLockedFor(db *gorm.DB, activityID, userID uint, ip string, now time.Time) (time.Duration, error) {
+	var wait time.Duration
+	for _, limit := range []struct {
+		column string
+		value  interface{}
+		max    int
+	}{
+		{"user_id", userID, checkinUserMaxFailures},
+		{"ip_address", ip, checkinIPMaxFailures},
+	} {
+		var failures []entity.CheckinAttempt
+		if err := db.Where("activity_id = ? AND attempted_at > ?", activityID, now.Add(-checkinLockout)).
+			Where(limit.column+" = ?", limit.value).
+			Order("attempted_at DESC").Limit(limit.max).Find(&failures).Error; err != nil {
+			return 0, err
+		}
+		if len(failures) >= limit.max {
+			wait = max(wait, failures[len(failures)-1].AttemptedAt.Add(checkinLockout).Sub(now))
+		}
+	}
+	return wait, nil
+}
+
+// เลื่อนช่วงเวลาเช็คชื่อตามวันเวลาใหม่ของกิจกรรม โดยคงระยะห่างจากเวลาเริ่มและเวลาสิ้นสุดไว้เท่าเดิม
+func shiftCheckinWindow(tx *gorm.DB, activityID uint, startOffset, endOffset time.Duration) error {
+	var setting entity.ActivityCheckinSetting
+	if err := tx.Where("activity_id = ?", activityID).First(&setting).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	return tx.Model(&setting).Updates(map[string]interface{}{
+		"window_start": setting.WindowStart.Add(startOffset),
+		"window_end":   setting.WindowEnd.Add(endOffset),
+	}).Error
+}
@@ -0,0 +1,736 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+	"final-project/cems/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var surveyQuestionTypes = []string{"likert", "choice", "text"}
+
+// คะแนน likert ใช้มาตรวัด 1-5
+var likertScale = []string{"1", "2", "3", "4", "5"}
+
+// เว้นระยะระหว่างการเตือนผู้ที่ยังไม่ตอบ
+const surveyReminderInterval = 48 * time.Hour
+
+type SurveyQuestionInput struct {
+	Label    string   `json:"label"`
+	Type     string   `json:"type"`
+	Options  []string `json:"options"`
+	Required bool     `json:"required"`
+}
+
+type SurveyInput struct {
+	Title            string                `json:"title" binding:"required"`
+	Description      string                `json:"description"`
+	IsAnonymous      bool                  `json:"is_anonymous"`
+	SendDelayMinutes *int                  `json:"send_delay_minutes"`
+	OpenDays         *int                  `json:"open_days"`
+	MaxReminders     *int                  `json:"max_reminders"`
+	Questions        []SurveyQuestionInput `json:"questions"`
+}
+
+type SurveyQuestionResponse struct {
+	ID        uint     `json:"id"`
+	Label     string   `json:"label"`
+	Type      string   `json:"type"`
+	Options   []string `json:"options"`
+	Required  bool     `json:"required"`
+	SortOrder int      `json:"sort_order"`
+}
+
+// ผลรวมรายคำถาม
+type SurveyQuestionResult struct {
+	QuestionID   uint           `json:"question_id"`
+	Label        string         `json:"label"`
+	Type         string         `json:"type"`
+	Responses    int            `json:"responses"`
+	Average      float64        `json:"average,omitempty"`
+	Distribution map[string]int `json:"distribution,omitempty"`
+	TextAnswers  []string       `json:"text_answers,omitempty"`
+}
+
+type SurveyResults struct {
+	SurveyID      uint                   `json:"survey_id"`
+	ActivityID    uint                   `json:"activity_id"`
+	ActivityTitle string                 `json:"activity_title"`
+	Invited       int64                  `json:"invited"`
+	Responded     int64                  `json:"responded"`
+	ResponseRate  float64                `json:"response_rate"`
+	Questions     []SurveyQuestionResult `json:"questions"`
+}
+
+func surveyQuestionOptions(q entity.SurveyQuestion) []string {
+	if q.Type == "likert" {
+		return likertScale
+	}
+	options := []string{}
+	if q.Options != "" {
+		_ = json.Unmarshal([]byte(q.Options), &options)
+	}
+	return options
+}
+
+func toSurveyQuestionResponse(q entity.SurveyQuestion) SurveyQuestionResponse {
+	return SurveyQuestionResponse{
+		ID:        q.ID,
+		Label:     q.Label,
+		Type:      q.Type,
+		Options:   surveyQuestionOptions(q),
+		Required:  q.Required,
+		SortOrder: q.SortOrder,
+	}
+}
+
+func loadActivitySurvey(db *gorm.DB, activityID uint) (*entity.Survey, error) {
+	var survey entity.Survey
+	if err := db.Preload("Questions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order ASC")
+	}).Where("activity_id = ?", activityID).First(&survey).Error; err != nil {
+		return nil, err
+	}
+	return &survey, nil
+}
+
+func surveyIsOpen(survey *entity.Survey, now time.Time) bool {
+	return survey.SentAt != nil && survey.ClosesAt != nil && now.Before(*survey.ClosesAt)
+}
+
+func validateSurveyInput(input SurveyInput) error {
+	if strings.TrimSpace(input.Title) == "" {
+		return errors.New("กรุณาระบุชื่อแบบสอบถาม")
+	}
+	if len(input.Questions) == 0 {
+		return errors.New("แบบสอบถามต้องมีคำถามอย่างน้อย 1 ข้อ")
+	}
+	for _, v := range []*int{input.SendDelayMinutes, input.MaxReminders} {
+		if v != nil && *v < 0 {
+			return errors.New("send_delay_minutes และ max_reminders ต้องไม่ติดลบ")
+		}
+	}
+	if input.OpenDays != nil && *input.OpenDays < 1 {
+		return errors.New("open_days ต้องมากกว่า 0")
+	}
+	for i, q := range input.Questions {
+		if strings.TrimSpace(q.Label) == "" {
+			return fmt.Errorf("คำถามข้อที่ %d ต้องมีหัวข้อ", i+1)
+		}
+		if !slices.Contains(surveyQuestionTypes, q.Type) {
+			return fmt.Errorf("คำถามข้อที่ %d มีประเภทไม่ถูกต้อง", i+1)
+		}
+		if q.Type == "choice" && len(q.Options) < 2 {
+			return fmt.Errorf("คำถามข้อที่ %d ต้องมีตัวเลือกอย่างน้อย 2 ตัวเลือก", i+1)
+		}
+	}
+	return nil
+}
+
+// ตรวจคำตอบตามคำถาม คืน error ข้อแรกที่พบ
+func validateSurveyAnswers(questions []entity.SurveyQuestion, answers map[uint]string) error {
+	for _, q := range questions {
+		value := strings.TrimSpace(answers[q.ID])
+		if value == "" {
+			if q.Required {
+				return fmt.Errorf("กรุณาตอบคำถาม '%s'", q.Label)
+			}
+			continue
+		}
+		switch q.Type {
+		case "likert", "choice":
+			if !slices.Contains(surveyQuestionOptions(q), value) {
+				return fmt.Errorf("คำตอบของ '%s' ไม่อยู่ในตัวเลือก", q.Label)
+			}
+		case "text":
+			if len([]rune(value)) > 2000 {
+				return fmt.Errorf("คำตอบของ '%s' ยาวเกิน 2000 ตัวอักษร", q.Label)
+			}
+		}
+	}
+	return nil
+}
+
+// ส่งแบบสอบถามให้ผู้ที่เข้าร่วม (attended) ทุกคนที่ยังไม่เคยได้รับ
+func sendSurveyInvitations(db *gorm.DB, survey *entity.Survey, activity *entity.Activity) (int, error) {
+	attendedID, err := getRegistrationStatusID(db, "attended")
+	if err != nil {
+		return 0, err
+	}
+	var users []entity.User
+	if err := db.Joins("JOIN activity_registrations ON activity_registrations.user_id = users.id").
+		Where("activity_registrations.activity_id = ? AND activity_registrations.status_id = ? AND activity_registrations.deleted_at IS NULL", activity.ID, attendedID).
+		Where("users.id NOT IN (?)", db.Model(&entity.SurveyInvitation{}).Select("user_id").Where("survey_id = ?", survey.ID)).
+		Find(&users).Error; err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	if survey.SentAt == nil {
+		closesAt := now.AddDate(0, 0, survey.OpenDays)
+		if err := db.Model(&entity.Survey{}).Where("id = ?", survey.ID).
+			Updates(map[string]interface{}{"sent_at": now, "closes_at": closesAt}).Error; err != nil {
+			return 0, err
+		}
+		survey.SentAt, survey.ClosesAt = &now, &closesAt
+	}
+
+	message := fmt.Sprintf("📝 ขอบคุณที่เข้าร่วมกิจกรรม '%s' กรุณาตอบแบบสอบถาม '%s' ภายใน %s",
+		activity.Title, survey.Title, formatThaiDateTime(*survey.ClosesAt))
+	for _, u := range users {
+		if err := db.Create(&entity.SurveyInvitation{SurveyID: survey.ID, UserID: u.ID, SentAt: now}).Error; err != nil {
+			return 0, err
+		}
+		sendSurveyMessage(activity, survey, u, message)
+	}
+	return len(users), nil
+}
+
+func sendSurveyMessage(activity *entity.Activity, survey *entity.Survey, user entity.User, message string) {
+	if err := getNotificationService().CreateNotification(user.ID, message, "activity"); err != nil {
+		fmt.Println("❌ Error creating survey notification:", err)
+	}
+	if user.Email == "" {
+		return
+	}
+	htmlBody, err := services.RenderTemplate("survey_invitation.html", map[string]string{
+		"Message":       message,
+		"ActivityTitle": activity.Title,
+		"SurveyTitle":   survey.Title,
+		"ClosesAt":      formatThaiDateTime(*survey.ClosesAt),
+	})
+	if err == nil {
+		go services.SendEmailHTML(user.Email, "📝 แบบสอบถามกิจกรรม "+activity.Title, htmlBody)
+	}
+}
+
+// งานตามตาราง: ส่งแบบสอบถามเมื่อกิจกรรมจบครบตามเวลาที่ตั้งไว้ และเตือนผู้ที่ยังไม่ตอบ
+func dispatchSurveys(db *gorm.DB) error {
+	now := time.Now()
+	cancelled, err := getActivityStatusByName(db, "cancelled")
+	if err != nil {
+		return err
+	}
+
+	var pending []entity.Survey
+	if err := db.Preload("Activity").Where("sent_at IS NULL").Find(&pending).Error; err != nil {
+		return err
+	}
+	for i := range pending {
+		survey := &pending[i]
+		if survey.Activity.StatusID == cancelled.ID {
+			continue
+		}
+		if now.Before(survey.Activity.DateEnd.Add(time.Duration(survey.SendDelayMinutes) * time.Minute)) {
+			continue
+		}
+		if _, err := sendSurveyInvitations(db, survey, &survey.Activity); err != nil {
+			return err
+		}
+	}
+
+	var invitations []entity.SurveyInvitation
+	if err := db.Preload("Survey.Activity").
+		Joins("JOIN surveys ON surveys.id = survey_invitations.survey_id AND surveys.deleted_at IS NULL").
+		Where("survey_invitations.responded_at IS NULL AND surveys.closes_at > ?", now).
+		Where("survey_invitations.reminders_sent < surveys.max_reminders").
+		Where("COALESCE(survey_invitations.last_reminded_at, survey_invitations.sent_at) < ?", now.Add(-surveyReminderInterval)).
+		Find(&invitations).Error; err != nil {
+		return err
+	}
+	for _, inv := range invitations {
+		var user entity.User
+		if err := db.First(&user, inv.UserID).Error; err != nil {
+			continue
+		}
+		message := fmt.Sprintf("⏰ ยังไม่ได้ตอบแบบสอบถามกิจกรรม '%s' ปิดรับคำตอบ %s",
+			inv.Survey.Activity.Title, formatThaiDateTime(*inv.Survey.ClosesAt))
+		sendSurveyMessage(&inv.Survey.Activity, &inv.Survey, user, message)
+		if err := db.Model(&entity.SurveyInvitation{}).Where("id = ?", inv.ID).Updates(map[string]interface{}{
+			"reminders_sent":   gorm.Expr("reminders_sent + 1"),
+			"last_reminded_at": now,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ผลรวมรายคำถาม: likert ได้ค่าเฉลี่ยและการกระจาย, choice ได้จำนวนต่อตัวเลือก, text ได้รายการคำตอบ
+func computeSurveyResults(db *gorm.DB, survey *entity.Survey) (SurveyResults, error) {
+	results := SurveyResults{SurveyID: survey.ID, ActivityID: survey.ActivityID, Questions: []SurveyQuestionResult{}}
+	var activity entity.Activity
+	if err := db.Select("id, title").First(&activity, survey.ActivityID).Error; err != nil {
+		return results, err
+	}
+	results.ActivityTitle = activity.Title
+	db.Model(&entity.SurveyInvitation{}).Where("survey_id = ?", survey.ID).Count(&results.Invited)
+	db.Model(&entity.SurveyResponse{}).Where("survey_id = ?", survey.ID).Count(&results.Responded)
+	if results.Invited > 0 {
+		results.ResponseRate = math.Round(float64(results.Responded)*10000/float64(results.Invited)) / 100
+	}
+
+	var answers []entity.SurveyAnswer
+	if err := db.Joins("JOIN survey_responses ON survey_responses.id = survey_answers.response_id AND survey_responses.deleted_at IS NULL").
+		Where("survey_responses.survey_id = ?", survey.ID).
+		Order("survey_answers.id ASC").Find(&answers).Error; err != nil {
+		return results, err
+	}
+	byQuestion := map[uint][]string{}
+	for _, a := range answers {
+		if a.Value != "" {
+			byQuestion[a.QuestionID] = append(byQuestion[a.QuestionID], a.Value)
+		}
+	}
+
+	for _, q := range survey.Questions {
+		values := byQuestion[q.ID]
+		result := SurveyQuestionResult{QuestionID: q.ID, Label: q.Label, Type: q.Type, Responses: len(values)}
+		switch q.Type {
+		case "likert", "choice":
+			result.Distribution = map[string]int{}
+			for _, option := range surveyQuestionOptions(q) {
+				result.Distribution[option] = 0
+			}
+			sum := 0
+			for _, v := range values {
+				result.Distribution[v]++
+				if n, err := strconv.Atoi(v); err == nil {
+					sum += n
+				}
+			}
+			if q.Type == "likert" && len(values) > 0 {
+				result.Average = math.Round(float64(sum)*100/float64(len(values))) / 100
+			}
+		case "text":
+			result.TextAnswers = values
+		}
+		results.Questions = append(results.Questions, result)
+	}
+	return results, nil
+}
+
+// บังคับสิทธิ์ดูผลแบบสอบถาม: officer ของชมรมผู้จัด หรือผู้ดูแลระบบ
+func requireSurveyViewer(c *gin.Context, activity *entity.Activity) bool {
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return false
+	}
+	if isAdmin(config.DB(), user) {
+		return true
+	}
+	_, err = requireActivityOfficer(c, activity)
+	return err == nil
+}
+
+// GET /activities/:id/survey - แบบสอบถามของกิจกรรม
+func GetActivitySurvey(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	survey, err := loadActivitySurvey(db, activity.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "กิจกรรมนี้ยังไม่มีแบบสอบถาม"})
+		return
+	}
+	questions := make([]SurveyQuestionResponse, 0, len(survey.Questions))
+	for _, q := range survey.Questions {
+		questions = append(questions, toSurveyQuestionResponse(q))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"data":      survey,
+		"questions": questions,
+		"is_open":   surveyIsOpen(survey, time.Now()),
+	})
+}
+
+// PUT /activities/:id/survey - สร้างหรือแก้ไขแบบสอบถาม (officer เท่านั้น)
+// เปลี่ยนคำถามไม่ได้หลังมีผู้ตอบแล้ว เพื่อไม่ให้คำตอบเดิมหลุดจากคำถาม
+func UpsertActivitySurvey(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	user, err := requireActivityOfficer(c, &activity)
+	if err != nil {
+		return
+	}
+
+	var input SurveyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	if err := validateSurveyInput(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	survey := entity.Survey{ActivityID: activity.ID, SendDelayMinutes: 60, OpenDays: 14, MaxReminders: 2, CreatedBy: user.ID}
+	if existing, err := loadActivitySurvey(db, activity.ID); err == nil {
+		var responseCount int64
+		db.Model(&entity.SurveyResponse{}).Where("survey_id = ?", existing.ID).Count(&responseCount)
+		if responseCount > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "มีผู้ตอบแบบสอบถามแล้ว ไม่สามารถแก้ไขได้"})
+			return
+		}
+		// ผู้รับแบบสอบถามได้รับแจ้งไปแล้วว่าตอบแบบระบุตัวตนหรือไม่ จึงเปลี่ยนหลังส่งไม่ได้
+		if existing.SentAt != nil && existing.IsAnonymous != input.IsAnonymous {
+			c.JSON(http.StatusConflict, gin.H{"error": "ส่งแบบสอบถามแล้ว ไม่สามารถเปลี่ยนการไม่ระบุตัวตนได้"})
+			return
+		}
+		survey = *existing
+		survey.Questions = nil
+	}
+	survey.Title = strings.TrimSpace(input.Title)
+	survey.Description = strings.TrimSpace(input.Description)
+	survey.IsAnonymous = input.IsAnonymous
+	if input.SendDelayMinutes != nil {
+		survey.SendDelayMinutes = *input.SendDelayMinutes
+	}
+	if input.OpenDays != nil {
+		survey.OpenDays = *input.OpenDays
+	}
+	if input.MaxReminders != nil {
+		survey.MaxReminders = *input.MaxReminders
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Activity", "Questions").Save(&survey).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("survey_id = ?", survey.ID).Delete(&entity.SurveyQuestion{}).Error; err != nil {
+			return err
+		}
+		for i, q := range input.Questions {
+			options := ""
+			if q.Type == "choice" {
+				raw, _ := json.Marshal(q.Options)
+				options = string(raw)
+			}
+			question := entity.SurveyQuestion{
+				SurveyID:  survey.ID,
+				Label:     strings.TrimSpace(q.Label),
+				Type:      q.Type,
+				Options:   options,
+				Required:  q.Required,
+				SortOrder: i + 1,
+			}
+			if err := tx.Create(&question).Error; err != nil {
+				return err
+			}
+			survey.Questions = append(survey.Questions, question)
+		}
+		return nil
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกแบบสอบถามไม่สำเร็จ"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "บันทึกแบบสอบถามเรียบร้อยแล้ว", "data": survey})
+}
+
+// DELETE /activities/:id/survey - ลบแบบสอบถามที่ยังไม่มีผู้ตอบ (officer เท่านั้น)
+func DeleteActivitySurvey(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	if _, err := requireActivityOfficer(c, &activity); err != nil {
+		return
+	}
+	survey, err := loadActivitySurvey(db, activity.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "กิจกรรมนี้ยังไม่มีแบบสอบถาม"})
+		return
+	}
+	var responseCount int64
+	db.Model(&entity.SurveyResponse{}).Where("survey_id = ?", survey.ID).Count(&responseCount)
+	if responseCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "มีผู้ตอบแบบสอบถามแล้ว ไม่สามารถลบได้"})
+		return
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("survey_id = ?", survey.ID).Delete(&entity.SurveyQuestion{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("survey_id = ?", survey.ID).Delete(&entity.SurveyInvitation{}).Error; err != nil {
+			return err
+		}
+		// ลบถาวรเพราะ activity_id เป็น unique index
+		return tx.Unscoped().Delete(&entity.Survey{}, survey.ID).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ลบแบบสอบถามไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ลบแบบสอบถามเรียบร้อยแล้ว"})
+}
+
+// POST /activities/:id/survey/send - ส่งแบบสอบถามทันทีโดยไม่รอเวลาอัตโนมัติ (officer เท่านั้น)
+// เรียกซ้ำได้เพื่อส่งให้ผู้ที่ถูกบันทึกว่าเข้าร่วมภายหลัง
+func SendActivitySurvey(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	if _, err := requireActivityOfficer(c, &activity); err != nil {
+		return
+	}
+	survey, err := loadActivitySurvey(db, activity.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "กิจกรรมนี้ยังไม่มีแบบสอบถาม"})
+		return
+	}
+	if time.Now().Before(activity.DateEnd) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ส่งแบบสอบถามได้หลังกิจกรรมสิ้นสุดแล้ว"})
+		return
+	}
+	if survey.SentAt != nil && !surveyIsOpen(survey, time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "แบบสอบถามปิดรับคำตอบแล้ว"})
+		return
+	}
+
+	sent, err := sendSurveyInvitations(db, survey, &activity)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ส่งแบบสอบถามไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ส่งแบบสอบถามเรียบร้อยแล้ว", "sent": sent, "closes_at": survey.ClosesAt})
+}
+
+// GET /surveys/pending - แบบสอบถามที่ผู้ใช้ยังไม่ได้ตอบและยังเปิดอยู่
+func GetMyPendingSurveys(c *gin.Context) {
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	var invitations []entity.SurveyInvitation
+	if err := config.DB().Preload("Survey.Activity").
+		Joins("JOIN surveys ON surveys.id = survey_invitations.survey_id AND surveys.deleted_at IS NULL").
+		Where("survey_invitations.user_id = ? AND survey_invitations.responded_at IS NULL AND surveys.closes_at > ?", user.ID, time.Now()).
+		Order("surveys.closes_at ASC").Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงแบบสอบถามได้"})
+		return
+	}
+	data := make([]gin.H, 0, len(invitations))
+	for _, inv := range invitations {
+		data = append(data, gin.H{
+			"survey_id":      inv.SurveyID,
+			"activity_id":    inv.Survey.ActivityID,
+			"activity_title": inv.Survey.Activity.Title,
+			"title":          inv.Survey.Title,
+			"is_anonymous":   inv.Survey.IsAnonymous,
+			"closes_at":      inv.Survey.ClosesAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": data})
+}
+
+// POST /activities/:id/survey/responses - ส่งคำตอบ (เฉพาะผู้ที่ได้รับแบบสอบถาม ตอบได้ครั้งเดียว)
+func SubmitSurveyResponse(c *gin.Context) {
+	db := config.DB()
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	survey, err := loadActivitySurvey(db, activity.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "กิจกรรมนี้ยังไม่มีแบบสอบถาม"})
+		return
+	}
+
+	var invitation entity.SurveyInvitation
+	if err := db.Where("survey_id = ? AND user_id = ?", survey.ID, user.ID).First(&invitation).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "คุณไม่ได้รับแบบสอบถามนี้"})
+		return
+	}
+	if invitation.RespondedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "คุณตอบแบบสอบถามนี้แล้ว"})
+		return
+	}
+	if !surveyIsOpen(survey, time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "แบบสอบถามปิดรับคำตอบแล้ว"})
+		return
+	}
+
+	var input struct {
+		Answers []RegistrationAnswerInput `json:"answers"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	answers := map[uint]string{}
+	for _, a := range input.Answers {
+		answers[a.QuestionID] = strings.TrimSpace(a.Value)
+	}
+	if err := validateSurveyAnswers(survey.Questions, answers); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondedAt := time.Now()
+	if survey.IsAnonymous {
+		// เก็บเวลาตอบแค่ระดับวันทั้งใน invitation และคำตอบ เพื่อไม่ให้จับคู่ผู้ตอบกับคำตอบจากเวลาได้
+		respondedAt = respondedAt.Truncate(24 * time.Hour)
+	}
+	response := entity.SurveyResponse{SurveyID: survey.ID}
+	response.CreatedAt, response.UpdatedAt = respondedAt, respondedAt
+	if !survey.IsAnonymous {
+		response.UserID = &user.ID
+	}
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		// ตรวจซ้ำใน transaction กันการส่งซ้อน
+		result := tx.Model(&entity.SurveyInvitation{}).
+			Where("id = ? AND responded_at IS NULL", invitation.ID).
+			Updates(map[string]interface{}{"responded_at": respondedAt, "updated_at": respondedAt})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("already responded")
+		}
+		if err := tx.Omit("User", "Answers").Create(&response).Error; err != nil {
+			return err
+		}
+		for _, q := range survey.Questions {
+			if answers[q.ID] == "" {
+				continue
+			}
+			answer := entity.SurveyAnswer{ResponseID: response.ID, QuestionID: q.ID, Value: answers[q.ID]}
+			answer.CreatedAt, answer.UpdatedAt = respondedAt, respondedAt
+			if err := tx.Create(&answer).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกคำตอบไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "ขอบคุณสำหรับการตอบแบบสอบถาม"})
+}
+
+// GET /activities/:id/survey/results - ผลรวมรายคำถาม (officer ของชมรมผู้จัด หรือ admin)
+func GetSurveyResults(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	if !requireSurveyViewer(c, &activity) {
+		return
+	}
+	survey, err := loadActivitySurvey(db, activity.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "กิจกรรมนี้ยังไม่มีแบบสอบถาม"})
+		return
+	}
+	results, err := computeSurveyResults(db, survey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถสรุปผลแบบสอบถามได้"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": results})
+}
+
+// GET /activities/:id/survey/export - ส่งออกคำตอบรายคนเป็น CSV (แบบไม่ระบุตัวตนจะไม่มีข้อมูลผู้ตอบ)
+func ExportSurveyResponses(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	if !requireSurveyViewer(c, &activity) {
+		return
+	}
+	survey, err := loadActivitySurvey(db, activity.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "กิจกรรมนี้ยังไม่มีแบบสอบถาม"})
+		return
+	}
+	var responses []entity.SurveyResponse
+	if err := db.Preload("User").Preload("Answers").Where("survey_id = ?", survey.ID).
+		Order("id ASC").Find(&responses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงคำตอบได้"})
+		return
+	}
+
+	filename := fmt.Sprintf("activity_%d_survey.csv", activity.ID)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+
+	// ใส่ BOM เพื่อให้ Excel อ่านภาษาไทยได้ถูกต้อง
+	c.Writer.Write([]byte("\xEF\xBB\xBF"))
+	w := csv.NewWriter(c.Writer)
+
+	header := []string{"response_id"}
+	if !survey.IsAnonymous {
+		header = append(header, "student_id", "first_name", "last_name", "submitted_at")
+	}
+	for _, q := range survey.Questions {
+		header = append(header, q.Label)
+	}
+	w.Write(header)
+
+	for _, r := range responses {
+		row := []string{strconv.FormatUint(uint64(r.ID), 10)}
+		if !survey.IsAnonymous {
+			if r.User != nil {
+				row = append(row, r.User.StudentID, r.User.FirstName, r.User.LastName)
+			} else {
+				row = append(row, "", "", "")
+			}
+			row = append(row, r.CreatedAt.Format(time.RFC3339))
+		}
+		values := map[uint]string{}
+		for _, a := range r.Answers {
+			values[a.QuestionID] = a.Value
+		}
+		for _, q := range survey.Questions {
+			row = append(row, values[q.ID])
+		}
+		w.Write(row)
+	}
+	w.Flush()
+}
@@ -0,0 +1,662 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TeamSettingInput struct {
+	Enabled      bool   `json:"enabled"`
+	MinTeamSize  int    `json:"min_team_size"`
+	MaxTeamSize  int    `json:"max_team_size"`
+	CapacityUnit string `json:"capacity_unit"`
+}
+
+// คืน team ที่ user อยู่ (เป็นกัปตันหรือตอบรับคำเชิญแล้ว) ในกิจกรรมนี้
+func findActiveTeamOfUser(db *gorm.DB, activityID, userID uint) (*entity.Team, error) {
+	var team entity.Team
+	err := db.Joins("JOIN team_members ON team_members.team_id = teams.id AND team_members.deleted_at IS NULL").
+		Where("teams.activity_id = ? AND teams.status <> ? AND team_members.user_id = ? AND team_members.status = ?",
+			activityID, "cancelled", userID, "accepted").
+		First(&team).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+func loadTeam(db *gorm.DB, id string) (*entity.Team, error) {
+	var team entity.Team
+	if err := db.Preload("Captain").Preload("Members").Preload("Members.User").First(&team, id).Error; err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+// ข้อมูลทีมที่ส่งออก เปิดเผยเฉพาะชื่อและรหัสนักศึกษาของสมาชิก
+type TeamResponse struct {
+	ID          uint                 `json:"id"`
+	ActivityID  uint                 `json:"activity_id"`
+	Name        string               `json:"name"`
+	Status      string               `json:"status"`
+	ConfirmedAt *time.Time           `json:"confirmed_at"`
+	CreatedAt   time.Time            `json:"created_at"`
+	Captain     TeamUserResponse     `json:"captain"`
+	Members     []TeamMemberResponse `json:"members"`
+}
+
+type TeamMemberResponse struct {
+	ID          uint             `json:"id"`
+	Status      string           `json:"status"`
+	InvitedAt   time.Time        `json:"invited_at"`
+	RespondedAt *time.Time       `json:"responded_at"`
+	User        TeamUserResponse `json:"user"`
+}
+
+type TeamUserResponse struct {
+	ID           uint   `json:"id"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+	StudentID    string `json:"student_id"`
+	ProfileImage string `json:"profile_image"`
+}
+
+func toTeamUserResponse(u entity.User) TeamUserResponse {
+	return TeamUserResponse{ID: u.ID, FirstName: u.FirstName, LastName: u.LastName, StudentID: u.StudentID, ProfileImage: u.ProfileImage}
+}
+
+func toTeamResponse(team *entity.Team) TeamResponse {
+	res := TeamResponse{
+		ID:          team.ID,
+		ActivityID:  team.ActivityID,
+		Name:        team.Name,
+		Status:      team.Status,
+		ConfirmedAt: team.ConfirmedAt,
+		CreatedAt:   team.CreatedAt,
+		Captain:     toTeamUserResponse(team.Captain),
+		Members:     []TeamMemberResponse{},
+	}
+	res.Captain.ID = team.CaptainID
+	for _, m := range team.Members {
+		member := TeamMemberResponse{
+			ID:          m.ID,
+			Status:      m.Status,
+			InvitedAt:   m.InvitedAt,
+			RespondedAt: m.RespondedAt,
+			User:        toTeamUserResponse(m.User),
+		}
+		member.User.ID = m.UserID
+		res.Members = append(res.Members, member)
+	}
+	return res
+}
+
+func countAcceptedMembers(team *entity.Team) int {
+	count := 0
+	for _, m := range team.Members {
+		if m.Status == "accepted" {
+			count++
+		}
+	}
+	return count
+}
+
+// GET /activities/:id/team-settings - ดูการตั้งค่าการสมัครแบบทีม
+func GetTeamSetting(c *gin.Context) {
+	var setting entity.ActivityTeamSetting
+	if err := config.DB().Where("activity_id = ?", c.Param("id")).First(&setting).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"success": true, "enabled": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "enabled": true, "data": setting})
+}
+
+// PUT /activities/:id/team-settings - เปิด/ปิดการสมัครแบบทีม (officer เท่านั้น)
+func UpdateTeamSetting(c *gin.Context) {
+	db := config.DB()
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+	if _, err := requireActivityOfficer(c, &activity); err != nil {
+		return
+	}
+
+	var input TeamSettingInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+
+	var teamCount int64
+	db.Model(&entity.Team{}).Where("activity_id = ? AND status <> ?", activity.ID, "cancelled").Count(&teamCount)
+
+	if !input.Enabled {
+		if teamCount > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "มีทีมสมัครแล้ว ไม่สามารถปิดการสมัครแบบทีมได้"})
+			return
+		}
+		db.Where("activity_id = ?", activity.ID).Delete(&entity.ActivityTeamSetting{})
+		c.JSON(http.StatusOK, gin.H{"success": true, "message": "ปิดการสมัครแบบทีมแล้ว"})
+		return
+	}
+
+	if input.MinTeamSize < 1 || input.MaxTeamSize < input.MinTeamSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ขนาดทีมไม่ถูกต้อง"})
+		return
+	}
+	if input.CapacityUnit == "" {
+		input.CapacityUnit = "people"
+	}
+	if input.CapacityUnit != "people" && input.CapacityUnit != "teams" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "capacity_unit ต้องเป็น people หรือ teams"})
+		return
+	}
+
+	var individualCount int64
+	db.Model(&entity.ActivityRegistration{}).Where("activity_id = ?", activity.ID).Count(&individualCount)
+	if teamCount == 0 && individualCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "มีผู้สมัครแบบเดี่ยวแล้ว ไม่สามารถเปลี่ยนเป็นการสมัครแบบทีมได้"})
+		return
+	}
+
+	var setting entity.ActivityTeamSetting
+	db.Where("activity_id = ?", activity.ID).FirstOrInit(&setting)
+	setting.ActivityID = activity.ID
+	setting.MinTeamSize = input.MinTeamSize
+	setting.MaxTeamSize = input.MaxTeamSize
+	setting.CapacityUnit = input.CapacityUnit
+	if err := db.Save(&setting).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "บันทึกการตั้งค่าไม่สำเร็จ"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "บันทึกการตั้งค่าสำเร็จ", "data": setting})
+}
+
+// GET /activities/:id/teams - รายชื่อทีมของกิจกรรม
+func GetTeamsByActivity(c *gin.Context) {
+	var teams []entity.Team
+	if err := config.DB().
+		Preload("Captain").
+		Preload("Members", "status = ?", "accepted").
+		Preload("Members.User").
+		Where("activity_id = ? AND status <> ?", c.Param("id"), "cancelled").
+		Order("created_at ASC").
+		Find(&teams).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงข้อมูลทีมได้"})
+		return
+	}
+	data := []TeamResponse{}
+	for i := range teams {
+		data = append(data, toTeamResponse(&teams[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": data})
+}
+
+// GET /teams/:id - ดูข้อมูลทีม
+func GetTeamByID(c *gin.Context) {
+	team, err := loadTeam(config.DB(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบทีม"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": toTeamResponse(team)})
+}
+
+// POST /activities/:id/teams - สร้างทีมใหม่ ผู้สร้างเป็นกัปตัน
+func CreateTeam(c *gin.Context) {
+	db := config.DB()
+
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var activity entity.Activity
+	if err := db.First(&activity, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "activity not found"})
+		return
+	}
+
+	var setting entity.ActivityTeamSetting
+	if err := db.Where("activity_id = ?", activity.ID).First(&setting).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กิจกรรมนี้ไม่ได้เปิดรับสมัครแบบทีม"})
+		return
+	}
+
+	var input struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาระบุชื่อทีม"})
+		return
+	}
+
+	existing, err := findActiveTeamOfUser(db, activity.ID, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "คุณอยู่ในทีมของกิจกรรมนี้แล้ว"})
+		return
+	}
+
+	now := time.Now()
+	team := entity.Team{
+		ActivityID: activity.ID,
+		Name:       strings.TrimSpace(input.Name),
+		CaptainID:  user.ID,
+		Status:     "forming",
+	}
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&team).Error; err != nil {
+			return err
+		}
+		return tx.Create(&entity.TeamMember{
+			TeamID:      team.ID,
+			UserID:      user.ID,
+			Status:      "accepted",
+			InvitedAt:   now,
+			RespondedAt: &now,
+		}).Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถสร้างทีมได้"})
+		return
+	}
+
+	team.Captain = *user
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "สร้างทีมสำเร็จ", "data": toTeamResponse(&team)})
+}
+
+// POST /teams/:id/invite - กัปตันเชิญเพื่อนร่วมทีม
+func InviteTeamMember(c *gin.Context) {
+	db := config.DB()
+
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	team, err := loadTeam(db, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบทีม"})
+		return
+	}
+	if team.CaptainID != user.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden: captain only"})
+		return
+	}
+	if team.Status != "forming" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ทีมนี้ยืนยันหรือยกเลิกไปแล้ว"})
+		return
+	}
+
+	var input struct {
+		UserID uint `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+
+	var invitee entity.User
+	if err := db.First(&invitee, input.UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบผู้ใช้"})
+		return
+	}
+
+	var setting entity.ActivityTeamSetting
+	db.Where("activity_id = ?", team.ActivityID).First(&setting)
+	if setting.MaxTeamSize > 0 && countAcceptedMembers(team) >= setting.MaxTeamSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ทีมมีสมาชิกครบ %d คนแล้ว", setting.MaxTeamSize)})
+		return
+	}
+
+	for _, m := range team.Members {
+		if m.UserID == invitee.ID && m.Status != "declined" {
+			c.JSON(http.StatusConflict, gin.H{"error": "ผู้ใช้นี้ได้รับคำเชิญหรืออยู่ในทีมแล้ว"})
+			return
+		}
+	}
+	if other, _ := findActiveTeamOfUser(db, team.ActivityID, invitee.ID); other != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "ผู้ใช้นี้อยู่ในทีมอื่นของกิจกรรมนี้แล้ว"})
+		return
+	}
+
+	member := entity.TeamMember{
+		TeamID:    team.ID,
+		UserID:    invitee.ID,
+		Status:    "invited",
+		InvitedAt: time.Now(),
+	}
+	if err := db.Create(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถส่งคำเชิญได้"})
+		return
+	}
+
+	var activity entity.Activity
+	db.First(&activity, team.ActivityID)
+	message := fmt.Sprintf("%s %s เชิญคุณเข้าร่วมทีม \"%s\" ในกิจกรรม %s", user.FirstName, user.LastName, team.Name, activity.Title)
+	if err := getNotificationService().CreateNotification(invitee.ID, message, "activity"); err != nil {
+		fmt.Println("❌ Error creating team invite notification:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ส่งคำเชิญเรียบร้อยแล้ว", "data": TeamMemberResponse{
+		ID:        member.ID,
+		Status:    member.Status,
+		InvitedAt: member.InvitedAt,
+		User:      toTeamUserResponse(invitee),
+	}})
+}
+
+// ตอบรับหรือปฏิเสธคำเชิญของผู้ใช้ปัจจุบัน
+func respondTeamInvite(c *gin.Context, accept bool) {
+	db := config.DB()
+
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	team, err := loadTeam(db, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบทีม"})
+		return
+	}
+	if team.Status != "forming" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ทีมนี้ยืนยันหรือยกเลิกไปแล้ว"})
+		return
+	}
+
+	var member entity.TeamMember
+	if err := db.Where("team_id = ? AND user_id = ? AND status = ?", team.ID, user.ID, "invited").
+		First(&member).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบคำเชิญ"})
+		return
+	}
+
+	if accept {
+		if other, _ := findActiveTeamOfUser(db, team.ActivityID, user.ID); other != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "คุณอยู่ในทีมอื่นของกิจกรรมนี้แล้ว"})
+			return
+		}
+		var setting entity.ActivityTeamSetting
+		db.Where("activity_id = ?", team.ActivityID).First(&setting)
+		if setting.MaxTeamSize > 0 && countAcceptedMembers(team) >= setting.MaxTeamSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ทีมมีสมาชิกครบแล้ว"})
+			return
+		}
+	}
+
+	now := time.Now()
+	member.RespondedAt = &now
+	member.Status = "declined"
+	if accept {
+		member.Status = "accepted"
+	}
+	if err := db.Save(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถบันทึกคำตอบได้"})
+		return
+	}
+
+	action := "ปฏิเสธ"
+	if accept {
+		action = "ตอบรับ"
+	}
+	message := fmt.Sprintf("%s %s %sคำเชิญเข้าร่วมทีม \"%s\"", user.FirstName, user.LastName, action, team.Name)
+	if err := getNotificationService().CreateNotification(team.CaptainID, message, "info"); err != nil {
+		fmt.Println("❌ Error creating team response notification:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": action + "คำเชิญเรียบร้อยแล้ว"})
+}
+
+// POST /teams/:id/accept - ตอบรับคำเชิญเข้าทีม
+func AcceptTeamInvite(c *gin.Context) {
+	respondTeamInvite(c, true)
+}
+
+// POST /teams/:id/decline - ปฏิเสธคำเชิญเข้าทีม
+func DeclineTeamInvite(c *gin.Context) {
+	respondTeamInvite(c, false)
+}
+
+// DELETE /teams/:id/members/:userId - กัปตันนำสมาชิกออก หรือสมาชิกออกจากทีมเอง
+func RemoveTeamMember(c *gin.Context) {
+	db := config.DB()
+
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	team, err := loadTeam(db, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบทีม"})
+		return
+	}
+
+	targetID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID ไม่ถูกต้อง"})
+		return
+	}
+	if user.ID != uint(targetID) && user.ID != team.CaptainID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden: captain only"})
+		return
+	}
+	if uint(targetID) == team.CaptainID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "กัปตันไม่สามารถออกจากทีมได้ ให้ยกเลิกทีมแทน"})
+		return
+	}
+	if team.Status != "forming" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ทีมยืนยันแล้ว ไม่สามารถเปลี่ยนสมาชิกได้"})
+		return
+	}
+
+	result := db.Where("team_id = ? AND user_id = ?", team.ID, targetID).Delete(&entity.TeamMember{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถลบสมาชิกได้"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบสมาชิกในทีม"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ลบสมาชิกออกจากทีมแล้ว"})
+}
+
+// POST /teams/:id/confirm - กัปตันยืนยันทีม และสร้างการลงทะเบียนให้สมาชิกทุกคน
+func ConfirmTeam(c *gin.Context) {
+	db := config.DB()
+
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	team, err := loadTeam(db, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบทีม"})
+		return
+	}
+	if team.CaptainID != user.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden: captain only"})
+		return
+	}
+	if team.Status != "forming" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ทีมนี้ยืนยันหรือยกเลิกไปแล้ว"})
+		return
+	}
+
+	registeredID, err := getRegistrationStatusID(db, "registered")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่พบสถานะ registered"})
+		return
+	}
+	cancelledID, _ := getRegistrationStatusID(db, "cancelled")
+
+	var memberIDs []uint
+	for _, m := range team.Members {
+		if m.Status == "accepted" {
+			memberIDs = append(memberIDs, m.UserID)
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var activity entity.Activity
+		if err := tx.First(&activity, team.ActivityID).Error; err != nil {
+			return errors.New("ไม่พบกิจกรรม")
+		}
+		var setting entity.ActivityTeamSetting
+		if err := tx.Where("activity_id = ?", team.ActivityID).First(&setting).Error; err != nil {
+			return errors.New("กิจกรรมนี้ไม่ได้เปิดรับสมัครแบบทีม")
+		}
+
+		size := len(memberIDs)
+		if size < setting.MinTeamSize || size > setting.MaxTeamSize {
+			return fmt.Errorf("ทีมต้องมีสมาชิก %d-%d คน (ปัจจุบัน %d คน)", setting.MinTeamSize, setting.MaxTeamSize, size)
+		}
+
+		if activity.Capacity > 0 {
+			if setting.CapacityUnit == "teams" {
+				var confirmed int64
+				tx.Model(&entity.Team{}).Where("activity_id = ? AND status = ?", activity.ID, "confirmed").Count(&confirmed)
+				if int(confirmed) >= activity.Capacity {
+					return errors.New("จำนวนทีมเต็มแล้ว")
+				}
+			} else {
+				var registered int64
+				tx.Model(&entity.ActivityRegistration{}).
+					Where("activity_id = ? AND status_id <> ?", activity.ID, cancelledID).
+					Count(&registered)
+				if int(registered)+size > activity.Capacity {
+					return fmt.Errorf("ที่นั่งเหลือไม่พอสำหรับทีม %d คน", size)
+				}
+			}
+		}
+		if err := checkSessionCapacity(tx, activity.ID, size); err != nil {
+			return err
+		}
+
+		var already int64
+		tx.Model(&entity.ActivityRegistration{}).
+			Where("activity_id = ? AND user_id IN ? AND status_id <> ?", activity.ID, memberIDs, cancelledID).
+			Count(&already)
+		if already > 0 {
+			return errors.New("มีสมาชิกในทีมลงทะเบียนกิจกรรมนี้แล้ว")
+		}
+
+		now := time.Now()
+		for _, uid := range memberIDs {
+			if err := tx.Create(&entity.ActivityRegistration{
+				ActivityID:   activity.ID,
+				UserID:       uid,
+				StatusID:     registeredID,
+				RegisteredAt: now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		// คำเชิญที่ยังไม่ได้ตอบถือว่าหมดอายุเมื่อยืนยันทีม
+		if err := tx.Model(&entity.TeamMember{}).
+			Where("team_id = ? AND status = ?", team.ID, "invited").
+			Updates(map[string]interface{}{"status": "declined", "responded_at": now}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&entity.Team{}).Where("id = ?", team.ID).
+			Updates(map[string]interface{}{"status": "confirmed", "confirmed_at": now}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message := fmt.Sprintf("ทีม \"%s\" ได้รับการยืนยันและลงทะเบียนกิจกรรมเรียบร้อยแล้ว", team.Name)
+	for _, uid := range memberIDs {
+		if err := getNotificationService().CreateNotification(uid, message, "success"); err != nil {
+			fmt.Println("❌ Error creating team confirm notification:", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ยืนยันทีมและลงทะเบียนเรียบร้อยแล้ว"})
+}
+
+// DELETE /teams/:id - กัปตันยกเลิกทีม (ถ้ายืนยันแล้วจะยกเลิกการลงทะเบียนของสมาชิกด้วย)
+func CancelTeam(c *gin.Context) {
+	db := config.DB()
+
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	team, err := loadTeam(db, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบทีม"})
+		return
+	}
+	if team.CaptainID != user.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden: captain only"})
+		return
+	}
+	if team.Status == "cancelled" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ทีมนี้ถูกยกเลิกไปแล้ว"})
+		return
+	}
+
+	cancelledID, err := getRegistrationStatusID(db, "cancelled")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่พบสถานะ cancelled"})
+		return
+	}
+
+	var memberIDs []uint
+	for _, m := range team.Members {
+		if m.Status == "accepted" {
+			memberIDs = append(memberIDs, m.UserID)
+		}
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if team.Status == "confirmed" && len(memberIDs) > 0 {
+			if err := tx.Model(&entity.ActivityRegistration{}).
+				Where("activity_id = ? AND user_id IN ?", team.ActivityID, memberIDs).
+				Update("status_id", cancelledID).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&entity.Team{}).Where("id = ?", team.ID).Update("status", "cancelled").Error
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถยกเลิกทีมได้"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "ยกเลิกทีมเรียบร้อยแล้ว"})
+}
@@ -1,12 +1,8 @@
 package controllers
 
 import (
-	"fmt"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
-	"time"
 
 	"final-project/cems/config"
 	"final-project/cems/entity"
@@ -154,37 +150,13 @@ func UpdateUserProfile(c *gin.Context) {
     user.Email = c.PostForm("Email")
     user.StudentID = c.PostForm("StudentID")
 
-    // 3. Parse multipart form (file)
-    file, err := c.FormFile("profile_picture")
-    if err == nil {
-        // 3.1 Validate file type
-        ext := strings.ToLower(filepath.Ext(file.Filename))
-        if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "Only JPG, JPEG, and PNG are allowed"})
-            return
-        }
-
-        // 3.2 Remove old profile image
-        imageDir := filepath.Join("images", "profiles", "admins")
-        pattern := fmt.Sprintf("admin%s.*", userID)
-        matches, _ := filepath.Glob(filepath.Join(imageDir, pattern))
-        for _, match := range matches {
-            _ = os.Remove(match)
-        }
-
-        // 3.3 Set new file path with timestamp to avoid caching issues
-        timestamp := time.Now().Unix()
-        filename := fmt.Sprintf("admin%s_%d%s", userID, timestamp, ext)
-        path := filepath.Join(imageDir, filename)
-
-        // 3.4 Save new file
-        if err := c.SaveUploadedFile(file, path); err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to save file"})
-            return
-        }
-
-        // 3.5 Update image path
-        user.ProfileImage = "/" + path
+    // 3. Parse multipart form (file) - ผ่านขั้นตอนอัปโหลดรูป รูปเก่าที่ไม่มีใครใช้จะถูกลบโดยงาน cleanup
+    picture, err := saveImageUpload(c, "profile_picture", purposeProfilePicture)
+    if respondImageUploadError(c, err) {
+        return
+    }
+    if picture != nil {
+        user.ProfileImage = storedImageURL(picture)
     }
 
     // 4. Save updated user
@@ -0,0 +1,434 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"final-project/cems/config"
+	"final-project/cems/entity"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type VenueInput struct {
+	Name       string   `json:"name" binding:"required"`
+	Building   string   `json:"building"`
+	Room       string   `json:"room"`
+	Capacity   int      `json:"capacity"`
+	Facilities []string `json:"facilities"`
+	Latitude   float64  `json:"latitude"`
+	Longitude  float64  `json:"longitude"`
+	IsActive   *bool    `json:"is_active"`
+}
+
+// สถานที่ถูกกิจกรรมอื่นจองไว้ในช่วงเวลาที่ซ้อนกัน
+type venueConflictError struct {
+	Conflicts []entity.VenueBooking
+}
+
+func (e *venueConflictError) Error() string {
+	return fmt.Sprintf("สถานที่ถูกจองแล้วในช่วงเวลานี้โดยกิจกรรม '%s'", e.Conflicts[0].Activity.Title)
+}
+
+var errVenueUnavailable = errors.New("ไม่พบสถานที่หรือสถานที่ปิดให้บริการ")
+
+func venueFacilities(v *entity.Venue) []string {
+	facilities := []string{}
+	if v.Facilities != "" {
+		json.Unmarshal([]byte(v.Facilities), &facilities)
+	}
+	return facilities
+}
+
+// การจองที่ active และช่วงเวลาซ้อนกับ [start, end) โดยไม่นับกิจกรรม excludeActivityID
+// การจองของกิจกรรมที่ถูกยกเลิกหรือลบไปแล้วไม่นับเป็นการชน แม้การจองจะยังไม่ถูกคืน
+func findVenueConflicts(db *gorm.DB, venueID uint, start, end time.Time, excludeActivityID uint) ([]entity.VenueBooking, error) {
+	var bookings []entity.VenueBooking
+	cancelled := db.Model(&entity.ActivityStatus{}).Select("id").Where("name = ?", "cancelled")
+	err := db.Preload("Activity").
+		Joins("JOIN activities ON activities.id = venue_bookings.activity_id AND activities.deleted_at IS NULL").
+		Where("venue_bookings.venue_id = ? AND venue_bookings.status = ? AND venue_bookings.activity_id <> ?", venueID, "active", excludeActivityID).
+		Where("venue_bookings.start_time < ? AND venue_bookings.end_time > ?", end, start).
+		Where("activities.status_id NOT IN (?)", cancelled).
+		Order("venue_bookings.start_time ASC").
+		Find(&bookings).Error
+	return bookings, err
+}
+
+// จอง (หรือย้ายการจอง) สถานที่ตาม VenueID และช่วงเวลาปัจจุบันของกิจกรรม
+// กิจกรรมที่แบ่งรอบจะจองเฉพาะช่วงของแต่ละรอบที่ใช้สถานที่ของกิจกรรม (รอบที่ระบุ Location เองไม่จอง)
+// กิจกรรมที่ไม่มี VenueID หรือถูกยกเลิกแล้วจะคืนการจองเดิมทั้งหมด
+func bookActivityVenue(tx *gorm.DB, activity *entity.Activity, bookedBy uint) error {
+	if activity.VenueID == nil {
+		return releaseActivityVenue(tx, activity.ID)
+	}
+	if cancelled, err := getActivityStatusByName(tx, "cancelled"); err == nil && activity.StatusID == cancelled.ID {
+		return releaseActivityVenue(tx, activity.ID)
+	}
+
+	var venue entity.Venue
+	if err := tx.Where("id = ? AND is_active = ?", *activity.VenueID, true).First(&venue).Error; err != nil {
+		return errVenueUnavailable
+	}
+
+	type slot struct{ start, end time.Time }
+	var sessions []entity.ActivitySession
+	if err := tx.Where("activity_id = ?", activity.ID).Order("date_start ASC").Find(&sessions).Error; err != nil {
+		return err
+	}
+	slots := []slot{{activity.DateStart, activity.DateEnd}}
+	if len(sessions) > 0 {
+		slots = slots[:0]
+		for _, s := range sessions {
+			if strings.TrimSpace(s.Location) == "" {
+				slots = append(slots, slot{s.DateStart, s.DateEnd})
+			}
+		}
+	}
+	for _, sl := range slots {
+		conflicts, err := findVenueConflicts(tx, venue.ID, sl.start, sl.end, activity.ID)
+		if err != nil {
+			return err
+		}
+		if len(conflicts) > 0 {
+			return &venueConflictError{Conflicts: conflicts}
+		}
+	}
+
+	// ใช้แถวการจองเดิมซ้ำตามลำดับ แถวที่เหลือเกินจำนวนช่วงจะถูกคืน
+	var bookings []entity.VenueBooking
+	if err := tx.Where("activity_id = ? AND status = ?", activity.ID, "active").Order("id ASC").Find(&bookings).Error; err != nil {
+		return err
+	}
+	for i, sl := range slots {
+		var booking entity.VenueBooking
+		if i < len(bookings) {
+			booking = bookings[i]
+		} else {
+			booking.BookedBy = bookedBy
+		}
+		booking.VenueID = venue.ID
+		booking.ActivityID = activity.ID
+		booking.StartTime = sl.start
+		booking.EndTime = sl.end
+		booking.Status = "active"
+		if err := tx.Omit("Venue", "Activity").Save(&booking).Error; err != nil {
+			return err
+		}
+	}
+	for _, b := range bookings[min(len(slots), len(bookings)):] {
+		if err := tx.Model(&b).Update("status", "released").Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func releaseActivityVenue(tx *gorm.DB, activityID uint) error {
+	return tx.Model(&entity.VenueBooking{}).
+		Where("activity_id = ? AND status = ?", activityID, "active").
+		Update("status", "released").Error
+}
+
+// คำเตือนที่ไม่ขัดขวางการบันทึก เช่น จำนวนรับสมัครเกินความจุของสถานที่
+func venueCapacityWarnings(db *gorm.DB, activity *entity.Activity) []string {
+	warnings := []string{}
+	if activity.VenueID == nil {
+		return warnings
+	}
+	var venue entity.Venue
+	if err := db.First(&venue, *activity.VenueID).Error; err != nil {
+		return warnings
+	}
+	if venue.Capacity > 0 && activity.Capacity > venue.Capacity {
+		warnings = append(warnings, fmt.Sprintf("จำนวนรับสมัคร %d คน เกินความจุของ%s (%d คน)", activity.Capacity, venue.Name, venue.Capacity))
+	}
+	return warnings
+}
+
+// อ่าน venue_id จาก form-data: present = false ถ้าไม่ได้ส่งมา, ค่าว่างหรือ 0 หมายถึงไม่ใช้สถานที่ในระบบ
+func parseVenueIDForm(c *gin.Context) (venueID *uint, present bool, err error) {
+	raw, present := c.GetPostForm("venue_id")
+	raw = strings.TrimSpace(raw)
+	if !present || raw == "" || raw == "0" {
+		return nil, present, nil
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		return nil, true, errors.New("Invalid venue_id")
+	}
+	v := uint(id)
+	return &v, true, nil
+}
+
+// ตอบกลับข้อผิดพลาดจากการจองสถานที่ คืน true ถ้าเป็นข้อผิดพลาดของการจอง
+func respondVenueError(c *gin.Context, err error) bool {
+	var conflict *venueConflictError
+	switch {
+	case errors.As(err, &conflict):
+		items := make([]gin.H, 0, len(conflict.Conflicts))
+		for _, b := range conflict.Conflicts {
+			items = append(items, gin.H{
+				"activity_id": b.ActivityID,
+				"title":       b.Activity.Title,
+				"start_time":  b.StartTime,
+				"end_time":    b.EndTime,
+			})
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": conflict.Error(), "conflicts": items})
+		return true
+	case errors.Is(err, errVenueUnavailable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return true
+	}
+	return false
+}
+
+// ช่วงเวลาจาก query ?start=&end= (RFC3339)
+func parseTimeRangeQuery(c *gin.Context) (time.Time, time.Time, error) {
+	start, err := time.Parse(time.RFC3339, c.Query("start"))
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("กรุณาระบุ start เป็น RFC3339")
+	}
+	end, err := time.Parse(time.RFC3339, c.Query("end"))
+	if err != nil || !end.After(start) {
+		return time.Time{}, time.Time{}, errors.New("กรุณาระบุ end เป็น RFC3339 และอยู่หลัง start")
+	}
+	return start, end, nil
+}
+
+func applyVenueInput(venue *entity.Venue, input VenueInput) error {
+	if strings.TrimSpace(input.Name) == "" {
+		return errors.New("กรุณาระบุชื่อสถานที่")
+	}
+	if input.Capacity < 0 {
+		return errors.New("capacity ต้องไม่ติดลบ")
+	}
+	if input.Latitude < -90 || input.Latitude > 90 || input.Longitude < -180 || input.Longitude > 180 {
+		return errors.New("พิกัดสถานที่ไม่ถูกต้อง")
+	}
+	facilities := input.Facilities
+	if facilities == nil {
+		facilities = []string{}
+	}
+	raw, _ := json.Marshal(facilities)
+
+	venue.Name = strings.TrimSpace(input.Name)
+	venue.Building = strings.TrimSpace(input.Building)
+	venue.Room = strings.TrimSpace(input.Room)
+	venue.Capacity = input.Capacity
+	venue.Facilities = string(raw)
+	venue.Latitude = input.Latitude
+	venue.Longitude = input.Longitude
+	if input.IsActive != nil {
+		venue.IsActive = *input.IsActive
+	}
+	return nil
+}
+
+// GET /venues - รายการสถานที่ กรองด้วย min_capacity, facility และถ้าระบุ start/end จะบอกว่าว่างหรือไม่
+func GetVenues(c *gin.Context) {
+	db := config.DB()
+
+	query := db.Where("is_active = ?", true)
+	if minCapacity, err := strconv.Atoi(c.Query("min_capacity")); err == nil && minCapacity > 0 {
+		query = query.Where("capacity >= ?", minCapacity)
+	}
+	var venues []entity.Venue
+	if err := query.Order("name ASC").Find(&venues).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงข้อมูลสถานที่ได้"})
+		return
+	}
+
+	checkAvailability := c.Query("start") != "" || c.Query("end") != ""
+	var start, end time.Time
+	if checkAvailability {
+		var err error
+		if start, end, err = parseTimeRangeQuery(c); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	facility := strings.TrimSpace(c.Query("facility"))
+
+	data := make([]gin.H, 0, len(venues))
+	for i := range venues {
+		v := &venues[i]
+		facilities := venueFacilities(v)
+		if facility != "" && !slices.Contains(facilities, facility) {
+			continue
+		}
+		item := gin.H{"venue": v, "facilities": facilities}
+		if checkAvailability {
+			conflicts, err := findVenueConflicts(db, v.ID, start, end, 0)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถตรวจสอบการจองได้"})
+				return
+			}
+			item["available"] = len(conflicts) == 0
+		}
+		data = append(data, item)
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": data})
+}
+
+// GET /venues/:id - รายละเอียดสถานที่
+func GetVenueByID(c *gin.Context) {
+	var venue entity.Venue
+	if err := config.DB().First(&venue, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบสถานที่"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": venue, "facilities": venueFacilities(&venue)})
+}
+
+// POST /venues - เพิ่มสถานที่ (admin เท่านั้น)
+func CreateVenue(c *gin.Context) {
+	if _, err := requireAdmin(c); err != nil {
+		return
+	}
+
+	var input VenueInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	venue := entity.Venue{IsActive: true}
+	if err := applyVenueInput(&venue, input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := config.DB()
+	var count int64
+	db.Model(&entity.Venue{}).Where("name = ?", venue.Name).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "มีสถานที่ชื่อนี้อยู่แล้ว"})
+		return
+	}
+	if err := db.Create(&venue).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "เพิ่มสถานที่ไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "เพิ่มสถานที่เรียบร้อยแล้ว", "data": venue})
+}
+
+// PUT /venues/:id - แก้ไขสถานที่ หรือปิดให้บริการด้วย is_active = false (admin เท่านั้น)
+// การจองเดิมยังคงอยู่ แต่จะจองใหม่ไม่ได้จนกว่าจะเปิดอีกครั้ง
+func UpdateVenue(c *gin.Context) {
+	if _, err := requireAdmin(c); err != nil {
+		return
+	}
+	db := config.DB()
+
+	var venue entity.Venue
+	if err := db.First(&venue, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบสถานที่"})
+		return
+	}
+	var input VenueInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ข้อมูลไม่ถูกต้อง"})
+		return
+	}
+	if err := applyVenueInput(&venue, input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var count int64
+	db.Model(&entity.Venue{}).Where("name = ? AND id <> ?", venue.Name, venue.ID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "มีสถานที่ชื่อนี้อยู่แล้ว"})
+		return
+	}
+	if err := db.Save(&venue).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "แก้ไขสถานที่ไม่สำเร็จ"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "แก้ไขสถานที่เรียบร้อยแล้ว", "data": venue})
+}
+
+// GET /venues/:id/bookings?start=&end= - ปฏิทินการจองของสถานที่ (ค่าเริ่มต้น 30 วันข้างหน้า)
+func GetVenueBookings(c *gin.Context) {
+	db := config.DB()
+
+	var venue entity.Venue
+	if err := db.First(&venue, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบสถานที่"})
+		return
+	}
+
+	start, end := time.Now(), time.Now().AddDate(0, 0, 30)
+	if c.Query("start") != "" || c.Query("end") != "" {
+		var err error
+		if start, end, err = parseTimeRangeQuery(c); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	bookings, err := findVenueConflicts(db, venue.ID, start, end, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถดึงการจองได้"})
+		return
+	}
+	data := make([]gin.H, 0, len(bookings))
+	for _, b := range bookings {
+		data = append(data, gin.H{
+			"booking_id":  b.ID,
+			"activity_id": b.ActivityID,
+			"title":       b.Activity.Title,
+			"start_time":  b.StartTime,
+			"end_time":    b.EndTime,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "venue": venue, "data": data})
+}
+
+// GET /venues/:id/availability?start=&end= - ตรวจว่าสถานที่ว่างในช่วงเวลาหรือไม่
+// ระบุ exclude_activity_id เพื่อไม่นับการจองของกิจกรรมที่กำลังแก้ไขอยู่
+func GetVenueAvailability(c *gin.Context) {
+	db := config.DB()
+
+	var venue entity.Venue
+	if err := db.First(&venue, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบสถานที่"})
+		return
+	}
+	start, end, err := parseTimeRangeQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	exclude, _ := strconv.ParseUint(c.Query("exclude_activity_id"), 10, 32)
+
+	conflicts, err := findVenueConflicts(db, venue.ID, start, end, uint(exclude))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ไม่สามารถตรวจสอบการจองได้"})
+		return
+	}
+	items := make([]gin.H, 0, len(conflicts))
+	for _, b := range conflicts {
+		items = append(items, gin.H{
+			"activity_id": b.ActivityID,
+			"title":       b.Activity.Title,
+			"start_time":  b.StartTime,
+			"end_time":    b.EndTime,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"available": venue.IsActive && len(conflicts) == 0,
+		"is_active": venue.IsActive,
+		"capacity":  venue.Capacity,
+		"conflicts": items,
+	})
+}
@@ -1,7 +1,9 @@
 package controllers
 
 import (
+	"encoding/json"
 	"errors"
+	"net/http"
 	"slices"
 	"strings"
 
@@ -45,23 +47,100 @@ func hasClubRole(db *gorm.DB, userID uint, clubID uint, roles ...string) (bool,
 	return slices.Contains(roles, m.Role), nil
 }
 
-// บังคับสิทธิ์: ต้องเป็นหัวหน้า/รองหัวหน้า
-func requireOfficer(c *gin.Context, clubID uint) (*entity.User, error) {
+// สิทธิ์ในชมรมที่กำหนดให้บทบาทได้
+const (
+	permManageMembers     = "manage_members"     // อนุมัติ/ลบสมาชิก
+	permPostAnnouncements = "post_announcements" // ประกาศข่าวชมรม
+	permCreateActivities  = "create_activities"  // สร้างและจัดการกิจกรรม
+	permManageBudget      = "manage_budget"      // งบประมาณและทรัพย์สินของชมรม
+	permViewMemberPII     = "view_member_pii"    // ดูข้อมูลส่วนตัวของสมาชิก เช่น อีเมล
+)
+
+var clubPermissions = []string{permManageMembers, permPostAnnouncements, permCreateActivities, permManageBudget, permViewMemberPII}
+
+// ชื่อบทบาทที่ระบบใช้เอง ตั้งเป็นบทบาทของชมรมไม่ได้
+var reservedClubRoles = []string{"president", "member", "pending", "advisor"}
+
+// สิทธิ์ของบทบาทในชมรม ประธานมีทุกสิทธิ์ บทบาทที่ไม่มีในชมรมถือว่าไม่มีสิทธิ์
+func clubRolePermissions(db *gorm.DB, clubID uint, role string) ([]string, error) {
+	if role == "president" {
+		return clubPermissions, nil
+	}
+	if slices.Contains(reservedClubRoles, role) {
+		return nil, nil
+	}
+	var r entity.ClubRole
+	if err := db.Where("club_id = ? AND name = ?", clubID, role).First(&r).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return decodeClubPermissions(r.Permissions), nil
+}
+
+func decodeClubPermissions(raw string) []string {
+	var perms []string
+	if err := json.Unmarshal([]byte(raw), &perms); err != nil {
+		return nil
+	}
+	return perms
+}
+
+// คืน true ถ้า user เป็นสมาชิกชมรมและมีสิทธิ์ใดสิทธิ์หนึ่งในที่กำหนด
+func hasClubPermission(db *gorm.DB, userID uint, clubID uint, perms ...string) (bool, error) {
+	var m entity.ClubMember
+	if err := db.Where("club_id = ? AND user_id = ?", clubID, userID).First(&m).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	granted, err := clubRolePermissions(db, clubID, m.Role)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range perms {
+		if slices.Contains(granted, p) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// สมาชิกของชมรมที่มีสิทธิ์ที่กำหนด ใช้หาผู้รับแจ้งเตือน
+func clubMemberIDsWithPermission(db *gorm.DB, clubID uint, perm string) []uint {
+	var members []entity.ClubMember
+	db.Where("club_id = ?", clubID).Find(&members)
+	var ids []uint
+	for _, m := range members {
+		if granted, err := clubRolePermissions(db, clubID, m.Role); err == nil && slices.Contains(granted, perm) {
+			ids = append(ids, m.UserID)
+		}
+	}
+	return ids
+}
+
+// บังคับสิทธิ์: ต้องมีสิทธิ์ใดสิทธิ์หนึ่งในชมรม
+func requireClubPermission(c *gin.Context, clubID uint, perms ...string) (*entity.User, error) {
 	db := config.DB()
 	user, err := getUserFromJWT(c)
 	if err != nil {
 		c.JSON(401, gin.H{"error": err.Error()})
 		return nil, err
 	}
-	ok, err := hasClubRole(db, user.ID, clubID, "president", "vice_president")
+	ok, err := hasClubPermission(db, user.ID, clubID, perms...)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "permission check failed"})
 		return nil, err
 	}
 	if !ok {
-		c.JSON(403, gin.H{"error": "forbidden: officer only"})
+		c.JSON(403, gin.H{"error": "forbidden: requires " + strings.Join(perms, " or ")})
 		return nil, errors.New("forbidden")
 	}
+	if err := rejectArchivedClubWrite(c, clubID); err != nil {
+		return nil, err
+	}
 	return user, nil
 }
 
@@ -82,5 +161,73 @@ func requirePresident(c *gin.Context, clubID uint) (*entity.User, error) {
 		c.JSON(403, gin.H{"error": "forbidden: president only"})
 		return nil, errors.New("forbidden")
 	}
+	if err := rejectArchivedClubWrite(c, clubID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// บังคับสิทธิ์: ต้องมีสิทธิ์จัดการกิจกรรมในชมรมใดชมรมหนึ่งที่จัดกิจกรรม (รวมชมรมผู้ร่วมจัด)
+func requireActivityOfficer(c *gin.Context, activity *entity.Activity) (*entity.User, error) {
+	db := config.DB()
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(401, gin.H{"error": err.Error()})
+		return nil, err
+	}
+	clubIDs, err := activityHostClubIDs(db, activity)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "permission check failed"})
+		return nil, err
+	}
+	for _, clubID := range clubIDs {
+		ok, err := hasClubPermission(db, user.ID, clubID, permCreateActivities)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "permission check failed"})
+			return nil, err
+		}
+		if ok {
+			if err := rejectArchivedClubWrite(c, activity.ClubID); err != nil {
+				return nil, err
+			}
+			return user, nil
+		}
+	}
+	c.JSON(403, gin.H{"error": "forbidden: requires " + permCreateActivities})
+	return nil, errors.New("forbidden")
+}
+
+// ชมรมที่ยุบแล้วเป็นแบบอ่านอย่างเดียว ทุก request ที่ไม่ใช่ GET ผ่าน helper สิทธิ์ชมรมจะถูกปฏิเสธ
+func rejectArchivedClubWrite(c *gin.Context, clubID uint) error {
+	if c.Request.Method == http.MethodGet {
+		return nil
+	}
+	if err := checkClubWritable(config.DB(), clubID); err != nil {
+		respondClubStateError(c, err)
+		return err
+	}
+	return nil
+}
+
+// คืน true ถ้า user มีบทบาทผู้ดูแลระบบ
+func isAdmin(db *gorm.DB, user *entity.User) bool {
+	var role entity.Role
+	if err := db.First(&role, user.RoleID).Error; err != nil {
+		return false
+	}
+	return role.RoleName == "admin"
+}
+
+// บังคับสิทธิ์: ต้องเป็นผู้ดูแลระบบ
+func requireAdmin(c *gin.Context) (*entity.User, error) {
+	user, err := getUserFromJWT(c)
+	if err != nil {
+		c.JSON(401, gin.H{"error": err.Error()})
+		return nil, err
+	}
+	if !isAdmin(config.DB(), user) {
+		c.JSON(403, gin.H{"error": "forbidden: admin only"})
+		return nil, errors.New("forbidden")
+	}
 	return user, nil
 }
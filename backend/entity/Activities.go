@@ -18,10 +18,32 @@ type Activity struct {
 	StatusID    uint
 	ClubID      uint
 	CategoryID  uint
+	VenueID     *uint // สถานที่ที่จองในระบบ (ถ้ามี) Location ยังใช้เป็นคำอธิบายสถานที่แบบข้อความ
+
+	// สถานะการพิจารณาอนุมัติ: รอบที่ส่ง และขั้นที่รออยู่ (0 = ไม่ได้อยู่ระหว่างพิจารณา)
+	SubmittedBy   uint
+	ApprovalRound int
+	ApprovalStep  int
+	// เวลาที่อนุมัติครบทุกขั้นครั้งแรก กิจกรรมที่มีค่านี้ถือว่าเผยแพร่แล้ว แม้จะกลับไปพิจารณารอบใหม่
+	ApprovedAt *time.Time
+
+	// กิจกรรมที่เกิดซ้ำ: series ที่สังกัด วันเวลาเดิมตามกฎ และถูกแก้ไขแยกจาก series หรือไม่
+	SeriesID        *uint
+	RecurrenceID    *time.Time
+	SeriesException bool
+
+	// เพิ่มทุกครั้งที่แก้ไขกิจกรรม ใช้เป็น SEQUENCE ในไฟล์ปฏิทิน (.ics)
+	CalendarSequence int
+
+	// กิจกรรมที่มีหลายรอบ: ต้องเข้าร่วมอย่างน้อยกี่เปอร์เซ็นต์ของรอบทั้งหมดจึงถือว่าผ่าน (0 = ต้องเข้าครบทุกรอบ)
+	MinAttendancePercent int
 
 	Status                ActivityStatus
 	Club                  Club
 	Category              EventCategory
+	Venue                 *Venue
 	ActivityRegistrations []ActivityRegistration `gorm:"foreignKey:ActivityID"`
 	ActivityPhotos        []ActivityPhoto        `gorm:"foreignKey:ActivityID"`
+	Sessions              []ActivitySession      `gorm:"foreignKey:ActivityID"`
+	Hosts                 []ActivityHost         `gorm:"foreignKey:ActivityID"`
 }
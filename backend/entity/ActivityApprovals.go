@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ขั้นตอนในสายการอนุมัติกิจกรรม เรียงตาม StepOrder
+type ApprovalStep struct {
+	gorm.Model
+	StepOrder    int
+	Name         string
+	ApproverRole string // club_president, club_advisor, admin
+}
+
+// ผลการพิจารณาของผู้อนุมัติแต่ละขั้น
+type ActivityApproval struct {
+	gorm.Model
+	ActivityID uint
+	Round      int
+	StepOrder  int
+	StepName   string
+	ApproverID uint
+	Decision   string // approved, rejected, changes_requested
+	Comment    string
+	DecidedAt  time.Time
+
+	Activity Activity `gorm:"foreignKey:ActivityID"`
+	Approver User     `gorm:"foreignKey:ApproverID"`
+}
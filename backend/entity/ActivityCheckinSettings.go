@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// การตั้งค่าการเช็คชื่อด้วยตนเองของกิจกรรม (รหัสหมุนเวียน + ขอบเขตพิกัด)
+type ActivityCheckinSetting struct {
+	gorm.Model
+	ActivityID   uint   `gorm:"uniqueIndex"`
+	Secret       string `json:"-"`
+	CodeInterval int    // วินาทีที่รหัสเปลี่ยน
+	WindowStart  time.Time
+	WindowEnd    time.Time
+	Latitude     float64
+	Longitude    float64
+	RadiusMeters float64 // 0 = ไม่ตรวจพิกัด
+
+	Activity Activity `gorm:"foreignKey:ActivityID"`
+}
@@ -0,0 +1,18 @@
+package entity
+
+import "gorm.io/gorm"
+
+// ชมรมที่ร่วมจัดกิจกรรม Activity.ClubID คือชมรมหลัก (lead host) และมีแถวที่ IsLead = true เสมอเมื่อมีผู้ร่วมจัด
+// กิจกรรมที่ไม่มีแถวในตารางนี้ถือว่าจัดโดย Activity.ClubID เพียงชมรมเดียว
+// ชมรมผู้ร่วมจัดมีสิทธิ์และได้เครดิตเมื่อ officer ของชมรมนั้นตอบรับแล้ว (Status = accepted) เท่านั้น
+type ActivityHost struct {
+	gorm.Model
+	ActivityID  uint `gorm:"uniqueIndex:idx_activity_host"`
+	ClubID      uint `gorm:"uniqueIndex:idx_activity_host"`
+	IsLead      bool
+	CreditShare float64 // เปอร์เซ็นต์ผลงานที่ชมรมได้รับในรายงาน รวมทุกชมรมต้องเป็น 100
+	Status      string  `gorm:"default:accepted"` // pending, accepted
+
+	Activity Activity `gorm:"foreignKey:ActivityID"`
+	Club     Club     `gorm:"foreignKey:ClubID"`
+}
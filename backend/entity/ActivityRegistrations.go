@@ -13,4 +13,8 @@ type ActivityRegistration struct {
 	Status		ActivityRegistrationStatus
 	
 	RegisteredAt time.Time
+
+	// เมื่อกิจกรรมถูกเลื่อน ผู้ลงทะเบียนต้องยืนยันภายใน ReconfirmBy ไม่เช่นนั้นจะถูกยกเลิกสิทธิ์
+	ReconfirmBy   *time.Time
+	ReconfirmedAt *time.Time
 }
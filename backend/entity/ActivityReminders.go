@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// กำหนดว่าจะเตือนผู้ลงทะเบียนก่อนกิจกรรมเริ่มกี่นาที เช่น 1440 (24 ชั่วโมง) และ 60 (1 ชั่วโมง)
+type ReminderRule struct {
+	gorm.Model
+	MinutesBefore int `gorm:"uniqueIndex"`
+	Enabled       bool
+}
+
+// บันทึกการเตือนที่ส่งไปแล้ว ใช้กันไม่ให้ส่งซ้ำ
+type ActivityReminderLog struct {
+	gorm.Model
+	ActivityID    uint
+	UserID        uint
+	Kind          string // scheduled, adhoc
+	MinutesBefore int    // ของ rule ที่ส่ง (0 สำหรับ adhoc)
+	Message       string
+	SentBy        uint // officer ที่สั่งส่ง (0 ถ้าระบบส่งเอง)
+	SentAt        time.Time
+
+	Activity Activity `gorm:"foreignKey:ActivityID"`
+	User     User     `gorm:"foreignKey:UserID"`
+}
@@ -3,12 +3,21 @@ import (
 	"gorm.io/gorm"
 	"time"
 )
-// รีวิวกิจกรรม
+// รีวิวกิจกรรม ผู้ใช้หนึ่งคนรีวิวกิจกรรมได้ครั้งเดียว
 type ActivityReview struct {
 	gorm.Model
-	ActivityID uint
-	UserID     uint
+	ActivityID uint `gorm:"uniqueIndex:idx_activity_review_user"`
+	UserID     uint `gorm:"uniqueIndex:idx_activity_review_user"`
 	Rating     int
 	Comment    string
 	CreatedAt  time.Time
+
+	// การซ่อนโดยผู้ดูแลระบบ รีวิวที่ถูกซ่อนไม่นับในคะแนนเฉลี่ย
+	IsHidden     bool
+	HiddenReason string
+	ModeratedBy  *uint
+	ModeratedAt  *time.Time
+
+	User    User           `gorm:"foreignKey:UserID"`
+	Reports []ReviewReport `gorm:"foreignKey:ReviewID"`
 }
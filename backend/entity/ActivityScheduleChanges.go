@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ประวัติการยกเลิกหรือเลื่อนกิจกรรม
+type ActivityScheduleChange struct {
+	gorm.Model
+	ActivityID        uint
+	Kind              string // cancel, reschedule
+	Reason            string
+	OldDateStart      time.Time
+	OldDateEnd        time.Time
+	NewDateStart      *time.Time
+	NewDateEnd        *time.Time
+	ReconfirmDeadline *time.Time
+	AffectedCount     int
+	ChangedBy         uint
+
+	Activity Activity `gorm:"foreignKey:ActivityID"`
+	User     User     `gorm:"foreignKey:ChangedBy"`
+}
@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ชุดกิจกรรมที่เกิดซ้ำตาม RRULE (RFC 5545) แต่ละครั้งถูกสร้างเป็น Activity แยกกัน
+type ActivitySeries struct {
+	gorm.Model
+	ClubID          uint
+	RRule           string
+	DTStart         time.Time
+	DurationMinutes int
+	ExDates         string // วันที่ที่ยกเลิกเฉพาะครั้ง เก็บเป็น JSON array ของเวลา
+	ParentSeriesID  *uint  // series เดิมก่อนถูกแยกด้วยการแก้ไข "ครั้งนี้และครั้งต่อไป"
+
+	Club        Club       `gorm:"foreignKey:ClubID"`
+	Occurrences []Activity `gorm:"foreignKey:SeriesID"`
+}
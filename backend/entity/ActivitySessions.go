@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// รอบย่อยของกิจกรรมที่จัดหลายวัน/หลายครั้ง เช่น ค่าย 3 วัน หรือ workshop 8 สัปดาห์
+type ActivitySession struct {
+	gorm.Model
+	ActivityID uint
+	Title      string
+	Location   string // ว่าง = ใช้สถานที่ของกิจกรรม
+	DateStart  time.Time
+	DateEnd    time.Time
+	Capacity   int // 0 = ไม่จำกัด
+
+	Activity Activity `gorm:"foreignKey:ActivityID"`
+}
+
+// การเข้าร่วมของผู้ลงทะเบียนในแต่ละรอบ
+type SessionAttendance struct {
+	gorm.Model
+	SessionID      uint `gorm:"uniqueIndex:idx_session_registration"`
+	RegistrationID uint `gorm:"uniqueIndex:idx_session_registration"`
+	Present        bool
+	CheckinTime    *time.Time
+	Method         string // self_checkin, manual
+	RecordedBy     uint
+
+	Session      ActivitySession      `gorm:"foreignKey:SessionID"`
+	Registration ActivityRegistration `gorm:"foreignKey:RegistrationID"`
+}
@@ -0,0 +1,23 @@
+package entity
+
+import "gorm.io/gorm"
+
+// แม่แบบกิจกรรมของชมรม ใช้สร้างกิจกรรมครั้งถัดไปได้ในคำสั่งเดียว
+type ActivityTemplate struct {
+	gorm.Model
+	ClubID          uint   `gorm:"uniqueIndex:idx_club_template_name"`
+	Name            string `gorm:"uniqueIndex:idx_club_template_name"`
+	Title           string
+	Description     string
+	Location        string
+	DurationMinutes int
+	Capacity        int
+	PosterImage     string
+	CategoryID      uint
+	VenueID         *uint
+	Settings        string // แบบฟอร์มลงทะเบียน การสมัครแบบทีม และการเช็คชื่อ เก็บเป็น JSON
+	CreatedBy       uint
+
+	Club     Club          `gorm:"foreignKey:ClubID"`
+	Category EventCategory `gorm:"foreignKey:CategoryID"`
+}
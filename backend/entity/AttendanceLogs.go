@@ -10,6 +10,9 @@ type AttendanceLog struct {
 	RegistrationID uint
 	CheckinTime    time.Time
 	CheckoutTime   time.Time
+	Method         string // วิธีเช็คชื่อ เช่น self_checkin
+	Latitude       *float64
+	Longitude      *float64
 
 	Registration ActivityRegistration `gorm:"foreignKey:RegistrationID"`
 }
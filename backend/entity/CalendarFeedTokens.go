@@ -0,0 +1,12 @@
+package entity
+
+import "gorm.io/gorm"
+
+// token ลับสำหรับ URL ปฏิทินส่วนตัวของผู้ใช้ (สร้างใหม่ได้เมื่อ URL รั่วไหล)
+type CalendarFeedToken struct {
+	gorm.Model
+	UserID uint   `gorm:"uniqueIndex"`
+	Token  string `gorm:"uniqueIndex"`
+
+	User User `gorm:"foreignKey:UserID"`
+}
@@ -0,0 +1,19 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// การกรอกรหัสเช็คชื่อด้วยตนเองที่ไม่ถูกต้อง ใช้จำกัดจำนวนครั้งเพื่อกันการเดารหัส
+type CheckinAttempt struct {
+	gorm.Model
+	ActivityID  uint   `gorm:"index"`
+	UserID      uint   `gorm:"index"`
+	IPAddress   string `gorm:"index"`
+	AttemptedAt time.Time
+
+	Activity Activity `gorm:"foreignKey:ActivityID"`
+	User     User     `gorm:"foreignKey:UserID"`
+}
@@ -0,0 +1,53 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// คำถามในใบสมัครเข้าชมรม
+type ClubApplicationQuestion struct {
+	gorm.Model
+	ClubID    uint `gorm:"index"`
+	Label     string
+	Type      string // text, choice, number
+	Options   string // ตัวเลือกของคำถามแบบ choice เก็บเป็น JSON array
+	Required  bool
+	SortOrder int
+}
+
+// ใบสมัครเข้าชมรม สมาชิกภาพระหว่างรอยังเป็น ClubMember role pending เหมือนเดิม
+// ใบสมัครเก็บคำตอบ บันทึกของกรรมการ และผลการพิจารณาไว้แม้สมาชิก pending จะถูกลบไปแล้ว
+type ClubApplication struct {
+	gorm.Model
+	ClubID          uint   `gorm:"index"`
+	UserID          uint   `gorm:"index"`
+	Status          string // pending, approved, rejected, cancelled
+	SubmittedAt     time.Time
+	RejectionReason string
+	DecidedBy       *uint
+	DecidedAt       *time.Time
+
+	User    User                    `gorm:"foreignKey:UserID"`
+	Answers []ClubApplicationAnswer `gorm:"foreignKey:ApplicationID"`
+}
+
+// คำตอบในใบสมัคร เก็บหัวข้อคำถาม ณ เวลาที่ตอบไว้ด้วย เพราะชมรมแก้ชุดคำถามได้ภายหลัง
+type ClubApplicationAnswer struct {
+	gorm.Model
+	ApplicationID uint `gorm:"index"`
+	QuestionID    uint
+	Question      string
+	Value         string
+}
+
+// บันทึกของกรรมการต่อใบสมัคร ผู้สมัครมองไม่เห็น
+type ClubApplicationNote struct {
+	gorm.Model
+	ApplicationID uint `gorm:"index"`
+	AuthorID      uint
+	Body          string
+
+	Author User `gorm:"foreignKey:AuthorID"`
+}
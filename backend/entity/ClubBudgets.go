@@ -0,0 +1,53 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// งบประมาณที่ชมรมได้รับจัดสรรในแต่ละปีการศึกษา (พ.ศ.)
+type ClubBudget struct {
+	gorm.Model
+	ClubID       uint `gorm:"uniqueIndex:idx_club_budget_year"`
+	AcademicYear int  `gorm:"uniqueIndex:idx_club_budget_year"`
+	Amount       float64
+	Note         string
+	AllocatedBy  uint
+
+	Club Club `gorm:"foreignKey:ClubID"`
+}
+
+// รายการค่าใช้จ่ายของชมรม ต้องผ่านการอนุมัติ เหรัญญิก → อาจารย์ที่ปรึกษา → กองกิจการนักศึกษา
+// จึงนับเป็นรายจ่ายจริงและหักจากงบประมาณ
+type ClubExpense struct {
+	gorm.Model
+	ClubID       uint  `gorm:"index"`
+	AcademicYear int   `gorm:"index"` // คำนวณจาก SpentAt
+	ActivityID   *uint `gorm:"index"`
+	Title        string
+	Description  string
+	Amount       float64
+	SpentAt      time.Time
+	ReceiptImage string // URL สำหรับดูใบเสร็จ (/expenses/:id/receipt ที่ตรวจสิทธิ์)
+	Status       string // pending, approved, rejected
+	ApprovalStep string // treasurer, advisor, student_affairs ว่างเมื่อพิจารณาเสร็จแล้ว
+	SubmittedBy  uint
+
+	Activity  *Activity             `gorm:"foreignKey:ActivityID"`
+	Submitter User                  `gorm:"foreignKey:SubmittedBy"`
+	Approvals []ClubExpenseApproval `gorm:"foreignKey:ExpenseID"`
+}
+
+// ผลการพิจารณาค่าใช้จ่ายของแต่ละขั้น
+type ClubExpenseApproval struct {
+	gorm.Model
+	ExpenseID  uint `gorm:"index"`
+	Step       string
+	ApproverID uint
+	Decision   string // approved, rejected, skipped (ขั้นที่ไม่มีผู้พิจารณา เช่น ชมรมไม่มีอาจารย์ที่ปรึกษา)
+	Comment    string
+	DecidedAt  time.Time
+
+	Approver User `gorm:"foreignKey:ApproverID"`
+}
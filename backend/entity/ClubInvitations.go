@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ลิงก์เชิญเข้าชมรมที่แชร์ได้ (เช่น QR code ในงานแนะนำชมรม) URL เป็น Code ที่ต่อท้ายด้วยลายเซ็น
+type ClubInviteLink struct {
+	gorm.Model
+	ClubID      uint   `gorm:"index"`
+	Code        string `gorm:"uniqueIndex"`
+	Label       string
+	ExpiresAt   *time.Time // nil = ไม่หมดอายุ
+	MaxUses     int        // 0 = ไม่จำกัดจำนวนครั้ง
+	UseCount    int
+	AutoApprove bool // true = เข้าเป็นสมาชิกทันที, false = ส่งคำขอเข้าคิวพิจารณา
+	CreatedBy   uint
+	RevokedAt   *time.Time
+}
+
+// คำเชิญเฉพาะอีเมล ผู้ที่เข้าสู่ระบบด้วยอีเมลนี้เท่านั้นจึงตอบรับได้ และเข้าเป็นสมาชิกทันที
+type ClubInvitation struct {
+	gorm.Model
+	ClubID     uint   `gorm:"index"`
+	Email      string `gorm:"index"`
+	Token      string `gorm:"uniqueIndex"`
+	Status     string // pending, accepted, revoked
+	ExpiresAt  *time.Time
+	InvitedBy  uint
+	AcceptedBy *uint
+	AcceptedAt *time.Time
+}
@@ -0,0 +1,54 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ประวัติการเปลี่ยนสถานะชมรม (อนุมัติ ระงับ ต่ออายุ ยุบ) เก็บไว้แม้ชมรมถูกเก็บถาวรแล้ว
+type ClubStatusChange struct {
+	gorm.Model
+	ClubID     uint `gorm:"index"`
+	FromStatus string
+	ToStatus   string
+	Reason     string // approved, rejected, renewal_overdue, renewal_approved, dissolved
+	Note       string
+	ChangedBy  *uint // nil = ระบบ เช่นงานระงับชมรมที่ไม่ต่ออายุ
+}
+
+// รอบการต่ออายุชมรมประจำปีการศึกษา ผู้ดูแลระบบกำหนดช่วงเวลาและจำนวนสมาชิกขั้นต่ำ
+type ClubRenewalCycle struct {
+	gorm.Model
+	AcademicYear int `gorm:"uniqueIndex"`
+	OpensAt      time.Time
+	Deadline     time.Time
+	MinMembers   int
+}
+
+// คำขอต่ออายุของชมรมในปีการศึกษา ส่งซ้ำได้จนกว่าจะได้รับอนุมัติ
+type ClubRenewal struct {
+	gorm.Model
+	ClubID       uint   `gorm:"uniqueIndex:idx_club_renewal_year"`
+	AcademicYear int    `gorm:"uniqueIndex:idx_club_renewal_year"`
+	Status       string // submitted, approved, rejected
+	MemberCount  int64  // จำนวนสมาชิกที่อนุมัติแล้ว ณ เวลาที่ส่ง
+	SubmittedBy  uint
+	SubmittedAt  time.Time
+	ReviewedBy   *uint
+	ReviewedAt   *time.Time
+	ReviewNote   string
+
+	Club     Club                 `gorm:"foreignKey:ClubID"`
+	Officers []ClubRenewalOfficer `gorm:"foreignKey:RenewalID"`
+}
+
+// กรรมการชุดที่เสนอในคำขอต่ออายุ มีผลเมื่อผู้ดูแลระบบอนุมัติ
+type ClubRenewalOfficer struct {
+	gorm.Model
+	RenewalID uint `gorm:"index"`
+	UserID    uint
+	Role      string
+
+	User User `gorm:"foreignKey:UserID"`
+}
@@ -0,0 +1,13 @@
+package entity
+
+import "gorm.io/gorm"
+
+// บทบาทที่แต่ละชมรมกำหนดเองได้ ผูกกับ ClubMember.Role ด้วยชื่อ
+// president มีทุกสิทธิ์เสมอ ส่วน member และ pending ไม่มีสิทธิ์จัดการใด ๆ จึงไม่ต้องมี record
+type ClubRole struct {
+	gorm.Model
+	ClubID      uint   `gorm:"uniqueIndex:idx_club_role_name"`
+	Name        string `gorm:"uniqueIndex:idx_club_role_name"` // ค่าที่เก็บใน ClubMember.Role เช่น treasurer
+	DisplayName string
+	Permissions string // สิทธิ์ของบทบาท เก็บเป็น JSON array เช่น ["manage_budget","view_member_pii"]
+}
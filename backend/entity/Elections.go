@@ -0,0 +1,75 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// การเลือกตั้งกรรมการชมรม ช่วงสมัคร/ลงคะแนนคำนวณจากเวลา ส่วน Status เก็บเฉพาะสถานะที่ผู้ใช้สั่ง
+type Election struct {
+	gorm.Model
+	ClubID               uint `gorm:"index"`
+	Title                string
+	Description          string
+	NominationStart      time.Time
+	NominationEnd        time.Time
+	VotingStart          time.Time
+	VotingEnd            time.Time
+	EndorsementsRequired int    // จำนวนผู้รับรองขั้นต่ำที่ผู้สมัครต้องได้จึงมีชื่อในบัตร
+	QuorumPercent        int    // ร้อยละของผู้มีสิทธิ์ที่ต้องมาลงคะแนนผลจึงใช้ได้
+	TieBreak             string // seniority, lot, runoff
+	Status               string // scheduled, cancelled, certified
+	CreatedBy            uint
+	CertifiedBy          *uint
+	CertifiedAt          *time.Time
+
+	Positions  []ElectionPosition  `gorm:"foreignKey:ElectionID"`
+	Candidates []ElectionCandidate `gorm:"foreignKey:ElectionID"`
+}
+
+// ตำแหน่งที่เลือกตั้ง หนึ่งตำแหน่งหนึ่งคน Role คือบทบาทในชมรมที่ผู้ชนะจะได้รับ
+type ElectionPosition struct {
+	gorm.Model
+	ElectionID uint
+	Role       string
+	Title      string
+}
+
+// ผู้สมัคร สมัครได้เฉพาะตัวเองและสมัครได้ตำแหน่งเดียวต่อการเลือกตั้ง
+type ElectionCandidate struct {
+	gorm.Model
+	ElectionID  uint `gorm:"uniqueIndex:idx_election_candidate_user"`
+	PositionID  uint
+	UserID      uint `gorm:"uniqueIndex:idx_election_candidate_user"`
+	Statement   string
+	WithdrawnAt *time.Time
+
+	User         User                  `gorm:"foreignKey:UserID"`
+	Endorsements []ElectionEndorsement `gorm:"foreignKey:CandidateID"`
+}
+
+// การรับรองผู้สมัครโดยสมาชิกคนอื่น
+type ElectionEndorsement struct {
+	gorm.Model
+	CandidateID uint `gorm:"uniqueIndex:idx_election_endorsement_user"`
+	UserID      uint `gorm:"uniqueIndex:idx_election_endorsement_user"`
+}
+
+// ทะเบียนผู้มาลงคะแนน บอกว่าใครลงคะแนนตำแหน่งไหนแล้ว แต่ไม่ผูกกับบัตร
+type ElectionVoter struct {
+	gorm.Model
+	ElectionID uint `gorm:"uniqueIndex:idx_election_voter"`
+	PositionID uint `gorm:"uniqueIndex:idx_election_voter"`
+	UserID     uint `gorm:"uniqueIndex:idx_election_voter"`
+}
+
+// บัตรลงคะแนน ไม่มีผู้ลงคะแนนและเวลาเพื่อรักษาความลับ
+// ReceiptHash คือ sha256 ของใบรับที่ผู้ลงคะแนนได้รับ ใช้ตรวจสอบว่าบัตรของตนถูกนับ
+// ตารางสร้างแบบ WITHOUT ROWID (ดู config.migrateElectionBallots) เพื่อไม่ให้ลำดับการบันทึกโยงกลับไปหา ElectionVoter
+type ElectionBallot struct {
+	ReceiptHash string `gorm:"primaryKey"`
+	ElectionID  uint   `gorm:"index"`
+	PositionID  uint
+	CandidateID uint
+}
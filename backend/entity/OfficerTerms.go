@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// วาระการดำรงตำแหน่งกรรมการชมรม หนึ่ง record ต่อหนึ่งคนต่อหนึ่งบทบาทต่อหนึ่งปีการศึกษา
+type OfficerTerm struct {
+	gorm.Model
+	ClubID       uint `gorm:"index"`
+	UserID       uint
+	Role         string
+	AcademicYear int // ปีการศึกษา (พ.ศ.)
+	StartDate    time.Time
+	EndDate      *time.Time // nil = ยังดำรงตำแหน่งอยู่
+	EndReason    string     // handover, reassigned, removed, left, role_deleted, renewal, dissolved
+
+	User User `gorm:"foreignKey:UserID"`
+}
+
+// การส่งต่อกรรมการชุดใหม่ที่ตั้งเวลาไว้ ระบบจะเปลี่ยนบทบาทให้อัตโนมัติเมื่อถึง EffectiveAt
+type ClubHandover struct {
+	gorm.Model
+	ClubID       uint `gorm:"index"`
+	AcademicYear int
+	EffectiveAt  time.Time
+	Status       string // scheduled, completed, failed, cancelled
+	Note         string // สาเหตุที่ส่งต่อไม่สำเร็จ
+	CreatedBy    uint
+	AppliedAt    *time.Time
+
+	Officers []ClubHandoverOfficer `gorm:"foreignKey:HandoverID"`
+}
+
+// กรรมการชุดใหม่ในการส่งต่อ
+type ClubHandoverOfficer struct {
+	gorm.Model
+	HandoverID uint
+	UserID     uint
+	Role       string
+
+	User User `gorm:"foreignKey:UserID"`
+}
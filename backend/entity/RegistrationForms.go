@@ -0,0 +1,27 @@
+package entity
+
+import "gorm.io/gorm"
+
+// คำถามในแบบฟอร์มลงทะเบียนของกิจกรรม
+type RegistrationQuestion struct {
+	gorm.Model
+	ActivityID uint
+	Label      string
+	Type       string // text, choice, number, file
+	Options    string // ตัวเลือกของคำถามแบบ choice เก็บเป็น JSON array
+	Required   bool
+	SortOrder  int
+
+	Activity Activity `gorm:"foreignKey:ActivityID"`
+}
+
+// คำตอบแบบฟอร์มลงทะเบียนของผู้สมัครแต่ละคน
+type RegistrationAnswer struct {
+	gorm.Model
+	RegistrationID uint
+	QuestionID     uint
+	Value          string
+
+	Registration ActivityRegistration `gorm:"foreignKey:RegistrationID"`
+	Question     RegistrationQuestion `gorm:"foreignKey:QuestionID"`
+}
@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// อุปกรณ์ที่ให้ยืมได้ เช่น โปรเจกเตอร์ ชุดเครื่องเสียง เต็นท์ อุปกรณ์กีฬา
+type Resource struct {
+	gorm.Model
+	Name        string `gorm:"uniqueIndex"`
+	Category    string // projector, sound_system, tent, sports, ...
+	Description string
+	Quantity    int   // จำนวนทั้งหมดในคลัง
+	OwnerClubID *uint // ชมรมเจ้าของอุปกรณ์ ถ้าเป็น nil คือของกองกิจการนักศึกษา (admin อนุมัติ)
+	IsActive    bool
+
+	OwnerClub *Club `gorm:"foreignKey:OwnerClubID"`
+}
+
+// คำขอยืมอุปกรณ์ของกิจกรรมในช่วงเวลาหนึ่ง
+type ResourceReservation struct {
+	gorm.Model
+	ResourceID  uint
+	ActivityID  uint
+	Quantity    int
+	StartTime   time.Time
+	EndTime     time.Time // กำหนดคืน
+	Status      string    // pending, approved, rejected, picked_up, returned, cancelled
+	Note        string
+	RequestedBy uint
+
+	ReviewedBy *uint
+	ReviewedAt *time.Time
+	ReviewNote string
+
+	PickedUpAt *time.Time
+	HandedBy   *uint // ผู้ส่งมอบอุปกรณ์ฝั่งเจ้าของ
+	ReturnedAt *time.Time
+	ReceivedBy *uint
+	// สภาพอุปกรณ์ตอนรับคืน
+	ReturnNote string
+
+	OverdueNotifiedAt *time.Time
+
+	Resource Resource `gorm:"foreignKey:ResourceID"`
+	Activity Activity `gorm:"foreignKey:ActivityID"`
+}
@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// การรายงานรีวิวที่ไม่เหมาะสม ผู้ใช้หนึ่งคนรายงานรีวิวเดียวกันได้ครั้งเดียว
+type ReviewReport struct {
+	gorm.Model
+	ReviewID   uint `gorm:"uniqueIndex:idx_review_reporter"`
+	ReporterID uint `gorm:"uniqueIndex:idx_review_reporter"`
+	Reason     string
+	Status     string // pending, upheld, dismissed
+	ResolvedBy *uint
+	ResolvedAt *time.Time
+
+	Reporter User `gorm:"foreignKey:ReporterID"`
+}
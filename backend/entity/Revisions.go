@@ -0,0 +1,16 @@
+package entity
+
+import "gorm.io/gorm"
+
+// สำเนาข้อมูลของกิจกรรมหรือชมรมหลังการแก้ไขแต่ละครั้ง ใช้ดูประวัติและย้อนกลับ
+type Revision struct {
+	gorm.Model
+	EntityType    string `gorm:"uniqueIndex:idx_revision_version"` // activity, club
+	EntityID      uint   `gorm:"uniqueIndex:idx_revision_version"`
+	Version       int    `gorm:"uniqueIndex:idx_revision_version"`
+	Snapshot      string // ข้อมูลทั้งชุดเก็บเป็น JSON
+	ChangedFields string // ชื่อฟิลด์ที่เปลี่ยนจากเวอร์ชันก่อนหน้า เก็บเป็น JSON array
+	Action        string // initial, update, revert, system (เปลี่ยนจากทางอื่น เช่น เลื่อนกิจกรรมหรือผลการอนุมัติ)
+	RevertedFrom  *int   // เวอร์ชันที่ย้อนกลับไป เมื่อ Action เป็น revert
+	EditedBy      uint   // 0 ถ้าไม่ทราบผู้แก้ไข (เช่น ข้อมูลก่อนเปิดใช้ประวัติ หรือเวอร์ชันของระบบ)
+}
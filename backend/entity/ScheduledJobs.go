@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// งานเบื้องหลังที่รันตามตาราง cron
+type ScheduledJob struct {
+	gorm.Model
+	Name        string `gorm:"uniqueIndex"`
+	Description string
+	CronExpr    string
+	Paused      bool
+	MaxRetries  int
+	NextRunAt   *time.Time
+	LastRunAt   *time.Time
+	LastStatus  string // success, failed
+
+	// lock กันไม่ให้หลาย instance ของเซิร์ฟเวอร์รันงานเดียวกันพร้อมกัน
+	LockedBy    string
+	LockedUntil *time.Time
+}
+
+// ประวัติการรันงานแต่ละครั้ง (หนึ่งแถวต่อหนึ่งครั้งที่พยายามรัน)
+type JobRun struct {
+	gorm.Model
+	JobID      uint
+	Trigger    string // schedule, manual
+	Attempt    int
+	RunnerID   string
+	Status     string // running, success, failed
+	Error      string
+	StartedAt  time.Time
+	FinishedAt *time.Time
+
+	Job ScheduledJob `gorm:"foreignKey:JobID"`
+}
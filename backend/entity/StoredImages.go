@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// รูปภาพที่ผ่านขั้นตอนอัปโหลด เก็บแบบ content-addressed ตาม hash ของไฟล์ต้นฉบับ
+// ไฟล์เดียวกันที่อัปโหลดซ้ำจะใช้ record และไฟล์ชุดเดิม
+type StoredImage struct {
+	gorm.Model
+	Hash       string `gorm:"uniqueIndex"` // sha256 ของไฟล์ต้นฉบับ
+	Purpose    string // club_logo, activity_poster, profile_picture
+	MimeType   string // ชนิดไฟล์ที่ตรวจจากเนื้อไฟล์
+	Size       int64
+	Width      int
+	Height     int
+	Variants   string // URL ของแต่ละขนาดและรูปแบบ เก็บเป็น JSON เช่น {"thumbnail":{"jpeg":"/images/..."}}
+	UploadedBy uint
+	LastUsedAt time.Time // เวลาที่ถูกอัปโหลด/อ้างอิงล่าสุด ใช้กันไม่ให้ลบรูปที่เพิ่งอัปโหลดแต่ยังไม่ได้บันทึก
+}
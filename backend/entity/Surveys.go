@@ -0,0 +1,66 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// แบบสอบถามหลังกิจกรรม กิจกรรมละหนึ่งชุด
+type Survey struct {
+	gorm.Model
+	ActivityID       uint `gorm:"uniqueIndex"`
+	Title            string
+	Description      string
+	IsAnonymous      bool // ไม่บันทึกว่าใครตอบคำตอบไหน
+	SendDelayMinutes int  // ส่งหลังกิจกรรมสิ้นสุดกี่นาที
+	OpenDays         int  // เปิดรับคำตอบกี่วันหลังส่ง
+	MaxReminders     int  // เตือนผู้ที่ยังไม่ตอบได้สูงสุดกี่ครั้ง
+	SentAt           *time.Time
+	ClosesAt         *time.Time
+	CreatedBy        uint
+
+	Activity  Activity         `gorm:"foreignKey:ActivityID"`
+	Questions []SurveyQuestion `gorm:"foreignKey:SurveyID"`
+}
+
+// คำถามในแบบสอบถาม
+type SurveyQuestion struct {
+	gorm.Model
+	SurveyID  uint
+	Label     string
+	Type      string // likert, choice, text
+	Options   string // ตัวเลือกของคำถามแบบ choice เก็บเป็น JSON array
+	Required  bool
+	SortOrder int
+}
+
+// ผู้ที่ได้รับแบบสอบถาม ใช้ติดตามการตอบและการเตือน แยกจากคำตอบเพื่อรองรับแบบไม่ระบุตัวตน
+type SurveyInvitation struct {
+	gorm.Model
+	SurveyID       uint `gorm:"uniqueIndex:idx_survey_invitation_user"`
+	UserID         uint `gorm:"uniqueIndex:idx_survey_invitation_user"`
+	SentAt         time.Time
+	RemindersSent  int
+	LastRemindedAt *time.Time
+	RespondedAt    *time.Time // แบบสอบถามไม่ระบุตัวตนเก็บแค่ระดับวัน
+
+	Survey Survey `gorm:"foreignKey:SurveyID"`
+}
+
+// ชุดคำตอบของผู้ตอบหนึ่งคน UserID เป็น nil ถ้าแบบสอบถามไม่ระบุตัวตน
+type SurveyResponse struct {
+	gorm.Model
+	SurveyID uint
+	UserID   *uint
+
+	User    *User          `gorm:"foreignKey:UserID"`
+	Answers []SurveyAnswer `gorm:"foreignKey:ResponseID"`
+}
+
+type SurveyAnswer struct {
+	gorm.Model
+	ResponseID uint
+	QuestionID uint
+	Value      string
+}
@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// การตั้งค่าการสมัครแบบทีมของกิจกรรม
+type ActivityTeamSetting struct {
+	gorm.Model
+	ActivityID   uint `gorm:"uniqueIndex"`
+	MinTeamSize  int
+	MaxTeamSize  int
+	CapacityUnit string // people หรือ teams
+
+	Activity Activity `gorm:"foreignKey:ActivityID"`
+}
+
+// ทีมที่สมัครเข้าร่วมกิจกรรม
+type Team struct {
+	gorm.Model
+	ActivityID  uint
+	Name        string
+	CaptainID   uint
+	Status      string // forming, confirmed, cancelled
+	ConfirmedAt *time.Time
+
+	Activity Activity     `gorm:"foreignKey:ActivityID"`
+	Captain  User         `gorm:"foreignKey:CaptainID"`
+	Members  []TeamMember `gorm:"foreignKey:TeamID"`
+}
+
+// สมาชิกในทีม รวมถึงคำเชิญที่ยังไม่ได้ตอบรับ
+type TeamMember struct {
+	gorm.Model
+	TeamID      uint
+	UserID      uint
+	Status      string // invited, accepted, declined
+	InvitedAt   time.Time
+	RespondedAt *time.Time
+
+	User User `gorm:"foreignKey:UserID"`
+}
@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// สถานที่จัดกิจกรรมที่จองได้
+type Venue struct {
+	gorm.Model
+	Name       string `gorm:"uniqueIndex"`
+	Building   string
+	Room       string
+	Capacity   int
+	Facilities string // สิ่งอำนวยความสะดวก เก็บเป็น JSON array เช่น ["projector","sound_system"]
+	Latitude   float64
+	Longitude  float64
+	IsActive   bool
+
+	Bookings []VenueBooking `gorm:"foreignKey:VenueID"`
+}
+
+// การจองสถานที่ของกิจกรรม แต่ละกิจกรรมมีการจองที่ active ได้ครั้งละหนึ่งรายการ
+type VenueBooking struct {
+	gorm.Model
+	VenueID    uint
+	ActivityID uint
+	StartTime  time.Time
+	EndTime    time.Time
+	Status     string // active, released
+	BookedBy   uint
+
+	Venue    Venue    `gorm:"foreignKey:VenueID"`
+	Activity Activity `gorm:"foreignKey:ActivityID"`
+}
@@ -57,6 +57,9 @@ func main() {
 	activityHandler := controllers.NewActivityHandler(db)
 
 	notificationHandler := controllers.NewNotificationHandler(db)
+
+	// เริ่มงานเบื้องหลังตามตาราง
+	controllers.StartScheduler(db)
 	
 	reportHandler := &controllers.ReportHandler{DB: db}
 
@@ -112,6 +115,11 @@ func main() {
 		router.PUT("/clubs/:id/announcements/:annId",controllers.UpdateClubAnnouncement)
 		router.DELETE("/clubs/:id/announcements/:annId",controllers.DeleteClubAnnouncement)
 		router.GET("/clubs/:id/announcements/:annId",  controllers.GetClubAnnouncementByID)
+		router.GET("/clubs/:id/calendar", controllers.GetClubCalendarFeed)
+		router.GET("/clubs/:id/activity-templates", controllers.GetActivityTemplates)
+		router.POST("/clubs/:id/activity-templates", controllers.CreateActivityTemplate)
+		router.DELETE("/clubs/:id/activity-templates/:templateId", controllers.DeleteActivityTemplate)
+		router.POST("/clubs/:id/activity-templates/:templateId/activities", controllers.CreateActivityFromTemplate)
 
 
 		
@@ -136,6 +144,203 @@ func main() {
 		router.GET("/activities/without-photo",controllers.GetActivitiesWithoutPhotos)
 		router.GET("/activities/photo/:id",controllers.GetPhotosByActivityId)
 		router.POST("/activities/photo/:id", controllers.AddPhotoToActivity)
+		router.GET("/activities/:id/form", controllers.GetRegistrationForm)
+		router.PUT("/activities/:id/form", controllers.UpdateRegistrationForm)
+		router.GET("/activities/:id/form/answers", controllers.GetRegistrationAnswers)
+		router.GET("/activities/:id/form/export", controllers.ExportRegistrationAnswers)
+		router.GET("/activities/:id/form/files/:registrationId/:questionId", controllers.GetRegistrationFormFile)
+		router.GET("/activities/:id/team-settings", controllers.GetTeamSetting)
+		router.PUT("/activities/:id/team-settings", controllers.UpdateTeamSetting)
+		router.GET("/activities/:id/teams", controllers.GetTeamsByActivity)
+		router.POST("/activities/:id/teams", controllers.CreateTeam)
+		router.GET("/activities/:id/checkin-settings", controllers.GetCheckinSetting)
+		router.PUT("/activities/:id/checkin-settings", controllers.UpdateCheckinSetting)
+		router.GET("/activities/:id/checkin-code", controllers.GetCheckinCode)
+		router.POST("/activities/:id/self-checkin", controllers.SelfCheckin)
+		router.POST("/activities/:id/submit", controllers.SubmitActivityForApproval)
+		router.POST("/activities/:id/approval", controllers.DecideActivityApproval)
+		router.GET("/activities/:id/approvals", controllers.GetActivityApprovals)
+		router.GET("/activities/:id/reminders", controllers.GetActivityReminderLogs)
+		router.POST("/activities/:id/reminders", controllers.SendAdhocReminder)
+		router.POST("/activities/:id/cancel", controllers.CancelActivity)
+		router.POST("/activities/:id/reschedule", controllers.RescheduleActivity)
+		router.POST("/activities/:id/reconfirm", controllers.ReconfirmRegistration)
+		router.GET("/activities/:id/changes", controllers.GetActivityScheduleChanges)
+		router.GET("/activities/:id/ics", controllers.DownloadActivityICS)
+		router.POST("/activities/:id/clone", controllers.CloneActivity)
+		router.GET("/activities/:id/sessions", controllers.GetActivitySessions)
+		router.POST("/activities/:id/sessions", controllers.CreateActivitySession)
+		router.PATCH("/activities/:id/sessions/:sessionId", controllers.UpdateActivitySession)
+		router.DELETE("/activities/:id/sessions/:sessionId", controllers.DeleteActivitySession)
+		router.GET("/activities/:id/sessions/:sessionId/attendance", controllers.GetSessionAttendance)
+		router.POST("/activities/:id/sessions/:sessionId/attendance", controllers.RecordSessionAttendanceBulk)
+		router.PUT("/activities/:id/attendance-rule", controllers.UpdateAttendanceRule)
+		router.GET("/activities/:id/attendance-summary", controllers.GetAttendanceSummary)
+		router.GET("/activities/:id/my-attendance", controllers.GetMyAttendance)
+		router.POST("/activities/:id/attendance/finalize", controllers.FinalizeActivityAttendance)
+		router.GET("/activities/:id/hosts", controllers.GetActivityHosts)
+		router.PUT("/activities/:id/hosts", controllers.UpdateActivityHosts)
+		router.POST("/activities/:id/hosts/:clubId/accept", controllers.AcceptActivityHost)
+		router.POST("/activities/:id/hosts/:clubId/decline", controllers.DeclineActivityHost)
+
+		// Routes for Activity Series
+		router.POST("/activity-series", controllers.CreateActivitySeries)
+		router.GET("/activity-series/:id", controllers.GetActivitySeries)
+		router.POST("/activity-series/:id/register", controllers.RegisterActivitySeries)
+		router.PATCH("/activity-series/:id/occurrences/:activityId", controllers.UpdateSeriesOccurrence)
+		router.POST("/activity-series/:id/occurrences/:activityId/cancel", controllers.CancelSeriesOccurrence)
+
+		// Routes for Venues
+		router.GET("/venues", controllers.GetVenues)
+		router.POST("/venues", controllers.CreateVenue)
+		router.GET("/venues/:id", controllers.GetVenueByID)
+		router.PUT("/venues/:id", controllers.UpdateVenue)
+		router.GET("/venues/:id/bookings", controllers.GetVenueBookings)
+		router.GET("/venues/:id/availability", controllers.GetVenueAvailability)
+
+		// Routes for Activity Reviews
+		router.GET("/activities/:id/reviews", controllers.GetActivityReviews)
+		router.GET("/activities/:id/reviews/me", controllers.GetMyActivityReview)
+		router.POST("/activities/:id/reviews", controllers.CreateActivityReview)
+		router.PUT("/reviews/:id", controllers.UpdateActivityReview)
+		router.DELETE("/reviews/:id", controllers.DeleteActivityReview)
+		router.POST("/reviews/:id/report", controllers.ReportActivityReview)
+		router.GET("/reviews/moderation", controllers.GetReviewModerationQueue)
+		router.POST("/reviews/:id/moderate", controllers.ModerateActivityReview)
+
+		// Routes for Revision History
+		router.GET("/activities/:id/revisions", controllers.GetActivityRevisions)
+		router.GET("/activities/:id/revisions/diff", controllers.DiffActivityRevisions)
+		router.POST("/activities/:id/revisions/:version/revert", controllers.RevertActivityRevision)
+		router.GET("/clubs/:id/revisions", controllers.GetClubRevisions)
+		router.GET("/clubs/:id/revisions/diff", controllers.DiffClubRevisions)
+		router.POST("/clubs/:id/revisions/:version/revert", controllers.RevertClubRevision)
+
+		// Routes for Club Roles & Permissions
+		router.GET("/clubs/:id/roles", controllers.GetClubRoles)
+		router.POST("/clubs/:id/roles", controllers.CreateClubRole)
+		router.PUT("/clubs/:id/roles/:roleId", controllers.UpdateClubRole)
+		router.DELETE("/clubs/:id/roles/:roleId", controllers.DeleteClubRole)
+		router.PUT("/clubs/:id/members/:userId/role", controllers.AssignClubMemberRole)
+		router.PUT("/clubs/:id/advisor", controllers.AssignClubAdvisor)
+		router.DELETE("/clubs/:id/advisor", controllers.RemoveClubAdvisor)
+		router.GET("/clubs/:id/permissions/me", controllers.GetMyClubPermissions)
+
+		// Routes for Officer Terms & Handover
+		router.GET("/clubs/:id/committees", controllers.GetClubCommittees)
+		router.GET("/clubs/:id/handovers", controllers.GetClubHandovers)
+		router.POST("/clubs/:id/handovers", controllers.ScheduleClubHandover)
+		router.POST("/clubs/:id/handovers/:handoverId/cancel", controllers.CancelClubHandover)
+
+		// Routes for Club Membership Applications
+		router.GET("/clubs/:id/application-questions", controllers.GetClubApplicationQuestions)
+		router.PUT("/clubs/:id/application-questions", controllers.UpdateClubApplicationQuestions)
+		router.GET("/clubs/:id/applications", controllers.GetClubApplications)
+		router.POST("/clubs/:id/applications/decision", controllers.DecideClubApplications)
+		router.GET("/clubs/:id/applications/:applicationId", controllers.GetClubApplicationByID)
+		router.POST("/clubs/:id/applications/:applicationId/notes", controllers.AddClubApplicationNote)
+		router.GET("/clubs/:id/my-application", controllers.GetMyClubApplication)
+
+		// Routes for Club Invitations & Member Import
+		router.GET("/clubs/:id/invite-links", controllers.GetClubInviteLinks)
+		router.POST("/clubs/:id/invite-links", controllers.CreateClubInviteLink)
+		router.POST("/clubs/:id/invite-links/:linkId/revoke", controllers.RevokeClubInviteLink)
+		router.GET("/club-invites/:token", controllers.GetClubInviteLink)
+		router.POST("/club-invites/:token/join", controllers.JoinClubByInviteLink)
+		router.GET("/clubs/:id/invitations", controllers.GetClubInvitations)
+		router.POST("/clubs/:id/invitations", controllers.CreateClubInvitations)
+		router.POST("/clubs/:id/invitations/:invitationId/revoke", controllers.RevokeClubInvitation)
+		router.POST("/club-invitations/:token/accept", controllers.AcceptClubInvitation)
+		router.POST("/clubs/:id/members/import", controllers.ImportClubMembers)
+
+		// Routes for Club Lifecycle (renewal, suspension, archival)
+		router.GET("/clubs/:id/status-history", controllers.GetClubStatusHistory)
+		router.POST("/clubs/:id/archive", controllers.ArchiveClub)
+		router.GET("/club-renewal-cycles", controllers.GetClubRenewalCycles)
+		router.PUT("/club-renewal-cycles/:year", controllers.UpsertClubRenewalCycle)
+		router.GET("/clubs/:id/renewals", controllers.GetClubRenewals)
+		router.POST("/clubs/:id/renewals", controllers.SubmitClubRenewal)
+		router.GET("/club-renewals", controllers.GetClubRenewalQueue)
+		router.POST("/club-renewals/:id/decision", controllers.DecideClubRenewal)
+
+		// Routes for Club Budgets & Expenses
+		router.GET("/clubs/:id/budgets", controllers.GetClubBudgets)
+		router.PUT("/clubs/:id/budgets/:year", controllers.SetClubBudget)
+		router.GET("/clubs/:id/budget", controllers.GetClubBudgetLedger)
+		router.GET("/clubs/:id/expenses", controllers.GetClubExpenses)
+		router.POST("/clubs/:id/expenses", controllers.CreateClubExpense)
+		router.GET("/expenses/pending", controllers.GetPendingClubExpenses)
+		router.GET("/expenses/:id", controllers.GetClubExpenseByID)
+		router.GET("/expenses/:id/receipt", controllers.GetClubExpenseReceipt)
+		router.PUT("/expenses/:id", controllers.UpdateClubExpense)
+		router.POST("/expenses/:id/decision", controllers.DecideClubExpense)
+
+		// Routes for Club Elections
+		router.GET("/clubs/:id/elections", controllers.GetClubElections)
+		router.POST("/clubs/:id/elections", controllers.CreateElection)
+		router.GET("/elections/:id", controllers.GetElectionByID)
+		router.POST("/elections/:id/cancel", controllers.CancelElection)
+		router.POST("/elections/:id/candidates", controllers.NominateElectionCandidate)
+		router.POST("/elections/:id/candidates/:candidateId/withdraw", controllers.WithdrawElectionCandidate)
+		router.POST("/elections/:id/candidates/:candidateId/endorse", controllers.EndorseElectionCandidate)
+		router.POST("/elections/:id/vote", controllers.CastElectionVote)
+		router.GET("/elections/:id/my-votes", controllers.GetMyElectionVotes)
+		router.GET("/elections/:id/results", controllers.GetElectionResults)
+		router.GET("/elections/:id/audit", controllers.GetElectionAudit)
+		router.GET("/elections/:id/verify", controllers.VerifyElectionBallot)
+		router.POST("/elections/:id/certify", controllers.CertifyElection)
+
+		router.GET("/activities/:id/survey", controllers.GetActivitySurvey)
+		router.PUT("/activities/:id/survey", controllers.UpsertActivitySurvey)
+		router.DELETE("/activities/:id/survey", controllers.DeleteActivitySurvey)
+		router.POST("/activities/:id/survey/send", controllers.SendActivitySurvey)
+		router.POST("/activities/:id/survey/responses", controllers.SubmitSurveyResponse)
+		router.GET("/activities/:id/survey/results", controllers.GetSurveyResults)
+		router.GET("/activities/:id/survey/export", controllers.ExportSurveyResponses)
+		router.GET("/surveys/pending", controllers.GetMyPendingSurveys)
+
+		router.GET("/resources", controllers.GetResources)
+		router.POST("/resources", controllers.CreateResource)
+		router.GET("/resources/:id", controllers.GetResourceByID)
+		router.PUT("/resources/:id", controllers.UpdateResource)
+		router.GET("/resources/:id/availability", controllers.GetResourceAvailability)
+		router.GET("/activities/:id/resources", controllers.GetActivityResources)
+		router.POST("/activities/:id/resources", controllers.RequestActivityResource)
+		router.GET("/resource-reservations", controllers.GetResourceReservationQueue)
+		router.POST("/resource-reservations/:id/approve", controllers.ApproveResourceReservation)
+		router.POST("/resource-reservations/:id/reject", controllers.RejectResourceReservation)
+		router.POST("/resource-reservations/:id/cancel", controllers.CancelResourceReservation)
+		router.POST("/resource-reservations/:id/pickup", controllers.PickupResourceReservation)
+		router.POST("/resource-reservations/:id/return", controllers.ReturnResourceReservation)
+
+		router.GET("/calendar/token", controllers.GetCalendarFeedToken)
+		router.POST("/calendar/token/regenerate", controllers.RegenerateCalendarFeedToken)
+		router.GET("/calendar/feed/:token", controllers.GetUserCalendarFeed)
+
+		// Routes for Activity Approval
+		router.GET("/approval-chain", controllers.GetApprovalChain)
+		router.PUT("/approval-chain", controllers.UpdateApprovalChain)
+		router.GET("/approvals/pending", controllers.GetPendingApprovals)
+
+		// Routes for Activity Reminders
+		router.GET("/reminder-rules", controllers.GetReminderRules)
+		router.PUT("/reminder-rules", controllers.UpdateReminderRules)
+
+		// Routes for Scheduled Jobs
+		router.GET("/jobs", controllers.GetScheduledJobs)
+		router.GET("/jobs/:name/runs", controllers.GetScheduledJobRuns)
+		router.POST("/jobs/:name/trigger", controllers.TriggerScheduledJob)
+		router.POST("/jobs/:name/pause", controllers.PauseScheduledJob)
+		router.POST("/jobs/:name/resume", controllers.ResumeScheduledJob)
+
+		// Routes for Teams
+		router.GET("/teams/:id", controllers.GetTeamByID)
+		router.DELETE("/teams/:id", controllers.CancelTeam)
+		router.POST("/teams/:id/invite", controllers.InviteTeamMember)
+		router.POST("/teams/:id/accept", controllers.AcceptTeamInvite)
+		router.POST("/teams/:id/decline", controllers.DeclineTeamInvite)
+		router.POST("/teams/:id/confirm", controllers.ConfirmTeam)
+		router.DELETE("/teams/:id/members/:userId", controllers.RemoveTeamMember)
 
 		// Routes for University
 		router.POST("/university", controllers.CreateUniversity)
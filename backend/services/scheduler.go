@@ -0,0 +1,290 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"final-project/cems/entity"
+	"final-project/cems/utils"
+
+	"gorm.io/gorm"
+)
+
+// JobFunc คืองานที่ scheduler เรียก ถ้าคืน error จะถือว่ารันไม่สำเร็จและ retry ตาม MaxRetries
+type JobFunc func(db *gorm.DB) error
+
+type registeredJob struct {
+	name        string
+	description string
+	cronExpr    string
+	maxRetries  int
+	run         JobFunc
+}
+
+var ErrJobLocked = errors.New("job is already running")
+var ErrJobNotFound = errors.New("job not found")
+
+type Scheduler struct {
+	DB         *gorm.DB
+	RunnerID   string
+	Interval   time.Duration // ความถี่ในการตรวจงานที่ถึงเวลา
+	LockTTL    time.Duration // อายุของ lock ต่อการต่ออายุหนึ่งครั้ง งานที่รันนานจะต่ออายุทุก 1/3 ของค่านี้
+	RetryDelay time.Duration
+
+	jobs  map[string]*registeredJob
+	mutex sync.RWMutex
+	stop  chan struct{}
+}
+
+func NewScheduler(db *gorm.DB) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{
+		DB:         db,
+		RunnerID:   fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano()),
+		Interval:   30 * time.Second,
+		LockTTL:    30 * time.Minute,
+		RetryDelay: time.Minute,
+		jobs:       make(map[string]*registeredJob),
+	}
+}
+
+// Register ลงทะเบียนงาน cronExpr เป็นค่าเริ่มต้น ถ้ามีงานชื่อนี้ใน DB อยู่แล้วจะใช้ค่าใน DB
+func (s *Scheduler) Register(name, description, cronExpr string, maxRetries int, run JobFunc) error {
+	if _, err := utils.ParseCron(cronExpr); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.jobs[name] = &registeredJob{
+		name:        name,
+		description: description,
+		cronExpr:    cronExpr,
+		maxRetries:  maxRetries,
+		run:         run,
+	}
+	s.mutex.Unlock()
+
+	var job entity.ScheduledJob
+	err := s.DB.Where("name = ?", name).First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		job = entity.ScheduledJob{
+			Name:        name,
+			Description: description,
+			CronExpr:    cronExpr,
+			MaxRetries:  maxRetries,
+		}
+		if next := nextRun(cronExpr, time.Now()); next != nil {
+			job.NextRunAt = next
+		}
+		return s.DB.Create(&job).Error
+	}
+	if err != nil {
+		return err
+	}
+	if job.NextRunAt == nil {
+		return s.DB.Model(&job).Update("next_run_at", nextRun(job.CronExpr, time.Now())).Error
+	}
+	return nil
+}
+
+func nextRun(cronExpr string, from time.Time) *time.Time {
+	schedule, err := utils.ParseCron(cronExpr)
+	if err != nil {
+		return nil
+	}
+	next := schedule.Next(from)
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
+
+// Start เริ่ม loop ตรวจงานที่ถึงเวลาใน goroutine แยก
+func (s *Scheduler) Start() {
+	s.stop = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+		s.tick()
+		for {
+			select {
+			case <-ticker.C:
+				s.tick()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	log.Printf("⏰ Scheduler started (runner %s)", s.RunnerID)
+}
+
+func (s *Scheduler) Stop() {
+	if s.stop != nil {
+		close(s.stop)
+	}
+}
+
+func (s *Scheduler) tick() {
+	now := time.Now()
+	var due []entity.ScheduledJob
+	if err := s.DB.Where("paused = ? AND next_run_at IS NOT NULL AND next_run_at <= ?", false, now).
+		Find(&due).Error; err != nil {
+		log.Println("❌ Scheduler: failed to load due jobs:", err)
+		return
+	}
+	for _, job := range due {
+		if !s.acquire(job, now, true) {
+			continue
+		}
+		go s.execute(job, "schedule")
+	}
+}
+
+// acquire จอง lock ของงานด้วย UPDATE แบบมีเงื่อนไข instance ที่ UPDATE สำเร็จก่อนเท่านั้นที่ได้รัน
+// งานตามตารางจะเลื่อน next_run_at ไปพร้อมกันเพื่อไม่ให้ถูกหยิบซ้ำ
+func (s *Scheduler) acquire(job entity.ScheduledJob, now time.Time, scheduled bool) bool {
+	lockedUntil := now.Add(s.LockTTL)
+	updates := map[string]interface{}{
+		"locked_by":    s.RunnerID,
+		"locked_until": lockedUntil,
+	}
+	query := s.DB.Model(&entity.ScheduledJob{}).
+		Where("id = ? AND (locked_until IS NULL OR locked_until < ?)", job.ID, now)
+	if scheduled {
+		updates["next_run_at"] = nextRun(job.CronExpr, now)
+		query = query.Where("paused = ? AND next_run_at <= ?", false, now)
+	}
+	result := query.Updates(updates)
+	if result.Error != nil {
+		log.Printf("❌ Scheduler: failed to lock job %s: %v", job.Name, result.Error)
+		return false
+	}
+	return result.RowsAffected == 1
+}
+
+func (s *Scheduler) release(job entity.ScheduledJob, status string) {
+	now := time.Now()
+	s.DB.Model(&entity.ScheduledJob{}).
+		Where("id = ? AND locked_by = ?", job.ID, s.RunnerID).
+		Updates(map[string]interface{}{
+			"locked_by":    "",
+			"locked_until": nil,
+			"last_run_at":  now,
+			"last_status":  status,
+		})
+}
+
+func (s *Scheduler) execute(job entity.ScheduledJob, trigger string) {
+	s.mutex.RLock()
+	registered := s.jobs[job.Name]
+	s.mutex.RUnlock()
+
+	status := "failed"
+	defer func() { s.release(job, status) }()
+
+	// ต่ออายุ lock ระหว่างที่งานยังรันอยู่ กันไม่ให้ instance อื่นหยิบงานเดียวกันไปรันซ้อนเมื่อเกิน LockTTL
+	done := make(chan struct{})
+	defer close(done)
+	go s.heartbeat(job, done)
+
+	if registered == nil {
+		log.Printf("❌ Scheduler: job %s has no handler on this server", job.Name)
+		return
+	}
+
+	for attempt := 1; attempt <= job.MaxRetries+1; attempt++ {
+		run := entity.JobRun{
+			JobID:     job.ID,
+			Trigger:   trigger,
+			Attempt:   attempt,
+			RunnerID:  s.RunnerID,
+			Status:    "running",
+			StartedAt: time.Now(),
+		}
+		s.DB.Create(&run)
+
+		err := runJobSafely(registered.run, s.DB)
+
+		finishedAt := time.Now()
+		run.FinishedAt = &finishedAt
+		run.Status = "success"
+		if err != nil {
+			run.Status = "failed"
+			run.Error = err.Error()
+		}
+		s.DB.Save(&run)
+
+		if err == nil {
+			status = "success"
+			return
+		}
+		log.Printf("❌ Scheduler: job %s attempt %d failed: %v", job.Name, attempt, err)
+		if attempt <= job.MaxRetries {
+			time.Sleep(s.RetryDelay)
+		}
+	}
+}
+
+// heartbeat เลื่อน locked_until ทุก 1/3 ของ LockTTL จนกว่า done จะถูกปิด
+func (s *Scheduler) heartbeat(job entity.ScheduledJob, done <-chan struct{}) {
+	ticker := time.NewTicker(s.LockTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			if err := s.DB.Model(&entity.ScheduledJob{}).
+				Where("id = ? AND locked_by = ?", job.ID, s.RunnerID).
+				Update("locked_until", now.Add(s.LockTTL)).Error; err != nil {
+				log.Printf("❌ Scheduler: failed to renew lock of job %s: %v", job.Name, err)
+			}
+		}
+	}
+}
+
+// กัน panic ในงานหนึ่งไม่ให้ทำให้ทั้งเซิร์ฟเวอร์ล่ม
+func runJobSafely(run JobFunc, db *gorm.DB) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return run(db)
+}
+
+// Trigger สั่งรันงานทันทีโดยไม่กระทบรอบตามตาราง
+func (s *Scheduler) Trigger(name string) (*entity.ScheduledJob, error) {
+	var job entity.ScheduledJob
+	if err := s.DB.Where("name = ?", name).First(&job).Error; err != nil {
+		return nil, ErrJobNotFound
+	}
+	if !s.acquire(job, time.Now(), false) {
+		return nil, ErrJobLocked
+	}
+	go s.execute(job, "manual")
+	return &job, nil
+}
+
+// SetPaused หยุดหรือเปิดการรันตามตารางของงาน เมื่อเปิดอีกครั้งจะคำนวณรอบถัดไปใหม่
+func (s *Scheduler) SetPaused(name string, paused bool) (*entity.ScheduledJob, error) {
+	var job entity.ScheduledJob
+	if err := s.DB.Where("name = ?", name).First(&job).Error; err != nil {
+		return nil, ErrJobNotFound
+	}
+	updates := map[string]interface{}{"paused": paused}
+	if !paused {
+		updates["next_run_at"] = nextRun(job.CronExpr, time.Now())
+	}
+	if err := s.DB.Model(&job).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	if err := s.DB.First(&job, job.ID).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
@@ -0,0 +1,21 @@
+package utils
+
+import "time"
+
+// ปีการศึกษาเริ่มต้นเดือนกรกฎาคม (ภาคการศึกษาที่ 1)
+const AcademicYearStartMonth = time.July
+
+// AcademicYearOf คืนปีการศึกษา (พ.ศ.) ของวันที่ เช่น มี.ค. 2026 อยู่ในปีการศึกษา 2568
+func AcademicYearOf(t time.Time) int {
+	year := t.Year()
+	if t.Month() < AcademicYearStartMonth {
+		year--
+	}
+	return year + 543
+}
+
+// AcademicYearRange คืนช่วงเวลา [start, end) ของปีการศึกษา (พ.ศ.)
+func AcademicYearRange(academicYear int, loc *time.Location) (time.Time, time.Time) {
+	start := time.Date(academicYear-543, AcademicYearStartMonth, 1, 0, 0, 0, 0, loc)
+	return start, start.AddDate(1, 0, 0)
+}
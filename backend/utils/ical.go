@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ICalEvent คือ VEVENT หนึ่งรายการในไฟล์ iCalendar (RFC 5545)
+// UID ต้องคงที่ตลอดอายุของกิจกรรม และ Sequence ต้องเพิ่มทุกครั้งที่แก้ไข ปฏิทินปลายทางจึงจะอัปเดตรายการเดิม
+type ICalEvent struct {
+	UID          string
+	Sequence     int
+	Summary      string
+	Description  string
+	Location     string
+	URL          string
+	Start        time.Time
+	End          time.Time
+	LastModified time.Time
+	Cancelled    bool
+	Tentative    bool
+}
+
+const icalTimeFormat = "20060102T150405Z"
+
+// BuildICalendar สร้างเนื้อหาไฟล์ .ics จากรายการกิจกรรม
+func BuildICalendar(name string, events []ICalEvent) string {
+	var b strings.Builder
+	write := func(line string) {
+		b.WriteString(foldICalLine(line))
+		b.WriteString("\r\n")
+	}
+
+	write("BEGIN:VCALENDAR")
+	write("VERSION:2.0")
+	write("PRODID:-//CEMS//Club Event Management System//TH")
+	write("CALSCALE:GREGORIAN")
+	write("METHOD:PUBLISH")
+	write("X-WR-CALNAME:" + escapeICalText(name))
+	write("X-WR-TIMEZONE:Asia/Bangkok")
+
+	now := time.Now().UTC().Format(icalTimeFormat)
+	for _, e := range events {
+		write("BEGIN:VEVENT")
+		write("UID:" + e.UID)
+		write(fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+		write("DTSTAMP:" + now)
+		write("DTSTART:" + e.Start.UTC().Format(icalTimeFormat))
+		write("DTEND:" + e.End.UTC().Format(icalTimeFormat))
+		if !e.LastModified.IsZero() {
+			write("LAST-MODIFIED:" + e.LastModified.UTC().Format(icalTimeFormat))
+		}
+		write("SUMMARY:" + escapeICalText(e.Summary))
+		if e.Description != "" {
+			write("DESCRIPTION:" + escapeICalText(e.Description))
+		}
+		if e.Location != "" {
+			write("LOCATION:" + escapeICalText(e.Location))
+		}
+		if e.URL != "" {
+			write("URL:" + e.URL)
+		}
+		if e.Cancelled {
+			write("STATUS:CANCELLED")
+		} else if e.Tentative {
+			write("STATUS:TENTATIVE")
+		} else {
+			write("STATUS:CONFIRMED")
+		}
+		write("END:VEVENT")
+	}
+
+	write("END:VCALENDAR")
+	return b.String()
+}
+
+func escapeICalText(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, ";", `\;`)
+	s = strings.ReplaceAll(s, ",", `\,`)
+	s = strings.ReplaceAll(s, "\r\n", `\n`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return s
+}
+
+// ตัดบรรทัดที่ยาวเกิน 75 octets ตาม RFC 5545 โดยไม่ตัดกลางตัวอักษร UTF-8
+func foldICalLine(line string) string {
+	if len(line) <= 75 {
+		return line
+	}
+	var b strings.Builder
+	width := 0
+	limit := 75
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 0
+			limit = 74 // บรรทัดต่อมีช่องว่างนำหน้าหนึ่ง octet
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
@@ -0,0 +1,221 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"net/http"
+
+	// ลงทะเบียน decoder ให้ image.Decode รู้จัก png และ gif
+	_ "image/gif"
+	_ "image/png"
+)
+
+// ชนิดรูปภาพที่ถอดรหัสได้ด้วย standard library
+var decodableImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// SniffImageType ตรวจชนิดไฟล์จากเนื้อไฟล์จริง (ไม่เชื่อนามสกุลหรือ Content-Type ที่ client ส่งมา)
+// คืน "" ถ้าไม่ใช่รูปภาพที่รองรับ
+func SniffImageType(data []byte) string {
+	mime := http.DetectContentType(data)
+	if decodableImageTypes[mime] {
+		return mime
+	}
+	return ""
+}
+
+// DecodeImage ถอดรหัสรูปแล้วหมุนตาม EXIF orientation
+// ผลลัพธ์เป็นพิกเซลล้วน ข้อมูล EXIF/metadata ทั้งหมดจึงหายไปเมื่อเข้ารหัสใหม่
+func DecodeImage(data []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return applyOrientation(img, jpegOrientation(data)), nil
+}
+
+// DecodeImageConfig อ่านขนาดรูปโดยไม่ถอดรหัสทั้งไฟล์ ใช้กันไฟล์ที่ขยายแล้วใหญ่ผิดปกติ
+func DecodeImageConfig(data []byte) (image.Config, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	return cfg, err
+}
+
+// ResizeToFit ย่อรูปให้อยู่ในกรอบ maxW x maxH โดยคงสัดส่วน ไม่ขยายรูปที่เล็กกว่ากรอบ
+// ใช้การเฉลี่ยพิกเซลในพื้นที่ (box filter) ซึ่งเพียงพอสำหรับการย่อ
+func ResizeToFit(src image.Image, maxW, maxH int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxW && h <= maxH {
+		return src
+	}
+	dw, dh := maxW, h*maxW/w
+	if dh > maxH {
+		dw, dh = w*maxH/h, maxH
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	rgba := toRGBA(src)
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, (y+1)*h/dh
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, (x+1)*w/dw
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				off := rgba.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint64(rgba.Pix[off])
+					g += uint64(rgba.Pix[off+1])
+					bl += uint64(rgba.Pix[off+2])
+					a += uint64(rgba.Pix[off+3])
+					off += 4
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(bl / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// EncodeJPEG เข้ารหัสเป็น JPEG โดยปูพื้นสีขาวใต้ส่วนโปร่งใส (JPEG ไม่มี alpha)
+func EncodeJPEG(w io.Writer, img image.Image, quality int) error {
+	b := img.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(flat, flat.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, b.Min, draw.Over)
+	return jpeg.Encode(w, flat, &jpeg.Options{Quality: quality})
+}
+
+func toRGBA(src image.Image) *image.RGBA {
+	if rgba, ok := src.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	b := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+	return rgba
+}
+
+// jpegOrientation อ่านค่า Orientation (tag 0x0112) จาก EXIF ใน APP1 ของ JPEG
+// คืน 1 (ปกติ) ถ้าไม่ใช่ JPEG หรือไม่มีข้อมูล
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		// SOS หรือ EOI แปลว่าเลยส่วน header ไปแล้ว
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if size < 2 || pos+2+size > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+size]
+		if marker == 0xE1 && len(segment) > 14 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		pos += 2 + size
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			v := int(order.Uint16(tiff[entry+8:]))
+			if v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyOrientation หมุน/กลับรูปให้ตรงตามค่า EXIF orientation 1-8
+func applyOrientation(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // กลับซ้ายขวา
+				dx, dy = w-1-x, y
+			case 3: // หมุน 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // กลับบนล่าง
+				dx, dy = x, h-1-y
+			case 5: // transpose
+				dx, dy = y, x
+			case 6: // หมุนตามเข็ม 90
+				dx, dy = h-1-y, x
+			case 7: // transverse
+				dx, dy = h-1-y, w-1-x
+			case 8: // หมุนทวนเข็ม 90
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, src.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,344 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RRule คือกฎการเกิดซ้ำตาม RFC 5545 เฉพาะส่วนที่ใช้กับกิจกรรมชมรม
+// รองรับ FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY และ BYMONTH
+type RRule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []RRuleWeekday
+	ByMonthDay []int
+	ByMonth    []int
+}
+
+// RRuleWeekday เช่น MO (N = 0) หรือ 1MO, -1FR สำหรับ "จันทร์แรก" และ "ศุกร์สุดท้าย" ของเดือน
+type RRuleWeekday struct {
+	N       int
+	Weekday time.Weekday
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// ParseRRule แปลงข้อความเช่น "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10" (จะมี "RRULE:" นำหน้าหรือไม่ก็ได้)
+func ParseRRule(s string) (*RRule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	r := &RRule{Interval: 1}
+
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("RRULE ไม่ถูกต้อง: %q", part)
+		}
+		key, value := strings.ToUpper(kv[0]), strings.ToUpper(kv[1])
+
+		switch key {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				r.Freq = value
+			default:
+				return nil, fmt.Errorf("ไม่รองรับ FREQ=%s", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("INTERVAL ไม่ถูกต้อง")
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("COUNT ไม่ถูกต้อง")
+			}
+			r.Count = n
+		case "UNTIL":
+			t, err := parseRRuleTime(value)
+			if err != nil {
+				return nil, err
+			}
+			r.Until = t
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				if len(d) < 2 {
+					return nil, fmt.Errorf("BYDAY ไม่ถูกต้อง: %q", d)
+				}
+				wd, ok := rruleWeekdays[d[len(d)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("BYDAY ไม่ถูกต้อง: %q", d)
+				}
+				n := 0
+				if prefix := d[:len(d)-2]; prefix != "" {
+					var err error
+					if n, err = strconv.Atoi(prefix); err != nil || n == 0 || n < -5 || n > 5 {
+						return nil, fmt.Errorf("BYDAY ไม่ถูกต้อง: %q", d)
+					}
+				}
+				r.ByDay = append(r.ByDay, RRuleWeekday{N: n, Weekday: wd})
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(d)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("BYMONTHDAY ไม่ถูกต้อง: %q", d)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, m := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(m)
+				if err != nil || n < 1 || n > 12 {
+					return nil, fmt.Errorf("BYMONTH ไม่ถูกต้อง: %q", m)
+				}
+				r.ByMonth = append(r.ByMonth, n)
+			}
+		case "WKST":
+			// ใช้วันจันทร์เป็นวันแรกของสัปดาห์เสมอ
+		default:
+			return nil, fmt.Errorf("ไม่รองรับ %s ใน RRULE", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("RRULE ต้องระบุ FREQ")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, fmt.Errorf("RRULE ระบุ COUNT และ UNTIL พร้อมกันไม่ได้")
+	}
+	return r, nil
+}
+
+func parseRRuleTime(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// UNTIL แบบวันที่ให้รวมทั้งวัน
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("UNTIL ไม่ถูกต้อง: %q", value)
+}
+
+// String แปลงกลับเป็นข้อความ RRULE
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			code := strings.ToUpper(d.Weekday.String()[:2])
+			if d.N != 0 {
+				code = strconv.Itoa(d.N) + code
+			}
+			days[i] = code
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	return strings.Join(parts, ";")
+}
+
+// ShiftDays เลื่อน BYDAY และ BYMONTHDAY ตามจำนวนวันที่เลื่อน dtstart เพื่อให้กฎยังตรงกับวันใหม่
+func (r *RRule) ShiftDays(n int) {
+	for i, d := range r.ByDay {
+		r.ByDay[i].Weekday = time.Weekday(((int(d.Weekday)+n)%7 + 7) % 7)
+	}
+	for i, d := range r.ByMonthDay {
+		if shifted := d + n; d > 0 && shifted >= 1 && shifted <= 31 {
+			r.ByMonthDay[i] = shifted
+		}
+	}
+}
+
+func joinInts(values []int) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = strconv.Itoa(v)
+	}
+	return strings.Join(s, ",")
+}
+
+// Occurrences คืนวันเวลาที่เกิดขึ้นทั้งหมดโดยเริ่มจาก dtstart (ไม่เกิน limit รายการ)
+func (r *RRule) Occurrences(dtstart time.Time, limit int) []time.Time {
+	var result []time.Time
+	count := 0
+
+	// ถ้าไม่มี COUNT/UNTIL จะหยุดที่ limit หรือเมื่อวนครบจำนวนรอบสูงสุด
+	for period := 0; period < 10000; period++ {
+		candidates := r.expandPeriod(dtstart, period)
+		for _, t := range candidates {
+			if t.Before(dtstart) {
+				continue
+			}
+			if !r.Until.IsZero() && t.After(r.Until) {
+				return result
+			}
+			result = append(result, t)
+			count++
+			if (r.Count > 0 && count >= r.Count) || len(result) >= limit {
+				return result
+			}
+		}
+	}
+	return result
+}
+
+func (r *RRule) expandPeriod(dtstart time.Time, period int) []time.Time {
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, dtstart.Location())
+	}
+	step := period * r.Interval
+	var days []time.Time
+
+	switch r.Freq {
+	case "DAILY":
+		days = []time.Time{at(dtstart.Year(), dtstart.Month(), dtstart.Day()+step)}
+	case "WEEKLY":
+		offset := (int(dtstart.Weekday()) + 6) % 7 // จำนวนวันนับจากวันจันทร์
+		monday := at(dtstart.Year(), dtstart.Month(), dtstart.Day()-offset+7*step)
+		if len(r.ByDay) == 0 {
+			days = []time.Time{monday.AddDate(0, 0, offset)}
+		} else {
+			for _, d := range r.ByDay {
+				days = append(days, monday.AddDate(0, 0, (int(d.Weekday)+6)%7))
+			}
+		}
+	case "MONTHLY":
+		first := at(dtstart.Year(), dtstart.Month()+time.Month(step), 1)
+		days = r.expandMonth(first, dtstart.Day())
+	case "YEARLY":
+		year := dtstart.Year() + step
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []int{int(dtstart.Month())}
+		}
+		for _, m := range months {
+			first := at(year, time.Month(m), 1)
+			if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+				if d := at(year, time.Month(m), dtstart.Day()); d.Month() == time.Month(m) {
+					days = append(days, d)
+				}
+				continue
+			}
+			days = append(days, r.expandMonth(first, dtstart.Day())...)
+		}
+	}
+
+	var result []time.Time
+	for _, d := range days {
+		if r.matchesFilters(d) {
+			result = append(result, d)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Before(result[j]) })
+	return result
+}
+
+// วันในเดือนตาม BYMONTHDAY/BYDAY ถ้าไม่ระบุทั้งสองใช้วันที่เดียวกับ dtstart (ข้ามเดือนที่ไม่มีวันนั้น)
+func (r *RRule) expandMonth(first time.Time, defaultDay int) []time.Time {
+	daysInMonth := first.AddDate(0, 1, -1).Day()
+	var days []time.Time
+
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, d := range r.ByMonthDay {
+			if d < 0 {
+				d = daysInMonth + d + 1
+			}
+			if d >= 1 && d <= daysInMonth {
+				days = append(days, first.AddDate(0, 0, d-1))
+			}
+		}
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			var matches []time.Time
+			for d := 0; d < daysInMonth; d++ {
+				if t := first.AddDate(0, 0, d); t.Weekday() == wd.Weekday {
+					matches = append(matches, t)
+				}
+			}
+			switch {
+			case wd.N == 0:
+				days = append(days, matches...)
+			case wd.N > 0 && wd.N <= len(matches):
+				days = append(days, matches[wd.N-1])
+			case wd.N < 0 && -wd.N <= len(matches):
+				days = append(days, matches[len(matches)+wd.N])
+			}
+		}
+	default:
+		if defaultDay <= daysInMonth {
+			days = append(days, first.AddDate(0, 0, defaultDay-1))
+		}
+	}
+	return days
+}
+
+func (r *RRule) matchesFilters(t time.Time) bool {
+	if len(r.ByMonth) > 0 && !containsInt(r.ByMonth, int(t.Month())) {
+		return false
+	}
+	// BYDAY เป็นตัวกรองเมื่อใช้กับ DAILY หรือ MONTHLY ที่มี BYMONTHDAY
+	if len(r.ByDay) > 0 && (r.Freq == "DAILY" || len(r.ByMonthDay) > 0) {
+		ok := false
+		for _, d := range r.ByDay {
+			if d.Weekday == t.Weekday() {
+				ok = true
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq == "DAILY" {
+		daysInMonth := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+		ok := false
+		for _, d := range r.ByMonthDay {
+			if d == t.Day() || daysInMonth+d+1 == t.Day() {
+				ok = true
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -1,10 +1,13 @@
 package utils
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"golang.org/x/crypto/bcrypt"
 	"log"
+	"strings"
 )
 
 // สร้าง Token ที่ปลอดภัย
@@ -34,3 +37,24 @@ func Must(err error) {
 		log.Fatal(err)
 	}
 }
+
+// SignToken ต่อท้าย token ด้วยลายเซ็น HMAC-SHA256 ในรูปแบบ <token>.<signature>
+// ใช้กับลิงก์สาธารณะเพื่อให้ตรวจได้ก่อนค้นฐานข้อมูลว่าไม่ได้ถูกแก้หรือเดาขึ้นมา
+func SignToken(secret, purpose, token string) string {
+	return token + "." + tokenSignature(secret, purpose, token)
+}
+
+// VerifySignedToken ตรวจลายเซ็นของ token ที่สร้างด้วย SignToken คืน token เดิมถ้าถูกต้อง
+func VerifySignedToken(secret, purpose, signed string) (string, bool) {
+	token, sig, ok := strings.Cut(signed, ".")
+	if !ok || token == "" {
+		return "", false
+	}
+	return token, hmac.Equal([]byte(sig), []byte(tokenSignature(secret, purpose, token)))
+}
+
+func tokenSignature(secret, purpose, token string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose + ":" + token))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// สร้างรหัส 6 หลักแบบ TOTP (RFC 6238) ที่เปลี่ยนทุก interval วินาที
+func GenerateRotatingCode(secret string, t time.Time, interval int) string {
+	counter := uint64(t.Unix() / int64(interval))
+	return hotp(secret, counter)
+}
+
+// ตรวจรหัสโดยยอมรับช่วงเวลาปัจจุบันและช่วงก่อนหน้า เผื่อผู้ใช้พิมพ์ช้า
+func VerifyRotatingCode(secret, code string, t time.Time, interval int) bool {
+	counter := uint64(t.Unix() / int64(interval))
+	for _, c := range []uint64{counter, counter - 1} {
+		if hmac.Equal([]byte(hotp(secret, c)), []byte(code)) {
+			return true
+		}
+	}
+	return false
+}
+
+func hotp(secret string, counter uint64) string {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, counter)
+
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write(buf)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+// ระยะทางระหว่างพิกัดสองจุดเป็นเมตร (สูตร haversine)
+func DistanceMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadius = 6371000.0
+	toRad := func(d float64) float64 { return d * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return earthRadius * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}